@@ -52,6 +52,9 @@ var SendNfDiscoveryToNrf = func(nrfUri string, targetNfType, requesterNfType mod
 		err = fmt.Errorf("temporary redirect for non NRF consumer")
 	}
 	defer func() {
+		if res == nil {
+			return
+		}
 		if bodyCloseErr := res.Body.Close(); bodyCloseErr != nil {
 			err = fmt.Errorf("SearchNFInstances' response body cannot close: %w", bodyCloseErr)
 		}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"sync"
+	"time"
+)
+
+// AuthFailureTracker counts the consecutive authentication failures of the SUPIs in each serving
+// network and blocks the authentication of a SUPI in a serving network for the block duration once
+// the threshold is reached. Failures older than the block duration are forgotten.
+// All methods are safe to call on a nil tracker, which never blocks.
+type AuthFailureTracker struct {
+	threshold     int
+	blockDuration time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	entries   map[authFailureKey]*authFailureEntry
+	lastPurge time.Time
+}
+
+type authFailureKey struct {
+	supi               string
+	servingNetworkName string
+}
+
+type authFailureEntry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+func NewAuthFailureTracker(threshold int, blockDuration time.Duration) *AuthFailureTracker {
+	return &AuthFailureTracker{
+		threshold:     threshold,
+		blockDuration: blockDuration,
+		now:           time.Now,
+		entries:       make(map[authFailureKey]*authFailureEntry),
+	}
+}
+
+// RecordFailure counts a failed authentication of supi in the serving network and reports whether
+// it reached the threshold, blocking the authentication of supi there
+func (t *AuthFailureTracker) RecordFailure(supi string, servingNetworkName string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.purge(now)
+	key := authFailureKey{supi: supi, servingNetworkName: servingNetworkName}
+	entry, ok := t.entries[key]
+	if !ok || t.expired(entry, now) {
+		entry = &authFailureEntry{}
+		t.entries[key] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+	if entry.failures < t.threshold {
+		return false
+	}
+	entry.failures = 0
+	entry.blockedUntil = now.Add(t.blockDuration)
+	return true
+}
+
+// RecordSuccess forgets the failures of supi in the serving network
+func (t *AuthFailureTracker) RecordSuccess(supi string, servingNetworkName string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := authFailureKey{supi: supi, servingNetworkName: servingNetworkName}
+	if entry, ok := t.entries[key]; ok && !entry.blockedUntil.After(t.now()) {
+		delete(t.entries, key)
+	}
+}
+
+// BlockedUntil returns the end of the block of the authentication of supi in the serving network,
+// and whether it is blocked
+func (t *AuthFailureTracker) BlockedUntil(supi string, servingNetworkName string) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[authFailureKey{supi: supi, servingNetworkName: servingNetworkName}]
+	if !ok || !entry.blockedUntil.After(t.now()) {
+		return time.Time{}, false
+	}
+	return entry.blockedUntil, true
+}
+
+// Len returns the number of SUPIs and serving networks with failures or blocked
+func (t *AuthFailureTracker) Len() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.entries)
+}
+
+func (t *AuthFailureTracker) expired(entry *authFailureEntry, now time.Time) bool {
+	return !entry.blockedUntil.After(now) && now.Sub(entry.lastFailure) >= t.blockDuration
+}
+
+// purge removes the expired entries, at most once per block duration
+func (t *AuthFailureTracker) purge(now time.Time) {
+	if now.Sub(t.lastPurge) < t.blockDuration {
+		return
+	}
+	t.lastPurge = now
+	for key, entry := range t.entries {
+		if t.expired(entry, now) {
+			delete(t.entries, key)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuthFailureTracker(t *testing.T) {
+	const (
+		supi        = "imsi-208930000000001"
+		snn         = "5G:mnc093.mcc208.3gppnetwork.org"
+		otherSnn    = "5G:mnc001.mcc001.3gppnetwork.org"
+		blockPeriod = time.Minute
+	)
+	now := time.Now()
+	tracker := NewAuthFailureTracker(3, blockPeriod)
+	tracker.now = func() time.Time { return now }
+
+	// a success resets the consecutive failures
+	tracker.RecordFailure(supi, snn)
+	tracker.RecordFailure(supi, snn)
+	tracker.RecordSuccess(supi, snn)
+	if tracker.RecordFailure(supi, snn) || tracker.RecordFailure(supi, snn) {
+		t.Fatalf("expected the failures before the success to be forgotten")
+	}
+	if !tracker.RecordFailure(supi, snn) {
+		t.Fatalf("expected the threshold to be reached")
+	}
+	blockedUntil, blocked := tracker.BlockedUntil(supi, snn)
+	if !blocked || !blockedUntil.Equal(now.Add(blockPeriod)) {
+		t.Fatalf("expected %s to be blocked until %v, got %v %v", supi, now.Add(blockPeriod), blocked, blockedUntil)
+	}
+	if _, blocked = tracker.BlockedUntil(supi, otherSnn); blocked {
+		t.Errorf("expected %s not to be blocked in another serving network", supi)
+	}
+	tracker.RecordSuccess(supi, snn)
+	if _, blocked = tracker.BlockedUntil(supi, snn); !blocked {
+		t.Errorf("expected a success not to lift the block")
+	}
+
+	now = now.Add(blockPeriod)
+	if _, blocked = tracker.BlockedUntil(supi, snn); blocked {
+		t.Errorf("expected the block to be lifted after %v", blockPeriod)
+	}
+
+	// failures older than the block duration are forgotten and purged
+	tracker.RecordFailure(supi, otherSnn)
+	tracker.RecordFailure(supi, otherSnn)
+	now = now.Add(blockPeriod)
+	if tracker.RecordFailure(supi, otherSnn) {
+		t.Errorf("expected the old failures to be forgotten")
+	}
+	if tracker.Len() != 1 {
+		t.Errorf("expected the expired entries to be purged, got %d entries", tracker.Len())
+	}
+
+	var disabled *AuthFailureTracker
+	for range 10 {
+		if disabled.RecordFailure(supi, snn) {
+			t.Fatalf("expected a disabled tracker not to block")
+		}
+	}
+	if _, blocked = disabled.BlockedUntil(supi, snn); blocked {
+		t.Errorf("expected a disabled tracker not to block")
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import "sync"
+
+// keyedLock is a set of mutexes created on demand for each key and removed once unused
+type keyedLock struct {
+	lock  sync.Mutex
+	locks map[string]*keyedLockEntry
+}
+
+type keyedLockEntry struct {
+	sync.Mutex
+	holders int // holding or waiting for the mutex
+}
+
+// acquire locks the mutex of the key, returning the function unlocking it
+func (l *keyedLock) acquire(key string) func() {
+	l.lock.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*keyedLockEntry)
+	}
+	entry, ok := l.locks[key]
+	if !ok {
+		entry = &keyedLockEntry{}
+		l.locks[key] = entry
+	}
+	entry.holders++
+	l.lock.Unlock()
+
+	entry.Lock()
+	return func() {
+		entry.Unlock()
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		if entry.holders--; entry.holders == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
+
+// LockAuthentication serializes the generation of the authentication vectors of a SUPI, so that the
+// requests of the UE handled in parallel do not read the same SQN. The returned function releases it.
+func (context *UDMContext) LockAuthentication(supi string) (unlock func()) {
+	return context.authenticationLocks.acquire(supi)
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockAuthentication(t *testing.T) {
+	udmContext := &UDMContext{}
+	const supi = "imsi-208930000000001"
+
+	// the lock of another SUPI is independent
+	unlock := udmContext.LockAuthentication(supi)
+	udmContext.LockAuthentication("imsi-208930000000002")()
+
+	acquired := make(chan struct{})
+	go func() {
+		defer udmContext.LockAuthentication(supi)()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatalf("expected the lock of %s to be held", supi)
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the lock of %s to be released", supi)
+	}
+
+	// the requests of a SUPI are serialized
+	var wg sync.WaitGroup
+	holding := 0
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer udmContext.LockAuthentication(supi)()
+			if holding++; holding != 1 {
+				t.Errorf("expected a single holder of the lock, got %d", holding)
+			}
+			holding--
+		}()
+	}
+	wg.Wait()
+
+	udmContext.authenticationLocks.lock.Lock()
+	defer udmContext.authenticationLocks.lock.Unlock()
+	if len(udmContext.authenticationLocks.locks) != 0 {
+		t.Errorf("expected the unused locks to be removed, got %d", len(udmContext.authenticationLocks.locks))
+	}
+}
@@ -8,17 +8,17 @@ package context
 
 import (
 	"fmt"
-	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/omec-project/openapi"
 	"github.com/omec-project/openapi/Nnrf_NFDiscovery"
 	"github.com/omec-project/openapi/models"
-	"github.com/omec-project/util/idgenerator"
-	"github.com/omec-project/util/util_3gpp/suci"
+	stats "github.com/omec-project/udm/metrics"
 )
 
 var udmContext UDMContext
@@ -26,14 +26,16 @@ var udmContext UDMContext
 const (
 	LocationUriAmf3GppAccessRegistration int = iota
 	LocationUriAmfNon3GppAccessRegistration
+	// Deprecated: a UE has several SMF registrations, use UdmUeContext.GetSmfRegistrationLocationURI.
 	LocationUriSmfRegistration
 	LocationUriSdmSubscription
 	LocationUriSharedDataSubscription
+	LocationUriSmsf3GppAccessRegistration
+	LocationUriSmsfNon3GppAccessRegistration
 )
 
 func init() {
 	UDM_Self().NfService = make(map[models.ServiceName]models.NfService)
-	UDM_Self().EeSubscriptionIDGenerator = idgenerator.NewGenerator(1, math.MaxInt32)
 }
 
 type UDMContext struct {
@@ -51,42 +53,69 @@ type UDMContext struct {
 	SharedSubsDataMap              map[string]models.SharedData // sharedDataIds as key
 	SubscriptionOfSharedDataChange sync.Map                     // subscriptionID as key
 	NfStatusSubscriptions          sync.Map                     // map[NfInstanceID]models.NrfSubscriptionData.SubscriptionId
-	SuciProfiles                   []suci.SuciProfile
-	EeSubscriptionIDGenerator      *idgenerator.IDGenerator
+	SuciKeyRing                    *SuciKeyRing
 	SBIPort                        int
 	EnableNrfCaching               bool
 	NrfCacheEvictionInterval       time.Duration
+	SubscriberDataCache            *SubscriberDataCache  // nil when the cache is disabled
+	UeContextIdleTimeout           time.Duration         // 0 disables the eviction of idle UE contexts
+	DefaultAmf                     []byte                // AMF of the subscribers without authenticationManagementField
+	SqnIndLength                   uint                  // 0 for the default IND length
+	SqnDelta                       uint64                // 0 for the default limit Δ
+	AuthFailureTracker             *AuthFailureTracker   // nil when the protection is disabled
+	SuciProtectionPolicy           *SuciProtectionPolicy // nil when all the protection schemes are accepted
+	udmUeCount                     atomic.Int64
+	ueIndexes                      ueIndexes
+	eeSubscriptions                map[string]map[string]*EeSubscriptionContext // ueIdentity and subscriptionID as keys
+	eeSubscriptionsLock            sync.RWMutex
+	eeSubscriptionsLoaded          map[string]bool // ueIdentity as key, under eeSubscriptionsLock
+	eeSubscriptionsLoadLocks       keyedLock       // ueIdentity as key
+	authenticationLocks            keyedLock       // SUPI as key
 }
 
 type UdmUeContext struct {
 	Supi                              string
-	Gpsi                              string
-	ExternalGroupID                   string
+	Gpsi                              string // set with UDMContext.SetUeGpsis/AddUeGpsi to keep the index
+	Pei                               string // set with UDMContext.SetUePei to keep the index
+	ExternalGroupID                   string // first external group, set with UDMContext.SetUeExternalGroupIDs
 	Nssai                             *models.Nssai
 	Amf3GppAccessRegistration         *models.Amf3GppAccessRegistration
 	AmfNon3GppAccessRegistration      *models.AmfNon3GppAccessRegistration
+	Smsf3GppAccessRegistration        *models.SmsfRegistration
+	SmsfNon3GppAccessRegistration     *models.SmsfRegistration
 	AccessAndMobilitySubscriptionData *models.AccessAndMobilitySubscriptionData
 	SmfSelSubsData                    *models.SmfSelectionSubscriptionData
 	UeCtxtInSmfData                   *models.UeContextInSmfData
+	UeCtxtInSmsfData                  *models.UeContextInSmsfData
+	SmsSubsData                       *models.SmsSubscriptionData
+	SmsMngData                        *models.SmsManagementSubscriptionData
 	TraceData                         *models.TraceData
 	SessionManagementSubsData         map[string]models.SessionManagementSubscriptionData
 	SubsDataSets                      *models.SubscriptionDataSets
 	SubscribeToNotifChange            map[string]*models.SdmSubscription
 	SubscribeToNotifSharedDataChange  *models.SdmSubscription
-	PduSessionID                      string
-	UdrUri                            string
+	smfRegistrations                  map[string]models.SmfRegistration // PDU session ID as key
+	udrUri                            string                            // guarded by stateLock
 	UdmSubsToNotify                   map[string]*models.SubscriptionDataSubscriptions
-	EeSubscriptions                   map[string]*models.EeSubscription // subscriptionID as key
 	TraceDataResponse                 models.TraceDataResponse
 	amSubsDataLock                    sync.Mutex
 	smfSelSubsDataLock                sync.Mutex
+	smsDataLock                       sync.Mutex
+	sdmSubscriptionLock               sync.RWMutex
+	udrSubscriptionLock               sync.Mutex
+	udrSubscribeLock                  sync.Mutex   // held while the data change subscription is created in the UDR
+	lastActivity                      atomic.Int64 // unix nanoseconds
 	SmSubsDataLock                    sync.RWMutex
+	smfRegistrationLock               sync.RWMutex
+	// stateLock is held by the writers of the registrations and the SDM subscriptions of the UE, which keep its context from being evicted, and by the eviction
+	stateLock sync.RWMutex
+	evicted   bool
 }
 
 func (ue *UdmUeContext) init() {
 	ue.UdmSubsToNotify = make(map[string]*models.SubscriptionDataSubscriptions)
-	ue.EeSubscriptions = make(map[string]*models.EeSubscription)
 	ue.SubscribeToNotifChange = make(map[string]*models.SdmSubscription)
+	ue.smfRegistrations = make(map[string]models.SmfRegistration)
 }
 
 type UdmNFContext struct {
@@ -191,13 +220,83 @@ func (context *UDMContext) CreateTraceDataforUe(supi string, body models.TraceDa
 	ue.TraceData = &body
 }
 
+// SetUdrUri records the apiRoot of the UDR serving the UE
+func (udmUeContext *UdmUeContext) SetUdrUri(udrUri string) {
+	udmUeContext.stateLock.Lock()
+	defer udmUeContext.stateLock.Unlock()
+	udmUeContext.udrUri = udrUri
+}
+
+// UdrUri returns the apiRoot of the UDR serving the UE
+func (udmUeContext *UdmUeContext) UdrUri() string {
+	udmUeContext.stateLock.RLock()
+	defer udmUeContext.stateLock.RUnlock()
+	return udmUeContext.udrUri
+}
+
 // functions related to sdmSubscription (subscribe to notification of data change)
 func (udmUeContext *UdmUeContext) CreateSubscriptiontoNotifChange(subscriptionID string, body *models.SdmSubscription) {
+	udmUeContext.stateLock.Lock()
+	defer udmUeContext.stateLock.Unlock()
+	udmUeContext.sdmSubscriptionLock.Lock()
+	defer udmUeContext.sdmSubscriptionLock.Unlock()
 	if _, exist := udmUeContext.SubscribeToNotifChange[subscriptionID]; !exist {
 		udmUeContext.SubscribeToNotifChange[subscriptionID] = body
 	}
 }
 
+func (udmUeContext *UdmUeContext) DeleteSubscriptiontoNotifChange(subscriptionID string) {
+	udmUeContext.stateLock.Lock()
+	defer udmUeContext.stateLock.Unlock()
+	udmUeContext.sdmSubscriptionLock.Lock()
+	defer udmUeContext.sdmSubscriptionLock.Unlock()
+	delete(udmUeContext.SubscribeToNotifChange, subscriptionID)
+}
+
+// SdmSubscriptionsToNotify returns a snapshot of the sdmSubscriptions of the UE
+func (udmUeContext *UdmUeContext) SdmSubscriptionsToNotify() []*models.SdmSubscription {
+	udmUeContext.sdmSubscriptionLock.RLock()
+	defer udmUeContext.sdmSubscriptionLock.RUnlock()
+	sdmSubscriptions := make([]*models.SdmSubscription, 0, len(udmUeContext.SubscribeToNotifChange))
+	for _, sdmSubscription := range udmUeContext.SubscribeToNotifChange {
+		sdmSubscriptions = append(sdmSubscriptions, sdmSubscription)
+	}
+	return sdmSubscriptions
+}
+
+// functions related to the UDM subscription to data change notifications of the UDR
+func (udmUeContext *UdmUeContext) UdrSubscriptionExists() bool {
+	udmUeContext.udrSubscriptionLock.Lock()
+	defer udmUeContext.udrSubscriptionLock.Unlock()
+	return len(udmUeContext.UdmSubsToNotify) != 0
+}
+
+// LockUdrSubscribe serializes the creation of the data change subscription of the UE in the UDR, so
+// that the concurrent requests of the UE create a single one. The returned function releases it.
+func (udmUeContext *UdmUeContext) LockUdrSubscribe() (unlock func()) {
+	udmUeContext.udrSubscribeLock.Lock()
+	return udmUeContext.udrSubscribeLock.Unlock
+}
+
+func (udmUeContext *UdmUeContext) CreateUdrSubscription(subscriptionID string,
+	body *models.SubscriptionDataSubscriptions,
+) {
+	udmUeContext.udrSubscriptionLock.Lock()
+	defer udmUeContext.udrSubscriptionLock.Unlock()
+	udmUeContext.UdmSubsToNotify[subscriptionID] = body
+}
+
+// UdrSubscriptionIDs returns the IDs of the data change subscriptions of the UE in the UDR
+func (udmUeContext *UdmUeContext) UdrSubscriptionIDs() []string {
+	udmUeContext.udrSubscriptionLock.Lock()
+	defer udmUeContext.udrSubscriptionLock.Unlock()
+	subscriptionIDs := make([]string, 0, len(udmUeContext.UdmSubsToNotify))
+	for subscriptionID := range udmUeContext.UdmSubsToNotify {
+		subscriptionIDs = append(subscriptionIDs, subscriptionID)
+	}
+	return subscriptionIDs
+}
+
 // TODO: this function has wrong UE pool key with subscriptionID
 func (context *UDMContext) CreateSubstoNotifSharedData(subscriptionID string, body *models.SdmSubscription) {
 	context.SubscriptionOfSharedDataChange.Store(subscriptionID, body)
@@ -235,37 +334,56 @@ func (udmUeContext *UdmUeContext) SetSMSubsData(smSubsData map[string]models.Ses
 	udmUeContext.SessionManagementSubsData = smSubsData
 }
 
+// SetSmsSubsData ... functions to set SmsSubscriptionData
+func (udmUeContext *UdmUeContext) SetSmsSubsData(smsSubsData *models.SmsSubscriptionData) {
+	udmUeContext.smsDataLock.Lock()
+	defer udmUeContext.smsDataLock.Unlock()
+	udmUeContext.SmsSubsData = smsSubsData
+}
+
+// SetSmsMngData ... functions to set SmsManagementSubscriptionData
+func (udmUeContext *UdmUeContext) SetSmsMngData(smsMngData *models.SmsManagementSubscriptionData) {
+	udmUeContext.smsDataLock.Lock()
+	defer udmUeContext.smsDataLock.Unlock()
+	udmUeContext.SmsMngData = smsMngData
+}
+
+// SetUeCtxtInSmsfData ... functions to set UeContextInSmsfData
+func (udmUeContext *UdmUeContext) SetUeCtxtInSmsfData(ueCtxtInSmsfData *models.UeContextInSmsfData) {
+	udmUeContext.smsDataLock.Lock()
+	defer udmUeContext.smsDataLock.Unlock()
+	udmUeContext.UeCtxtInSmsfData = ueCtxtInSmsfData
+}
+
+// NewUdmUe returns the UE context of supi, creating it if it does not exist yet. An existing
+// context is never replaced, so that its registrations and subscriptions are kept.
 func (context *UDMContext) NewUdmUe(supi string) *UdmUeContext {
 	ue := new(UdmUeContext)
 	ue.init()
 	ue.Supi = supi
-	context.UdmUePool.Store(supi, ue)
-	return ue
+	for {
+		value, loaded := context.UdmUePool.LoadOrStore(supi, ue)
+		existing := value.(*UdmUeContext)
+		// an evicted context has left the pool by the time it is marked
+		if !existing.touch() {
+			continue
+		}
+		if !loaded {
+			stats.SetUdmUeContextPoolSize("total", float64(context.udmUeCount.Add(1)))
+		}
+		return existing
+	}
 }
 
 func (context *UDMContext) UdmUeFindBySupi(supi string) (*UdmUeContext, bool) {
 	if value, ok := context.UdmUePool.Load(supi); ok {
-		return value.(*UdmUeContext), ok
+		ue := value.(*UdmUeContext)
+		return ue, ue.touch()
 	} else {
 		return nil, false
 	}
 }
 
-func (context *UDMContext) UdmUeFindByGpsi(gpsi string) (*UdmUeContext, bool) {
-	var ue *UdmUeContext
-	ok := false
-	context.UdmUePool.Range(func(key, value interface{}) bool {
-		candidate := value.(*UdmUeContext)
-		if candidate.Gpsi == gpsi {
-			ue = candidate
-			ok = true
-			return false
-		}
-		return true
-	})
-	return ue, ok
-}
-
 // Function to create the AccessAndMobilitySubscriptionData for Ue
 func (context *UDMContext) CreateAccessMobilitySubsDataForUe(supi string,
 	body models.AccessAndMobilitySubscriptionData,
@@ -300,38 +418,125 @@ func (context *UDMContext) UdmAmfNon3gppRegContextExists(supi string) bool {
 	}
 }
 
-func (context *UDMContext) UdmSmfRegContextNotExists(supi string) bool {
+func (context *UDMContext) UdmSmfRegContextExists(supi string, pduSessionID string) bool {
 	if ue, ok := context.UdmUeFindBySupi(supi); ok {
-		return ue.PduSessionID == ""
+		_, exists := ue.SmfRegistration(pduSessionID)
+		return exists
 	} else {
-		return true
+		return false
 	}
 }
 
 func (context *UDMContext) CreateAmf3gppRegContext(supi string, body models.Amf3GppAccessRegistration) {
-	ue, ok := context.UdmUeFindBySupi(supi)
-	if !ok {
-		ue = context.NewUdmUe(supi)
-	}
+	ue := context.lockUdmUe(supi)
+	defer ue.stateLock.Unlock()
 	ue.Amf3GppAccessRegistration = &body
+	if body.Pei != "" {
+		context.SetUePei(ue, body.Pei)
+	}
 }
 
 func (context *UDMContext) CreateAmfNon3gppRegContext(supi string, body models.AmfNon3GppAccessRegistration) {
-	ue, ok := context.UdmUeFindBySupi(supi)
-	if !ok {
-		ue = context.NewUdmUe(supi)
-	}
+	ue := context.lockUdmUe(supi)
+	defer ue.stateLock.Unlock()
 	ue.AmfNon3GppAccessRegistration = &body
+	if body.Pei != "" {
+		context.SetUePei(ue, body.Pei)
+	}
 }
 
-func (context *UDMContext) CreateSmfRegContext(supi string, pduSessionID string) {
-	ue, ok := context.UdmUeFindBySupi(supi)
-	if !ok {
-		ue = context.NewUdmUe(supi)
+func (context *UDMContext) UdmSmsf3gppRegContextExists(supi string) bool {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		return ue.Smsf3GppAccessRegistration != nil
+	} else {
+		return false
+	}
+}
+
+func (context *UDMContext) UdmSmsfNon3gppRegContextExists(supi string) bool {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		return ue.SmsfNon3GppAccessRegistration != nil
+	} else {
+		return false
+	}
+}
+
+func (context *UDMContext) CreateSmsf3gppRegContext(supi string, body models.SmsfRegistration) {
+	ue := context.lockUdmUe(supi)
+	defer ue.stateLock.Unlock()
+	ue.Smsf3GppAccessRegistration = &body
+}
+
+func (context *UDMContext) CreateSmsfNon3gppRegContext(supi string, body models.SmsfRegistration) {
+	ue := context.lockUdmUe(supi)
+	defer ue.stateLock.Unlock()
+	ue.SmsfNon3GppAccessRegistration = &body
+}
+
+func (context *UDMContext) DeleteSmsf3gppRegContext(supi string) {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		ue.stateLock.Lock()
+		defer ue.stateLock.Unlock()
+		ue.Smsf3GppAccessRegistration = nil
+	}
+}
+
+func (context *UDMContext) DeleteSmsfNon3gppRegContext(supi string) {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		ue.stateLock.Lock()
+		defer ue.stateLock.Unlock()
+		ue.SmsfNon3GppAccessRegistration = nil
+	}
+}
+
+// CreateSmfRegContext stores the SMF registration of the PDU session, reporting whether it is new
+// rather than replacing the registration of an existing session
+func (context *UDMContext) CreateSmfRegContext(supi string, pduSessionID string, body models.SmfRegistration) bool {
+	ue := context.lockUdmUe(supi)
+	defer ue.stateLock.Unlock()
+	ue.smfRegistrationLock.Lock()
+	defer ue.smfRegistrationLock.Unlock()
+	_, exists := ue.smfRegistrations[pduSessionID]
+	ue.smfRegistrations[pduSessionID] = body
+	return !exists
+}
+
+func (context *UDMContext) DeleteSmfRegContext(supi string, pduSessionID string) {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		ue.stateLock.Lock()
+		defer ue.stateLock.Unlock()
+		ue.smfRegistrationLock.Lock()
+		defer ue.smfRegistrationLock.Unlock()
+		delete(ue.smfRegistrations, pduSessionID)
 	}
-	if ue.PduSessionID == "" {
-		ue.PduSessionID = pduSessionID
+}
+
+// SmfRegistration returns the SMF registration of the PDU session
+func (ue *UdmUeContext) SmfRegistration(pduSessionID string) (models.SmfRegistration, bool) {
+	ue.smfRegistrationLock.RLock()
+	defer ue.smfRegistrationLock.RUnlock()
+	registration, ok := ue.smfRegistrations[pduSessionID]
+	return registration, ok
+}
+
+// SmfRegistrations returns the SMF registrations of the UE ordered by PDU session ID
+func (ue *UdmUeContext) SmfRegistrations() []models.SmfRegistration {
+	ue.smfRegistrationLock.RLock()
+	defer ue.smfRegistrationLock.RUnlock()
+	registrations := make([]models.SmfRegistration, 0, len(ue.smfRegistrations))
+	for _, registration := range ue.smfRegistrations {
+		registrations = append(registrations, registration)
 	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].PduSessionId < registrations[j].PduSessionId
+	})
+	return registrations
+}
+
+func (ue *UdmUeContext) hasSmfRegistrations() bool {
+	ue.smfRegistrationLock.RLock()
+	defer ue.smfRegistrationLock.RUnlock()
+	return len(ue.smfRegistrations) != 0
 }
 
 func (context *UDMContext) GetAmf3gppRegContext(supi string) *models.Amf3GppAccessRegistration {
@@ -350,18 +555,41 @@ func (context *UDMContext) GetAmfNon3gppRegContext(supi string) *models.AmfNon3G
 	}
 }
 
+func (context *UDMContext) GetSmsf3gppRegContext(supi string) *models.SmsfRegistration {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		return ue.Smsf3GppAccessRegistration
+	} else {
+		return nil
+	}
+}
+
+func (context *UDMContext) GetSmsfNon3gppRegContext(supi string) *models.SmsfRegistration {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		return ue.SmsfNon3GppAccessRegistration
+	} else {
+		return nil
+	}
+}
+
 func (ue *UdmUeContext) GetLocationURI(types int) string {
 	switch types {
 	case LocationUriAmf3GppAccessRegistration:
 		return UDM_Self().GetIPv4Uri() + "/nudm-uecm/v1/" + ue.Supi + "/registrations/amf-3gpp-access"
 	case LocationUriAmfNon3GppAccessRegistration:
 		return UDM_Self().GetIPv4Uri() + "/nudm-uecm/v1/" + ue.Supi + "/registrations/amf-non-3gpp-access"
-	case LocationUriSmfRegistration:
-		return UDM_Self().GetIPv4Uri() + "/nudm-uecm/v1/" + ue.Supi + "/registrations/smf-registrations/" + ue.PduSessionID
+	case LocationUriSmsf3GppAccessRegistration:
+		return UDM_Self().GetIPv4Uri() + "/nudm-uecm/v1/" + ue.Supi + "/registrations/smsf-3gpp-access"
+	case LocationUriSmsfNon3GppAccessRegistration:
+		return UDM_Self().GetIPv4Uri() + "/nudm-uecm/v1/" + ue.Supi + "/registrations/smsf-non-3gpp-access"
 	}
 	return ""
 }
 
+// GetSmfRegistrationLocationURI returns the URI of the SMF registration of the PDU session
+func (ue *UdmUeContext) GetSmfRegistrationLocationURI(pduSessionID string) string {
+	return UDM_Self().GetIPv4Uri() + "/nudm-uecm/v1/" + ue.Supi + "/registrations/smf-registrations/" + pduSessionID
+}
+
 func (ue *UdmUeContext) GetLocationURI2(types int, supi string) string {
 	switch types {
 	case LocationUriSharedDataSubscription:
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestCreateSmfRegContext_PerPduSession(t *testing.T) {
+	udmContext := &UDMContext{}
+	supi := "imsi-208930000000001"
+
+	for _, pduSessionID := range []string{"5", "1", "3"} {
+		if !udmContext.CreateSmfRegContext(supi, pduSessionID, models.SmfRegistration{Dnn: "internet"}) {
+			t.Fatalf("expected PDU session %s to be created", pduSessionID)
+		}
+	}
+	if udmContext.CreateSmfRegContext(supi, "3", models.SmfRegistration{Dnn: "ims"}) {
+		t.Fatal("expected the registration of PDU session 3 to be replaced")
+	}
+
+	ue, _ := udmContext.UdmUeFindBySupi(supi)
+	registration, ok := ue.SmfRegistration("3")
+	if !ok || registration.Dnn != "ims" {
+		t.Fatalf("unexpected registration of PDU session 3: %+v, %v", registration, ok)
+	}
+	if registrations := ue.SmfRegistrations(); len(registrations) != 3 {
+		t.Fatalf("expected 3 registrations, got %d", len(registrations))
+	}
+
+	udmContext.DeleteSmfRegContext(supi, "3")
+	if udmContext.UdmSmfRegContextExists(supi, "3") {
+		t.Error("expected PDU session 3 to be deleted")
+	}
+	if !udmContext.UdmSmfRegContextExists(supi, "1") || !udmContext.UdmSmfRegContextExists(supi, "5") {
+		t.Error("expected PDU sessions 1 and 5 to be kept")
+	}
+	if !udmContext.UeHasActiveState(ue) {
+		t.Error("expected a UE with SMF registrations to be active")
+	}
+	udmContext.DeleteSmfRegContext(supi, "1")
+	udmContext.DeleteSmfRegContext(supi, "5")
+	if udmContext.UeHasActiveState(ue) {
+		t.Error("expected a UE without registrations to be inactive")
+	}
+}
+
+// TestLocationUriValues keeps the values of the exported location URI types
+func TestLocationUriValues(t *testing.T) {
+	for expected, value := range []int{
+		LocationUriAmf3GppAccessRegistration,
+		LocationUriAmfNon3GppAccessRegistration,
+		LocationUriSmfRegistration,
+		LocationUriSdmSubscription,
+		LocationUriSharedDataSubscription,
+		LocationUriSmsf3GppAccessRegistration,
+		LocationUriSmsfNon3GppAccessRegistration,
+	} {
+		if value != expected {
+			t.Errorf("expected location URI type %d, got %d", expected, value)
+		}
+	}
+}
+
+func TestUdrUri_Concurrent(t *testing.T) {
+	ue := (&UDMContext{}).NewUdmUe("imsi-208930000000001")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ue.SetUdrUri("https://udr:8000")
+		}()
+		go func() {
+			defer wg.Done()
+			_ = ue.UdrUri()
+		}()
+	}
+	wg.Wait()
+	if ue.UdrUri() != "https://udr:8000" {
+		t.Errorf("unexpected UDR URI %q", ue.UdrUri())
+	}
+}
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"sync"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+)
+
+// AnyUE is the ueIdentity of the EE subscriptions that apply to any UE
+const AnyUE = "anyUE"
+
+// EeSubscriptionContext is an EE subscription created by an NF consumer (TS 29.503 5.5.2.2)
+type EeSubscriptionContext struct {
+	SubscriptionID   string // ID of the subscription in the UDR
+	UeIdentity       string // GPSI, external group ID or anyUE
+	lock             sync.Mutex
+	subscription     models.EeSubscription
+	numOfReports     int32
+	expiryTimer      *time.Timer
+	amfSubscriptions map[string]AmfEventSubscriptionRef // supi as key
+}
+
+// AmfEventSubscriptionRef is a subscription created in the serving AMF of a UE (TS 29.518 5.3.2.2)
+// to which the UDM delegates the detection of events of an EE subscription
+type AmfEventSubscriptionRef struct {
+	AmfApiRoot     string
+	SubscriptionID string
+}
+
+func NewEeSubscriptionContext(subscriptionID string, ueIdentity string,
+	eeSubscription models.EeSubscription,
+) *EeSubscriptionContext {
+	return &EeSubscriptionContext{
+		SubscriptionID: subscriptionID,
+		UeIdentity:     ueIdentity,
+		subscription:   eeSubscription,
+	}
+}
+
+// EeSubscription returns a copy of the subscription
+func (s *EeSubscriptionContext) EeSubscription() models.EeSubscription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.subscription
+}
+
+func (s *EeSubscriptionContext) SetEeSubscription(eeSubscription models.EeSubscription) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.subscription = eeSubscription
+}
+
+// Expiry returns the time after which the subscription is no longer valid, if any
+func (s *EeSubscriptionContext) Expiry() *time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.subscription.ReportingOptions == nil {
+		return nil
+	}
+	return s.subscription.ReportingOptions.Expiry
+}
+
+// CountReport accounts for a report about to be sent. It returns whether the report may be sent,
+// and whether it is the last one allowed by the maxNumOfReports of the subscription.
+func (s *EeSubscriptionContext) CountReport() (allowed bool, last bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.subscription.ReportingOptions != nil && s.subscription.ReportingOptions.Expiry != nil &&
+		!time.Now().Before(*s.subscription.ReportingOptions.Expiry) {
+		return false, true
+	}
+	maxNumOfReports := int32(0)
+	if s.subscription.ReportingOptions != nil {
+		maxNumOfReports = s.subscription.ReportingOptions.MaxNumOfReports
+	}
+	if maxNumOfReports > 0 && s.numOfReports >= maxNumOfReports {
+		return false, true
+	}
+	s.numOfReports++
+	return true, maxNumOfReports > 0 && s.numOfReports >= maxNumOfReports
+}
+
+// ScheduleExpiry calls onExpiry when the expiry of the subscription is reached, replacing any
+// previously scheduled call
+func (s *EeSubscriptionContext) ScheduleExpiry(onExpiry func()) {
+	expiry := s.Expiry()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.expiryTimer != nil {
+		s.expiryTimer.Stop()
+		s.expiryTimer = nil
+	}
+	if expiry != nil {
+		s.expiryTimer = time.AfterFunc(time.Until(*expiry), onExpiry)
+	}
+}
+
+// AmfEventSubscription returns the AMF subscription delegated for the UE
+func (s *EeSubscriptionContext) AmfEventSubscription(supi string) (AmfEventSubscriptionRef, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ref, ok := s.amfSubscriptions[supi]
+	return ref, ok
+}
+
+func (s *EeSubscriptionContext) SetAmfEventSubscription(supi string, ref AmfEventSubscriptionRef) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.amfSubscriptions == nil {
+		s.amfSubscriptions = make(map[string]AmfEventSubscriptionRef)
+	}
+	s.amfSubscriptions[supi] = ref
+}
+
+// TakeAmfEventSubscriptions removes and returns all the AMF subscriptions delegated for the UEs
+func (s *EeSubscriptionContext) TakeAmfEventSubscriptions() map[string]AmfEventSubscriptionRef {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	amfSubscriptions := s.amfSubscriptions
+	s.amfSubscriptions = nil
+	return amfSubscriptions
+}
+
+func (s *EeSubscriptionContext) stopExpiryTimer() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.expiryTimer != nil {
+		s.expiryTimer.Stop()
+		s.expiryTimer = nil
+	}
+}
+
+// AddEeSubscription registers the subscription under its ueIdentity
+func (context *UDMContext) AddEeSubscription(s *EeSubscriptionContext) {
+	context.eeSubscriptionsLock.Lock()
+	defer context.eeSubscriptionsLock.Unlock()
+	if context.eeSubscriptions == nil {
+		context.eeSubscriptions = make(map[string]map[string]*EeSubscriptionContext)
+	}
+	if _, ok := context.eeSubscriptions[s.UeIdentity]; !ok {
+		context.eeSubscriptions[s.UeIdentity] = make(map[string]*EeSubscriptionContext)
+	}
+	context.eeSubscriptions[s.UeIdentity][s.SubscriptionID] = s
+}
+
+func (context *UDMContext) FindEeSubscription(ueIdentity string, subscriptionID string) (
+	*EeSubscriptionContext, bool,
+) {
+	context.eeSubscriptionsLock.RLock()
+	defer context.eeSubscriptionsLock.RUnlock()
+	s, ok := context.eeSubscriptions[ueIdentity][subscriptionID]
+	return s, ok
+}
+
+// RemoveEeSubscription unregisters the subscription and cancels its expiry
+func (context *UDMContext) RemoveEeSubscription(ueIdentity string, subscriptionID string) (
+	*EeSubscriptionContext, bool,
+) {
+	context.eeSubscriptionsLock.Lock()
+	defer context.eeSubscriptionsLock.Unlock()
+	s, ok := context.eeSubscriptions[ueIdentity][subscriptionID]
+	if !ok {
+		return nil, false
+	}
+	delete(context.eeSubscriptions[ueIdentity], subscriptionID)
+	if len(context.eeSubscriptions[ueIdentity]) == 0 {
+		delete(context.eeSubscriptions, ueIdentity)
+		delete(context.eeSubscriptionsLoaded, ueIdentity)
+	}
+	s.stopExpiryTimer()
+	return s, true
+}
+
+// LockEeSubscriptionsLoad serializes the loading of the subscriptions of the ueIdentity stored in
+// the UDR, the loads of the other ueIdentities going on in parallel. The returned function releases it.
+func (context *UDMContext) LockEeSubscriptionsLoad(ueIdentity string) (unlock func()) {
+	return context.eeSubscriptionsLoadLocks.acquire(ueIdentity)
+}
+
+// EeSubscriptionsLoaded reports whether the subscriptions of the ueIdentity stored in the UDR were
+// loaded since the ueIdentity last had none
+func (context *UDMContext) EeSubscriptionsLoaded(ueIdentity string) bool {
+	context.eeSubscriptionsLock.RLock()
+	defer context.eeSubscriptionsLock.RUnlock()
+	return context.eeSubscriptionsLoaded[ueIdentity]
+}
+
+func (context *UDMContext) SetEeSubscriptionsLoaded(ueIdentity string) {
+	context.eeSubscriptionsLock.Lock()
+	defer context.eeSubscriptionsLock.Unlock()
+	if context.eeSubscriptionsLoaded == nil {
+		context.eeSubscriptionsLoaded = make(map[string]bool)
+	}
+	context.eeSubscriptionsLoaded[ueIdentity] = true
+}
+
+// forgetEeSubscriptionsLoaded clears the loading of the ueIdentities without subscriptions, so that
+// they are loaded again from the UDR when next used
+func (context *UDMContext) forgetEeSubscriptionsLoaded(ueIdentities []string) {
+	context.eeSubscriptionsLock.Lock()
+	defer context.eeSubscriptionsLock.Unlock()
+	for _, ueIdentity := range ueIdentities {
+		if len(context.eeSubscriptions[ueIdentity]) == 0 {
+			delete(context.eeSubscriptionsLoaded, ueIdentity)
+		}
+	}
+}
+
+// EeSubscriptionTargets returns the ueIdentities the subscriptions that apply to the UE are addressed
+// to: its GPSIs, its external groups and any UE
+func (context *UDMContext) EeSubscriptionTargets(ue *UdmUeContext) []string {
+	ueIdentities := append(context.ueGpsis(ue), context.ueExternalGroupIDs(ue)...)
+	return append(ueIdentities, AnyUE)
+}
+
+// EeSubscriptionsForUe returns the subscriptions that apply to the UE: those addressed to one of
+// its GPSIs or external groups, and those for any UE
+func (context *UDMContext) EeSubscriptionsForUe(ue *UdmUeContext) []*EeSubscriptionContext {
+	ueIdentities := context.EeSubscriptionTargets(ue)
+
+	context.eeSubscriptionsLock.RLock()
+	defer context.eeSubscriptionsLock.RUnlock()
+	var subscriptions []*EeSubscriptionContext
+	for _, ueIdentity := range ueIdentities {
+		for _, s := range context.eeSubscriptions[ueIdentity] {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+	return subscriptions
+}
+
+// hasUeEeSubscriptions reports whether an EE subscription is addressed to one of the GPSIs of the UE
+func (context *UDMContext) hasUeEeSubscriptions(ue *UdmUeContext) bool {
+	gpsis := context.ueGpsis(ue)
+	context.eeSubscriptionsLock.RLock()
+	defer context.eeSubscriptionsLock.RUnlock()
+	for _, gpsi := range gpsis {
+		if len(context.eeSubscriptions[gpsi]) != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestEeSubscriptionContext_CountReport(t *testing.T) {
+	s := NewEeSubscriptionContext("1", AnyUE, models.EeSubscription{
+		ReportingOptions: &models.ReportingOptions{MaxNumOfReports: 2},
+	})
+	if allowed, last := s.CountReport(); !allowed || last {
+		t.Errorf("expected the first report to be allowed and not the last")
+	}
+	if allowed, last := s.CountReport(); !allowed || !last {
+		t.Errorf("expected the second report to be allowed and the last")
+	}
+	if allowed, _ := s.CountReport(); allowed {
+		t.Errorf("expected the third report to be rejected")
+	}
+
+	expiry := time.Now().Add(-time.Second)
+	expired := NewEeSubscriptionContext("2", AnyUE, models.EeSubscription{
+		ReportingOptions: &models.ReportingOptions{Expiry: &expiry},
+	})
+	if allowed, _ := expired.CountReport(); allowed {
+		t.Errorf("expected no report after the expiry")
+	}
+}
+
+func TestEeSubscriptionsForUe(t *testing.T) {
+	udmContext := &UDMContext{}
+	ue := udmContext.NewUdmUe("imsi-208930000000001")
+	udmContext.SetUeGpsis(ue, []string{"msisdn-0900000001"})
+	udmContext.SetUeExternalGroupID(ue, "extgroupid-group1@example.com")
+
+	udmContext.AddEeSubscription(NewEeSubscriptionContext("1", "msisdn-0900000001", models.EeSubscription{}))
+	udmContext.AddEeSubscription(NewEeSubscriptionContext("2", "extgroupid-group1@example.com", models.EeSubscription{}))
+	udmContext.AddEeSubscription(NewEeSubscriptionContext("3", AnyUE, models.EeSubscription{}))
+	udmContext.AddEeSubscription(NewEeSubscriptionContext("4", "msisdn-0900000002", models.EeSubscription{}))
+
+	if subscriptions := udmContext.EeSubscriptionsForUe(ue); len(subscriptions) != 3 {
+		t.Errorf("expected 3 subscriptions for the UE, got %d", len(subscriptions))
+	}
+
+	ue.lastActivity.Store(time.Now().Add(-time.Hour).UnixNano())
+	if evicted := udmContext.EvictIdleUdmUes(time.Minute); len(evicted) != 0 {
+		t.Errorf("expected a UE with an EE subscription to be kept")
+	}
+
+	if _, ok := udmContext.RemoveEeSubscription("msisdn-0900000001", "1"); !ok {
+		t.Fatalf("expected the subscription to be removed")
+	}
+	if _, ok := udmContext.FindEeSubscription("msisdn-0900000001", "1"); ok {
+		t.Errorf("expected the removed subscription not to be found")
+	}
+	if evicted := udmContext.EvictIdleUdmUes(time.Minute); len(evicted) != 1 {
+		t.Errorf("expected the idle UE to be evicted once its EE subscription is removed")
+	}
+}
+
+func TestEeSubscriptionsLoaded(t *testing.T) {
+	udmContext := &UDMContext{}
+	const gpsi = "msisdn-0900000003"
+
+	udmContext.SetEeSubscriptionsLoaded(gpsi)
+	udmContext.AddEeSubscription(NewEeSubscriptionContext("1", gpsi, models.EeSubscription{}))
+	udmContext.RemoveEeSubscription(gpsi, "1")
+	if udmContext.EeSubscriptionsLoaded(gpsi) {
+		t.Error("expected the loading to be cleared with the last subscription of the ueIdentity")
+	}
+
+	ue := udmContext.NewUdmUe("imsi-208930000000003")
+	udmContext.SetUeGpsis(ue, []string{gpsi})
+	udmContext.SetEeSubscriptionsLoaded(gpsi)
+	ue.lastActivity.Store(time.Now().Add(-time.Hour).UnixNano())
+	if evicted := udmContext.EvictIdleUdmUes(time.Minute); len(evicted) != 1 {
+		t.Fatalf("expected the UE to be evicted, got %d UEs", len(evicted))
+	}
+	if udmContext.EeSubscriptionsLoaded(gpsi) {
+		t.Error("expected the loading to be cleared with the eviction of the UE")
+	}
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// SubscriberDataCache is a read-through cache of the subscription data retrieved from the UDR.
+// Entries expire after the TTL and the least recently used entry is evicted when the cache is full.
+// All methods are safe to call on a nil cache, which behaves as a disabled cache.
+type SubscriberDataCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu       sync.Mutex
+	lru      *list.List                          // front is the most recently used entry
+	entries  map[string]*list.Element            // cache key as key
+	supiKeys map[string]map[string]*list.Element // supi as key
+}
+
+type subscriberDataCacheEntry struct {
+	key      string
+	supi     string
+	resource string
+	value    interface{}
+	expiry   time.Time
+}
+
+func NewSubscriberDataCache(ttl time.Duration, maxEntries int) *SubscriberDataCache {
+	return &SubscriberDataCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		supiKeys:   make(map[string]map[string]*list.Element),
+	}
+}
+
+func subscriberDataCacheKey(supi string, resource string, qualifier string) string {
+	return supi + "/" + resource + "/" + qualifier
+}
+
+// Get returns the cached value of the resource of supi, qualifier distinguishing the query
+// parameters (e.g. the serving PLMN) the resource was retrieved with
+func (c *SubscriberDataCache) Get(supi string, resource string, qualifier string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[subscriberDataCacheKey(supi, resource, qualifier)]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*subscriberDataCacheEntry)
+	if !c.now().Before(entry.expiry) {
+		c.removeElement(element)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return entry.value, true
+}
+
+// Put stores the value of the resource of supi, evicting the least recently used entry if the
+// cache is full
+func (c *SubscriberDataCache) Put(supi string, resource string, qualifier string, value interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := subscriberDataCacheKey(supi, resource, qualifier)
+	expiry := c.now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*subscriberDataCacheEntry)
+		entry.value = value
+		entry.expiry = expiry
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	for c.maxEntries > 0 && c.lru.Len() >= c.maxEntries {
+		c.removeElement(c.lru.Back())
+	}
+	element := c.lru.PushFront(&subscriberDataCacheEntry{
+		key:      key,
+		supi:     supi,
+		resource: resource,
+		value:    value,
+		expiry:   expiry,
+	})
+	c.entries[key] = element
+	if _, ok := c.supiKeys[supi]; !ok {
+		c.supiKeys[supi] = make(map[string]*list.Element)
+	}
+	c.supiKeys[supi][key] = element
+}
+
+// Invalidate removes the cached entries of the resource of supi, or all the entries of supi if
+// resource is empty
+func (c *SubscriberDataCache) Invalidate(supi string, resource string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, element := range c.supiKeys[supi] {
+		if resource == "" || element.Value.(*subscriberDataCacheEntry).resource == resource {
+			c.removeElement(element)
+		}
+	}
+}
+
+// Len returns the number of cached entries, including the expired ones not yet removed
+func (c *SubscriberDataCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *SubscriberDataCache) removeElement(element *list.Element) {
+	entry := element.Value.(*subscriberDataCacheEntry)
+	c.lru.Remove(element)
+	delete(c.entries, entry.key)
+	if keys, ok := c.supiKeys[entry.supi]; ok {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(c.supiKeys, entry.supi)
+		}
+	}
+}
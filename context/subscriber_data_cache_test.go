@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscriberDataCache_Expiry(t *testing.T) {
+	now := time.Now()
+	cache := NewSubscriberDataCache(time.Minute, 0)
+	cache.now = func() time.Time { return now }
+
+	cache.Put("imsi-1", "am-data", "20893", "am")
+	if value, ok := cache.Get("imsi-1", "am-data", "20893"); !ok || value != "am" {
+		t.Fatalf("expected cached value am, got %v %v", value, ok)
+	}
+	if _, ok := cache.Get("imsi-1", "am-data", "20801"); ok {
+		t.Errorf("expected a miss for another qualifier")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get("imsi-1", "am-data", "20893"); ok {
+		t.Errorf("expected the entry to be expired")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected the expired entry to be removed, %d entries left", cache.Len())
+	}
+}
+
+func TestSubscriberDataCache_LruEviction(t *testing.T) {
+	cache := NewSubscriberDataCache(time.Minute, 2)
+
+	cache.Put("imsi-1", "am-data", "", 1)
+	cache.Put("imsi-2", "am-data", "", 2)
+	// imsi-1 becomes the most recently used entry
+	cache.Get("imsi-1", "am-data", "")
+	cache.Put("imsi-3", "am-data", "", 3)
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+	if _, ok := cache.Get("imsi-2", "am-data", ""); ok {
+		t.Errorf("expected the least recently used entry to be evicted")
+	}
+	if _, ok := cache.Get("imsi-1", "am-data", ""); !ok {
+		t.Errorf("expected imsi-1 to be kept")
+	}
+	if _, ok := cache.Get("imsi-3", "am-data", ""); !ok {
+		t.Errorf("expected imsi-3 to be kept")
+	}
+}
+
+func TestSubscriberDataCache_Invalidate(t *testing.T) {
+	cache := NewSubscriberDataCache(time.Minute, 0)
+	cache.Put("imsi-1", "am-data", "20893", 1)
+	cache.Put("imsi-1", "sm-data", "20893/", 2)
+	cache.Put("imsi-1", "sm-data", "20893/01010203", 3)
+	cache.Put("imsi-2", "sm-data", "20893/", 4)
+
+	cache.Invalidate("imsi-1", "sm-data")
+	if _, ok := cache.Get("imsi-1", "sm-data", "20893/01010203"); ok {
+		t.Errorf("expected sm-data of imsi-1 to be invalidated")
+	}
+	if _, ok := cache.Get("imsi-1", "am-data", "20893"); !ok {
+		t.Errorf("expected am-data of imsi-1 to be kept")
+	}
+	if _, ok := cache.Get("imsi-2", "sm-data", "20893/"); !ok {
+		t.Errorf("expected sm-data of imsi-2 to be kept")
+	}
+
+	cache.Invalidate("imsi-1", "")
+	if cache.Len() != 1 {
+		t.Errorf("expected only imsi-2 to be cached, got %d entries", cache.Len())
+	}
+}
+
+func TestSubscriberDataCache_Disabled(t *testing.T) {
+	var cache *SubscriberDataCache
+	cache.Put("imsi-1", "am-data", "", 1)
+	if _, ok := cache.Get("imsi-1", "am-data", ""); ok {
+		t.Errorf("expected a nil cache to never hit")
+	}
+	cache.Invalidate("imsi-1", "")
+}
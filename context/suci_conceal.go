@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/omec-project/util/util_3gpp/suci"
+)
+
+// NewSuciKeyPair generates a home network key pair of the protection scheme, hex encoded. The public
+// key of profile B is compressed, as provisioned in the USIMs.
+func NewSuciKeyPair(protectionScheme string) (privateKey string, publicKey string, err error) {
+	switch protectionScheme {
+	case ProfileAScheme:
+		key, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", err
+		}
+		return hex.EncodeToString(key.Bytes()), hex.EncodeToString(key.PublicKey().Bytes()), nil
+	case ProfileBScheme:
+		key, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", err
+		}
+		return hex.EncodeToString(key.Bytes()), hex.EncodeToString(compressP256(key.PublicKey())), nil
+	default:
+		return "", "", fmt.Errorf("unsupported protection scheme %q", protectionScheme)
+	}
+}
+
+// ConcealImsi returns the SUCI of an IMSI, whose MSIN is concealed with the protection scheme and the
+// home network public key of the key ID (TS 33.501 6.12.2 and Annex C.3). The null scheme takes no key.
+func ConcealImsi(mcc, mnc, msin, routingIndicator, protectionScheme string, keyID int, hnPublicKey string) (
+	string, error,
+) {
+	if !isDigits(mcc) || len(mcc) != 3 || !isDigits(mnc) || (len(mnc) != 2 && len(mnc) != 3) {
+		return "", fmt.Errorf("invalid PLMN ID %s-%s", mcc, mnc)
+	}
+	if !isDigits(msin) || msin == "" || len(mcc)+len(mnc)+len(msin) > 15 {
+		return "", fmt.Errorf("invalid MSIN %s", msin)
+	}
+	if !isDigits(routingIndicator) || routingIndicator == "" || len(routingIndicator) > 4 {
+		return "", fmt.Errorf("invalid routing indicator %s", routingIndicator)
+	}
+
+	var schemeOutput string
+	switch protectionScheme {
+	case NullScheme:
+		keyID, schemeOutput = 0, msin
+	case ProfileAScheme, ProfileBScheme:
+		if keyID < 1 || keyID > 255 {
+			return "", fmt.Errorf("key ID %d out of range [1, 255]", keyID)
+		}
+		publicKey, err := hex.DecodeString(hnPublicKey)
+		if err != nil {
+			return "", fmt.Errorf("invalid home network public key: %w", err)
+		}
+		output, err := concealMsin(protectionScheme, msinToBcd(msin), publicKey)
+		if err != nil {
+			return "", err
+		}
+		schemeOutput = hex.EncodeToString(output)
+	default:
+		return "", fmt.Errorf("unsupported protection scheme %q", protectionScheme)
+	}
+	return strings.Join([]string{
+		"suci", "0", mcc, mnc, routingIndicator, protectionScheme, strconv.Itoa(keyID), schemeOutput,
+	}, "-"), nil
+}
+
+// concealMsin returns the scheme output of the ECIES profiles of TS 33.501 Annex C.3: the ephemeral
+// public key, the cipher text and the MAC tag
+func concealMsin(protectionScheme string, plainText []byte, hnPublicKey []byte) ([]byte, error) {
+	var curve ecdh.Curve
+	switch protectionScheme {
+	case ProfileAScheme:
+		curve = ecdh.X25519()
+	default:
+		curve = ecdh.P256()
+		if len(hnPublicKey) == 33 {
+			uncompressed, err := uncompressP256(hnPublicKey)
+			if err != nil {
+				return nil, err
+			}
+			hnPublicKey = uncompressed
+		}
+	}
+	hnKey, err := curve.NewPublicKey(hnPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid home network public key: %w", err)
+	}
+
+	var ephemeralPublicKey, sharedKey []byte
+	for {
+		ephemeralKey, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		if sharedKey, err = ephemeralKey.ECDH(hnKey); err != nil {
+			return nil, err
+		}
+		ephemeralPublicKey = ephemeralKey.PublicKey().Bytes()
+		if protectionScheme == ProfileAScheme {
+			break
+		}
+		ephemeralPublicKey = compressP256(ephemeralKey.PublicKey())
+		// the de-concealment of profile B drops the leading zeros of the shared key
+		if sharedKey[0] != 0 {
+			break
+		}
+	}
+
+	// the key lengths of the profiles are the same
+	kdfKey := suci.AnsiX963KDF(sharedKey, ephemeralPublicKey,
+		suci.ProfileAEncKeyLen, suci.ProfileAMacKeyLen, suci.ProfileAHashLen)
+	encKey := kdfKey[:suci.ProfileAEncKeyLen]
+	icb := kdfKey[suci.ProfileAEncKeyLen : suci.ProfileAEncKeyLen+suci.ProfileAIcbLen]
+	macKey := kdfKey[len(kdfKey)-suci.ProfileAMacKeyLen:]
+
+	cipherText := suci.Aes128ctr(plainText, encKey, icb)
+	mac := suci.HmacSha256(cipherText, macKey, suci.ProfileAMacLen)
+	return append(append(append([]byte{}, ephemeralPublicKey...), cipherText...), mac...), nil
+}
+
+// msinToBcd encodes the MSIN in BCD with swapped nibbles, padded with F
+func msinToBcd(msin string) []byte {
+	if len(msin)%2 != 0 {
+		msin += "f"
+	}
+	bcd := make([]byte, len(msin)/2)
+	for i := range bcd {
+		digits, _ := hex.DecodeString(string([]byte{msin[2*i+1], msin[2*i]}))
+		bcd[i] = digits[0]
+	}
+	return bcd
+}
+
+// compressP256 returns the compressed point of SEC 1 2.3.3 of a P-256 public key
+func compressP256(key *ecdh.PublicKey) []byte {
+	uncompressed := key.Bytes() // 0x04 || X || Y
+	compressed := append([]byte{0x02}, uncompressed[1:33]...)
+	if uncompressed[64]&1 == 1 {
+		compressed[0] = 0x03
+	}
+	return compressed
+}
+
+// uncompressP256 returns the uncompressed point of a compressed P-256 public key
+func uncompressP256(compressed []byte) ([]byte, error) {
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), compressed)
+	if x == nil {
+		return nil, fmt.Errorf("invalid compressed public key %x", compressed)
+	}
+	uncompressed := make([]byte, 65)
+	uncompressed[0] = 0x04
+	x.FillBytes(uncompressed[1:33])
+	y.FillBytes(uncompressed[33:])
+	return uncompressed, nil
+}
+
+func isDigits(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestConcealImsi_RoundTrip(t *testing.T) {
+	for _, scheme := range []string{ProfileAScheme, ProfileBScheme} {
+		privateKey, publicKey, err := NewSuciKeyPair(scheme)
+		if err != nil {
+			t.Fatalf("scheme %s: failed to generate the key: %v", scheme, err)
+		}
+		keyRing, err := NewSuciKeyRing([]SuciKey{
+			{KeyID: 3, ProtectionScheme: scheme, PrivateKey: privateKey, PublicKey: publicKey},
+		})
+		if err != nil {
+			t.Fatalf("scheme %s: failed to create the key ring: %v", scheme, err)
+		}
+
+		for _, msin := range []string{"0000000001", "123456789"} {
+			suciValue, err := ConcealImsi("208", "93", msin, "0001", scheme, 3, publicKey)
+			if err != nil {
+				t.Fatalf("scheme %s: failed to conceal %s: %v", scheme, msin, err)
+			}
+			if prefix := "suci-0-208-93-0001-" + scheme + "-3-"; !strings.HasPrefix(suciValue, prefix) {
+				t.Fatalf("scheme %s: expected the prefix %s, got %s", scheme, prefix, suciValue)
+			}
+			if strings.Contains(suciValue, msin) {
+				t.Errorf("scheme %s: MSIN %s not concealed in %s", scheme, msin, suciValue)
+			}
+			supi, err := keyRing.ToSupi(suciValue)
+			if err != nil || supi != "imsi-20893"+msin {
+				t.Errorf("scheme %s: expected imsi-20893%s, got %s, %v", scheme, msin, supi, err)
+			}
+		}
+	}
+}
+
+func TestConcealImsi_ProfileBUncompressedKey(t *testing.T) {
+	privateKey, publicKey, err := NewSuciKeyPair(ProfileBScheme)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	if len(publicKey) != 66 {
+		t.Fatalf("expected a compressed public key, got %s", publicKey)
+	}
+	compressed, err := hex.DecodeString(publicKey)
+	if err != nil {
+		t.Fatalf("invalid public key: %v", err)
+	}
+	uncompressed, err := uncompressP256(compressed)
+	if err != nil {
+		t.Fatalf("failed to uncompress the key: %v", err)
+	}
+
+	suciValue, err := ConcealImsi("001", "001", "000000001", "0", ProfileBScheme, 1, hex.EncodeToString(uncompressed))
+	if err != nil {
+		t.Fatalf("failed to conceal: %v", err)
+	}
+	keyRing, err := NewSuciKeyRing([]SuciKey{{KeyID: 1, ProtectionScheme: ProfileBScheme, PrivateKey: privateKey}})
+	if err != nil {
+		t.Fatalf("failed to create the key ring: %v", err)
+	}
+	if supi, err := keyRing.ToSupi(suciValue); err != nil || supi != "imsi-001001000000001" {
+		t.Errorf("expected imsi-001001000000001, got %s, %v", supi, err)
+	}
+}
+
+func TestConcealImsi_NullSchemeAndInvalid(t *testing.T) {
+	suciValue, err := ConcealImsi("208", "93", "0000000001", "0000", NullScheme, 5, "")
+	if err != nil || suciValue != "suci-0-208-93-0000-0-0-0000000001" {
+		t.Errorf("expected suci-0-208-93-0000-0-0-0000000001, got %s, %v", suciValue, err)
+	}
+
+	_, publicKey, err := NewSuciKeyPair(ProfileAScheme)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	testCases := []struct {
+		name                                  string
+		mcc, mnc, msin, routingIndicator, key string
+		scheme                                string
+		keyID                                 int
+	}{
+		{name: "MCC", mcc: "20", mnc: "93", msin: "1", routingIndicator: "0", scheme: NullScheme},
+		{name: "MNC", mcc: "208", mnc: "9", msin: "1", routingIndicator: "0", scheme: NullScheme},
+		{name: "MSIN", mcc: "208", mnc: "93", msin: "12345678901", routingIndicator: "0", scheme: NullScheme},
+		{name: "routing indicator", mcc: "208", mnc: "93", msin: "1", routingIndicator: "12345", scheme: NullScheme},
+		{name: "scheme", mcc: "208", mnc: "93", msin: "1", routingIndicator: "0", scheme: "3", keyID: 1},
+		{name: "key ID", mcc: "208", mnc: "93", msin: "1", routingIndicator: "0", scheme: ProfileAScheme,
+			key: publicKey},
+		{name: "public key", mcc: "208", mnc: "93", msin: "1", routingIndicator: "0", scheme: ProfileBScheme,
+			key: publicKey, keyID: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if suciValue, err := ConcealImsi(tc.mcc, tc.mnc, tc.msin, tc.routingIndicator, tc.scheme, tc.keyID,
+				tc.key); err == nil {
+				t.Errorf("expected an error, got %s", suciValue)
+			}
+		})
+	}
+	if _, _, err := NewSuciKeyPair(NullScheme); err == nil {
+		t.Errorf("expected no key pair of the null scheme")
+	}
+}
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	stats "github.com/omec-project/udm/metrics"
+	"github.com/omec-project/util/util_3gpp/suci"
+)
+
+// Protection schemes of TS 33.501 Annex C
+const (
+	NullScheme     = "0"
+	ProfileAScheme = "1"
+	ProfileBScheme = "2"
+)
+
+// SUCI fields: suci-<supi type>-<mcc>-<mnc>-<routing indicator>-<protection scheme>-<HN public key ID>-<scheme output>
+const (
+	suciSchemePlace        = 5
+	suciHNPublicKeyIDPlace = 6
+	suciMinParts           = 8
+)
+
+// SuciKeyState is the state of a home network key in the key ring
+type SuciKeyState string
+
+const (
+	// SuciKeyStatePending keys are not used yet
+	SuciKeyStatePending SuciKeyState = "pending"
+	// SuciKeyStateActive keys de-conceal the SUCIs
+	SuciKeyStateActive SuciKeyState = "active"
+	// SuciKeyStateRetired keys are no longer accepted
+	SuciKeyStateRetired SuciKeyState = "retired"
+)
+
+// SuciKey is a home network key pair, identified in the SUCIs by its home network public key ID
+type SuciKey struct {
+	KeyID            int
+	ProtectionScheme string
+	PrivateKey       string
+	PublicKey        string
+	State            SuciKeyState
+	// ActivationTime and RetirementTime, when not zero, override State before and after them
+	ActivationTime time.Time
+	RetirementTime time.Time
+}
+
+// StateAt returns the state of the key at the given time
+func (k *SuciKey) StateAt(now time.Time) SuciKeyState {
+	if !k.ActivationTime.IsZero() && now.Before(k.ActivationTime) {
+		return SuciKeyStatePending
+	}
+	if !k.RetirementTime.IsZero() && !now.Before(k.RetirementTime) {
+		return SuciKeyStateRetired
+	}
+	// a pending key with an activation time becomes active at that time
+	if k.State == "" || (k.State == SuciKeyStatePending && !k.ActivationTime.IsZero()) {
+		return SuciKeyStateActive
+	}
+	return k.State
+}
+
+// SuciKeyRing holds the home network keys used to de-conceal the SUCIs, so that keys can be rotated
+// while the UEs provisioned with the previous key are still served
+type SuciKeyRing struct {
+	lock sync.RWMutex
+	keys map[int]SuciKey // key ID as key
+	now  func() time.Time
+}
+
+func NewSuciKeyRing(keys []SuciKey) (*SuciKeyRing, error) {
+	keyRing := &SuciKeyRing{now: time.Now}
+	if err := keyRing.SetKeys(keys); err != nil {
+		return nil, err
+	}
+	return keyRing, nil
+}
+
+// SetKeys replaces the keys of the key ring, leaving it unchanged if one of the keys is invalid
+func (r *SuciKeyRing) SetKeys(keys []SuciKey) error {
+	keyMap := make(map[int]SuciKey, len(keys))
+	for _, key := range keys {
+		if key.KeyID < 1 || key.KeyID > 255 {
+			return fmt.Errorf("key ID %d out of range [1, 255]", key.KeyID)
+		}
+		if _, ok := keyMap[key.KeyID]; ok {
+			return fmt.Errorf("duplicate key ID %d", key.KeyID)
+		}
+		if key.ProtectionScheme != ProfileAScheme && key.ProtectionScheme != ProfileBScheme {
+			return fmt.Errorf("key ID %d: unsupported protection scheme %q", key.KeyID, key.ProtectionScheme)
+		}
+		if key.PrivateKey == "" {
+			return fmt.Errorf("key ID %d: missing private key", key.KeyID)
+		}
+		switch key.State {
+		case "", SuciKeyStatePending, SuciKeyStateActive, SuciKeyStateRetired:
+		default:
+			return fmt.Errorf("key ID %d: unknown state %q", key.KeyID, key.State)
+		}
+		keyMap[key.KeyID] = key
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.keys = keyMap
+	return nil
+}
+
+// Key returns the key with the home network public key ID. A nil key ring has no key.
+func (r *SuciKeyRing) Key(keyID int) (SuciKey, bool) {
+	if r == nil {
+		return SuciKey{}, false
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	key, ok := r.keys[keyID]
+	return key, ok
+}
+
+// Keys returns the keys ordered by key ID
+func (r *SuciKeyRing) Keys() []SuciKey {
+	if r == nil {
+		return nil
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	keys := make([]SuciKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].KeyID < keys[j].KeyID })
+	return keys
+}
+
+// ToSupi de-conceals the SUCI with the key selected by its home network public key ID. SUPIs and
+// SUCIs using the null scheme are returned as SUPIs without any key.
+func (r *SuciKeyRing) ToSupi(supiOrSuci string) (string, error) {
+	suciPart := strings.Split(supiOrSuci, "-")
+	if suciPart[0] != "suci" || len(suciPart) < suciMinParts || suciPart[suciSchemePlace] == NullScheme {
+		return suci.ToSupi(supiOrSuci, nil)
+	}
+
+	keyID, err := strconv.Atoi(suciPart[suciHNPublicKeyIDPlace])
+	if err != nil {
+		stats.IncrementUdmSuciDeconcealmentStats("invalid", "INVALID_KEY_ID")
+		return "", fmt.Errorf("invalid home network public key ID %q", suciPart[suciHNPublicKeyIDPlace])
+	}
+	keyIDLabel := strconv.Itoa(keyID)
+	key, ok := r.Key(keyID)
+	if !ok {
+		// the label of unknown key IDs is fixed to bound the cardinality of the metric
+		stats.IncrementUdmSuciDeconcealmentStats("unknown", "UNKNOWN_KEY")
+		return "", fmt.Errorf("unknown home network public key ID %d", keyID)
+	}
+	if state := key.StateAt(r.now()); state != SuciKeyStateActive {
+		stats.IncrementUdmSuciDeconcealmentStats(keyIDLabel, "KEY_NOT_ACTIVE")
+		return "", fmt.Errorf("home network public key ID %d is %s", keyID, state)
+	}
+	if suciPart[suciSchemePlace] != key.ProtectionScheme {
+		stats.IncrementUdmSuciDeconcealmentStats(keyIDLabel, "SCHEME_MISMATCH")
+		return "", fmt.Errorf("protection scheme %s does not match the scheme %s of key ID %d",
+			suciPart[suciSchemePlace], key.ProtectionScheme, keyID)
+	}
+
+	// suci.ToSupi selects the profile by position, the key is passed as the only profile
+	suciPart[suciHNPublicKeyIDPlace] = "1"
+	supi, err := suci.ToSupi(strings.Join(suciPart, "-"), []suci.SuciProfile{{
+		ProtectionScheme: key.ProtectionScheme,
+		PrivateKey:       key.PrivateKey,
+		PublicKey:        key.PublicKey,
+	}})
+	if err != nil {
+		stats.IncrementUdmSuciDeconcealmentStats(keyIDLabel, "FAILURE")
+		return "", err
+	}
+	stats.IncrementUdmSuciDeconcealmentStats(keyIDLabel, "SUCCESS")
+	return supi, nil
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// newProfileAKeyPair generates a home network key pair of profile A (X25519)
+func newProfileAKeyPair(t *testing.T) (privateKey string, publicKey string) {
+	t.Helper()
+	privateKey, publicKey, err := NewSuciKeyPair(ProfileAScheme)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	return privateKey, publicKey
+}
+
+// concealProfileA conceals the MSIN of an IMSI with the profile A of TS 33.501 Annex C.3
+func concealProfileA(t *testing.T, msin string, hnPublicKey string) string {
+	t.Helper()
+	publicKey, err := hex.DecodeString(hnPublicKey)
+	if err != nil {
+		t.Fatalf("invalid public key: %v", err)
+	}
+	output, err := concealMsin(ProfileAScheme, msinToBcd(msin), publicKey)
+	if err != nil {
+		t.Fatalf("failed to conceal the MSIN: %v", err)
+	}
+	return hex.EncodeToString(output)
+}
+
+func TestSuciKeyRing_SelectsKeyByID(t *testing.T) {
+	oldPrivateKey, oldPublicKey := newProfileAKeyPair(t)
+	newPrivateKey, newPublicKey := newProfileAKeyPair(t)
+	keyRing, err := NewSuciKeyRing([]SuciKey{
+		{KeyID: 1, ProtectionScheme: ProfileAScheme, PrivateKey: oldPrivateKey, PublicKey: oldPublicKey},
+		{KeyID: 7, ProtectionScheme: ProfileAScheme, PrivateKey: newPrivateKey, PublicKey: newPublicKey},
+	})
+	if err != nil {
+		t.Fatalf("failed to create the key ring: %v", err)
+	}
+
+	for _, tc := range []struct {
+		keyID     string
+		publicKey string
+	}{
+		{keyID: "1", publicKey: oldPublicKey},
+		{keyID: "7", publicKey: newPublicKey},
+	} {
+		suciValue := "suci-0-208-93-0000-1-" + tc.keyID + "-" + concealProfileA(t, "0000000001", tc.publicKey)
+		supi, err := keyRing.ToSupi(suciValue)
+		if err != nil {
+			t.Fatalf("key ID %s: unexpected error: %v", tc.keyID, err)
+		}
+		if supi != "imsi-208930000000001" {
+			t.Errorf("key ID %s: expected imsi-208930000000001, got %s", tc.keyID, supi)
+		}
+	}
+
+	// a SUCI concealed with the key of another ID fails the MAC verification
+	suciValue := "suci-0-208-93-0000-1-7-" + concealProfileA(t, "0000000001", oldPublicKey)
+	if _, err := keyRing.ToSupi(suciValue); err == nil {
+		t.Errorf("expected the SUCI concealed with another key to be rejected")
+	}
+}
+
+func TestSuciKeyRing_KeyStates(t *testing.T) {
+	privateKey, publicKey := newProfileAKeyPair(t)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	keyRing, err := NewSuciKeyRing([]SuciKey{
+		{KeyID: 1, ProtectionScheme: ProfileAScheme, PrivateKey: privateKey, PublicKey: publicKey,
+			State: SuciKeyStateRetired},
+		{KeyID: 2, ProtectionScheme: ProfileAScheme, PrivateKey: privateKey, PublicKey: publicKey,
+			State: SuciKeyStatePending},
+		{KeyID: 3, ProtectionScheme: ProfileAScheme, PrivateKey: privateKey, PublicKey: publicKey,
+			ActivationTime: now.Add(time.Hour)},
+		{KeyID: 4, ProtectionScheme: ProfileAScheme, PrivateKey: privateKey, PublicKey: publicKey,
+			RetirementTime: now.Add(-time.Hour)},
+		{KeyID: 5, ProtectionScheme: ProfileAScheme, PrivateKey: privateKey, PublicKey: publicKey,
+			State: SuciKeyStatePending, ActivationTime: now.Add(-time.Hour), RetirementTime: now.Add(time.Hour)},
+		{KeyID: 6, ProtectionScheme: ProfileBScheme, PrivateKey: privateKey, PublicKey: publicKey},
+	})
+	if err != nil {
+		t.Fatalf("failed to create the key ring: %v", err)
+	}
+	keyRing.now = func() time.Time { return now }
+
+	testCases := []struct {
+		name    string
+		keyID   string
+		scheme  string
+		success bool
+	}{
+		{name: "retired key", keyID: "1", scheme: ProfileAScheme},
+		{name: "pending key", keyID: "2", scheme: ProfileAScheme},
+		{name: "before the activation time", keyID: "3", scheme: ProfileAScheme},
+		{name: "after the retirement time", keyID: "4", scheme: ProfileAScheme},
+		{name: "within the activation window", keyID: "5", scheme: ProfileAScheme, success: true},
+		{name: "scheme mismatch", keyID: "6", scheme: ProfileAScheme},
+		{name: "unknown key", keyID: "8", scheme: ProfileAScheme},
+		{name: "invalid key ID", keyID: "x", scheme: ProfileAScheme},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			suciValue := "suci-0-208-93-0000-" + tc.scheme + "-" + tc.keyID + "-" +
+				concealProfileA(t, "0000000001", publicKey)
+			supi, err := keyRing.ToSupi(suciValue)
+			if tc.success {
+				if err != nil || supi != "imsi-208930000000001" {
+					t.Errorf("expected imsi-208930000000001, got %s, %v", supi, err)
+				}
+			} else if err == nil {
+				t.Errorf("expected the SUCI to be rejected, got %s", supi)
+			}
+		})
+	}
+}
+
+func TestSuciKeyRing_NullSchemeAndSupi(t *testing.T) {
+	var keyRing *SuciKeyRing
+	for input, expected := range map[string]string{
+		"suci-0-208-93-0000-0-0-0000000001": "imsi-208930000000001",
+		"imsi-208930000000001":              "imsi-208930000000001",
+	} {
+		supi, err := keyRing.ToSupi(input)
+		if err != nil || supi != expected {
+			t.Errorf("%s: expected %s, got %s, %v", input, expected, supi, err)
+		}
+	}
+	if _, err := keyRing.ToSupi("suci-0-208-93-0000-1-1-00"); err == nil {
+		t.Errorf("expected a protected SUCI to be rejected without keys")
+	}
+}
+
+func TestSuciKeyRing_SetKeys(t *testing.T) {
+	privateKey, publicKey := newProfileAKeyPair(t)
+	valid := SuciKey{KeyID: 1, ProtectionScheme: ProfileAScheme, PrivateKey: privateKey, PublicKey: publicKey}
+	keyRing, err := NewSuciKeyRing([]SuciKey{valid})
+	if err != nil {
+		t.Fatalf("failed to create the key ring: %v", err)
+	}
+
+	invalid := map[string][]SuciKey{
+		"key ID out of range": {{KeyID: 256, ProtectionScheme: ProfileAScheme, PrivateKey: privateKey}},
+		"duplicate key ID":    {valid, valid},
+		"null scheme":         {{KeyID: 2, ProtectionScheme: NullScheme, PrivateKey: privateKey}},
+		"missing private key": {{KeyID: 2, ProtectionScheme: ProfileAScheme}},
+		"unknown state":       {{KeyID: 2, ProtectionScheme: ProfileAScheme, PrivateKey: privateKey, State: "revoked"}},
+	}
+	for name, keys := range invalid {
+		if err := keyRing.SetKeys(keys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if keys := keyRing.Keys(); len(keys) != 1 || keys[0].KeyID != 1 {
+		t.Errorf("expected the key ring to be unchanged, got %+v", keys)
+	}
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	stats "github.com/omec-project/udm/metrics"
+)
+
+// Causes of the SUCIs rejected by the protection policy
+const (
+	CauseNullSchemeNotAllowed       = "NULL_SCHEME_NOT_ALLOWED"
+	CauseProtectionSchemeNotAllowed = "PROTECTION_SCHEME_NOT_ALLOWED"
+)
+
+// IMSI type SUCI fields, the protection scheme and following fields being the same for all types
+const (
+	suciTypeIMSI              = "0"
+	suciMccPlace              = 2
+	suciMncPlace              = 3
+	suciRoutingIndicatorPlace = 4
+)
+
+// SuciProtectionRule lists the protection schemes accepted for the SUCIs of a home network PLMN,
+// restricted to a routing indicator when not empty
+type SuciProtectionRule struct {
+	Mcc              string
+	Mnc              string
+	RoutingIndicator string
+	Schemes          []string // NullScheme, ProfileAScheme or ProfileBScheme
+}
+
+// SuciProtectionError is the rejection of a SUCI by the protection policy
+type SuciProtectionError struct {
+	Cause  string
+	Scheme string
+	suci   string
+}
+
+func (e *SuciProtectionError) Error() string {
+	return fmt.Sprintf("protection scheme %s of %s not allowed", e.Scheme, e.suci)
+}
+
+// SuciProtectionPolicy accepts the SUCIs whose protection scheme is listed by the most specific rule
+// matching their home network PLMN and routing indicator, or by the default schemes.
+// All methods are safe to call on a nil policy, which accepts all the SUCIs.
+type SuciProtectionPolicy struct {
+	lock           sync.RWMutex
+	defaultSchemes []string
+	rules          []SuciProtectionRule
+}
+
+func NewSuciProtectionPolicy(defaultSchemes []string, rules []SuciProtectionRule) *SuciProtectionPolicy {
+	policy := &SuciProtectionPolicy{}
+	policy.Set(defaultSchemes, rules)
+	return policy
+}
+
+// Set replaces the default schemes and the rules of the policy
+func (p *SuciProtectionPolicy) Set(defaultSchemes []string, rules []SuciProtectionRule) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.defaultSchemes = defaultSchemes
+	p.rules = rules
+}
+
+// Check returns a *SuciProtectionError if the protection scheme of the SUCI is not accepted. SUPIs
+// are accepted, malformed SUCIs being left to the de-concealment.
+func (p *SuciProtectionPolicy) Check(supiOrSuci string) error {
+	if p == nil {
+		return nil
+	}
+	suciPart := strings.Split(supiOrSuci, "-")
+	if suciPart[0] != "suci" || len(suciPart) < suciMinParts {
+		return nil
+	}
+	scheme := suciPart[suciSchemePlace]
+
+	p.lock.RLock()
+	schemes := p.defaultSchemes
+	// the rules are given per PLMN, which only IMSI type SUCIs carry
+	if suciPart[1] == suciTypeIMSI {
+		schemes = p.schemes(suciPart[suciMccPlace], suciPart[suciMncPlace], suciPart[suciRoutingIndicatorPlace])
+	}
+	p.lock.RUnlock()
+
+	if slices.Contains(schemes, scheme) {
+		stats.IncrementUdmSuciProtectionPolicyStats(schemeLabel(scheme), "ACCEPTED")
+		return nil
+	}
+	cause := CauseProtectionSchemeNotAllowed
+	if scheme == NullScheme {
+		cause = CauseNullSchemeNotAllowed
+	}
+	stats.IncrementUdmSuciProtectionPolicyStats(schemeLabel(scheme), cause)
+	return &SuciProtectionError{Cause: cause, Scheme: scheme, suci: supiOrSuci}
+}
+
+// schemes returns the schemes of the rule of the routing indicator in the PLMN, else of the rule
+// of the PLMN, else the default ones
+func (p *SuciProtectionPolicy) schemes(mcc, mnc, routingIndicator string) []string {
+	var plmnRule *SuciProtectionRule
+	for i := range p.rules {
+		rule := &p.rules[i]
+		if rule.Mcc != mcc || rule.Mnc != mnc {
+			continue
+		}
+		if rule.RoutingIndicator == routingIndicator {
+			return rule.Schemes
+		}
+		if rule.RoutingIndicator == "" && plmnRule == nil {
+			plmnRule = rule
+		}
+	}
+	if plmnRule != nil {
+		return plmnRule.Schemes
+	}
+	return p.defaultSchemes
+}
+
+// schemeLabel bounds the cardinality of the scheme label of the metric
+func schemeLabel(scheme string) string {
+	switch scheme {
+	case NullScheme:
+		return "null"
+	case ProfileAScheme:
+		return "profileA"
+	case ProfileBScheme:
+		return "profileB"
+	default:
+		return "unknown"
+	}
+}
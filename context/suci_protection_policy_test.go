@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSuciProtectionPolicy_Check(t *testing.T) {
+	policy := NewSuciProtectionPolicy([]string{ProfileAScheme, ProfileBScheme}, []SuciProtectionRule{
+		{Mcc: "001", Mnc: "01", Schemes: []string{NullScheme, ProfileAScheme}},
+		{Mcc: "208", Mnc: "93", RoutingIndicator: "0001", Schemes: []string{NullScheme}},
+		{Mcc: "208", Mnc: "93", Schemes: []string{ProfileBScheme}},
+	})
+
+	testCases := []struct {
+		name          string
+		supiOrSuci    string
+		expectedCause string
+	}{
+		{name: "SUPI", supiOrSuci: "imsi-208930000000001"},
+		{name: "malformed SUCI", supiOrSuci: "suci-0-208-93"},
+		{name: "default profileA", supiOrSuci: "suci-0-310-410-0000-1-1-0a0b"},
+		{
+			name:          "default null",
+			supiOrSuci:    "suci-0-310-410-0000-0-0-0000000001",
+			expectedCause: CauseNullSchemeNotAllowed,
+		},
+		{name: "test PLMN null", supiOrSuci: "suci-0-001-01-0000-0-0-0000000001"},
+		{
+			name:          "test PLMN profileB",
+			supiOrSuci:    "suci-0-001-01-0000-2-2-0a0b",
+			expectedCause: CauseProtectionSchemeNotAllowed,
+		},
+		{name: "routing indicator null", supiOrSuci: "suci-0-208-93-0001-0-0-0000000001"},
+		{
+			name:          "routing indicator profileB",
+			supiOrSuci:    "suci-0-208-93-0001-2-2-0a0b",
+			expectedCause: CauseProtectionSchemeNotAllowed,
+		},
+		{name: "PLMN profileB", supiOrSuci: "suci-0-208-93-0002-2-2-0a0b"},
+		{
+			name:          "PLMN null",
+			supiOrSuci:    "suci-0-208-93-0002-0-0-0000000001",
+			expectedCause: CauseNullSchemeNotAllowed,
+		},
+		{
+			name:          "unknown scheme",
+			supiOrSuci:    "suci-0-310-410-0000-7-1-0a0b",
+			expectedCause: CauseProtectionSchemeNotAllowed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.supiOrSuci)
+			if tc.expectedCause == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var protectionErr *SuciProtectionError
+			if !errors.As(err, &protectionErr) || protectionErr.Cause != tc.expectedCause {
+				t.Errorf("expected %s, got %v", tc.expectedCause, err)
+			}
+		})
+	}
+}
+
+func TestSuciProtectionPolicy_Set(t *testing.T) {
+	var nilPolicy *SuciProtectionPolicy
+	if err := nilPolicy.Check("suci-0-208-93-0000-0-0-0000000001"); err != nil {
+		t.Errorf("expected a nil policy to accept the null scheme, got %v", err)
+	}
+
+	policy := NewSuciProtectionPolicy([]string{ProfileAScheme}, nil)
+	if policy.Check("suci-0-208-93-0000-0-0-0000000001") == nil {
+		t.Fatalf("expected the null scheme to be rejected")
+	}
+	policy.Set([]string{ProfileAScheme}, []SuciProtectionRule{{Mcc: "208", Mnc: "93", Schemes: []string{NullScheme}}})
+	if err := policy.Check("suci-0-208-93-0000-0-0-0000000001"); err != nil {
+		t.Errorf("expected the null scheme to be accepted after the update, got %v", err)
+	}
+}
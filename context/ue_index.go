@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"sync"
+)
+
+// ueIndexes are the secondary indexes of the UE pool, so that UEs can be found by GPSI, PEI or
+// external group ID without scanning the pool
+type ueIndexes struct {
+	lock          sync.RWMutex
+	gpsiToSupi    map[string]string
+	peiToSupi     map[string]string
+	extGroupSupis map[string]map[string]struct{}
+	supiGpsis     map[string][]string
+	supiExtGroups map[string][]string
+	// owners are the contexts the entries of each SUPI were set for, so that a context evicted
+	// while a new one is created for its SUPI does not remove the entries of the new one
+	owners map[string]*UdmUeContext
+}
+
+func (indexes *ueIndexes) initLocked() {
+	if indexes.gpsiToSupi == nil {
+		indexes.gpsiToSupi = make(map[string]string)
+		indexes.peiToSupi = make(map[string]string)
+		indexes.extGroupSupis = make(map[string]map[string]struct{})
+		indexes.supiGpsis = make(map[string][]string)
+		indexes.supiExtGroups = make(map[string][]string)
+		indexes.owners = make(map[string]*UdmUeContext)
+	}
+}
+
+// ownLocked makes the UE the owner of the entries of its SUPI, removing those left by a
+// previous context of the same SUPI
+func (indexes *ueIndexes) ownLocked(ue *UdmUeContext) {
+	if owner, ok := indexes.owners[ue.Supi]; ok && owner != ue {
+		indexes.removeLocked(owner)
+	}
+	indexes.owners[ue.Supi] = ue
+}
+
+// ownedLocked tells whether the entries of the SUPI of the UE were set for that context
+func (indexes *ueIndexes) ownedLocked(ue *UdmUeContext) bool {
+	return indexes.owners[ue.Supi] == ue
+}
+
+// SetUeGpsis replaces the GPSIs of the UE, e.g. with the GPSIs of its AM subscription data
+func (context *UDMContext) SetUeGpsis(ue *UdmUeContext, gpsis []string) {
+	indexes := &context.ueIndexes
+	indexes.lock.Lock()
+	defer indexes.lock.Unlock()
+	indexes.initLocked()
+	indexes.ownLocked(ue)
+
+	indexes.removeGpsisLocked(ue)
+	ue.Gpsi = ""
+	for _, gpsi := range gpsis {
+		context.addUeGpsiLocked(ue, gpsi)
+	}
+}
+
+// AddUeGpsi associates one more GPSI to the UE, e.g. after an identifier translation
+func (context *UDMContext) AddUeGpsi(ue *UdmUeContext, gpsi string) {
+	indexes := &context.ueIndexes
+	indexes.lock.Lock()
+	defer indexes.lock.Unlock()
+	indexes.initLocked()
+	indexes.ownLocked(ue)
+	context.addUeGpsiLocked(ue, gpsi)
+}
+
+func (context *UDMContext) addUeGpsiLocked(ue *UdmUeContext, gpsi string) {
+	indexes := &context.ueIndexes
+	if gpsi == "" {
+		return
+	}
+	if supi, ok := indexes.gpsiToSupi[gpsi]; ok && supi == ue.Supi {
+		return
+	}
+	indexes.gpsiToSupi[gpsi] = ue.Supi
+	indexes.supiGpsis[ue.Supi] = append(indexes.supiGpsis[ue.Supi], gpsi)
+	if ue.Gpsi == "" {
+		ue.Gpsi = gpsi
+	}
+}
+
+// SetUePei sets the PEI of the UE, as provided by the AMF at registration
+func (context *UDMContext) SetUePei(ue *UdmUeContext, pei string) {
+	indexes := &context.ueIndexes
+	indexes.lock.Lock()
+	defer indexes.lock.Unlock()
+	indexes.initLocked()
+	indexes.ownLocked(ue)
+
+	if ue.Pei == pei {
+		return
+	}
+	if ue.Pei != "" && indexes.peiToSupi[ue.Pei] == ue.Supi {
+		delete(indexes.peiToSupi, ue.Pei)
+	}
+	ue.Pei = pei
+	if pei != "" {
+		indexes.peiToSupi[pei] = ue.Supi
+	}
+}
+
+// SetUeExternalGroupID sets the external group the UE belongs to
+func (context *UDMContext) SetUeExternalGroupID(ue *UdmUeContext, externalGroupID string) {
+	var externalGroupIDs []string
+	if externalGroupID != "" {
+		externalGroupIDs = []string{externalGroupID}
+	}
+	context.SetUeExternalGroupIDs(ue, externalGroupIDs)
+}
+
+// SetUeExternalGroupIDs replaces the external groups the UE belongs to, e.g. with those of the
+// internal groups of its AM subscription data
+func (context *UDMContext) SetUeExternalGroupIDs(ue *UdmUeContext, externalGroupIDs []string) {
+	indexes := &context.ueIndexes
+	indexes.lock.Lock()
+	defer indexes.lock.Unlock()
+	indexes.initLocked()
+	indexes.ownLocked(ue)
+
+	indexes.removeExtGroupsLocked(ue)
+	ue.ExternalGroupID = ""
+	for _, externalGroupID := range externalGroupIDs {
+		if externalGroupID == "" {
+			continue
+		}
+		if _, ok := indexes.extGroupSupis[externalGroupID][ue.Supi]; ok {
+			continue
+		}
+		if _, ok := indexes.extGroupSupis[externalGroupID]; !ok {
+			indexes.extGroupSupis[externalGroupID] = make(map[string]struct{})
+		}
+		indexes.extGroupSupis[externalGroupID][ue.Supi] = struct{}{}
+		indexes.supiExtGroups[ue.Supi] = append(indexes.supiExtGroups[ue.Supi], externalGroupID)
+		if ue.ExternalGroupID == "" {
+			ue.ExternalGroupID = externalGroupID
+		}
+	}
+}
+
+func (indexes *ueIndexes) removeExtGroupsLocked(ue *UdmUeContext) {
+	for _, externalGroupID := range indexes.supiExtGroups[ue.Supi] {
+		if supis, ok := indexes.extGroupSupis[externalGroupID]; ok {
+			delete(supis, ue.Supi)
+			if len(supis) == 0 {
+				delete(indexes.extGroupSupis, externalGroupID)
+			}
+		}
+	}
+	delete(indexes.supiExtGroups, ue.Supi)
+}
+
+func (indexes *ueIndexes) removeGpsisLocked(ue *UdmUeContext) {
+	for _, gpsi := range indexes.supiGpsis[ue.Supi] {
+		if indexes.gpsiToSupi[gpsi] == ue.Supi {
+			delete(indexes.gpsiToSupi, gpsi)
+		}
+	}
+	delete(indexes.supiGpsis, ue.Supi)
+}
+
+func (indexes *ueIndexes) removeLocked(ue *UdmUeContext) {
+	indexes.removeGpsisLocked(ue)
+	if ue.Pei != "" && indexes.peiToSupi[ue.Pei] == ue.Supi {
+		delete(indexes.peiToSupi, ue.Pei)
+	}
+	indexes.removeExtGroupsLocked(ue)
+	delete(indexes.owners, ue.Supi)
+}
+
+// removeUeFromIndexes removes the UE from all the secondary indexes, when it leaves the pool,
+// unless a new context of its SUPI has set its own entries in the meantime
+func (context *UDMContext) removeUeFromIndexes(ue *UdmUeContext) {
+	indexes := &context.ueIndexes
+	indexes.lock.Lock()
+	defer indexes.lock.Unlock()
+	indexes.initLocked()
+
+	if indexes.ownedLocked(ue) {
+		indexes.removeLocked(ue)
+	}
+}
+
+// ueExternalGroupIDs returns the external groups of the UE
+func (context *UDMContext) ueExternalGroupIDs(ue *UdmUeContext) []string {
+	context.ueIndexes.lock.RLock()
+	defer context.ueIndexes.lock.RUnlock()
+	if !context.ueIndexes.ownedLocked(ue) {
+		return nil
+	}
+	return append([]string(nil), context.ueIndexes.supiExtGroups[ue.Supi]...)
+}
+
+// ueGpsis returns the GPSIs of the UE
+func (context *UDMContext) ueGpsis(ue *UdmUeContext) []string {
+	context.ueIndexes.lock.RLock()
+	defer context.ueIndexes.lock.RUnlock()
+	if !context.ueIndexes.ownedLocked(ue) {
+		return nil
+	}
+	return append([]string(nil), context.ueIndexes.supiGpsis[ue.Supi]...)
+}
+
+func (context *UDMContext) UdmUeFindByGpsi(gpsi string) (*UdmUeContext, bool) {
+	context.ueIndexes.lock.RLock()
+	supi, ok := context.ueIndexes.gpsiToSupi[gpsi]
+	context.ueIndexes.lock.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return context.UdmUeFindBySupi(supi)
+}
+
+func (context *UDMContext) UdmUeFindByPei(pei string) (*UdmUeContext, bool) {
+	context.ueIndexes.lock.RLock()
+	supi, ok := context.ueIndexes.peiToSupi[pei]
+	context.ueIndexes.lock.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return context.UdmUeFindBySupi(supi)
+}
+
+// UdmUesFindByExternalGroupID returns the UEs that belong to the external group
+func (context *UDMContext) UdmUesFindByExternalGroupID(externalGroupID string) []*UdmUeContext {
+	context.ueIndexes.lock.RLock()
+	supis := make([]string, 0, len(context.ueIndexes.extGroupSupis[externalGroupID]))
+	for supi := range context.ueIndexes.extGroupSupis[externalGroupID] {
+		supis = append(supis, supi)
+	}
+	context.ueIndexes.lock.RUnlock()
+
+	ues := make([]*UdmUeContext, 0, len(supis))
+	for _, supi := range supis {
+		if ue, ok := context.UdmUeFindBySupi(supi); ok {
+			ues = append(ues, ue)
+		}
+	}
+	return ues
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestUeIndexes_Gpsi(t *testing.T) {
+	udmContext := &UDMContext{}
+	ue := udmContext.NewUdmUe("imsi-208930000000001")
+
+	udmContext.SetUeGpsis(ue, []string{"msisdn-0900000001", "extid-ue1@example.com"})
+	for _, gpsi := range []string{"msisdn-0900000001", "extid-ue1@example.com"} {
+		if found, ok := udmContext.UdmUeFindByGpsi(gpsi); !ok || found != ue {
+			t.Errorf("expected %s to be found", gpsi)
+		}
+	}
+	if ue.Gpsi != "msisdn-0900000001" {
+		t.Errorf("expected Gpsi msisdn-0900000001, got %s", ue.Gpsi)
+	}
+
+	udmContext.SetUeGpsis(ue, []string{"msisdn-0900000002"})
+	if _, ok := udmContext.UdmUeFindByGpsi("msisdn-0900000001"); ok {
+		t.Errorf("expected the replaced GPSI to be removed from the index")
+	}
+	udmContext.AddUeGpsi(ue, "extid-ue1@example.com")
+	if found, ok := udmContext.UdmUeFindByGpsi("extid-ue1@example.com"); !ok || found != ue {
+		t.Errorf("expected the added GPSI to be found")
+	}
+}
+
+func TestUeIndexes_Pei(t *testing.T) {
+	udmContext := &UDMContext{}
+	udmContext.CreateAmf3gppRegContext("imsi-208930000000001", models.Amf3GppAccessRegistration{
+		Pei: "imeisv-4370816125816151",
+	})
+
+	ue, ok := udmContext.UdmUeFindByPei("imeisv-4370816125816151")
+	if !ok || ue.Supi != "imsi-208930000000001" {
+		t.Fatalf("expected the UE to be found by PEI")
+	}
+
+	udmContext.SetUePei(ue, "imeisv-4370816125816152")
+	if _, ok := udmContext.UdmUeFindByPei("imeisv-4370816125816151"); ok {
+		t.Errorf("expected the old PEI to be removed from the index")
+	}
+	if _, ok := udmContext.UdmUeFindByPei("imeisv-4370816125816152"); !ok {
+		t.Errorf("expected the new PEI to be found")
+	}
+}
+
+func TestUeIndexes_ExternalGroupIDAndEviction(t *testing.T) {
+	udmContext := &UDMContext{}
+	ue1 := udmContext.NewUdmUe("imsi-208930000000001")
+	ue2 := udmContext.NewUdmUe("imsi-208930000000002")
+	udmContext.SetUeExternalGroupID(ue1, "extgroupid-group1@example.com")
+	udmContext.SetUeExternalGroupID(ue2, "extgroupid-group1@example.com")
+	udmContext.SetUeGpsis(ue1, []string{"msisdn-0900000001"})
+
+	if ues := udmContext.UdmUesFindByExternalGroupID("extgroupid-group1@example.com"); len(ues) != 2 {
+		t.Fatalf("expected 2 UEs in the group, got %d", len(ues))
+	}
+
+	ue1.lastActivity.Store(time.Now().Add(-time.Hour).UnixNano())
+	udmContext.EvictIdleUdmUes(time.Minute)
+
+	ues := udmContext.UdmUesFindByExternalGroupID("extgroupid-group1@example.com")
+	if len(ues) != 1 || ues[0] != ue2 {
+		t.Errorf("expected only the remaining UE in the group, got %d UEs", len(ues))
+	}
+	if _, ok := udmContext.UdmUeFindByGpsi("msisdn-0900000001"); ok {
+		t.Errorf("expected the GPSI of the evicted UE to be removed from the index")
+	}
+}
+
+func TestUeIndexes_ExternalGroupIDs(t *testing.T) {
+	udmContext := &UDMContext{}
+	ue := udmContext.NewUdmUe("imsi-208930000000001")
+	udmContext.SetUeExternalGroupIDs(ue, []string{"extgroupid-group1@example.com", "extgroupid-group2@example.com"})
+	if ue.ExternalGroupID != "extgroupid-group1@example.com" {
+		t.Errorf("expected the first group to be the external group of the UE, got %s", ue.ExternalGroupID)
+	}
+	targets := udmContext.EeSubscriptionTargets(ue)
+	if len(targets) != 3 || targets[0] != "extgroupid-group1@example.com" ||
+		targets[1] != "extgroupid-group2@example.com" || targets[2] != AnyUE {
+		t.Errorf("unexpected EE subscription targets %v", targets)
+	}
+
+	udmContext.SetUeExternalGroupIDs(ue, []string{"extgroupid-group2@example.com"})
+	if ues := udmContext.UdmUesFindByExternalGroupID("extgroupid-group1@example.com"); len(ues) != 0 {
+		t.Errorf("expected the UE to leave group1, got %d UEs", len(ues))
+	}
+	if ues := udmContext.UdmUesFindByExternalGroupID("extgroupid-group2@example.com"); len(ues) != 1 {
+		t.Errorf("expected the UE to stay in group2, got %d UEs", len(ues))
+	}
+}
+
+func TestUeIndexes_EvictionAfterNewContext(t *testing.T) {
+	udmContext := &UDMContext{}
+	evicted := udmContext.NewUdmUe("imsi-208930000000001")
+	udmContext.SetUeGpsis(evicted, []string{"msisdn-0900000001"})
+	udmContext.SetUeExternalGroupID(evicted, "extgroupid-group1@example.com")
+
+	// a new context is created for the SUPI between the removal from the pool and from the indexes
+	udmContext.UdmUePool.Delete(evicted.Supi)
+	ue := udmContext.NewUdmUe("imsi-208930000000001")
+	udmContext.SetUeGpsis(ue, []string{"msisdn-0900000002"})
+	if _, ok := udmContext.UdmUeFindByGpsi("msisdn-0900000001"); ok {
+		t.Errorf("expected the GPSI of the evicted context to be removed from the index")
+	}
+	if ues := udmContext.UdmUesFindByExternalGroupID("extgroupid-group1@example.com"); len(ues) != 0 {
+		t.Errorf("expected the group of the evicted context to be removed from the index")
+	}
+	if gpsis := udmContext.ueGpsis(evicted); len(gpsis) != 0 {
+		t.Errorf("expected no GPSI for the evicted context, got %v", gpsis)
+	}
+
+	udmContext.removeUeFromIndexes(evicted)
+	if found, ok := udmContext.UdmUeFindByGpsi("msisdn-0900000002"); !ok || found != ue {
+		t.Errorf("expected the GPSI of the new context to be kept")
+	}
+}
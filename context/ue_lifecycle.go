@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"time"
+
+	stats "github.com/omec-project/udm/metrics"
+)
+
+// touch records that the UE context has been used, reporting false if it has been evicted, in which
+// case it is no longer in the UE pool
+func (ue *UdmUeContext) touch() bool {
+	ue.stateLock.RLock()
+	defer ue.stateLock.RUnlock()
+	if ue.evicted {
+		return false
+	}
+	ue.lastActivity.Store(time.Now().UnixNano())
+	return true
+}
+
+// IdleSince returns the last time the UE context has been used
+func (ue *UdmUeContext) IdleSince() time.Time {
+	return time.Unix(0, ue.lastActivity.Load())
+}
+
+// lockUdmUe returns the context of the UE, created if needed, with its state locked against the
+// eviction. The caller unlocks ue.stateLock.
+func (context *UDMContext) lockUdmUe(supi string) *UdmUeContext {
+	for {
+		ue, ok := context.UdmUeFindBySupi(supi)
+		if !ok {
+			ue = context.NewUdmUe(supi)
+		}
+		ue.stateLock.Lock()
+		if !ue.evicted {
+			return ue
+		}
+		ue.stateLock.Unlock()
+	}
+}
+
+// UeHasActiveState reports whether the UE has an AMF, SMF or SMSF registration, an SDM subscription
+// or an EE subscription addressed to one of its GPSIs, in which case its context must not be evicted
+func (context *UDMContext) UeHasActiveState(ue *UdmUeContext) bool {
+	ue.stateLock.RLock()
+	defer ue.stateLock.RUnlock()
+	return context.ueHasActiveStateLocked(ue)
+}
+
+func (context *UDMContext) ueHasActiveStateLocked(ue *UdmUeContext) bool {
+	if ue.Amf3GppAccessRegistration != nil || ue.AmfNon3GppAccessRegistration != nil ||
+		ue.Smsf3GppAccessRegistration != nil || ue.SmsfNon3GppAccessRegistration != nil ||
+		ue.hasSmfRegistrations() || context.hasUeEeSubscriptions(ue) {
+		return true
+	}
+	ue.sdmSubscriptionLock.RLock()
+	defer ue.sdmSubscriptionLock.RUnlock()
+	return len(ue.SubscribeToNotifChange) != 0
+}
+
+// EvictIdleUdmUes removes from the UE pool the contexts without active state that have not been
+// used for idleTimeout, together with their cached subscriber data, and returns them
+func (context *UDMContext) EvictIdleUdmUes(idleTimeout time.Duration) []*UdmUeContext {
+	var evicted []*UdmUeContext
+	idle := 0
+	deadline := time.Now().Add(-idleTimeout)
+	context.UdmUePool.Range(func(key, value interface{}) bool {
+		ue := value.(*UdmUeContext)
+		if context.UeHasActiveState(ue) {
+			return true
+		}
+		idle++
+		if ue.IdleSince().After(deadline) {
+			return true
+		}
+		if context.evictUdmUe(ue, deadline) {
+			evicted = append(evicted, ue)
+		}
+		return true
+	})
+
+	stats.SetUdmUeContextPoolSize("total", float64(context.udmUeCount.Add(-int64(len(evicted)))))
+	stats.SetUdmUeContextPoolSize("idle", float64(idle-len(evicted)))
+	stats.AddUdmUeContextEvictions(len(evicted))
+	return evicted
+}
+
+// evictUdmUe removes the UE context from the pool, unless it has gained active state or has been
+// used since the deadline, which is checked again under the lock held by the writers of its state
+func (context *UDMContext) evictUdmUe(ue *UdmUeContext, deadline time.Time) bool {
+	ue.stateLock.Lock()
+	defer ue.stateLock.Unlock()
+	if context.ueHasActiveStateLocked(ue) || ue.IdleSince().After(deadline) ||
+		!context.UdmUePool.CompareAndDelete(ue.Supi, ue) {
+		return false
+	}
+	ue.evicted = true
+	context.SubscriberDataCache.Invalidate(ue.Supi, "")
+	context.forgetEeSubscriptionsLoaded(append(context.ueGpsis(ue), context.ueExternalGroupIDs(ue)...))
+	context.removeUeFromIndexes(ue)
+	return true
+}
+
+// StartUdmUeEviction periodically evicts the idle UE contexts until done is closed, calling
+// onEvict for each evicted context
+func (context *UDMContext) StartUdmUeEviction(done <-chan struct{}, onEvict func(*UdmUeContext)) {
+	if context.UeContextIdleTimeout <= 0 {
+		return
+	}
+	interval := context.UeContextIdleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for _, ue := range context.EvictIdleUdmUes(context.UeContextIdleTimeout) {
+				if onEvict != nil {
+					onEvict(ue)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestNewUdmUe_KeepsExistingContext(t *testing.T) {
+	udmContext := &UDMContext{}
+	ue := udmContext.NewUdmUe("imsi-208930000000001")
+	ue.Amf3GppAccessRegistration = &models.Amf3GppAccessRegistration{AmfInstanceId: "amf-1"}
+
+	again := udmContext.NewUdmUe("imsi-208930000000001")
+	if again != ue {
+		t.Fatalf("expected the existing UE context to be returned")
+	}
+	if again.Amf3GppAccessRegistration == nil {
+		t.Errorf("expected the AMF registration to be kept")
+	}
+}
+
+func TestEvictIdleUdmUes(t *testing.T) {
+	udmContext := &UDMContext{}
+	idleTimeout := time.Minute
+	staleActivity := time.Now().Add(-2 * idleTimeout).UnixNano()
+
+	idle := udmContext.NewUdmUe("imsi-208930000000001")
+	idle.lastActivity.Store(staleActivity)
+
+	registered := udmContext.NewUdmUe("imsi-208930000000002")
+	registered.Amf3GppAccessRegistration = &models.Amf3GppAccessRegistration{AmfInstanceId: "amf-1"}
+	registered.lastActivity.Store(staleActivity)
+
+	subscribed := udmContext.NewUdmUe("imsi-208930000000003")
+	subscribed.CreateSubscriptiontoNotifChange("1", &models.SdmSubscription{})
+	subscribed.lastActivity.Store(staleActivity)
+
+	udmContext.NewUdmUe("imsi-208930000000004")
+
+	evicted := udmContext.EvictIdleUdmUes(idleTimeout)
+	if len(evicted) != 1 || evicted[0] != idle {
+		t.Fatalf("expected only the idle UE to be evicted, got %d UEs", len(evicted))
+	}
+	if _, ok := udmContext.UdmUeFindBySupi("imsi-208930000000001"); ok {
+		t.Errorf("expected the idle UE to be removed from the pool")
+	}
+	for _, supi := range []string{"imsi-208930000000002", "imsi-208930000000003", "imsi-208930000000004"} {
+		if _, ok := udmContext.UdmUeFindBySupi(supi); !ok {
+			t.Errorf("expected %s to be kept", supi)
+		}
+	}
+}
+
+func TestEvictIdleUdmUes_ActiveState(t *testing.T) {
+	udmContext := &UDMContext{}
+	idleTimeout := time.Minute
+	staleActivity := time.Now().Add(-2 * idleTimeout).UnixNano()
+
+	monitored := udmContext.NewUdmUe("imsi-208930000000001")
+	udmContext.SetUeGpsis(monitored, []string{"msisdn-0900000002"})
+	udmContext.AddEeSubscription(NewEeSubscriptionContext("1", "msisdn-0900000002", models.EeSubscription{}))
+	monitored.lastActivity.Store(staleActivity)
+
+	if evicted := udmContext.EvictIdleUdmUes(idleTimeout); len(evicted) != 0 {
+		t.Fatalf("expected no UE to be evicted, got %d UEs", len(evicted))
+	}
+
+	udmContext.RemoveEeSubscription("msisdn-0900000002", "1")
+	if evicted := udmContext.EvictIdleUdmUes(idleTimeout); len(evicted) != 1 {
+		t.Fatalf("expected the UE to be evicted once its state is released, got %d UEs", len(evicted))
+	}
+}
+
+func TestEvictUdmUe_RecheckedUnderLock(t *testing.T) {
+	udmContext := &UDMContext{}
+	supi := "imsi-208930000000001"
+	deadline := time.Now().Add(-time.Minute)
+	staleActivity := deadline.Add(-time.Minute).UnixNano()
+
+	// the UE is used after it was found idle and before it is evicted
+	ue := udmContext.NewUdmUe(supi)
+	ue.lastActivity.Store(staleActivity)
+	udmContext.UdmUeFindBySupi(supi)
+	if udmContext.evictUdmUe(ue, deadline) {
+		t.Fatal("expected a UE used since the deadline to be kept")
+	}
+
+	// the UE is registered after it was found idle and before it is evicted
+	ue.lastActivity.Store(staleActivity)
+	ue.stateLock.Lock()
+	ue.Amf3GppAccessRegistration = &models.Amf3GppAccessRegistration{AmfInstanceId: "amf-1"}
+	ue.stateLock.Unlock()
+	if udmContext.evictUdmUe(ue, deadline) {
+		t.Fatal("expected a registered UE to be kept")
+	}
+
+	ue.Amf3GppAccessRegistration = nil
+	if !udmContext.evictUdmUe(ue, deadline) {
+		t.Fatal("expected the idle UE to be evicted")
+	}
+	// the next registration of the UE is stored in a new context
+	udmContext.CreateAmf3gppRegContext(supi, models.Amf3GppAccessRegistration{AmfInstanceId: "amf-2"})
+	current, ok := udmContext.UdmUeFindBySupi(supi)
+	if !ok || current == ue || current.Amf3GppAccessRegistration == nil {
+		t.Fatalf("expected the registration to be stored in a new context")
+	}
+	if ue.touch() {
+		t.Error("expected the evicted context not to be used anymore")
+	}
+}
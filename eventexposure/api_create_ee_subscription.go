@@ -70,6 +70,9 @@ func HTTPCreateEeSubscription(c *gin.Context) {
 		}
 		c.JSON(http.StatusInternalServerError, problemDetails)
 	} else {
+		for key, val := range rsp.Header {
+			c.Header(key, val[0])
+		}
 		c.Data(rsp.Status, "application/json", responseBody)
 	}
 }
@@ -15,7 +15,12 @@
 package eventexposure
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/logger"
 	"github.com/omec-project/udm/producer"
 	"github.com/omec-project/util/httpwrapper"
 )
@@ -27,6 +32,21 @@ func HTTPDeleteEeSubscription(c *gin.Context) {
 	req.Params["subscriptionID"] = c.Params.ByName("subscriptionId")
 
 	rsp := producer.HandleDeleteEeSubscription(req)
-	// only return 204 no content
-	c.Status(rsp.Status)
+
+	if rsp.Status == http.StatusNoContent {
+		c.Status(rsp.Status)
+	} else {
+		responseBody, err := openapi.Serialize(rsp.Body, "application/json")
+		if err != nil {
+			logger.EeLog.Errorln(err)
+			problemDetails := models.ProblemDetails{
+				Status: http.StatusInternalServerError,
+				Cause:  "SYSTEM_FAILURE",
+				Detail: err.Error(),
+			}
+			c.JSON(http.StatusInternalServerError, problemDetails)
+		} else {
+			c.Data(rsp.Status, "application/json", responseBody)
+		}
+	}
 }
@@ -34,17 +34,79 @@ const (
 	UDM_DEFAULT_IPV4     = "127.0.0.3"
 	UDM_DEFAULT_PORT     = "8000"
 	UDM_DEFAULT_PORT_INT = 8000
+	UDM_DEFAULT_AMF      = "8000"
 )
 
 type Configuration struct {
-	UdmName                  string   `yaml:"udmName,omitempty"`
-	Sbi                      *Sbi     `yaml:"sbi,omitempty"`
-	ServiceList              []string `yaml:"serviceList,omitempty"`
-	NrfUri                   string   `yaml:"nrfUri,omitempty"`
-	WebuiUri                 string   `yaml:"webuiUri"`
-	Keys                     *Keys    `yaml:"keys,omitempty"`
-	EnableNrfCaching         bool     `yaml:"enableNrfCaching"`
-	NrfCacheEvictionInterval int      `yaml:"nrfCacheEvictionInterval,omitempty"`
+	UdmName                  string               `yaml:"udmName,omitempty"`
+	Sbi                      *Sbi                 `yaml:"sbi,omitempty"`
+	ServiceList              []string             `yaml:"serviceList,omitempty"`
+	NrfUri                   string               `yaml:"nrfUri,omitempty"`
+	WebuiUri                 string               `yaml:"webuiUri"`
+	Keys                     *Keys                `yaml:"keys,omitempty"`
+	EnableNrfCaching         bool                 `yaml:"enableNrfCaching"`
+	NrfCacheEvictionInterval int                  `yaml:"nrfCacheEvictionInterval,omitempty"`
+	SubscriberDataCache      *SubscriberDataCache `yaml:"subscriberDataCache,omitempty"`
+	UeContextIdleTimeout     int                  `yaml:"ueContextIdleTimeout,omitempty"` // in seconds, 0 disables eviction
+	// DefaultAmf is the AMF of the subscribers without authenticationManagementField, 4 hex digits
+	DefaultAmf    string         `yaml:"defaultAmf,omitempty"`
+	SqnManagement *SqnManagement `yaml:"sqnManagement,omitempty"`
+	// AuthFailureProtection rejects the authentication of a SUPI in a serving network after
+	// consecutive failures
+	AuthFailureProtection *AuthFailureProtection `yaml:"authFailureProtection,omitempty"`
+	// SuciProtectionPolicy restricts the protection schemes of the SUCIs per home network PLMN and
+	// routing indicator, all the schemes being accepted without it
+	SuciProtectionPolicy *SuciProtectionPolicy `yaml:"suciProtectionPolicy,omitempty"`
+	// S6a serves the MMEs of E-UTRAN as an HSS over Diameter
+	S6a *S6a `yaml:"s6a,omitempty"`
+}
+
+// S6a configures the Diameter front end of TS 29.272, serving the Authentication-Information,
+// Update-Location and Purge-UE requests
+type S6a struct {
+	Enable bool `yaml:"enable,omitempty"`
+	// ListenAddress is the TCP address of the Diameter connections, :3868 by default
+	ListenAddress string `yaml:"listenAddress,omitempty"`
+	// OriginHost and OriginRealm identify the UDM to the MMEs, the UDM name and "epc" by default
+	OriginHost  string `yaml:"originHost,omitempty"`
+	OriginRealm string `yaml:"originRealm,omitempty"`
+}
+
+// SuciProtectionPolicy lists the protection schemes accepted in the SUCIs: null, profileA or
+// profileB. It is also the document polled from the webconsole.
+type SuciProtectionPolicy struct {
+	// DefaultSchemes are accepted for the SUCIs matching no rule, profileA and profileB by default
+	DefaultSchemes []string             `yaml:"defaultSchemes,omitempty" json:"defaultSchemes,omitempty"`
+	Rules          []SuciProtectionRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+	// PollWebconsole replaces the policy by the one published by the webconsole
+	PollWebconsole bool `yaml:"pollWebconsole,omitempty" json:"-"`
+}
+
+// SuciProtectionRule lists the schemes accepted for a home network PLMN, restricted to a routing
+// indicator when given. Rules with a routing indicator take precedence.
+type SuciProtectionRule struct {
+	Mcc              string   `yaml:"mcc" json:"mcc"`
+	Mnc              string   `yaml:"mnc" json:"mnc"`
+	RoutingIndicator string   `yaml:"routingIndicator,omitempty" json:"routingIndicator,omitempty"`
+	Schemes          []string `yaml:"schemes" json:"schemes"`
+}
+
+type AuthFailureProtection struct {
+	Enable        bool `yaml:"enable"`
+	Threshold     int  `yaml:"threshold,omitempty"`     // consecutive failures, 5 by default
+	BlockDuration int  `yaml:"blockDuration,omitempty"` // in seconds, 300 by default
+}
+
+// SqnManagement configures the sequence numbers of TS 33.102 Annex C
+type SqnManagement struct {
+	IndLength int   `yaml:"indLength,omitempty"` // bits of IND in SQN, 5 by default
+	Delta     int64 `yaml:"delta,omitempty"`     // limit Δ on SEQ - SEQ_MS accepted by the USIMs, 2^28 by default
+}
+
+type SubscriberDataCache struct {
+	Enable     bool `yaml:"enable"`
+	Ttl        int  `yaml:"ttl,omitempty"`        // in seconds
+	MaxEntries int  `yaml:"maxEntries,omitempty"` // 0 means unlimited
 }
 
 type Sbi struct {
@@ -67,6 +129,23 @@ type Keys struct {
 	UdmProfileAHNPublicKey  string `yaml:"udmProfileAHNPublicKey,omitempty"`
 	UdmProfileBHNPrivateKey string `yaml:"udmProfileBHNPrivateKey,omitempty"`
 	UdmProfileBHNPublicKey  string `yaml:"udmProfileBHNPublicKey,omitempty"`
+	// HomeNetworkKeys are selected by the home network public key ID of the SUCI. When empty, the
+	// udmProfileA and udmProfileB keys are used as key IDs 1 and 2.
+	HomeNetworkKeys []HomeNetworkKey `yaml:"homeNetworkKeys,omitempty"`
+	// SoftTokenDirectory holds the tokens of the pkcs11: private key references
+	SoftTokenDirectory string `yaml:"softTokenDirectory,omitempty"`
+	// ReloadInterval is the interval in seconds at which the referenced private keys are reloaded
+	ReloadInterval int `yaml:"reloadInterval,omitempty"`
+}
+
+type HomeNetworkKey struct {
+	KeyId          int    `yaml:"keyId"`
+	Scheme         string `yaml:"scheme"`     // profileA or profileB
+	PrivateKey     string `yaml:"privateKey"` // hex, file://, env:// or pkcs11: reference
+	PublicKey      string `yaml:"publicKey,omitempty"`
+	State          string `yaml:"state,omitempty"`          // pending, active (default) or retired
+	ActivationTime string `yaml:"activationTime,omitempty"` // RFC 3339, the key is pending before
+	RetirementTime string `yaml:"retirementTime,omitempty"` // RFC 3339, the key is retired after
 }
 
 func (c *Config) GetVersion() string {
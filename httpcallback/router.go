@@ -69,4 +69,8 @@ var routes = Routes{
 		Name: "DataChangeNotificationToNF", Method: "POST",
 		Pattern: "/sdm-subscriptions", HandlerFunc: HTTPDataChangeNotificationToNF,
 	},
+	{
+		Name: "AmfEventNotification", Method: "POST",
+		Pattern: "/amf-event-notify", HandlerFunc: HTTPAmfEventNotification,
+	},
 }
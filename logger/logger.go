@@ -31,6 +31,7 @@ var (
 	ProducerLog        *zap.SugaredLogger
 	PollConfigLog      *zap.SugaredLogger
 	NrfRegistrationLog *zap.SugaredLogger
+	S6aLog             *zap.SugaredLogger
 	atomicLevel        zap.AtomicLevel
 )
 
@@ -77,6 +78,7 @@ func init() {
 	ProducerLog = log.Sugar().With("component", "UDM", "category", "Producer")
 	PollConfigLog = log.Sugar().With("component", "UDM", "category", "PollConfig")
 	NrfRegistrationLog = log.Sugar().With("component", "UDM", "category", "NrfRegistration")
+	S6aLog = log.Sugar().With("component", "UDM", "category", "S6a")
 }
 
 func GetLogger() *zap.Logger {
@@ -20,6 +20,15 @@ type UdmStats struct {
 	udmSubscriberDataManagement *prometheus.CounterVec
 	udmUeContextManagement      *prometheus.CounterVec
 	udmUeAuthentication         *prometheus.CounterVec
+	udmUeAuthenticationFailure  *prometheus.CounterVec
+	udmSdmNotification          *prometheus.CounterVec
+	udmEeNotification           *prometheus.CounterVec
+	udmSuciDeconcealment        *prometheus.CounterVec
+	udmSuciProtectionPolicy     *prometheus.CounterVec
+	udmSubscriberDataCache      *prometheus.CounterVec
+	udmS6aRequests              *prometheus.CounterVec
+	udmUeContextPool            *prometheus.GaugeVec
+	udmUeContextEvictions       prometheus.Counter
 }
 
 var udmStats *UdmStats
@@ -38,6 +47,42 @@ func initUdmStats() *UdmStats {
 			Name: "udm_ue_authentication",
 			Help: "Counter of total UE authentication queries",
 		}, []string{"query_type", "result"}),
+		udmUeAuthenticationFailure: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udm_ue_authentication_failure",
+			Help: "Counter of total UE authentication failures per reason",
+		}, []string{"reason"}),
+		udmSdmNotification: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udm_sdm_notification",
+			Help: "Counter of total SDM data change notifications sent to subscribed NFs",
+		}, []string{"target", "result"}),
+		udmEeNotification: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udm_ee_notification",
+			Help: "Counter of total EE monitoring reports sent to subscribed NFs",
+		}, []string{"event_type", "result"}),
+		udmSuciDeconcealment: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udm_suci_deconcealment",
+			Help: "Counter of total SUCI de-concealments per home network public key ID",
+		}, []string{"key_id", "result"}),
+		udmSuciProtectionPolicy: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udm_suci_protection_policy",
+			Help: "Counter of total SUCIs checked against the protection policy per protection scheme",
+		}, []string{"scheme", "result"}),
+		udmSubscriberDataCache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udm_subscriber_data_cache",
+			Help: "Counter of total subscriber data cache lookups",
+		}, []string{"requested_data_type", "result"}),
+		udmS6aRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udm_s6a_requests",
+			Help: "Counter of total S6a requests of the MMEs per command",
+		}, []string{"command", "result"}),
+		udmUeContextPool: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "udm_ue_context_pool",
+			Help: "Number of UE contexts held by the UDM",
+		}, []string{"state"}),
+		udmUeContextEvictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "udm_ue_context_evictions",
+			Help: "Counter of total idle UE contexts evicted",
+		}),
 	}
 }
 
@@ -51,6 +96,33 @@ func (ps *UdmStats) register() error {
 	if err := prometheus.Register(ps.udmUeAuthentication); err != nil {
 		return err
 	}
+	if err := prometheus.Register(ps.udmUeAuthenticationFailure); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udmSdmNotification); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udmEeNotification); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udmSuciDeconcealment); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udmSuciProtectionPolicy); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udmSubscriberDataCache); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udmS6aRequests); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udmUeContextPool); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udmUeContextEvictions); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -84,3 +156,48 @@ func IncrementUdmUeContextManagementStats(queryType, requestedDataType, result s
 func IncrementUdmUeAuthenticationStats(queryType, result string) {
 	udmStats.udmUeAuthentication.WithLabelValues(queryType, result).Inc()
 }
+
+// IncrementUdmUeAuthenticationFailureStats increments number of total UE authentication failures per reason
+func IncrementUdmUeAuthenticationFailureStats(reason string) {
+	udmStats.udmUeAuthenticationFailure.WithLabelValues(reason).Inc()
+}
+
+// IncrementUdmSdmNotificationStats increments number of total SDM data change notifications per target
+func IncrementUdmSdmNotificationStats(target, result string) {
+	udmStats.udmSdmNotification.WithLabelValues(target, result).Inc()
+}
+
+// IncrementUdmEeNotificationStats increments number of total EE monitoring reports per event type
+func IncrementUdmEeNotificationStats(eventType, result string) {
+	udmStats.udmEeNotification.WithLabelValues(eventType, result).Inc()
+}
+
+// IncrementUdmSuciDeconcealmentStats increments number of total SUCI de-concealments per key ID
+func IncrementUdmSuciDeconcealmentStats(keyID, result string) {
+	udmStats.udmSuciDeconcealment.WithLabelValues(keyID, result).Inc()
+}
+
+// IncrementUdmSuciProtectionPolicyStats increments number of total SUCIs checked against the protection policy
+func IncrementUdmSuciProtectionPolicyStats(scheme, result string) {
+	udmStats.udmSuciProtectionPolicy.WithLabelValues(scheme, result).Inc()
+}
+
+// IncrementUdmSubscriberDataCacheStats increments number of total subscriber data cache lookups
+func IncrementUdmSubscriberDataCacheStats(requestedDataType, result string) {
+	udmStats.udmSubscriberDataCache.WithLabelValues(requestedDataType, result).Inc()
+}
+
+// IncrementUdmS6aRequestsStats increments number of total S6a requests per command
+func IncrementUdmS6aRequestsStats(command, result string) {
+	udmStats.udmS6aRequests.WithLabelValues(command, result).Inc()
+}
+
+// SetUdmUeContextPoolSize sets the number of UE contexts in the given state
+func SetUdmUeContextPoolSize(state string, size float64) {
+	udmStats.udmUeContextPool.WithLabelValues(state).Set(size)
+}
+
+// AddUdmUeContextEvictions increments number of total idle UE contexts evicted
+func AddUdmUeContextEvictions(count int) {
+	udmStats.udmUeContextEvictions.Add(float64(count))
+}
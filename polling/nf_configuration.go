@@ -8,6 +8,7 @@ package polling
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -66,43 +67,57 @@ var fetchPlmnConfig = func(p *nfConfigPoller, endpoint string) ([]models.PlmnId,
 }
 
 func (p *nfConfigPoller) fetchPlmnConfig(pollingEndpoint string) ([]models.PlmnId, error) {
+	var config []models.PlmnId
+	if err := getJSON(p.client, pollingEndpoint, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// errNotFound is returned by getJSON when the webconsole does not publish the document
+var errNotFound = errors.New("server returned 404 error code")
+
+// getJSON fetches the JSON document of the endpoint into v
+func getJSON(client *http.Client, endpoint string, v any) error {
 	ctx, cancel := context.WithTimeout(context.Background(), initialPollingInterval)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pollingEndpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := p.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP GET %v failed: %w", pollingEndpoint, err)
+		return fmt.Errorf("HTTP GET %v failed: %w", endpoint, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
 	contentType := resp.Header.Get("Content-Type")
 	if !strings.Contains(contentType, "application/json") {
-		return nil, fmt.Errorf("unexpected Content-Type: got %s, want application/json", contentType)
+		return fmt.Errorf("unexpected Content-Type: got %s, want application/json", contentType)
 	}
 
 	switch resp.StatusCode {
 	case http.StatusOK:
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", err)
+			return fmt.Errorf("failed to read response body: %w", err)
 		}
 
-		var config []models.PlmnId
-		if err := json.Unmarshal(body, &config); err != nil {
-			return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+		if err := json.Unmarshal(body, v); err != nil {
+			return fmt.Errorf("failed to parse JSON response: %w", err)
 		}
-		return config, nil
+		return nil
 
 	case http.StatusBadRequest, http.StatusInternalServerError:
-		return nil, fmt.Errorf("server returned %d error code", resp.StatusCode)
+		return fmt.Errorf("server returned %d error code", resp.StatusCode)
 	default:
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 }
 
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package polling
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/omec-project/udm/factory"
+	"github.com/omec-project/udm/logger"
+)
+
+const suciProtectionPolicyPath = "/nfconfig/suci-protection-policy"
+
+type suciProtectionPolicyPoller struct {
+	policyChan    chan<- factory.SuciProtectionPolicy
+	currentPolicy *factory.SuciProtectionPolicy
+	client        *http.Client
+}
+
+// StartSuciProtectionPolicyPolling polls the SUCI protection policy published by the webconsole and
+// sends it when it changes. The current policy is kept while the webconsole does not publish any.
+func StartSuciProtectionPolicyPolling(ctx context.Context, webuiUri string,
+	policyChan chan<- factory.SuciProtectionPolicy,
+) {
+	poller := suciProtectionPolicyPoller{
+		policyChan: policyChan,
+		client:     &http.Client{Timeout: initialPollingInterval},
+	}
+	interval := initialPollingInterval
+	pollingEndpoint := webuiUri + suciProtectionPolicyPath
+	logger.PollConfigLog.Infof("Started polling SUCI protection policy on %s every %v", pollingEndpoint,
+		initialPollingInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.PollConfigLog.Infoln("SUCI protection policy polling shutting down")
+			return
+		case <-time.After(interval):
+			newPolicy, err := fetchSuciProtectionPolicy(&poller, pollingEndpoint)
+			if errors.Is(err, errNotFound) {
+				interval = initialPollingInterval
+				logger.PollConfigLog.Debugln("no SUCI protection policy published")
+				continue
+			}
+			if err != nil {
+				interval = minDuration(interval*time.Duration(pollingBackoffFactor), pollingMaxBackoff)
+				logger.PollConfigLog.Errorf("SUCI protection policy polling error. Retrying in %v: %+v", interval,
+					err)
+				continue
+			}
+			interval = initialPollingInterval
+			poller.handlePolledPolicy(newPolicy)
+		}
+	}
+}
+
+var fetchSuciProtectionPolicy = func(p *suciProtectionPolicyPoller, endpoint string) (
+	factory.SuciProtectionPolicy, error,
+) {
+	var policy factory.SuciProtectionPolicy
+	err := getJSON(p.client, endpoint, &policy)
+	return policy, err
+}
+
+func (p *suciProtectionPolicyPoller) handlePolledPolicy(newPolicy factory.SuciProtectionPolicy) {
+	if p.currentPolicy != nil && reflect.DeepEqual(*p.currentPolicy, newPolicy) {
+		logger.PollConfigLog.Debugf("SUCI protection policy did not change %+v", newPolicy)
+		return
+	}
+	p.currentPolicy = &newPolicy
+	logger.PollConfigLog.Infof("SUCI protection policy changed: %+v", newPolicy)
+	p.policyChan <- newPolicy
+}
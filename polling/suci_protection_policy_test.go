@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package polling
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/omec-project/udm/factory"
+)
+
+func TestFetchSuciProtectionPolicy(t *testing.T) {
+	published := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != suciProtectionPolicyPath {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if !published {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"defaultSchemes":["profileA"],` +
+			`"rules":[{"mcc":"001","mnc":"01","routingIndicator":"0001","schemes":["null"]}]}`))
+		if err != nil {
+			t.Fail()
+		}
+	}))
+	defer server.Close()
+	poller := suciProtectionPolicyPoller{client: &http.Client{}}
+
+	policy, err := fetchSuciProtectionPolicy(&poller, server.URL+suciProtectionPolicyPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := factory.SuciProtectionPolicy{
+		DefaultSchemes: []string{"profileA"},
+		Rules: []factory.SuciProtectionRule{
+			{Mcc: "001", Mnc: "01", RoutingIndicator: "0001", Schemes: []string{"null"}},
+		},
+	}
+	if !reflect.DeepEqual(expected, policy) {
+		t.Errorf("expected %+v, got %+v", expected, policy)
+	}
+
+	published = false
+	if _, err = fetchSuciProtectionPolicy(&poller, server.URL+suciProtectionPolicyPath); !errors.Is(err, errNotFound) {
+		t.Errorf("expected the policy not to be found, got %v", err)
+	}
+}
+
+func TestHandlePolledSuciProtectionPolicy(t *testing.T) {
+	ch := make(chan factory.SuciProtectionPolicy, 1)
+	poller := suciProtectionPolicyPoller{policyChan: ch}
+	policy := factory.SuciProtectionPolicy{DefaultSchemes: []string{"profileA"}}
+
+	poller.handlePolledPolicy(policy)
+	select {
+	case received := <-ch:
+		if !reflect.DeepEqual(policy, received) {
+			t.Errorf("expected %+v, got %+v", policy, received)
+		}
+	default:
+		t.Fatalf("expected the first policy to be sent")
+	}
+
+	poller.handlePolledPolicy(factory.SuciProtectionPolicy{DefaultSchemes: []string{"profileA"}})
+	select {
+	case received := <-ch:
+		t.Errorf("expected the unchanged policy not to be sent, got %+v", received)
+	default:
+	}
+}
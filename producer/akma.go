@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/Nudr_DataRepository"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/logger"
+)
+
+// defaultRoutingIndicator is the routing indicator of the UEs without one provisioned (TS 23.003 2.2B)
+const defaultRoutingIndicator = "0"
+
+// suciRoutingIndicatorPlace is the place of the routing indicator in the SUCIs
+// suci-<supi type>-<mcc>-<mnc>-<routing indicator>-<protection scheme>-<HN public key ID>-<scheme output>
+const (
+	suciRoutingIndicatorPlace = 4
+	suciMinParts              = 8
+)
+
+// AuthenticationSubscription is the authentication subscription of the UDR with the AKMA subscription
+// data of TS 29.505, which models.AuthenticationSubscription lacks
+type AuthenticationSubscription struct {
+	models.AuthenticationSubscription
+	AkmaAllowed bool   `json:"akmaAllowed,omitempty"`
+	RoutingId   string `json:"routingId,omitempty"`
+}
+
+// queryAuthenticationSubscription reads the authentication subscription as QueryAuthSubsData, which
+// drops the AKMA subscription data, returning it with its etag
+func queryAuthenticationSubscription(cfg *Nudr_DataRepository.Configuration, supi string) (
+	*AuthenticationSubscription, string, error,
+) {
+	headers := map[string]string{
+		"Accept": "application/json, application/problem+json",
+	}
+	request, err := openapi.PrepareRequest(context.Background(), cfg,
+		cfg.BasePath()+"/subscription-data/"+url.PathEscape(supi)+"/authentication-data/authentication-subscription",
+		http.MethodGet, nil, headers, url.Values{}, url.Values{}, "", "", nil)
+	if err != nil {
+		return nil, "", err
+	}
+	res, err := openapi.CallAPI(cfg, request)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+			logger.UeauLog.Errorf("QueryAuthSubsData response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	var authSubs AuthenticationSubscription
+	if err = openapi.Deserialize(&authSubs, body, res.Header.Get("Content-Type")); err != nil {
+		return nil, "", err
+	}
+	return &authSubs, res.Header.Get("ETag"), nil
+}
+
+// routingIndicator returns the routing indicator of the UE for the A-KID of AKMA (TS 33.535 6.1):
+// the one of its SUCI, else the one provisioned in its subscription, else the default one
+func routingIndicator(supiOrSuci string, authSubs *AuthenticationSubscription) string {
+	if suciPart := strings.Split(supiOrSuci, "-"); suciPart[0] == "suci" && len(suciPart) >= suciMinParts {
+		return suciPart[suciRoutingIndicatorPlace]
+	}
+	if authSubs.RoutingId != "" {
+		return authSubs.RoutingId
+	}
+	return defaultRoutingIndicator
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestRoutingIndicator(t *testing.T) {
+	testCases := []struct {
+		name       string
+		supiOrSuci string
+		routingId  string
+		expected   string
+	}{
+		{name: "SUCI", supiOrSuci: "suci-0-208-93-0012-0-0-0000000001", routingId: "0034", expected: "0012"},
+		{name: "SUPI", supiOrSuci: "imsi-208930000000001", routingId: "0034", expected: "0034"},
+		{name: "SUPI without routing indicator", supiOrSuci: "imsi-208930000000001", expected: defaultRoutingIndicator},
+		{name: "malformed SUCI", supiOrSuci: "suci-0-208-93-0012", routingId: "0034", expected: "0034"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authSubs := &AuthenticationSubscription{RoutingId: tc.routingId}
+			if routingId := routingIndicator(tc.supiOrSuci, authSubs); routingId != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, routingId)
+			}
+		})
+	}
+}
+
+func TestGenerateAuthDataProcedure_Akma(t *testing.T) {
+	testCases := []struct {
+		name              string
+		method            models.AuthMethod
+		akma              AuthenticationSubscription
+		supiOrSuci        string
+		servingNetworkId  *models.PlmnId
+		expectedAkmaInd   bool
+		expectedRoutingId string
+	}{
+		{
+			name:       "AKMA not allowed",
+			method:     models.AuthMethod__5_G_AKA,
+			akma:       AuthenticationSubscription{RoutingId: "0034"},
+			supiOrSuci: "imsi-208930000000001",
+		},
+		{
+			name:              "5G AKA",
+			method:            models.AuthMethod__5_G_AKA,
+			akma:              AuthenticationSubscription{AkmaAllowed: true, RoutingId: "0034"},
+			supiOrSuci:        "imsi-208930000000001",
+			expectedAkmaInd:   true,
+			expectedRoutingId: "0034",
+		},
+		{
+			name:              "EAP-AKA' with SUCI",
+			method:            models.AuthMethod_EAP_AKA_PRIME,
+			akma:              AuthenticationSubscription{AkmaAllowed: true, RoutingId: "0034"},
+			supiOrSuci:        "suci-0-208-93-0012-0-0-0000000001",
+			expectedAkmaInd:   true,
+			expectedRoutingId: "0012",
+		},
+		{
+			name:              "default routing indicator",
+			method:            models.AuthMethod__5_G_AKA,
+			akma:              AuthenticationSubscription{AkmaAllowed: true},
+			supiOrSuci:        "imsi-208930000000001",
+			expectedAkmaInd:   true,
+			expectedRoutingId: defaultRoutingIndicator,
+		},
+		{
+			name:             "EPS-AKA",
+			method:           models.AuthMethod__5_G_AKA,
+			akma:             AuthenticationSubscription{AkmaAllowed: true, RoutingId: "0034"},
+			supiOrSuci:       "imsi-208930000000001",
+			servingNetworkId: &models.PlmnId{Mcc: "208", Mnc: "93"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := currentSqnScheme()
+			udr := &fakeAuthSubsUdr{
+				authSubs: models.AuthenticationSubscription{
+					AuthenticationMethod: tc.method,
+					PermanentKey:         &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"},
+					Opc:                  &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+					SequenceNumber:       formatSqn(scheme.join(100, 2)),
+				},
+				akma: tc.akma,
+			}
+			discoverFakeUdr(t, newFakeAuthSubsUdrConfiguration(t, udr))
+
+			request := AuthenticationInfoRequest{ServingNetworkId: tc.servingNetworkId}
+			request.ServingNetworkName = "5G:mnc093.mcc208.3gppnetwork.org"
+			response, problemDetails := GenerateAuthDataProcedure(request, tc.supiOrSuci)
+			if problemDetails != nil {
+				t.Fatalf("unexpected problem: %+v", problemDetails)
+			}
+			if response.AkmaInd != tc.expectedAkmaInd || response.RoutingId != tc.expectedRoutingId {
+				t.Errorf("expected akmaInd %v and routingId %q, got %v and %q", tc.expectedAkmaInd,
+					tc.expectedRoutingId, response.AkmaInd, response.RoutingId)
+			}
+
+			body, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var fields map[string]any
+			if err = json.Unmarshal(body, &fields); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := fields["akmaInd"]; ok != tc.expectedAkmaInd {
+				t.Errorf("expected akmaInd to be serialized: %v, got %s", tc.expectedAkmaInd, body)
+			}
+		})
+	}
+}
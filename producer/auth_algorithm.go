@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/logger"
+	"github.com/omec-project/udm/util/tuak"
+	"github.com/omec-project/util/milenage"
+)
+
+// Lengths, in bytes, of the outputs of the authentication functions, as carried in the
+// authentication vectors (TS 33.102 6.3.2)
+const (
+	macLen = 8
+	resLen = 8
+	ckLen  = 16
+	ikLen  = 16
+	akLen  = 6
+)
+
+// authAlgorithm is the set of authentication and key generation functions of TS 33.102 6.3 used with
+// a subscriber, selected by the vectorAlgorithm of its authentication subscription
+type authAlgorithm interface {
+	// f1 returns MAC-A (f1) and MAC-S (f1*)
+	f1(rand, sqn, amf []byte) (macA []byte, macS []byte, err error)
+	// f2345 returns RES (f2), CK (f3), IK (f4), AK (f5) and AK* (f5*)
+	f2345(rand []byte) (res []byte, ck []byte, ik []byte, ak []byte, akStar []byte, err error)
+}
+
+// newAuthAlgorithm returns the algorithm of the authentication subscription with its subscriber
+// key and operator variant, Milenage when no vectorAlgorithm is provisioned. The algorithm is
+// selected by vectorAlgorithm: it is the attribute of the AuthenticationSubscription of TS 29.505
+// telling Milenage from TUAK, the algorithmId of later releases being absent from the UDR data.
+func newAuthAlgorithm(authSubs *models.AuthenticationSubscription) (authAlgorithm, error) {
+	if authSubs.PermanentKey == nil {
+		return nil, fmt.Errorf("nil PermanentKey")
+	}
+	k, err := hex.DecodeString(authSubs.PermanentKey.PermanentKeyValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PermanentKey: %w", err)
+	}
+
+	switch authSubs.VectorAlgorithm {
+	case "", models.VectorAlgorithm_MILENAGE:
+		return newMilenageAlgorithm(authSubs, k)
+	case models.VectorAlgorithm_TUAK:
+		return newTuakAlgorithm(authSubs, k)
+	default:
+		return nil, fmt.Errorf("unsupported vectorAlgorithm %s", authSubs.VectorAlgorithm)
+	}
+}
+
+// milenageAlgorithm is the Milenage algorithm set of TS 35.206
+type milenageAlgorithm struct {
+	opc []byte
+	k   []byte
+}
+
+func newMilenageAlgorithm(authSubs *models.AuthenticationSubscription, k []byte) (*milenageAlgorithm, error) {
+	if len(k) != keyStrLen/2 {
+		return nil, fmt.Errorf("PermanentKey is %d bytes, expected %d", len(k), keyStrLen/2)
+	}
+
+	if authSubs.Opc != nil && authSubs.Opc.OpcValue != "" {
+		if len(authSubs.Opc.OpcValue) == opcStrLen {
+			if opc, err := hex.DecodeString(authSubs.Opc.OpcValue); err == nil {
+				return &milenageAlgorithm{opc: opc, k: k}, nil
+			}
+		}
+		logger.UeauLog.Errorln("invalid Opc, deriving it from Op")
+	}
+
+	if authSubs.Milenage == nil || authSubs.Milenage.Op == nil {
+		return nil, fmt.Errorf("neither Opc nor Op provisioned")
+	}
+	if len(authSubs.Milenage.Op.OpValue) != opStrLen {
+		return nil, fmt.Errorf("Op length is %d", len(authSubs.Milenage.Op.OpValue))
+	}
+	op, err := hex.DecodeString(authSubs.Milenage.Op.OpValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Op: %w", err)
+	}
+	opc, err := milenage.GenerateOPC(k, op)
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive Opc: %w", err)
+	}
+	return &milenageAlgorithm{opc: opc, k: k}, nil
+}
+
+func (a *milenageAlgorithm) f1(rand, sqn, amf []byte) ([]byte, []byte, error) {
+	macA, macS := make([]byte, macLen), make([]byte, macLen)
+	if err := milenage.F1(a.opc, a.k, rand, sqn, amf, macA, macS); err != nil {
+		return nil, nil, err
+	}
+	return macA, macS, nil
+}
+
+func (a *milenageAlgorithm) f2345(rand []byte) ([]byte, []byte, []byte, []byte, []byte, error) {
+	res, ck, ik, ak, akStar := make([]byte, resLen), make([]byte, ckLen), make([]byte, ikLen),
+		make([]byte, akLen), make([]byte, akLen)
+	if err := milenage.F2345(a.opc, a.k, rand, res, ck, ik, ak, akStar); err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	return res, ck, ik, ak, akStar, nil
+}
+
+// tuakAlgorithm is the TUAK algorithm set of TS 35.231
+type tuakAlgorithm struct {
+	topc       []byte
+	k          []byte
+	iterations int
+}
+
+func newTuakAlgorithm(authSubs *models.AuthenticationSubscription, k []byte) (*tuakAlgorithm, error) {
+	if len(k) != 16 && len(k) != 32 {
+		return nil, fmt.Errorf("PermanentKey is %d bytes, expected 16 or 32", len(k))
+	}
+	iterations := tuak.DefaultIterations
+	if authSubs.Tuak != nil && authSubs.Tuak.KeccakIterations > 0 {
+		iterations = int(authSubs.Tuak.KeccakIterations)
+	}
+
+	if authSubs.Topc != nil && authSubs.Topc.TopcValue != "" {
+		topc, err := hex.DecodeString(authSubs.Topc.TopcValue)
+		if err != nil || len(topc) != 32 {
+			return nil, fmt.Errorf("invalid Topc")
+		}
+		return &tuakAlgorithm{topc: topc, k: k, iterations: iterations}, nil
+	}
+
+	if authSubs.Tuak == nil || authSubs.Tuak.Top == nil {
+		return nil, fmt.Errorf("neither Topc nor Top provisioned")
+	}
+	top, err := hex.DecodeString(authSubs.Tuak.Top.TopValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Top: %w", err)
+	}
+	topc, err := tuak.ComputeTopc(top, k, iterations)
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive Topc: %w", err)
+	}
+	return &tuakAlgorithm{topc: topc, k: k, iterations: iterations}, nil
+}
+
+func (a *tuakAlgorithm) f1(rand, sqn, amf []byte) ([]byte, []byte, error) {
+	macA, macS := make([]byte, macLen), make([]byte, macLen)
+	if err := tuak.F1(a.topc, a.k, rand, sqn, amf, macA, macS, a.iterations); err != nil {
+		return nil, nil, err
+	}
+	return macA, macS, nil
+}
+
+func (a *tuakAlgorithm) f2345(rand []byte) ([]byte, []byte, []byte, []byte, []byte, error) {
+	res, ck, ik, ak, akStar := make([]byte, resLen), make([]byte, ckLen), make([]byte, ikLen),
+		make([]byte, akLen), make([]byte, akLen)
+	if err := tuak.F2345(a.topc, a.k, rand, res, ck, ik, ak, akStar, a.iterations); err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	return res, ck, ik, ak, akStar, nil
+}
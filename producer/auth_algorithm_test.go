@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func decodeHexString(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %s: %v", s, err)
+	}
+	return b
+}
+
+func checkAuthAlgorithmOutputs(t *testing.T, algorithm authAlgorithm, rand, sqn, amf string,
+	expected map[string]string,
+) {
+	t.Helper()
+	macA, macS, err := algorithm.f1(decodeHexString(t, rand), decodeHexString(t, sqn), decodeHexString(t, amf))
+	if err != nil {
+		t.Fatalf("f1: unexpected error: %v", err)
+	}
+	res, ck, ik, ak, akStar, err := algorithm.f2345(decodeHexString(t, rand))
+	if err != nil {
+		t.Fatalf("f2345: unexpected error: %v", err)
+	}
+	actual := map[string][]byte{"f1": macA, "f1*": macS, "f2": res, "f3": ck, "f4": ik, "f5": ak, "f5*": akStar}
+	for name, value := range expected {
+		if hex.EncodeToString(actual[name]) != value {
+			t.Errorf("%s: expected %s, got %x", name, value, actual[name])
+		}
+	}
+}
+
+// TestAuthAlgorithm_Milenage uses the test set 1 of TS 35.208
+func TestAuthAlgorithm_Milenage(t *testing.T) {
+	expected := map[string]string{
+		"f1":  "4a9ffac354dfafb3",
+		"f1*": "01cfaf9ec4e871e9",
+		"f2":  "a54211d5e3ba50bf",
+		"f3":  "b40ba9a3c58b2a05bbf0d987b21bf8cb",
+		"f4":  "f769bcd751044604127672711c6d3441",
+		"f5":  "aa689c648370",
+		"f5*": "451e8beca43b",
+	}
+	permanentKey := &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"}
+
+	for name, authSubs := range map[string]models.AuthenticationSubscription{
+		"Op": {
+			PermanentKey: permanentKey,
+			Milenage:     &models.Milenage{Op: &models.Op{OpValue: "cdc202d5123e20f62b6d676ac72cb318"}},
+		},
+		"Opc without vectorAlgorithm": {
+			PermanentKey: permanentKey,
+			Opc:          &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+		},
+		"Opc": {
+			PermanentKey:    permanentKey,
+			VectorAlgorithm: models.VectorAlgorithm_MILENAGE,
+			Opc:             &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			algorithm, err := newAuthAlgorithm(&authSubs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkAuthAlgorithmOutputs(t, algorithm, "23553cbe9637a89d218ae64dae47bf35", "ff9bb4d0b607", "b9b9",
+				expected)
+		})
+	}
+}
+
+// TestAuthAlgorithm_Tuak uses the test set 1 of TS 35.232. Its 32 bits RES is part of the INSTANCE of
+// f2 to f5, so only f1, f1* and f5* are compared with the 64 bits RES of the vectors.
+func TestAuthAlgorithm_Tuak(t *testing.T) {
+	expected := map[string]string{
+		"f1":  "f9a54e6aeaa8618d",
+		"f1*": "e94b4dc6c7297df3",
+		"f5*": "e7af6b3d0e38",
+	}
+	permanentKey := &models.PermanentKey{PermanentKeyValue: "abababababababababababababababab"}
+
+	for name, authSubs := range map[string]models.AuthenticationSubscription{
+		"Top": {
+			PermanentKey:    permanentKey,
+			VectorAlgorithm: models.VectorAlgorithm_TUAK,
+			Tuak: &models.Tuak{
+				Top:              &models.Top{TopValue: "5555555555555555555555555555555555555555555555555555555555555555"},
+				KeccakIterations: 1,
+			},
+		},
+		"Topc": {
+			PermanentKey:    permanentKey,
+			VectorAlgorithm: models.VectorAlgorithm_TUAK,
+			Topc:            &models.Topc{TopcValue: "bd04d9530e87513c5d837ac2ad954623a8e2330c115305a73eb45d1f40cccbff"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			algorithm, err := newAuthAlgorithm(&authSubs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkAuthAlgorithmOutputs(t, algorithm, "42424242424242424242424242424242", "111111111111", "ffff",
+				expected)
+		})
+	}
+}
+
+func TestAuthAlgorithm_Rejected(t *testing.T) {
+	for name, authSubs := range map[string]models.AuthenticationSubscription{
+		"no PermanentKey": {Opc: &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"}},
+		"no Op nor Opc": {
+			PermanentKey: &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"},
+			Milenage:     &models.Milenage{},
+		},
+		"256 bits K with Milenage": {
+			PermanentKey: &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc" +
+				"465b5ce8b199b49faa5f0a2ee238a6bc"},
+			Opc: &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+		},
+		"no Top nor Topc": {
+			PermanentKey:    &models.PermanentKey{PermanentKeyValue: "abababababababababababababababab"},
+			VectorAlgorithm: models.VectorAlgorithm_TUAK,
+			Tuak:            &models.Tuak{KeccakIterations: 1},
+		},
+		"unsupported vectorAlgorithm": {
+			PermanentKey:    &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"},
+			VectorAlgorithm: "COMP128",
+		},
+	} {
+		if _, err := newAuthAlgorithm(&authSubs); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
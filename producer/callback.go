@@ -27,7 +27,10 @@ var (
 func HandleDataChangeNotificationToNFRequest(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.CallbackLog.Infoln("handle DataChangeNotificationToNF")
 	dataChangeNotify := request.Body.(models.DataChangeNotify)
-	supi := request.Params["supi"]
+	supi := dataChangeNotify.UeId
+	if supi == "" {
+		supi = request.Params["supi"]
+	}
 	problemDetails := callback.DataChangeNotificationProcedure(dataChangeNotify.NotifyItems, supi)
 	if problemDetails != nil {
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
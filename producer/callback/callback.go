@@ -8,50 +8,188 @@ package callback
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/omec-project/openapi/Nudm_SubscriberDataManagement"
 	"github.com/omec-project/openapi/Nudm_UEContextManagement"
 	"github.com/omec-project/openapi/models"
 	udm_context "github.com/omec-project/udm/context"
 	"github.com/omec-project/udm/logger"
+	stats "github.com/omec-project/udm/metrics"
 )
 
+var (
+	// notificationMaxAttempts bounds the attempts made to deliver one ModificationNotification
+	notificationMaxAttempts = 3
+	// notificationRetryInterval is the delay before the first retry, doubled after each retry
+	notificationRetryInterval = time.Second
+)
+
+// udrResourceToSdmResource maps the resource names found in the resource ids notified by the UDR
+// to the SDM resource names used in the monitoredResourceUris of the sdmSubscriptions
+var udrResourceToSdmResource = map[string]string{
+	"am-data":                         "am-data",
+	"smf-selection-subscription-data": "smf-select-data",
+	"smf-select-data":                 "smf-select-data",
+	"sm-data":                         "sm-data",
+	"sms-data":                        "sms-data",
+	"sms-mng-data":                    "sms-mng-data",
+	"trace-data":                      "trace-data",
+	"smf-registrations":               "ue-context-in-smf-data",
+	"ue-context-in-smf-data":          "ue-context-in-smf-data",
+	"smsf-3gpp-access":                "ue-context-in-smsf-data",
+	"smsf-non-3gpp-access":            "ue-context-in-smsf-data",
+	"ue-context-in-smsf-data":         "ue-context-in-smsf-data",
+}
+
+// DataChangeNotificationProcedure maps the resources changed in the UDR to the sdmSubscriptions of
+// the UE and notifies each subscribed NF asynchronously
 func DataChangeNotificationProcedure(notifyItems []models.NotifyItem, supi string) *models.ProblemDetails {
-	ue, _ := udm_context.UDM_Self().UdmUeFindBySupi(supi)
-	configuration := Nudm_SubscriberDataManagement.NewConfiguration()
-	clientAPI := Nudm_SubscriberDataManagement.NewAPIClient(configuration)
+	if supi == "" {
+		return &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_MISSING",
+			Detail: "Missing IE [UeId] in DataChangeNotify",
+		}
+	}
 
-	var problemDetails *models.ProblemDetails
-	for _, subscriptionDataSubscription := range ue.UdmSubsToNotify {
-		onDataChangeNotificationurl := subscriptionDataSubscription.OriginalCallbackReference
-		dataChangeNotification := models.ModificationNotification{}
-		dataChangeNotification.NotifyItems = notifyItems
-		httpResponse, err := clientAPI.DataChangeNotificationCallbackDocumentApi.OnDataChangeNotification(
-			context.TODO(), onDataChangeNotificationurl, dataChangeNotification)
-		if err != nil {
-			if httpResponse == nil {
-				logger.HttpLog.Error(err.Error())
-				problemDetails = &models.ProblemDetails{
-					Status: http.StatusForbidden,
-					Detail: err.Error(),
-				}
-			} else {
-				logger.HttpLog.Errorln(err.Error())
+	invalidateSubscriberData(notifyItems, supi)
+
+	ue, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		logger.CallbackLog.Infof("no UE context for %s, data change notification not forwarded", supi)
+		return nil
+	}
 
-				problemDetails = &models.ProblemDetails{
-					Status: int32(httpResponse.StatusCode),
-					Detail: err.Error(),
+	for _, sdmSubscription := range ue.SdmSubscriptionsToNotify() {
+		matchedItems := matchMonitoredResources(sdmSubscription.MonitoredResourceUris, notifyItems, supi)
+		if len(matchedItems) == 0 {
+			continue
+		}
+		modificationNotification := models.ModificationNotification{
+			NotifyItems: matchedItems,
+		}
+		go sendDataChangeNotification(sdmSubscription.CallbackReference, modificationNotification)
+	}
+	return nil
+}
+
+// invalidateSubscriberData removes the changed resources of supi from the subscriber data cache
+func invalidateSubscriberData(notifyItems []models.NotifyItem, supi string) {
+	cache := udm_context.UDM_Self().SubscriberDataCache
+	if cache == nil {
+		return
+	}
+	for _, notifyItem := range notifyItems {
+		// unknown resources invalidate all the cached data of supi
+		resource, _ := sdmResourceOf(notifyItem.ResourceId, supi)
+		cache.Invalidate(supi, resource)
+	}
+	if len(notifyItems) == 0 {
+		cache.Invalidate(supi, "")
+	}
+}
+
+// matchMonitoredResources returns the notifyItems concerning the monitoredResourceUris, with the
+// resourceId set to the monitored resource URI as required by TS 29.503 6.1.6.2.8
+func matchMonitoredResources(monitoredResourceUris []string, notifyItems []models.NotifyItem,
+	supi string,
+) []models.NotifyItem {
+	if len(monitoredResourceUris) == 0 {
+		return notifyItems
+	}
+
+	var matchedItems []models.NotifyItem
+	for _, monitoredResourceUri := range monitoredResourceUris {
+		monitoredResource, ok := sdmResourceOf(monitoredResourceUri, supi)
+		if !ok {
+			continue
+		}
+		for _, notifyItem := range notifyItems {
+			if monitoredResource != "" {
+				notifiedResource, ok := sdmResourceOf(notifyItem.ResourceId, supi)
+				if !ok || notifiedResource != monitoredResource {
+					continue
 				}
 			}
+			matchedItems = append(matchedItems, models.NotifyItem{
+				ResourceId: monitoredResourceUri,
+				Changes:    notifyItem.Changes,
+			})
+		}
+	}
+	return matchedItems
+}
+
+// sdmResourceOf returns the SDM resource name addressed by uri, or an empty string when uri
+// addresses the whole subscriber data of supi
+func sdmResourceOf(uri string, supi string) (string, bool) {
+	path := uri
+	if parsedUri, err := url.Parse(uri); err == nil {
+		path = parsedUri.Path
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	supiFound := false
+	for i, segment := range segments {
+		if segment == supi {
+			segments = segments[i+1:]
+			supiFound = true
+			break
+		}
+	}
+	for _, segment := range segments {
+		if sdmResource, ok := udrResourceToSdmResource[segment]; ok {
+			return sdmResource, true
 		}
-		defer func() {
+	}
+	if supiFound && len(segments) == 0 {
+		return "", true
+	}
+	return "", false
+}
+
+// sendDataChangeNotification delivers the ModificationNotification to the callbackReference,
+// retrying with backoff when the NF is unreachable or fails with a server error
+func sendDataChangeNotification(callbackReference string, modificationNotification models.ModificationNotification) {
+	target := notificationTarget(callbackReference)
+	configuration := Nudm_SubscriberDataManagement.NewConfiguration()
+	clientAPI := Nudm_SubscriberDataManagement.NewAPIClient(configuration)
+
+	retryInterval := notificationRetryInterval
+	for attempt := 1; ; attempt++ {
+		httpResponse, err := clientAPI.DataChangeNotificationCallbackDocumentApi.OnDataChangeNotification(
+			context.TODO(), callbackReference, modificationNotification)
+		if httpResponse != nil {
 			if rspCloseErr := httpResponse.Body.Close(); rspCloseErr != nil {
 				logger.HttpLog.Errorf("OnDataChangeNotification response body cannot close: %+v", rspCloseErr)
 			}
-		}()
+		}
+		if err == nil {
+			stats.IncrementUdmSdmNotificationStats(target, "SUCCESS")
+			return
+		}
+
+		logger.HttpLog.Warnf("data change notification to %s failed (attempt %d/%d): %+v",
+			callbackReference, attempt, notificationMaxAttempts, err)
+		retriable := httpResponse == nil || httpResponse.StatusCode >= http.StatusInternalServerError
+		if !retriable || attempt >= notificationMaxAttempts {
+			stats.IncrementUdmSdmNotificationStats(target, "FAILURE")
+			return
+		}
+		stats.IncrementUdmSdmNotificationStats(target, "RETRY")
+		time.Sleep(retryInterval)
+		retryInterval *= 2
 	}
+}
 
-	return problemDetails
+// notificationTarget returns the host of the callbackReference, used to label the notification metrics
+func notificationTarget(callbackReference string) string {
+	if parsedUri, err := url.Parse(callbackReference); err == nil && parsedUri.Host != "" {
+		return parsedUri.Host
+	}
+	return "unknown"
 }
 
 func SendOnDeregistrationNotification(ueId string, onDeregistrationNotificationUrl string,
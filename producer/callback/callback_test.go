@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package callback
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestMatchMonitoredResources(t *testing.T) {
+	supi := "imsi-208930000000001"
+	notifyItems := []models.NotifyItem{
+		{ResourceId: "/nudr-dr/v1/subscription-data/" + supi + "/20893/provisioned-data/am-data"},
+		{ResourceId: "/nudr-dr/v1/subscription-data/" + supi + "/20893/provisioned-data/smf-selection-subscription-data"},
+	}
+
+	testCases := []struct {
+		name                  string
+		monitoredResourceUris []string
+		expectedResourceIds   []string
+	}{
+		{
+			name:                  "single dataset monitored",
+			monitoredResourceUris: []string{"http://udm:29503/nudm-sdm/v2/" + supi + "/am-data"},
+			expectedResourceIds:   []string{"http://udm:29503/nudm-sdm/v2/" + supi + "/am-data"},
+		},
+		{
+			name:                  "renamed dataset monitored",
+			monitoredResourceUris: []string{"/nudm-sdm/v2/" + supi + "/smf-select-data"},
+			expectedResourceIds:   []string{"/nudm-sdm/v2/" + supi + "/smf-select-data"},
+		},
+		{
+			name:                  "whole subscriber monitored",
+			monitoredResourceUris: []string{"/nudm-sdm/v2/" + supi},
+			expectedResourceIds:   []string{"/nudm-sdm/v2/" + supi, "/nudm-sdm/v2/" + supi},
+		},
+		{
+			name:                  "unrelated dataset monitored",
+			monitoredResourceUris: []string{"/nudm-sdm/v2/" + supi + "/sm-data"},
+			expectedResourceIds:   nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matchedItems := matchMonitoredResources(tc.monitoredResourceUris, notifyItems, supi)
+			if len(matchedItems) != len(tc.expectedResourceIds) {
+				t.Fatalf("expected %d notify items, got %d: %+v", len(tc.expectedResourceIds), len(matchedItems), matchedItems)
+			}
+			for i, matchedItem := range matchedItems {
+				if matchedItem.ResourceId != tc.expectedResourceIds[i] {
+					t.Errorf("expected resourceId %s, got %s", tc.expectedResourceIds[i], matchedItem.ResourceId)
+				}
+			}
+		})
+	}
+}
+
+func TestSendDataChangeNotification_Retries(t *testing.T) {
+	originalRetryInterval := notificationRetryInterval
+	defer func() {
+		notificationRetryInterval = originalRetryInterval
+	}()
+	notificationRetryInterval = time.Millisecond
+
+	var attempts atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < int32(notificationMaxAttempts) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	sendDataChangeNotification(server.URL, models.ModificationNotification{
+		NotifyItems: []models.NotifyItem{{ResourceId: "/nudm-sdm/v2/imsi-208930000000001/am-data"}},
+	})
+
+	if attempts.Load() != int32(notificationMaxAttempts) {
+		t.Errorf("expected %d attempts, got %d", notificationMaxAttempts, attempts.Load())
+	}
+}
+
+func TestSendDataChangeNotification_NoRetryOnClientError(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	sendDataChangeNotification(server.URL, models.ModificationNotification{})
+
+	if attempts.Load() != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts.Load())
+	}
+}
+
+func TestSendMonitoringReports_Retries(t *testing.T) {
+	originalRetryInterval := notificationRetryInterval
+	defer func() {
+		notificationRetryInterval = originalRetryInterval
+	}()
+	notificationRetryInterval = time.Millisecond
+
+	var attempts atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < int32(notificationMaxAttempts) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	delivered := SendMonitoringReports(server.URL, []MonitoringReport{
+		{MonitoringReport: models.MonitoringReport{ReferenceId: 1, EventType: models.EventType_UE_REACHABILITY_FOR_DATA}},
+	})
+
+	if !delivered || attempts.Load() != int32(notificationMaxAttempts) {
+		t.Errorf("expected delivery after %d attempts, got %v after %d", notificationMaxAttempts, delivered,
+			attempts.Load())
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package callback
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/Nudm_EventExposure"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/logger"
+	stats "github.com/omec-project/udm/metrics"
+)
+
+// MonitoringReport is the MonitoringReport of TS 29.503 6.4.6.2.7, whose report may be any of the
+// reports defined for its event type and not only those of the generated model
+type MonitoringReport struct {
+	models.MonitoringReport
+	Report interface{} `json:"report,omitempty"`
+}
+
+// SendMonitoringReports delivers the monitoring reports of an EE subscription to its
+// callbackReference (TS 29.503 6.4.5.2), retrying with backoff when the NF is unreachable or fails
+// with a server error. It reports whether the reports were delivered.
+func SendMonitoringReports(callbackReference string, monitoringReports []MonitoringReport) bool {
+	retryInterval := notificationRetryInterval
+	for attempt := 1; ; attempt++ {
+		httpResponse, err := postMonitoringReports(callbackReference, monitoringReports)
+		if err == nil {
+			incrementEeNotificationStats(monitoringReports, "SUCCESS")
+			return true
+		}
+
+		logger.HttpLog.Warnf("EE monitoring reports to %s failed (attempt %d/%d): %+v",
+			callbackReference, attempt, notificationMaxAttempts, err)
+		retriable := httpResponse == nil || httpResponse.StatusCode >= http.StatusInternalServerError
+		if !retriable || attempt >= notificationMaxAttempts {
+			incrementEeNotificationStats(monitoringReports, "FAILURE")
+			return false
+		}
+		incrementEeNotificationStats(monitoringReports, "RETRY")
+		time.Sleep(retryInterval)
+		retryInterval *= 2
+	}
+}
+
+func postMonitoringReports(callbackReference string, monitoringReports []MonitoringReport) (
+	*http.Response, error,
+) {
+	configuration := Nudm_EventExposure.NewConfiguration()
+	headerParams := map[string]string{
+		"Content-Type": "application/json",
+		"Accept":       "application/problem+json",
+	}
+	request, err := openapi.PrepareRequest(context.TODO(), configuration, callbackReference, http.MethodPost,
+		&monitoringReports, headerParams, url.Values{}, url.Values{}, "", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResponse, err := openapi.CallAPI(configuration, request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rspCloseErr := httpResponse.Body.Close(); rspCloseErr != nil {
+			logger.HttpLog.Errorf("EE monitoring reports response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+	if _, err = io.Copy(io.Discard, httpResponse.Body); err != nil {
+		return httpResponse, err
+	}
+	if httpResponse.StatusCode != http.StatusNoContent && httpResponse.StatusCode != http.StatusOK {
+		return httpResponse, fmt.Errorf("unexpected status %s", httpResponse.Status)
+	}
+	return httpResponse, nil
+}
+
+func incrementEeNotificationStats(monitoringReports []MonitoringReport, result string) {
+	for _, monitoringReport := range monitoringReports {
+		stats.IncrementUdmEeNotificationStats(string(monitoringReport.EventType), result)
+	}
+}
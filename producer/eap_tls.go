@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/omec-project/openapi/models"
+)
+
+// Certificate based authentication methods of TS 33.501 Annex B and Annex U, for the SNPN subscribers
+// whose credentials are held by a Credentials Holder (TS 23.501 5.30.2.9). No vector is generated.
+const (
+	AuthMethodEapTls  models.AuthMethod = "EAP_TLS"
+	AuthMethodEapTtls models.AuthMethod = "EAP_TTLS"
+	AuthTypeEapTtls   models.AuthType   = "EAP_TTLS"
+)
+
+const (
+	imsiPrefix = "imsi-"
+	naiPrefix  = "nai-"
+)
+
+// isCertificateBased tells whether the authentication method is EAP-TLS or EAP-TTLS, returning its type
+func isCertificateBased(method models.AuthMethod) (models.AuthType, bool) {
+	switch method {
+	case AuthMethodEapTls:
+		return models.AuthType_EAP_TLS, true
+	case AuthMethodEapTtls:
+		return AuthTypeEapTtls, true
+	default:
+		return "", false
+	}
+}
+
+// validateSupi checks the format of a SUPI of TS 29.571 5.3.2: an IMSI of 5 to 15 digits, or a NAI
+// username@realm of RFC 7542, as the Network Specific Identifiers of SNPNs (TS 23.003 28.7.2)
+func validateSupi(supi string) error {
+	switch {
+	case strings.HasPrefix(supi, imsiPrefix):
+		imsi := strings.TrimPrefix(supi, imsiPrefix)
+		if len(imsi) < 5 || len(imsi) > 15 || strings.Trim(imsi, "0123456789") != "" {
+			return fmt.Errorf("invalid IMSI %s", imsi)
+		}
+		return nil
+	case strings.HasPrefix(supi, naiPrefix):
+		return validateNai(strings.TrimPrefix(supi, naiPrefix))
+	default:
+		return fmt.Errorf("unknown SUPI type of %s", supi)
+	}
+}
+
+// validateNai checks a NAI username@realm (RFC 7542 2.2), whose realm is a domain name
+func validateNai(nai string) error {
+	separator := strings.LastIndex(nai, "@")
+	if separator < 0 {
+		return fmt.Errorf("NAI %s without realm", nai)
+	}
+	username, realm := nai[:separator], nai[separator+1:]
+	if username == "" || strings.ContainsAny(username, "@ \t\"") {
+		return fmt.Errorf("invalid username of NAI %s", nai)
+	}
+	if len(realm) > 253 {
+		return fmt.Errorf("realm of NAI %s too long", nai)
+	}
+	for _, label := range strings.Split(realm, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' ||
+			strings.Trim(strings.ToLower(label), "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+			return fmt.Errorf("invalid realm of NAI %s", nai)
+		}
+	}
+	return nil
+}
+
+// credentialHolderId returns the identity of the Credentials Holder of a SUPI, which is the realm of
+// its NAI (TS 23.501 5.30.2.9.1), or "" for an IMSI
+func credentialHolderId(supi string) string {
+	if !strings.HasPrefix(supi, naiPrefix) {
+		return ""
+	}
+	return strings.ToLower(supi[strings.LastIndex(supi, "@")+1:])
+}
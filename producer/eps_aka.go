@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"fmt"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/util/ueauth"
+)
+
+// EPS-AKA vectors of TS 33.401 6.1.2, for the serving networks of E-UTRAN
+const (
+	AuthTypeEpsAka models.AuthType = "EPS_AKA"
+	AvTypeEpsAka   models.AvType   = "EPS_AKA"
+)
+
+// fcForKasmeDerivation is the FC of the derivation of KASME (TS 33.401 A.2)
+const fcForKasmeDerivation = "10"
+
+// servingNetworkId encodes the PLMN ID of a serving network as the SN id of TS 33.401 A.2, which is
+// the PLMN identity of TS 24.301 9.9.3.12: MCC digit 2 | MCC digit 1, MNC digit 3 | MCC digit 3,
+// MNC digit 2 | MNC digit 1, the MNC digit 3 of a 2 digits MNC being 0xf
+func servingNetworkId(plmnId models.PlmnId) ([]byte, error) {
+	if len(plmnId.Mcc) != 3 || (len(plmnId.Mnc) != 2 && len(plmnId.Mnc) != 3) {
+		return nil, fmt.Errorf("invalid PLMN ID %s-%s", plmnId.Mcc, plmnId.Mnc)
+	}
+	digits := make([]byte, 0, 6)
+	for _, digit := range plmnId.Mcc + plmnId.Mnc {
+		if digit < '0' || digit > '9' {
+			return nil, fmt.Errorf("invalid PLMN ID %s-%s", plmnId.Mcc, plmnId.Mnc)
+		}
+		digits = append(digits, byte(digit-'0'))
+	}
+	mnc3 := byte(0xf)
+	if len(digits) == 6 {
+		mnc3 = digits[5]
+	}
+	return []byte{
+		digits[1]<<4 | digits[0],
+		mnc3<<4 | digits[2],
+		digits[4]<<4 | digits[3],
+	}, nil
+}
+
+// deriveKasme derives KASME from CK, IK, the SN id of the serving network and SQN xor AK
+// (TS 33.401 A.2)
+func deriveKasme(ck, ik, snId, sqnXorAk []byte) ([]byte, error) {
+	key := append(append([]byte{}, ck...), ik...)
+	return ueauth.GetKDFValue(key, fcForKasmeDerivation, snId, ueauth.KDFLen(snId), sqnXorAk,
+		ueauth.KDFLen(sqnXorAk))
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestServingNetworkId(t *testing.T) {
+	testCases := []struct {
+		plmnId   models.PlmnId
+		expected string
+	}{
+		{plmnId: models.PlmnId{Mcc: "208", Mnc: "93"}, expected: "02f839"},
+		{plmnId: models.PlmnId{Mcc: "310", Mnc: "410"}, expected: "130014"},
+		{plmnId: models.PlmnId{Mcc: "001", Mnc: "01"}, expected: "00f110"},
+	}
+	for _, tc := range testCases {
+		snId, err := servingNetworkId(tc.plmnId)
+		if err != nil {
+			t.Fatalf("%s-%s: unexpected error: %v", tc.plmnId.Mcc, tc.plmnId.Mnc, err)
+		}
+		if hex.EncodeToString(snId) != tc.expected {
+			t.Errorf("%s-%s: expected %s, got %x", tc.plmnId.Mcc, tc.plmnId.Mnc, tc.expected, snId)
+		}
+	}
+
+	for _, invalid := range []models.PlmnId{{Mcc: "20", Mnc: "93"}, {Mcc: "208", Mnc: "9"}, {Mcc: "2o8", Mnc: "93"}} {
+		if _, err := servingNetworkId(invalid); err == nil {
+			t.Errorf("%s-%s: expected an error", invalid.Mcc, invalid.Mnc)
+		}
+	}
+}
+
+// TestGenerateAuthenticationVector_EpsAka checks the EPS-AKA vector against the outputs of the algorithm
+// with the test set 1 of TS 35.208 and KASME against the KDF of TS 33.220 B.2 with the parameters of
+// TS 33.401 A.2
+func TestGenerateAuthenticationVector_EpsAka(t *testing.T) {
+	algorithm, err := newAuthAlgorithm(&models.AuthenticationSubscription{
+		PermanentKey: &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"},
+		Opc:          &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sqn := decodeHexString(t, "ff9bb4d0b607")
+	amf := decodeHexString(t, "b9b9")
+	snId := decodeHexString(t, "02f839")
+
+	av, err := generateAuthenticationVector(algorithm, AuthTypeEpsAka, sqn, amf, "", snId)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if av.AvType != AvTypeEpsAka || av.CkPrime != "" || av.XresStar != "" || av.Kausf != "" {
+		t.Errorf("expected an EPS-AKA vector, got %+v", av)
+	}
+
+	rand := decodeHexString(t, av.Rand)
+	res, ck, ik, ak, _, err := algorithm.f2345(rand)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	macA, _, err := algorithm.f1(rand, sqn, amf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sqnXorAk := make([]byte, len(sqn))
+	for i := range sqn {
+		sqnXorAk[i] = sqn[i] ^ ak[i]
+	}
+	if expected := hex.EncodeToString(append(append(append([]byte{}, sqnXorAk...), amf...), macA...)); av.Autn != expected {
+		t.Errorf("expected AUTN %s, got %s", expected, av.Autn)
+	}
+	if av.Xres != hex.EncodeToString(res) {
+		t.Errorf("expected XRES %x, got %s", res, av.Xres)
+	}
+
+	mac := hmac.New(sha256.New, append(append([]byte{}, ck...), ik...))
+	mac.Write([]byte{0x10})
+	mac.Write(snId)
+	mac.Write([]byte{0x00, 0x03})
+	mac.Write(sqnXorAk)
+	mac.Write([]byte{0x00, 0x06})
+	if expected := hex.EncodeToString(mac.Sum(nil)); av.Kasme != expected {
+		t.Errorf("expected KASME %s, got %s", expected, av.Kasme)
+	}
+}
+
+func TestRequestedVectors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		request       AuthenticationInfoRequest
+		expectedCount int
+		expectedSnId  string
+		expectedCause string
+	}{
+		{name: "default", expectedCount: 1},
+		{name: "batch", request: AuthenticationInfoRequest{NumberOfRequestedVectors: 3}, expectedCount: 3},
+		{
+			name:          "batch bounded",
+			request:       AuthenticationInfoRequest{NumberOfRequestedVectors: maxRequestedVectors},
+			expectedCount: maxRequestedVectors,
+		},
+		{
+			name:          "too many vectors",
+			request:       AuthenticationInfoRequest{NumberOfRequestedVectors: maxRequestedVectors + 1},
+			expectedCause: "OPTIONAL_IE_INCORRECT",
+		},
+		{
+			name:          "EPS-AKA",
+			request:       AuthenticationInfoRequest{ServingNetworkId: &models.PlmnId{Mcc: "208", Mnc: "93"}},
+			expectedCount: 1,
+			expectedSnId:  "02f839",
+		},
+		{
+			name:          "negative",
+			request:       AuthenticationInfoRequest{NumberOfRequestedVectors: -1},
+			expectedCause: "OPTIONAL_IE_INCORRECT",
+		},
+		{
+			name:          "invalid servingNetworkId",
+			request:       AuthenticationInfoRequest{ServingNetworkId: &models.PlmnId{Mcc: "208"}},
+			expectedCause: "OPTIONAL_IE_INCORRECT",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			count, snId, problemDetails := requestedVectors(tc.request)
+			if tc.expectedCause != "" {
+				if problemDetails == nil || problemDetails.Status != http.StatusBadRequest ||
+					problemDetails.Cause != tc.expectedCause {
+					t.Errorf("expected %s, got %+v", tc.expectedCause, problemDetails)
+				}
+				return
+			}
+			if problemDetails != nil {
+				t.Fatalf("unexpected problem: %+v", problemDetails)
+			}
+			if count != tc.expectedCount || hex.EncodeToString(snId) != tc.expectedSnId {
+				t.Errorf("expected %d vectors and SN id %s, got %d and %x", tc.expectedCount, tc.expectedSnId, count,
+					snId)
+			}
+		})
+	}
+}
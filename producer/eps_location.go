@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/logger"
+)
+
+// EpsSubscriptionData is the subscription data of a UE attached to an MME of E-UTRAN, returned by the
+// S6a front end in the Subscription-Data of TS 29.272 7.3.2
+type EpsSubscriptionData struct {
+	AmData *models.AccessAndMobilitySubscriptionData
+	SmData []models.SessionManagementSubscriptionData
+}
+
+// mmeDeregCallbackUri is the DiameterURI of an MME (RFC 6733 4.3.1), standing for the deregistration
+// callback of its registration, as the MMEs are not notified through Nudm_UECM
+func mmeDeregCallbackUri(mmeHost string) string {
+	return "aaa://" + mmeHost
+}
+
+// isSbiCallback reports whether the callback URI of a registration is an HTTP one of an NF, rather
+// than the DiameterURI of an MME, which has no Nudm_UECM or Namf_EventExposure service
+func isSbiCallback(callbackUri string) bool {
+	parsedUri, err := url.Parse(callbackUri)
+	if err != nil || parsedUri.Host == "" {
+		return false
+	}
+	return parsedUri.Scheme == "http" || parsedUri.Scheme == "https"
+}
+
+// UpdateLocationProcedure returns the subscription data of the UE attached to the MME in the visited
+// PLMN and registers the MME as serving its 3GPP access, as the HSS of TS 29.272 5.2.1.1.3 would
+func UpdateLocationProcedure(supi string, mmeHost string, visitedPlmnId models.PlmnId, initialAttach bool) (
+	response *EpsSubscriptionData, problemDetails *models.ProblemDetails,
+) {
+	if err := validateSupi(supi); err != nil {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: err.Error(),
+		}
+		return nil, problemDetails
+	}
+	plmnID := visitedPlmnId.Mcc + visitedPlmnId.Mnc
+
+	amData, problemDetails := getAmDataProcedure(supi, plmnID, "")
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+	smData, problemDetails := fetchSmData(supi, plmnID, "")
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+
+	_, _, problemDetails = RegistrationAmf3gppAccessProcedure(models.Amf3GppAccessRegistration{
+		AmfInstanceId:          mmeHost,
+		DeregCallbackUri:       mmeDeregCallbackUri(mmeHost),
+		InitialRegistrationInd: initialAttach,
+		Guami:                  &models.Guami{PlmnId: &visitedPlmnId},
+		RatType:                models.RatType_EUTRA,
+	}, supi)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+	logger.UecmLog.Infof("MME %s registered for %s", mmeHost, supi)
+	return &EpsSubscriptionData{AmData: amData, SmData: smData}, nil
+}
+
+// PurgeUeProcedure marks the UE purged in the MME if it is the registered one (TS 29.272 5.2.1.3.3),
+// reporting whether it was
+func PurgeUeProcedure(supi string, mmeHost string) (purged bool, problemDetails *models.ProblemDetails) {
+	registration := udm_context.UDM_Self().GetAmf3gppRegContext(supi)
+	if registration == nil || registration.AmfInstanceId != mmeHost {
+		logger.UecmLog.Infof("purge of %s by MME %s, which is not registered", supi, mmeHost)
+		return false, nil
+	}
+	problemDetails = UpdateAmf3gppAccessProcedure(models.Amf3GppAccessRegistrationModification{
+		PurgeFlag: true,
+	}, supi)
+	if problemDetails != nil {
+		return false, problemDetails
+	}
+	return true, nil
+}
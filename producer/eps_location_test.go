@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+)
+
+func TestUpdateLocationProcedure_InvalidSupi(t *testing.T) {
+	_, problemDetails := UpdateLocationProcedure("imsi-0010a", "mme.epc", models.PlmnId{Mcc: "001", Mnc: "01"}, true)
+	if problemDetails == nil || problemDetails.Status != http.StatusBadRequest {
+		t.Fatalf("expected a bad request, got %+v", problemDetails)
+	}
+}
+
+func TestPurgeUeProcedure_OtherMme(t *testing.T) {
+	const supi = "imsi-001010000000031"
+	udm_context.UDM_Self().CreateAmf3gppRegContext(supi, models.Amf3GppAccessRegistration{
+		AmfInstanceId: "mme2.epc",
+	})
+
+	testCases := []struct {
+		name string
+		supi string
+	}{
+		{name: "registered in another MME", supi: supi},
+		{name: "not registered", supi: "imsi-001010000000032"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			purged, problemDetails := PurgeUeProcedure(tc.supi, "mme.epc")
+			if purged || problemDetails != nil {
+				t.Fatalf("purged %v, %+v", purged, problemDetails)
+			}
+		})
+	}
+	if registration := udm_context.UDM_Self().GetAmf3gppRegContext(supi); registration.PurgeFlag {
+		t.Fatal("registration of another MME purged")
+	}
+}
+
+func TestRegistrationAmf3gppAccessProcedure_AfterUpdateLocation(t *testing.T) {
+	const supi = "imsi-001010000000033"
+	udr := newFakeUdr()
+	udr.set("/subscription-data/"+supi+"/00101/provisioned-data/am-data",
+		models.AccessAndMobilitySubscriptionData{Gpsis: []string{"msisdn-0900000033"}})
+	udr.set("/subscription-data/"+supi+"/00101/provisioned-data/sm-data",
+		[]models.SessionManagementSubscriptionData{{SingleNssai: &models.Snssai{Sst: 1}}})
+	useFakeUdr(t, udr)
+
+	originalSendOnDeregistrationNotification := sendOnDeregistrationNotification
+	defer func() {
+		sendOnDeregistrationNotification = originalSendOnDeregistrationNotification
+	}()
+	var notified []string
+	sendOnDeregistrationNotification = func(ueId string, onDeregistrationNotificationUrl string,
+		deregistData models.DeregistrationData,
+	) *models.ProblemDetails {
+		notified = append(notified, onDeregistrationNotificationUrl)
+		return nil
+	}
+
+	plmnID := models.PlmnId{Mcc: "001", Mnc: "01"}
+	for _, initialAttach := range []bool{true, false} {
+		if _, problemDetails := UpdateLocationProcedure(supi, "mme.epc", plmnID, initialAttach); problemDetails != nil {
+			t.Fatalf("UpdateLocationProcedure failed: %+v", problemDetails)
+		}
+	}
+	ue, _ := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if _, ok := amfApiRootOf(ue); ok {
+		t.Error("expected no Namf_EventExposure apiRoot for the MME")
+	}
+
+	_, _, problemDetails := RegistrationAmf3gppAccessProcedure(models.Amf3GppAccessRegistration{
+		AmfInstanceId:    "e6a8b4d2-7c1f-4a5e-9b3d-2f6c8a0e1b47",
+		DeregCallbackUri: "https://amf:29518/namf-callback/v1/" + supi + "/dereg-notify",
+		Guami:            &models.Guami{PlmnId: &plmnID},
+		RatType:          models.RatType_NR,
+	}, supi)
+	if problemDetails != nil {
+		t.Fatalf("RegistrationAmf3gppAccessProcedure failed: %+v", problemDetails)
+	}
+	if len(notified) != 0 {
+		t.Errorf("expected the MME not to be notified, got notifications to %v", notified)
+	}
+	if amfApiRoot, ok := amfApiRootOf(ue); !ok || amfApiRoot != "https://amf:29518" {
+		t.Errorf("expected the apiRoot of the AMF, got %q", amfApiRoot)
+	}
+}
@@ -6,17 +6,28 @@
 package producer
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/omec-project/openapi"
 	"github.com/omec-project/openapi/models"
 	udm_context "github.com/omec-project/udm/context"
 	"github.com/omec-project/udm/logger"
+	"github.com/omec-project/udm/util"
 	"github.com/omec-project/util/httpwrapper"
 )
 
-const anyUE = "anyUE"
+// createdEeSubscription is the CreatedEeSubscription of TS 29.503 6.4.6.2.3, which carries the
+// subscriptionId missing from the generated model
+type createdEeSubscription struct {
+	models.CreatedEeSubscription
+	SubscriptionId string `json:"subscriptionId"`
+}
 
 func HandleCreateEeSubscription(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.EeLog.Infoln("Handle Create EE Subscription")
@@ -26,7 +37,11 @@ func HandleCreateEeSubscription(request *httpwrapper.Request) *httpwrapper.Respo
 
 	createdEESubscription, problemDetails := CreateEeSubscriptionProcedure(ueIdentity, eesubscription)
 	if createdEESubscription != nil {
-		return httpwrapper.NewResponse(http.StatusCreated, nil, createdEESubscription)
+		headers := http.Header{
+			"Location": {udm_context.UDM_Self().GetIPv4Uri() + "/nudm-ee/v1/" + ueIdentity + "/ee-subscriptions/" +
+				createdEESubscription.SubscriptionId},
+		}
+		return httpwrapper.NewResponse(http.StatusCreated, headers, createdEESubscription)
 	} else if problemDetails != nil {
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	} else {
@@ -38,36 +53,24 @@ func HandleCreateEeSubscription(request *httpwrapper.Request) *httpwrapper.Respo
 	}
 }
 
-// TODO: complete this procedure based on TS 29503 5.5
 func CreateEeSubscriptionProcedure(ueIdentity string,
 	eesubscription models.EeSubscription,
-) (*models.CreatedEeSubscription, *models.ProblemDetails) {
+) (*createdEeSubscription, *models.ProblemDetails) {
 	udmSelf := udm_context.UDM_Self()
 
 	logger.EeLog.Debugf("udIdentity: %s", ueIdentity)
+	if problemDetails := validateEeSubscription(eesubscription); problemDetails != nil {
+		return nil, problemDetails
+	}
+
+	numberOfUes := int32(0)
 	switch {
 	// GPSI (MSISDN identifier) represents a single UE
 	case strings.HasPrefix(ueIdentity, "msisdn-"):
 		fallthrough
 	// GPSI (External identifier) represents a single UE
 	case strings.HasPrefix(ueIdentity, "extid-"):
-		if ue, ok := udmSelf.UdmUeFindByGpsi(ueIdentity); ok {
-			id, err := udmSelf.EeSubscriptionIDGenerator.Allocate()
-			if err != nil {
-				problemDetails := &models.ProblemDetails{
-					Status: http.StatusInternalServerError,
-					Cause:  "UNSPECIFIED_NF_FAILURE",
-				}
-				return nil, problemDetails
-			}
-
-			subscriptionID := strconv.Itoa(int(id))
-			ue.EeSubscriptions[subscriptionID] = &eesubscription
-			createdEeSubscription := &models.CreatedEeSubscription{
-				EeSubscription: &eesubscription,
-			}
-			return createdEeSubscription, nil
-		} else {
+		if _, ok := udmSelf.UdmUeFindByGpsi(ueIdentity); !ok {
 			problemDetails := &models.ProblemDetails{
 				Status: http.StatusNotFound,
 				Cause:  "USER_NOT_FOUND",
@@ -76,47 +79,9 @@ func CreateEeSubscriptionProcedure(ueIdentity string,
 		}
 	// external groupID represents a group of UEs
 	case strings.HasPrefix(ueIdentity, "extgroupid-"):
-		id, err := udmSelf.EeSubscriptionIDGenerator.Allocate()
-		if err != nil {
-			problemDetails := &models.ProblemDetails{
-				Status: http.StatusInternalServerError,
-				Cause:  "UNSPECIFIED_NF_FAILURE",
-			}
-			return nil, problemDetails
-		}
-		subscriptionID := strconv.Itoa(int(id))
-		createdEeSubscription := &models.CreatedEeSubscription{
-			EeSubscription: &eesubscription,
-		}
-
-		udmSelf.UdmUePool.Range(func(key, value interface{}) bool {
-			ue := value.(*udm_context.UdmUeContext)
-			if ue.ExternalGroupID == ueIdentity {
-				ue.EeSubscriptions[subscriptionID] = &eesubscription
-			}
-			return true
-		})
-		return createdEeSubscription, nil
+		numberOfUes = int32(len(udmSelf.UdmUesFindByExternalGroupID(ueIdentity)))
 	// represents any UEs
-	case ueIdentity == anyUE:
-		id, err := udmSelf.EeSubscriptionIDGenerator.Allocate()
-		if err != nil {
-			problemDetails := &models.ProblemDetails{
-				Status: http.StatusInternalServerError,
-				Cause:  "UNSPECIFIED_NF_FAILURE",
-			}
-			return nil, problemDetails
-		}
-		subscriptionID := strconv.Itoa(int(id))
-		createdEeSubscription := &models.CreatedEeSubscription{
-			EeSubscription: &eesubscription,
-		}
-		udmSelf.UdmUePool.Range(func(key, value interface{}) bool {
-			ue := value.(*udm_context.UdmUeContext)
-			ue.EeSubscriptions[subscriptionID] = &eesubscription
-			return true
-		})
-		return createdEeSubscription, nil
+	case ueIdentity == udm_context.AnyUE:
 	default:
 		problemDetails := &models.ProblemDetails{
 			Status: http.StatusBadRequest,
@@ -130,52 +95,356 @@ func CreateEeSubscriptionProcedure(ueIdentity string,
 		}
 		return nil, problemDetails
 	}
+
+	// the subscriptions take the ID allocated by the UDR, under which they are restored after a restart.
+	// Those stored before are loaded first, not to restore the new one a second time.
+	if err := LoadEeSubscriptions(ueIdentity); err != nil {
+		logger.EeLog.Warnf("EE subscriptions of %s not loaded from the UDR: %+v", ueIdentity, err)
+	}
+	subscriptionID, problemDetails := storeEeSubscription(ueIdentity, eesubscription)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+
+	eeSubscriptionContext := udm_context.NewEeSubscriptionContext(subscriptionID, ueIdentity, eesubscription)
+	udmSelf.AddEeSubscription(eeSubscriptionContext)
+	scheduleEeSubscriptionExpiry(eeSubscriptionContext)
+	if isGpsi(ueIdentity) {
+		reportImmediateUeEvents(eeSubscriptionContext)
+	}
+	go delegateAmfEventsOfSubscription(eeSubscriptionContext)
+
+	createdEeSubscription := &createdEeSubscription{
+		CreatedEeSubscription: models.CreatedEeSubscription{
+			EeSubscription: &eesubscription,
+			NumberOfUes:    numberOfUes,
+		},
+		SubscriptionId: subscriptionID,
+	}
+	return createdEeSubscription, nil
+}
+
+func validateEeSubscription(eesubscription models.EeSubscription) *models.ProblemDetails {
+	var missing []models.InvalidParam
+	if eesubscription.CallbackReference == "" {
+		missing = append(missing, models.InvalidParam{Param: "callbackReference", Reason: "missing"})
+	}
+	if len(eesubscription.MonitoringConfigurations) == 0 {
+		missing = append(missing, models.InvalidParam{Param: "monitoringConfigurations", Reason: "missing"})
+	}
+	if len(missing) != 0 {
+		return &models.ProblemDetails{
+			Status:        http.StatusBadRequest,
+			Cause:         "MANDATORY_IE_MISSING",
+			InvalidParams: missing,
+		}
+	}
+	if reportingOptions := eesubscription.ReportingOptions; reportingOptions != nil {
+		if reportingOptions.Expiry != nil && !reportingOptions.Expiry.After(time.Now()) {
+			return &models.ProblemDetails{
+				Status:        http.StatusBadRequest,
+				Cause:         "OPTIONAL_IE_INCORRECT",
+				InvalidParams: []models.InvalidParam{{Param: "reportingOptions.expiry", Reason: "in the past"}},
+			}
+		}
+		if reportingOptions.MaxNumOfReports < 0 {
+			return &models.ProblemDetails{
+				Status:        http.StatusBadRequest,
+				Cause:         "OPTIONAL_IE_INCORRECT",
+				InvalidParams: []models.InvalidParam{{Param: "reportingOptions.maxNumOfReports", Reason: "negative"}},
+			}
+		}
+	}
+	return nil
+}
+
+func isGpsi(ueIdentity string) bool {
+	return strings.HasPrefix(ueIdentity, "msisdn-") || strings.HasPrefix(ueIdentity, "extid-")
+}
+
+// storedEeSubscription is the EeSubscription stored in the UDR, with the subscriptionId identifying
+// it once it is read back
+type storedEeSubscription struct {
+	models.EeSubscription
+	SubscriptionId string `json:"subscriptionId,omitempty"`
+}
+
+// storeEeSubscription stores the subscription in the UDR (TS 29.505 5.2.10) and returns its ID there
+func storeEeSubscription(ueIdentity string, eesubscription models.EeSubscription) (
+	string, *models.ProblemDetails,
+) {
+	clientAPI, err := createUDMClientToUDR(ueIdentity)
+	if err != nil {
+		logger.EeLog.Errorf("EE subscription of %s not stored in the UDR: %+v", ueIdentity, err)
+		return "", util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	var res *http.Response
+	if isGpsi(ueIdentity) {
+		_, res, err = clientAPI.EventExposureSubscriptionsCollectionApi.CreateEeSubscriptions(
+			context.Background(), ueIdentity, eesubscription)
+	} else {
+		_, res, err = clientAPI.EventExposureGroupSubscriptionsCollectionApi.CreateEeGroupSubscriptions(
+			context.Background(), ueIdentity, eesubscription)
+	}
+	if res != nil {
+		defer func() {
+			if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+				logger.EeLog.Errorf("CreateEeSubscriptions response body cannot close: %+v", rspCloseErr)
+			}
+		}()
+	}
+	if err != nil {
+		logger.EeLog.Errorf("EE subscription of %s not stored in the UDR: %+v", ueIdentity, err)
+		if res == nil {
+			return "", util.ProblemDetailsSystemFailure(err.Error())
+		}
+		problemDetails := &models.ProblemDetails{
+			Status: int32(res.StatusCode),
+			Cause:  "UNSPECIFIED_NF_FAILURE",
+			Detail: err.Error(),
+		}
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if model, ok := apiErr.Model().(models.ProblemDetails); ok && model.Cause != "" {
+				problemDetails.Cause = model.Cause
+			}
+		}
+		return "", problemDetails
+	}
+
+	location := res.Header.Get("Location")
+	subscriptionID := location[strings.LastIndex(location, "/")+1:]
+	if subscriptionID == "" {
+		return "", util.ProblemDetailsSystemFailure("no subscription ID allocated by the UDR")
+	}
+	// the UDR does not return the IDs of the subscriptions it lists
+	if err := updateStoredEeSubscription(ueIdentity, subscriptionID, eesubscription); err != nil {
+		logger.EeLog.Warnf("EE subscription %s of %s stored without its ID, it cannot be restored: %+v",
+			subscriptionID, ueIdentity, err)
+	}
+	return subscriptionID, nil
+}
+
+// LoadEeSubscriptions restores the subscriptions of the ueIdentity stored in the UDR before the UDM
+// restarted, once per ueIdentity. The UDR lists the subscriptions by ueIdentity only, so those of the
+// GPSIs and groups are loaded when they are first used.
+func LoadEeSubscriptions(ueIdentity string) error {
+	udmSelf := udm_context.UDM_Self()
+	if udmSelf.EeSubscriptionsLoaded(ueIdentity) {
+		return nil
+	}
+	unlock := udmSelf.LockEeSubscriptionsLoad(ueIdentity)
+	defer unlock()
+	if udmSelf.EeSubscriptionsLoaded(ueIdentity) {
+		return nil
+	}
+
+	storedEeSubscriptions, err := queryStoredEeSubscriptions(ueIdentity)
+	if err != nil {
+		return err
+	}
+	for _, stored := range storedEeSubscriptions {
+		if stored.SubscriptionId == "" {
+			logger.EeLog.Warnf("EE subscription of %s stored without its ID, not restored", ueIdentity)
+			continue
+		}
+		if _, ok := udmSelf.FindEeSubscription(ueIdentity, stored.SubscriptionId); ok {
+			continue
+		}
+		logger.EeLog.Infof("EE subscription %s of %s restored from the UDR", stored.SubscriptionId, ueIdentity)
+		eeSubscriptionContext := udm_context.NewEeSubscriptionContext(stored.SubscriptionId, ueIdentity,
+			stored.EeSubscription)
+		udmSelf.AddEeSubscription(eeSubscriptionContext)
+		scheduleEeSubscriptionExpiry(eeSubscriptionContext)
+		go delegateAmfEventsOfSubscription(eeSubscriptionContext)
+	}
+	udmSelf.SetEeSubscriptionsLoaded(ueIdentity)
+	return nil
+}
+
+// loadUeEeSubscriptions restores the stored subscriptions that apply to the UE
+func loadUeEeSubscriptions(ue *udm_context.UdmUeContext) {
+	for _, ueIdentity := range udm_context.UDM_Self().EeSubscriptionTargets(ue) {
+		if err := LoadEeSubscriptions(ueIdentity); err != nil {
+			logger.EeLog.Warnf("EE subscriptions of %s not loaded from the UDR: %+v", ueIdentity, err)
+		}
+	}
+}
+
+// queryStoredEeSubscriptions returns the subscriptions of the ueIdentity stored in the UDR. The request
+// is built here because the generated query drops the subscriptionId of the stored subscriptions.
+func queryStoredEeSubscriptions(ueIdentity string) ([]storedEeSubscription, error) {
+	res, body, err := sendEeSubscriptionsRequest(ueIdentity, http.MethodGet, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		var storedEeSubscriptions []storedEeSubscription
+		if err := openapi.Deserialize(&storedEeSubscriptions, body, res.Header.Get("Content-Type")); err != nil {
+			return nil, err
+		}
+		return storedEeSubscriptions, nil
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+}
+
+// updateStoredEeSubscription replaces the subscription stored in the UDR, along with its ID. The
+// request is built here because the generated update only takes an EeSubscription.
+func updateStoredEeSubscription(ueIdentity string, udrSubscriptionID string,
+	eesubscription models.EeSubscription,
+) error {
+	stored := storedEeSubscription{EeSubscription: eesubscription, SubscriptionId: udrSubscriptionID}
+	res, _, err := sendEeSubscriptionsRequest(ueIdentity, http.MethodPut, udrSubscriptionID, &stored)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+// sendEeSubscriptionsRequest sends the request to the EE subscriptions of the ueIdentity in the UDR,
+// or to the subscription if udrSubscriptionID is set, and returns the response with its body read
+func sendEeSubscriptionsRequest(ueIdentity string, method string, udrSubscriptionID string,
+	body interface{},
+) (*http.Response, []byte, error) {
+	cfg, err := createUDRConfiguration(ueIdentity)
+	if err != nil {
+		return nil, nil, err
+	}
+	path := cfg.BasePath() + "/subscription-data/" + url.PathEscape(ueIdentity) + "/context-data/ee-subscriptions"
+	if !isGpsi(ueIdentity) {
+		path = cfg.BasePath() + "/subscription-data/group-data/" + url.PathEscape(ueIdentity) + "/ee-subscriptions"
+	}
+	if udrSubscriptionID != "" {
+		path += "/" + url.PathEscape(udrSubscriptionID)
+	}
+	headers := map[string]string{"Accept": "application/json, application/problem+json"}
+	if body != nil {
+		headers["Content-Type"] = "application/json"
+	}
+	request, err := openapi.PrepareRequest(context.Background(), cfg, path, method, body, headers,
+		url.Values{}, url.Values{}, "", "", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := openapi.CallAPI(cfg, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+			logger.EeLog.Errorf("EE subscriptions response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, resBody, nil
+}
+
+// removeStoredEeSubscription removes the subscription from the UDR and returns the status of the
+// UDR response, 0 if the UDR could not be reached
+func removeStoredEeSubscription(ueIdentity string, udrSubscriptionID string) (int, error) {
+	clientAPI, err := createUDMClientToUDR(ueIdentity)
+	if err != nil {
+		return 0, err
+	}
+
+	var res *http.Response
+	if isGpsi(ueIdentity) {
+		res, err = clientAPI.EventExposureSubscriptionDocumentApi.RemoveeeSubscriptions(
+			context.Background(), ueIdentity, udrSubscriptionID)
+	} else {
+		res, err = clientAPI.EventExposureSubscriptionDocumentApi.RemoveEeGroupSubscriptions(
+			context.Background(), ueIdentity, udrSubscriptionID)
+	}
+	if res == nil {
+		return 0, err
+	}
+	if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+		logger.EeLog.Errorf("RemoveeeSubscriptions response body cannot close: %+v", rspCloseErr)
+	}
+	return res.StatusCode, err
+}
+
+// scheduleEeSubscriptionExpiry removes the subscription once its expiry is reached
+func scheduleEeSubscriptionExpiry(eeSubscriptionContext *udm_context.EeSubscriptionContext) {
+	eeSubscriptionContext.ScheduleExpiry(func() {
+		logger.EeLog.Infof("EE subscription %s of %s expired", eeSubscriptionContext.SubscriptionID,
+			eeSubscriptionContext.UeIdentity)
+		RemoveEeSubscription(eeSubscriptionContext)
+	})
+}
+
+// RemoveEeSubscription removes a subscription that expired or reached its maximum number of reports
+func RemoveEeSubscription(eeSubscriptionContext *udm_context.EeSubscriptionContext) {
+	udmSelf := udm_context.UDM_Self()
+	if _, ok := udmSelf.RemoveEeSubscription(eeSubscriptionContext.UeIdentity,
+		eeSubscriptionContext.SubscriptionID); !ok {
+		return
+	}
+	releaseEeSubscription(eeSubscriptionContext)
+}
+
+// releaseEeSubscription removes the subscription from the AMFs it was delegated to and from the UDR
+func releaseEeSubscription(eeSubscriptionContext *udm_context.EeSubscriptionContext) {
+	removeAmfEventSubscriptions(eeSubscriptionContext)
+	if _, err := removeStoredEeSubscription(eeSubscriptionContext.UeIdentity,
+		eeSubscriptionContext.SubscriptionID); err != nil {
+		logger.EeLog.Warnf("removal of EE subscription %s of %s from the UDR failed: %+v",
+			eeSubscriptionContext.SubscriptionID, eeSubscriptionContext.UeIdentity, err)
+	}
 }
 
 func HandleDeleteEeSubscription(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.EeLog.Infoln("Handle Delete EE Subscription")
+
 	ueIdentity := request.Params["ueIdentity"]
 	subscriptionID := request.Params["subscriptionID"]
 
-	DeleteEeSubscriptionProcedure(ueIdentity, subscriptionID)
+	problemDetails := DeleteEeSubscriptionProcedure(ueIdentity, subscriptionID)
+	if problemDetails != nil {
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, nil)
 }
 
-// TODO: complete this procedure based on TS 29503 5.5
-func DeleteEeSubscriptionProcedure(ueIdentity string, subscriptionID string) {
-	udmSelf := udm_context.UDM_Self()
+func DeleteEeSubscriptionProcedure(ueIdentity string, subscriptionID string) *models.ProblemDetails {
+	if err := LoadEeSubscriptions(ueIdentity); err != nil {
+		logger.EeLog.Warnf("EE subscriptions of %s not loaded from the UDR: %+v", ueIdentity, err)
+	}
+	if eeSubscriptionContext, ok := udm_context.UDM_Self().RemoveEeSubscription(ueIdentity, subscriptionID); ok {
+		releaseEeSubscription(eeSubscriptionContext)
+		return nil
+	}
 
-	switch {
-	case strings.HasPrefix(ueIdentity, "msisdn-"):
-		fallthrough
-	case strings.HasPrefix(ueIdentity, "extid-"):
-		if ue, ok := udmSelf.UdmUeFindByGpsi(ueIdentity); ok {
-			delete(ue.EeSubscriptions, subscriptionID)
+	// the subscription may have been stored without its ID, in which case it is only in the UDR
+	status, err := removeStoredEeSubscription(ueIdentity, subscriptionID)
+	if err == nil {
+		return nil
+	}
+	logger.EeLog.Warnf("removal of EE subscription %s of %s from the UDR failed: %+v", subscriptionID, ueIdentity, err)
+	if status == 0 || status == http.StatusNotFound {
+		return &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "SUBSCRIPTION_NOT_FOUND",
 		}
-	case strings.HasPrefix(ueIdentity, "extgroupid-"):
-		udmSelf.UdmUePool.Range(func(key, value interface{}) bool {
-			ue := value.(*udm_context.UdmUeContext)
-			if ue.ExternalGroupID == ueIdentity {
-				delete(ue.EeSubscriptions, subscriptionID)
-			}
-			return true
-		})
-	case ueIdentity == anyUE:
-		udmSelf.UdmUePool.Range(func(key, value interface{}) bool {
-			ue := value.(*udm_context.UdmUeContext)
-			delete(ue.EeSubscriptions, subscriptionID)
-			return true
-		})
-	}
-	if id, err := strconv.ParseInt(subscriptionID, 10, 64); err != nil {
-		logger.EeLog.Warnf("subscriptionID covert type error: %+v", err)
-	} else {
-		udmSelf.EeSubscriptionIDGenerator.FreeID(id)
+	}
+	return &models.ProblemDetails{
+		Status: http.StatusInternalServerError,
+		Cause:  "UNSPECIFIED_NF_FAILURE",
 	}
 }
 
 func HandleUpdateEeSubscription(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.EeLog.Infoln("Handle Update EE subscription")
-	logger.EeLog.Warnln("Update EE Subscription is not implemented")
 
 	patchList := request.Body.([]models.PatchItem)
 	ueIdentity := request.Params["ueIdentity"]
@@ -189,74 +458,53 @@ func HandleUpdateEeSubscription(request *httpwrapper.Request) *httpwrapper.Respo
 	}
 }
 
-// TODO: complete this procedure based on TS 29503 5.5
 func UpdateEeSubscriptionProcedure(ueIdentity string, subscriptionID string,
 	patchList []models.PatchItem,
 ) *models.ProblemDetails {
-	udmSelf := udm_context.UDM_Self()
+	if err := LoadEeSubscriptions(ueIdentity); err != nil {
+		logger.EeLog.Warnf("EE subscriptions of %s not loaded from the UDR: %+v", ueIdentity, err)
+	}
+	eeSubscriptionContext, ok := udm_context.UDM_Self().FindEeSubscription(ueIdentity, subscriptionID)
+	if !ok {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "SUBSCRIPTION_NOT_FOUND",
+		}
+		return problemDetails
+	}
 
-	switch {
-	case strings.HasPrefix(ueIdentity, "msisdn-"):
-		fallthrough
-	case strings.HasPrefix(ueIdentity, "extid-"):
-		if ue, ok := udmSelf.UdmUeFindByGpsi(ueIdentity); ok {
-			if _, ok := ue.EeSubscriptions[subscriptionID]; ok {
-				for _, patchItem := range patchList {
-					logger.EeLog.Debugf("patch item: %+v", patchItem)
-					// TODO: patch the Eesubscription
-				}
-				return nil
-			} else {
-				problemDetails := &models.ProblemDetails{
-					Status: http.StatusNotFound,
-					Cause:  "SUBSCRIPTION_NOT_FOUND",
-				}
-				return problemDetails
-			}
-		} else {
-			problemDetails := &models.ProblemDetails{
-				Status: http.StatusNotFound,
-				Cause:  "SUBSCRIPTION_NOT_FOUND",
-			}
-			return problemDetails
+	eesubscription := eeSubscriptionContext.EeSubscription()
+	if err := util.ApplyJSONPatch(&eesubscription, patchList); err != nil {
+		logger.EeLog.Warnf("EE subscription %s of %s cannot be patched: %+v", subscriptionID, ueIdentity, err)
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  "MODIFICATION_NOT_ALLOWED",
+			Detail: err.Error(),
 		}
-	case strings.HasPrefix(ueIdentity, "extgroupid-"):
-		udmSelf.UdmUePool.Range(func(key, value interface{}) bool {
-			ue := value.(*udm_context.UdmUeContext)
-			if ue.ExternalGroupID == ueIdentity {
-				if _, ok := ue.EeSubscriptions[subscriptionID]; ok {
-					for _, patchItem := range patchList {
-						logger.EeLog.Debugf("patch item: %+v", patchItem)
-						// TODO: patch the Eesubscription
-					}
-				}
-			}
-			return true
-		})
-		return nil
-	case ueIdentity == anyUE:
-		udmSelf.UdmUePool.Range(func(key, value interface{}) bool {
-			ue := value.(*udm_context.UdmUeContext)
-			if _, ok := ue.EeSubscriptions[subscriptionID]; ok {
-				for _, patchItem := range patchList {
-					logger.EeLog.Debugf("patch item: %+v", patchItem)
-					// TODO: patch the Eesubscription
-				}
-			}
-			return true
-		})
-		return nil
-	default:
+		return problemDetails
+	}
+	if problemDetails := validateEeSubscription(eesubscription); problemDetails != nil {
+		problemDetails.Status = http.StatusForbidden
+		problemDetails.Cause = "MODIFICATION_NOT_ALLOWED"
+		return problemDetails
+	}
+
+	if err := updateStoredEeSubscription(ueIdentity, subscriptionID, eesubscription); err != nil {
+		logger.EeLog.Errorf("update of EE subscription %s of %s in the UDR failed: %+v",
+			subscriptionID, ueIdentity, err)
 		problemDetails := &models.ProblemDetails{
-			Status: http.StatusBadRequest,
-			Cause:  "MANDATORY_IE_INCORRECT",
-			InvalidParams: []models.InvalidParam{
-				{
-					Param:  "ueIdentity",
-					Reason: "incorrect format",
-				},
-			},
+			Status: http.StatusInternalServerError,
+			Cause:  "UNSPECIFIED_NF_FAILURE",
 		}
 		return problemDetails
 	}
+
+	eeSubscriptionContext.SetEeSubscription(eesubscription)
+	scheduleEeSubscriptionExpiry(eeSubscriptionContext)
+	// the AMF subscriptions are recreated with the new monitoring configurations
+	go func() {
+		removeAmfEventSubscriptions(eeSubscriptionContext)
+		delegateAmfEventsOfSubscription(eeSubscriptionContext)
+	}()
+	return nil
 }
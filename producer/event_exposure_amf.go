@@ -0,0 +1,344 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/Namf_EventExposure"
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/logger"
+	"github.com/omec-project/util/httpwrapper"
+)
+
+// amfEventTypes maps the EE event types detected by the AMF to the AMF event reporting them
+// (TS 23.502 4.15.3.2.2)
+var amfEventTypes = map[models.EventType]models.AmfEventType{
+	models.EventType_LOCATION_REPORTING:       models.AmfEventType_LOCATION_REPORT,
+	models.EventType_LOSS_OF_CONNECTIVITY:     models.AmfEventType_REACHABILITY_REPORT,
+	models.EventType_UE_REACHABILITY_FOR_DATA: models.AmfEventType_REACHABILITY_REPORT,
+}
+
+// locationReport is the LocationReport of TS 29.503
+type locationReport struct {
+	Location *models.UserLocation `json:"location"`
+}
+
+// reachabilityReport is the ReachabilityReport of TS 29.503
+type reachabilityReport struct {
+	AmfInstanceId  string                `json:"amfInstanceId,omitempty"`
+	AccessTypeList []models.AccessType   `json:"accessTypeList,omitempty"`
+	Reachability   models.UeReachability `json:"reachability,omitempty"`
+}
+
+// amfEventNotifyUri is the URI on which the UDM receives the reports of the AMF subscriptions
+func amfEventNotifyUri() string {
+	return udm_context.UDM_Self().GetIPv4Uri() + "/amf-event-notify"
+}
+
+func amfNotifyCorrelationID(eeSubscriptionContext *udm_context.EeSubscriptionContext) string {
+	return eeSubscriptionContext.UeIdentity + "/" + eeSubscriptionContext.SubscriptionID
+}
+
+// amfEventsOf returns the AMF events detecting the events monitored by the subscription
+func amfEventsOf(eeSubscription models.EeSubscription) []models.AmfEvent {
+	var amfEvents []models.AmfEvent
+	indexes := make(map[models.AmfEventType]int)
+	for _, monitoringConfiguration := range eeSubscription.MonitoringConfigurations {
+		amfEventType, ok := amfEventTypes[monitoringConfiguration.EventType]
+		if !ok {
+			continue
+		}
+		if i, ok := indexes[amfEventType]; ok {
+			amfEvents[i].ImmediateFlag = amfEvents[i].ImmediateFlag || monitoringConfiguration.ImmediateFlag
+			continue
+		}
+		indexes[amfEventType] = len(amfEvents)
+		amfEvents = append(amfEvents, models.AmfEvent{
+			Type:          amfEventType,
+			ImmediateFlag: monitoringConfiguration.ImmediateFlag,
+		})
+	}
+	return amfEvents
+}
+
+// amfApiRootOf returns the apiRoot of the AMF serving the UE over 3GPP access, taken from the
+// deregistration callback URI it registered
+func amfApiRootOf(ue *udm_context.UdmUeContext) (string, bool) {
+	registration := ue.Amf3GppAccessRegistration
+	if registration == nil || registration.PurgeFlag {
+		return "", false
+	}
+	// an MME registered through S6a has no Namf_EventExposure service
+	if !isSbiCallback(registration.DeregCallbackUri) {
+		return "", false
+	}
+	deregCallbackUri, err := url.Parse(registration.DeregCallbackUri)
+	if err != nil {
+		return "", false
+	}
+	return deregCallbackUri.Scheme + "://" + deregCallbackUri.Host, true
+}
+
+func createAMFClient(amfApiRoot string) *Namf_EventExposure.APIClient {
+	configuration := Namf_EventExposure.NewConfiguration()
+	configuration.SetBasePath(amfApiRoot)
+	return Namf_EventExposure.NewAPIClient(configuration)
+}
+
+// delegateAmfEvents subscribes the UDM to the serving AMF of the UE for the events of the
+// subscription detected by the AMF, unless that AMF already has such a subscription
+func delegateAmfEvents(eeSubscriptionContext *udm_context.EeSubscriptionContext, ue *udm_context.UdmUeContext) {
+	eeSubscription := eeSubscriptionContext.EeSubscription()
+	amfEvents := amfEventsOf(eeSubscription)
+	if len(amfEvents) == 0 {
+		return
+	}
+	amfApiRoot, ok := amfApiRootOf(ue)
+	if !ok {
+		logger.EeLog.Debugf("no serving AMF known for %s, events of EE subscription %s not delegated yet",
+			ue.Supi, eeSubscriptionContext.SubscriptionID)
+		return
+	}
+	if ref, ok := eeSubscriptionContext.AmfEventSubscription(ue.Supi); ok {
+		if ref.AmfApiRoot == amfApiRoot {
+			return
+		}
+		// the UE moved to another AMF
+		deleteAmfEventSubscription(ref)
+	}
+
+	amfEventSubscription := models.AmfEventSubscription{
+		EventList:           &amfEvents,
+		EventNotifyUri:      amfEventNotifyUri(),
+		NotifyCorrelationId: amfNotifyCorrelationID(eeSubscriptionContext),
+		NfId:                udm_context.UDM_Self().NfId,
+		Supi:                ue.Supi,
+		Gpsi:                ue.Gpsi,
+	}
+	if reportingOptions := eeSubscription.ReportingOptions; reportingOptions != nil {
+		amfEventSubscription.Options = &models.AmfEventMode{
+			Trigger:    models.AmfEventTrigger_CONTINUOUS,
+			MaxReports: reportingOptions.MaxNumOfReports,
+			Expiry:     reportingOptions.Expiry,
+		}
+		if reportingOptions.MaxNumOfReports == 1 {
+			amfEventSubscription.Options.Trigger = models.AmfEventTrigger_ONE_TIME
+		}
+	}
+
+	clientAPI := createAMFClient(amfApiRoot)
+	createdEventSubscription, res, err := clientAPI.SubscriptionsCollectionDocumentApi.CreateSubscription(
+		context.Background(), models.AmfCreateEventSubscription{Subscription: &amfEventSubscription})
+	if res != nil {
+		defer func() {
+			if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+				logger.EeLog.Errorf("CreateSubscription response body cannot close: %+v", rspCloseErr)
+			}
+		}()
+	}
+	if err != nil {
+		logger.EeLog.Errorf("delegation of EE subscription %s of %s to AMF %s failed: %+v",
+			eeSubscriptionContext.SubscriptionID, ue.Supi, amfApiRoot, err)
+		return
+	}
+
+	logger.EeLog.Infof("EE subscription %s of %s delegated to AMF %s as subscription %s",
+		eeSubscriptionContext.SubscriptionID, ue.Supi, amfApiRoot, createdEventSubscription.SubscriptionId)
+	eeSubscriptionContext.SetAmfEventSubscription(ue.Supi, udm_context.AmfEventSubscriptionRef{
+		AmfApiRoot:     amfApiRoot,
+		SubscriptionID: createdEventSubscription.SubscriptionId,
+	})
+	relayAmfEventReports(eeSubscriptionContext, createdEventSubscription.ReportList)
+}
+
+// delegateAmfEventsOfSubscription delegates the events of the subscription to the serving AMFs of
+// the UEs it applies to
+func delegateAmfEventsOfSubscription(eeSubscriptionContext *udm_context.EeSubscriptionContext) {
+	if len(amfEventsOf(eeSubscriptionContext.EeSubscription())) == 0 {
+		return
+	}
+	udmSelf := udm_context.UDM_Self()
+	var ues []*udm_context.UdmUeContext
+	switch {
+	case isGpsi(eeSubscriptionContext.UeIdentity):
+		if ue, ok := udmSelf.UdmUeFindByGpsi(eeSubscriptionContext.UeIdentity); ok {
+			ues = append(ues, ue)
+		}
+	case eeSubscriptionContext.UeIdentity == udm_context.AnyUE:
+		udmSelf.UdmUePool.Range(func(key, value interface{}) bool {
+			ues = append(ues, value.(*udm_context.UdmUeContext))
+			return true
+		})
+	default:
+		ues = udmSelf.UdmUesFindByExternalGroupID(eeSubscriptionContext.UeIdentity)
+	}
+	for _, ue := range ues {
+		delegateAmfEvents(eeSubscriptionContext, ue)
+	}
+}
+
+// delegateAmfEventsOfUe delegates the events of the subscriptions applying to the UE to its
+// serving AMF, once the AMF has registered
+func delegateAmfEventsOfUe(supi string) {
+	ue, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		return
+	}
+	loadUeEeSubscriptions(ue)
+	for _, eeSubscriptionContext := range udm_context.UDM_Self().EeSubscriptionsForUe(ue) {
+		delegateAmfEvents(eeSubscriptionContext, ue)
+	}
+}
+
+// removeAmfEventSubscriptions removes the subscriptions delegated to the AMFs
+func removeAmfEventSubscriptions(eeSubscriptionContext *udm_context.EeSubscriptionContext) {
+	for _, ref := range eeSubscriptionContext.TakeAmfEventSubscriptions() {
+		deleteAmfEventSubscription(ref)
+	}
+}
+
+// deleteAmfEventSubscription removes a subscription from the AMF (TS 29.518 5.3.2.3). The request is
+// built here because the generated DeleteSubscription panics on its empty list of content types.
+func deleteAmfEventSubscription(ref udm_context.AmfEventSubscriptionRef) {
+	configuration := Namf_EventExposure.NewConfiguration()
+	configuration.SetBasePath(ref.AmfApiRoot)
+	request, err := openapi.PrepareRequest(context.Background(), configuration,
+		configuration.BasePath()+"/subscriptions/"+url.PathEscape(ref.SubscriptionID), http.MethodDelete, nil,
+		map[string]string{"Accept": "application/problem+json"}, url.Values{}, url.Values{}, "", "", nil)
+	if err == nil {
+		var res *http.Response
+		if res, err = openapi.CallAPI(configuration, request); err == nil {
+			if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+				logger.EeLog.Errorf("DeleteSubscription response body cannot close: %+v", rspCloseErr)
+			}
+			if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent &&
+				res.StatusCode != http.StatusNotFound {
+				err = fmt.Errorf("unexpected status %s", res.Status)
+			}
+		}
+	}
+	if err != nil {
+		logger.EeLog.Warnf("removal of subscription %s from AMF %s failed: %+v", ref.SubscriptionID,
+			ref.AmfApiRoot, err)
+	}
+}
+
+func HandleAmfEventNotification(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.EeLog.Infoln("Handle AMF Event Notification")
+
+	amfEventNotification := request.Body.(models.AmfEventNotification)
+
+	problemDetails := AmfEventNotificationProcedure(amfEventNotification)
+	if problemDetails != nil {
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, nil)
+}
+
+// AmfEventNotificationProcedure relays the reports of an AMF subscription (TS 29.518 5.3.2.4) to
+// the NF consumer of the EE subscription it was delegated for
+func AmfEventNotificationProcedure(amfEventNotification models.AmfEventNotification) *models.ProblemDetails {
+	correlationID := amfEventNotification.NotifyCorrelationId
+	separator := strings.LastIndex(correlationID, "/")
+	if separator < 0 {
+		return &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			InvalidParams: []models.InvalidParam{
+				{Param: "notifyCorrelationId", Reason: "incorrect format"},
+			},
+		}
+	}
+	eeSubscriptionContext, ok := udm_context.UDM_Self().FindEeSubscription(correlationID[:separator],
+		correlationID[separator+1:])
+	if !ok || !amfEventReportsDelegated(eeSubscriptionContext, amfEventNotification.ReportList) {
+		return &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "SUBSCRIPTION_NOT_FOUND",
+		}
+	}
+
+	go relayAmfEventReports(eeSubscriptionContext, amfEventNotification.ReportList)
+	return nil
+}
+
+// amfEventReportsDelegated tells whether every report comes from an AMF subscription the UDM
+// created for the subscription: the SUPI of the report must have a delegated AMF subscription and
+// the subscriptionId of the report, when present, must be the one of that AMF subscription
+func amfEventReportsDelegated(eeSubscriptionContext *udm_context.EeSubscriptionContext,
+	amfEventReports []models.AmfEventReport,
+) bool {
+	for _, amfEventReport := range amfEventReports {
+		ref, ok := eeSubscriptionContext.AmfEventSubscription(amfEventReport.Supi)
+		if !ok {
+			logger.EeLog.Warnf("AMF event report for %s not delegated by EE subscription %s",
+				amfEventReport.Supi, eeSubscriptionContext.SubscriptionID)
+			return false
+		}
+		if amfEventReport.SubscriptionId != "" && amfEventReport.SubscriptionId != ref.SubscriptionID {
+			logger.EeLog.Warnf("AMF event report for %s from subscription %s, expected %s",
+				amfEventReport.Supi, amfEventReport.SubscriptionId, ref.SubscriptionID)
+			return false
+		}
+	}
+	return true
+}
+
+// relayAmfEventReports converts the AMF event reports into monitoring reports of the subscription
+func relayAmfEventReports(eeSubscriptionContext *udm_context.EeSubscriptionContext,
+	amfEventReports []models.AmfEventReport,
+) {
+	for _, amfEventReport := range amfEventReports {
+		events := ueEventsOfAmfEventReport(amfEventReport)
+		if len(events) == 0 {
+			continue
+		}
+		gpsi := amfEventReport.Gpsi
+		if gpsi == "" {
+			if ue, ok := udm_context.UDM_Self().UdmUeFindBySupi(amfEventReport.Supi); ok {
+				gpsi = ue.Gpsi
+			}
+		}
+		sendUeEvents(eeSubscriptionContext, gpsi, events)
+	}
+}
+
+// ueEventsOfAmfEventReport returns the EE events reported by an AMF event report
+func ueEventsOfAmfEventReport(amfEventReport models.AmfEventReport) []ueEvent {
+	switch amfEventReport.Type {
+	case models.AmfEventType_LOCATION_REPORT:
+		if amfEventReport.Location == nil {
+			return nil
+		}
+		return []ueEvent{{
+			eventType: models.EventType_LOCATION_REPORTING,
+			report:    &locationReport{Location: amfEventReport.Location},
+		}}
+	case models.AmfEventType_REACHABILITY_REPORT:
+		switch amfEventReport.Reachability {
+		case models.UeReachability_UNREACHABLE:
+			return []ueEvent{{eventType: models.EventType_LOSS_OF_CONNECTIVITY}}
+		case models.UeReachability_REACHABLE:
+			report := &reachabilityReport{
+				AccessTypeList: amfEventReport.AccessTypeList,
+				Reachability:   amfEventReport.Reachability,
+			}
+			if ue, ok := udm_context.UDM_Self().UdmUeFindBySupi(amfEventReport.Supi); ok &&
+				ue.Amf3GppAccessRegistration != nil {
+				report.AmfInstanceId = ue.Amf3GppAccessRegistration.AmfInstanceId
+			}
+			return []ueEvent{{eventType: models.EventType_UE_REACHABILITY_FOR_DATA, report: report}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/producer/callback"
+)
+
+func TestAmfEventsOf(t *testing.T) {
+	amfEvents := amfEventsOf(models.EeSubscription{
+		MonitoringConfigurations: map[string]models.MonitoringConfiguration{
+			"1": {EventType: models.EventType_LOSS_OF_CONNECTIVITY},
+			"2": {EventType: models.EventType_UE_REACHABILITY_FOR_DATA, ImmediateFlag: true},
+			"3": {EventType: models.EventType_ROAMING_STATUS},
+		},
+	})
+	if len(amfEvents) != 1 {
+		t.Fatalf("expected 1 AMF event, got %+v", amfEvents)
+	}
+	if amfEvents[0].Type != models.AmfEventType_REACHABILITY_REPORT || !amfEvents[0].ImmediateFlag {
+		t.Errorf("unexpected AMF event %+v", amfEvents[0])
+	}
+}
+
+func TestDelegateAmfEvents(t *testing.T) {
+	originalSendMonitoringReports := sendMonitoringReports
+	defer func() {
+		sendMonitoringReports = originalSendMonitoringReports
+	}()
+	var lock sync.Mutex
+	var sentReports []callback.MonitoringReport
+	sendMonitoringReports = func(callbackReference string, monitoringReports []callback.MonitoringReport) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		sentReports = append(sentReports, monitoringReports...)
+		return true
+	}
+
+	var createdSubscription models.AmfCreateEventSubscription
+	var deletedPath string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/namf-evts/v1/subscriptions":
+			if err := json.NewDecoder(r.Body).Decode(&createdSubscription); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(models.AmfCreatedEventSubscription{
+				Subscription:   createdSubscription.Subscription,
+				SubscriptionId: "amf-subscription-1",
+				ReportList: []models.AmfEventReport{{
+					Type:     models.AmfEventType_LOCATION_REPORT,
+					Supi:     "imsi-208930000000101",
+					Location: &models.UserLocation{NrLocation: &models.NrLocation{}},
+				}},
+			})
+		case r.Method == http.MethodDelete:
+			deletedPath = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	udmSelf := udm_context.UDM_Self()
+	ue := udmSelf.NewUdmUe("imsi-208930000000101")
+	ue.Amf3GppAccessRegistration = &models.Amf3GppAccessRegistration{
+		AmfInstanceId:    "amf-1",
+		DeregCallbackUri: server.URL + "/namf-callback/v1/imsi-208930000000101/dereg-notify",
+	}
+	udmSelf.SetUeGpsis(ue, []string{"msisdn-0900000101"})
+	eeSubscriptionContext := udm_context.NewEeSubscriptionContext("101", "msisdn-0900000101", models.EeSubscription{
+		CallbackReference: "http://nef/callback",
+		MonitoringConfigurations: map[string]models.MonitoringConfiguration{
+			"1": {EventType: models.EventType_LOCATION_REPORTING, ImmediateFlag: true},
+		},
+	})
+	udmSelf.AddEeSubscription(eeSubscriptionContext)
+	defer udmSelf.RemoveEeSubscription("msisdn-0900000101", "101")
+
+	delegateAmfEvents(eeSubscriptionContext, ue)
+
+	subscription := createdSubscription.Subscription
+	if subscription == nil || subscription.Supi != ue.Supi ||
+		subscription.NotifyCorrelationId != "msisdn-0900000101/101" {
+		t.Fatalf("unexpected AMF subscription %+v", subscription)
+	}
+	if ref, ok := eeSubscriptionContext.AmfEventSubscription(ue.Supi); !ok || ref.SubscriptionID != "amf-subscription-1" {
+		t.Errorf("expected the AMF subscription to be recorded, got %+v", ref)
+	}
+	if len(sentReports) != 1 || sentReports[0].EventType != models.EventType_LOCATION_REPORTING ||
+		sentReports[0].Gpsi != "msisdn-0900000101" {
+		t.Errorf("expected the immediate location report to be relayed, got %+v", sentReports)
+	}
+
+	removeAmfEventSubscriptions(eeSubscriptionContext)
+	if deletedPath != "/namf-evts/v1/subscriptions/amf-subscription-1" {
+		t.Errorf("expected the AMF subscription to be deleted, got %q", deletedPath)
+	}
+}
+
+func TestAmfEventNotificationProcedure(t *testing.T) {
+	originalSendMonitoringReports := sendMonitoringReports
+	defer func() {
+		sendMonitoringReports = originalSendMonitoringReports
+	}()
+	sent := make(chan []callback.MonitoringReport, 1)
+	sendMonitoringReports = func(callbackReference string, monitoringReports []callback.MonitoringReport) bool {
+		sent <- monitoringReports
+		return true
+	}
+
+	udmSelf := udm_context.UDM_Self()
+	eeSubscriptionContext := udm_context.NewEeSubscriptionContext("102", udm_context.AnyUE, models.EeSubscription{
+		CallbackReference: "http://nef/callback",
+		MonitoringConfigurations: map[string]models.MonitoringConfiguration{
+			"7": {EventType: models.EventType_LOSS_OF_CONNECTIVITY},
+		},
+	})
+	eeSubscriptionContext.SetAmfEventSubscription("imsi-208930000000102", udm_context.AmfEventSubscriptionRef{
+		AmfApiRoot:     "https://amf",
+		SubscriptionID: "amf-subscription-102",
+	})
+	udmSelf.AddEeSubscription(eeSubscriptionContext)
+	defer udmSelf.RemoveEeSubscription(udm_context.AnyUE, "102")
+
+	if problemDetails := AmfEventNotificationProcedure(models.AmfEventNotification{
+		NotifyCorrelationId: udm_context.AnyUE + "/103",
+	}); problemDetails == nil || problemDetails.Status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown subscription, got %+v", problemDetails)
+	}
+
+	reachabilityReport := models.AmfEventReport{
+		Type:           models.AmfEventType_REACHABILITY_REPORT,
+		SubscriptionId: "amf-subscription-102",
+		Supi:           "imsi-208930000000102",
+		Gpsi:           "msisdn-0900000102",
+		Reachability:   models.UeReachability_UNREACHABLE,
+	}
+	otherUeReport := reachabilityReport
+	otherUeReport.Supi = "imsi-208930000000103"
+	otherSubscriptionReport := reachabilityReport
+	otherSubscriptionReport.SubscriptionId = "amf-subscription-103"
+	for name, amfEventReport := range map[string]models.AmfEventReport{
+		"undelegated SUPI":        otherUeReport,
+		"unknown subscription ID": otherSubscriptionReport,
+	} {
+		if problemDetails := AmfEventNotificationProcedure(models.AmfEventNotification{
+			NotifyCorrelationId: udm_context.AnyUE + "/102",
+			ReportList:          []models.AmfEventReport{reachabilityReport, amfEventReport},
+		}); problemDetails == nil || problemDetails.Status != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %+v", name, problemDetails)
+		}
+	}
+	select {
+	case monitoringReports := <-sent:
+		t.Fatalf("rejected notifications relayed %+v", monitoringReports)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	problemDetails := AmfEventNotificationProcedure(models.AmfEventNotification{
+		NotifyCorrelationId: udm_context.AnyUE + "/102",
+		ReportList:          []models.AmfEventReport{reachabilityReport},
+	})
+	if problemDetails != nil {
+		t.Fatalf("unexpected problem %+v", problemDetails)
+	}
+	select {
+	case monitoringReports := <-sent:
+		if len(monitoringReports) != 1 || monitoringReports[0].EventType != models.EventType_LOSS_OF_CONNECTIVITY ||
+			monitoringReports[0].ReferenceId != 7 || monitoringReports[0].Gpsi != "msisdn-0900000102" {
+			t.Errorf("unexpected monitoring reports %+v", monitoringReports)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the AMF report to be relayed")
+	}
+}
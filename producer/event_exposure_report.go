@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/logger"
+	"github.com/omec-project/udm/producer/callback"
+)
+
+// sendMonitoringReports is overridden in tests
+var sendMonitoringReports = callback.SendMonitoringReports
+
+// ueEventState is the state of a UE from which the UDM detects the events of TS 29.503 5.5.2.2:
+// its PEI, its serving PLMN and whether it is registered in an AMF
+type ueEventState struct {
+	pei         string
+	servingPlmn *models.PlmnId
+	registered  bool
+}
+
+// ueEvent is an event detected by the UDM for a UE
+type ueEvent struct {
+	eventType models.EventType
+	report    interface{}
+}
+
+func ueEventStateOf(supi string) ueEventState {
+	ue, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		return ueEventState{}
+	}
+	state := ueEventState{pei: ue.Pei}
+	if registration := ue.Amf3GppAccessRegistration; registration != nil && !registration.PurgeFlag {
+		state.registered = true
+		if registration.Guami != nil {
+			state.servingPlmn = registration.Guami.PlmnId
+		}
+	}
+	if registration := ue.AmfNon3GppAccessRegistration; registration != nil && !registration.PurgeFlag {
+		state.registered = true
+		if state.servingPlmn == nil && registration.Guami != nil {
+			state.servingPlmn = registration.Guami.PlmnId
+		}
+	}
+	return state
+}
+
+// detectUeEvents compares the state of the UE before and after an AMF registration or update
+func detectUeEvents(supi string, before ueEventState, after ueEventState) []ueEvent {
+	var events []ueEvent
+	if before.pei != "" && after.pei != "" && before.pei != after.pei {
+		events = append(events, ueEvent{
+			eventType: models.EventType_CHANGE_OF_SUPI_PEI_ASSOCIATION,
+			report:    &models.Report{NewPei: after.pei},
+		})
+	}
+	if after.servingPlmn != nil && (before.servingPlmn == nil || *before.servingPlmn != *after.servingPlmn) {
+		events = append(events, roamingStatusEvent(supi, after.servingPlmn))
+	}
+	if !before.registered && after.registered {
+		events = append(events,
+			ueEvent{eventType: models.EventType_UE_REACHABILITY_FOR_DATA},
+			ueEvent{eventType: models.EventType_UE_REACHABILITY_FOR_SMS})
+	}
+	return detectedByUdm(events)
+}
+
+// currentUeEvents returns the events describing the current state of the UE, reported to the
+// subscriptions created with the immediateFlag
+func currentUeEvents(supi string, state ueEventState) []ueEvent {
+	var events []ueEvent
+	if state.pei != "" {
+		events = append(events, ueEvent{
+			eventType: models.EventType_CHANGE_OF_SUPI_PEI_ASSOCIATION,
+			report:    &models.Report{NewPei: state.pei},
+		})
+	}
+	if state.servingPlmn != nil {
+		events = append(events, roamingStatusEvent(supi, state.servingPlmn))
+	}
+	if state.registered {
+		events = append(events,
+			ueEvent{eventType: models.EventType_UE_REACHABILITY_FOR_DATA},
+			ueEvent{eventType: models.EventType_UE_REACHABILITY_FOR_SMS})
+	}
+	return detectedByUdm(events)
+}
+
+// detectedByUdm drops the events detected by the AMF, which reach the subscriptions through the
+// AMF subscriptions they are delegated to and must not be reported twice
+func detectedByUdm(events []ueEvent) []ueEvent {
+	filtered := events[:0]
+	for _, event := range events {
+		if _, ok := amfEventTypes[event.eventType]; !ok {
+			filtered = append(filtered, event)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return filtered
+}
+
+// roamingStatusEvent reports whether the serving PLMN is the home PLMN of an IMSI based SUPI
+func roamingStatusEvent(supi string, servingPlmn *models.PlmnId) ueEvent {
+	servingPlmnID := *servingPlmn
+	roaming := false
+	if strings.HasPrefix(supi, "imsi-") {
+		roaming = !strings.HasPrefix(strings.TrimPrefix(supi, "imsi-"), servingPlmnID.Mcc+servingPlmnID.Mnc)
+	}
+	return ueEvent{
+		eventType: models.EventType_ROAMING_STATUS,
+		report:    &models.Report{Roaming: roaming, NewServingPlmn: &servingPlmnID},
+	}
+}
+
+// reportUeEvents detects the events of the UE since before and reports them to the EE
+// subscriptions monitoring them
+func reportUeEvents(supi string, before ueEventState) {
+	ue, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		return
+	}
+	events := detectUeEvents(supi, before, ueEventStateOf(supi))
+	if len(events) == 0 {
+		return
+	}
+	go func() {
+		loadUeEeSubscriptions(ue)
+		for _, eeSubscriptionContext := range udm_context.UDM_Self().EeSubscriptionsForUe(ue) {
+			go sendUeEvents(eeSubscriptionContext, ue.Gpsi, events)
+		}
+	}()
+}
+
+// reportImmediateUeEvents reports the current state of the UE identified by the GPSI to a new
+// subscription whose monitoring configurations have the immediateFlag set
+func reportImmediateUeEvents(eeSubscriptionContext *udm_context.EeSubscriptionContext) {
+	ue, ok := udm_context.UDM_Self().UdmUeFindByGpsi(eeSubscriptionContext.UeIdentity)
+	if !ok {
+		return
+	}
+	var immediateEvents []ueEvent
+	for _, event := range currentUeEvents(ue.Supi, ueEventStateOf(ue.Supi)) {
+		for _, monitoringConfiguration := range eeSubscriptionContext.EeSubscription().MonitoringConfigurations {
+			if monitoringConfiguration.ImmediateFlag && monitoringConfiguration.EventType == event.eventType {
+				immediateEvents = append(immediateEvents, event)
+				break
+			}
+		}
+	}
+	if len(immediateEvents) != 0 {
+		go sendUeEvents(eeSubscriptionContext, eeSubscriptionContext.UeIdentity, immediateEvents)
+	}
+}
+
+// sendUeEvents sends a monitoring report for each monitoring configuration of the subscription
+// matching one of the events, within the limits of its reporting options
+func sendUeEvents(eeSubscriptionContext *udm_context.EeSubscriptionContext, gpsi string, events []ueEvent) {
+	eeSubscription := eeSubscriptionContext.EeSubscription()
+	if strings.HasPrefix(eeSubscriptionContext.UeIdentity, "msisdn-") ||
+		strings.HasPrefix(eeSubscriptionContext.UeIdentity, "extid-") {
+		gpsi = eeSubscriptionContext.UeIdentity
+	}
+
+	var monitoringReports []callback.MonitoringReport
+	lastReport := false
+	now := time.Now()
+	for referenceID, monitoringConfiguration := range eeSubscription.MonitoringConfigurations {
+		for _, event := range events {
+			if event.eventType != monitoringConfiguration.EventType {
+				continue
+			}
+			allowed, last := eeSubscriptionContext.CountReport()
+			lastReport = lastReport || last
+			if !allowed {
+				continue
+			}
+			monitoringReports = append(monitoringReports, callback.MonitoringReport{
+				MonitoringReport: models.MonitoringReport{
+					ReferenceId: monitoringReferenceID(referenceID),
+					EventType:   event.eventType,
+					Gpsi:        gpsi,
+					TimeStamp:   &now,
+				},
+				Report: event.report,
+			})
+		}
+	}
+
+	if len(monitoringReports) != 0 {
+		logger.EeLog.Infof("sending %d monitoring reports of EE subscription %s to %s", len(monitoringReports),
+			eeSubscriptionContext.SubscriptionID, eeSubscription.CallbackReference)
+		sendMonitoringReports(eeSubscription.CallbackReference, monitoringReports)
+	}
+	// TS 29.503 5.5.2.2: the subscription ends once the maximum number of reports is reached
+	if lastReport {
+		RemoveEeSubscription(eeSubscriptionContext)
+	}
+}
+
+// monitoringReferenceID returns the referenceId of a monitoring configuration, keyed by its
+// referenceId in the decimal representation
+func monitoringReferenceID(key string) int32 {
+	referenceID, err := strconv.ParseInt(key, 10, 32)
+	if err != nil {
+		logger.EeLog.Warnf("monitoring configuration key %s is not a referenceId", key)
+		return 0
+	}
+	return int32(referenceID)
+}
@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/producer/callback"
+)
+
+func TestDetectUeEvents(t *testing.T) {
+	supi := "imsi-208930000000001"
+	homePlmn := &models.PlmnId{Mcc: "208", Mnc: "93"}
+	visitedPlmn := &models.PlmnId{Mcc: "001", Mnc: "01"}
+
+	testCases := []struct {
+		name     string
+		before   ueEventState
+		after    ueEventState
+		expected []models.EventType
+		roaming  bool
+	}{
+		{
+			name:   "initial registration in the home PLMN",
+			before: ueEventState{},
+			after:  ueEventState{pei: "imeisv-4370816125816151", servingPlmn: homePlmn, registered: true},
+			expected: []models.EventType{
+				models.EventType_ROAMING_STATUS,
+				models.EventType_UE_REACHABILITY_FOR_SMS,
+			},
+		},
+		{
+			name:     "registration in a visited PLMN",
+			before:   ueEventState{servingPlmn: homePlmn, registered: true},
+			after:    ueEventState{servingPlmn: visitedPlmn, registered: true},
+			expected: []models.EventType{models.EventType_ROAMING_STATUS},
+			roaming:  true,
+		},
+		{
+			name:     "PEI change",
+			before:   ueEventState{pei: "imeisv-4370816125816151", servingPlmn: homePlmn, registered: true},
+			after:    ueEventState{pei: "imeisv-4370816125816152", servingPlmn: homePlmn, registered: true},
+			expected: []models.EventType{models.EventType_CHANGE_OF_SUPI_PEI_ASSOCIATION},
+		},
+		{
+			name:     "no change",
+			before:   ueEventState{pei: "imeisv-4370816125816151", servingPlmn: homePlmn, registered: true},
+			after:    ueEventState{pei: "imeisv-4370816125816151", servingPlmn: homePlmn, registered: true},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			events := detectUeEvents(supi, tc.before, tc.after)
+			if len(events) != len(tc.expected) {
+				t.Fatalf("expected %d events, got %d: %+v", len(tc.expected), len(events), events)
+			}
+			for i, event := range events {
+				if event.eventType != tc.expected[i] {
+					t.Errorf("expected event %s, got %s", tc.expected[i], event.eventType)
+				}
+				if event.eventType == models.EventType_ROAMING_STATUS && event.report.(*models.Report).Roaming != tc.roaming {
+					t.Errorf("expected roaming %v, got %v", tc.roaming, event.report.(*models.Report).Roaming)
+				}
+			}
+		})
+	}
+}
+
+func TestSendUeEvents_MaxNumOfReports(t *testing.T) {
+	originalSendMonitoringReports := sendMonitoringReports
+	defer func() {
+		sendMonitoringReports = originalSendMonitoringReports
+	}()
+	var sentReports []callback.MonitoringReport
+	sendMonitoringReports = func(callbackReference string, monitoringReports []callback.MonitoringReport) bool {
+		sentReports = append(sentReports, monitoringReports...)
+		return true
+	}
+
+	eeSubscriptionContext := udm_context.NewEeSubscriptionContext("1", "msisdn-0900000001", models.EeSubscription{
+		CallbackReference: "http://nef/callback",
+		MonitoringConfigurations: map[string]models.MonitoringConfiguration{
+			"1": {EventType: models.EventType_CHANGE_OF_SUPI_PEI_ASSOCIATION},
+			"2": {EventType: models.EventType_LOSS_OF_CONNECTIVITY},
+		},
+		ReportingOptions: &models.ReportingOptions{MaxNumOfReports: 1},
+	})
+	udm_context.UDM_Self().AddEeSubscription(eeSubscriptionContext)
+
+	sendUeEvents(eeSubscriptionContext, "", []ueEvent{{
+		eventType: models.EventType_CHANGE_OF_SUPI_PEI_ASSOCIATION,
+		report:    &models.Report{NewPei: "imeisv-4370816125816152"},
+	}})
+
+	if len(sentReports) != 1 {
+		t.Fatalf("expected 1 monitoring report, got %d", len(sentReports))
+	}
+	if sentReports[0].ReferenceId != 1 || sentReports[0].Gpsi != "msisdn-0900000001" ||
+		sentReports[0].Report.(*models.Report).NewPei != "imeisv-4370816125816152" {
+		t.Errorf("unexpected monitoring report %+v", sentReports[0])
+	}
+	if _, ok := udm_context.UDM_Self().FindEeSubscription("msisdn-0900000001", "1"); ok {
+		t.Errorf("expected the subscription to be removed once the maximum number of reports is reached")
+	}
+}
+
+func TestReportUeEvents_ReachabilityReportedOnce(t *testing.T) {
+	originalSendMonitoringReports := sendMonitoringReports
+	defer func() {
+		sendMonitoringReports = originalSendMonitoringReports
+	}()
+	sent := make(chan []callback.MonitoringReport, 4)
+	sendMonitoringReports = func(callbackReference string, monitoringReports []callback.MonitoringReport) bool {
+		sent <- monitoringReports
+		return true
+	}
+
+	udmSelf := udm_context.UDM_Self()
+	ue := udmSelf.NewUdmUe("imsi-208930000000111")
+	udmSelf.SetUeGpsis(ue, []string{"msisdn-0900000111"})
+	eeSubscriptionContext := udm_context.NewEeSubscriptionContext("111", "msisdn-0900000111", models.EeSubscription{
+		CallbackReference: "http://nef/callback",
+		MonitoringConfigurations: map[string]models.MonitoringConfiguration{
+			"1": {EventType: models.EventType_UE_REACHABILITY_FOR_DATA},
+		},
+	})
+	udmSelf.AddEeSubscription(eeSubscriptionContext)
+	defer udmSelf.RemoveEeSubscription("msisdn-0900000111", "111")
+
+	// the AMF registration, followed by the reachability report of the AMF subscription it was
+	// delegated to
+	before := ueEventStateOf(ue.Supi)
+	ue.Amf3GppAccessRegistration = &models.Amf3GppAccessRegistration{AmfInstanceId: "amf-1"}
+	reportUeEvents(ue.Supi, before)
+	relayAmfEventReports(eeSubscriptionContext, []models.AmfEventReport{{
+		Type:         models.AmfEventType_REACHABILITY_REPORT,
+		Supi:         ue.Supi,
+		Reachability: models.UeReachability_REACHABLE,
+	}})
+
+	var reports []callback.MonitoringReport
+	timeout := time.After(200 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case monitoringReports := <-sent:
+			reports = append(reports, monitoringReports...)
+		case <-timeout:
+			done = true
+		}
+	}
+	if len(reports) != 1 || reports[0].EventType != models.EventType_UE_REACHABILITY_FOR_DATA {
+		t.Fatalf("expected exactly one reachability report, got %+v", reports)
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+)
+
+// forgetEeSubscription drops the EE subscription held by the UDM, as a restart does
+func forgetEeSubscription(ueIdentity string, subscriptionID string) {
+	udm_context.UDM_Self().RemoveEeSubscription(ueIdentity, subscriptionID)
+}
+
+func TestEeSubscriptionRestoredFromUdr(t *testing.T) {
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+	const ueIdentity = "extgroupid-restore@example.com"
+	const collection = "/subscription-data/group-data/" + ueIdentity + "/ee-subscriptions"
+
+	created, problemDetails := CreateEeSubscriptionProcedure(ueIdentity, models.EeSubscription{
+		CallbackReference: "http://nef/callback",
+		MonitoringConfigurations: map[string]models.MonitoringConfiguration{
+			"1": {EventType: models.EventType_LOSS_OF_CONNECTIVITY},
+		},
+	})
+	if problemDetails != nil {
+		t.Fatalf("unexpected problem details: %+v", problemDetails)
+	}
+	var stored storedEeSubscription
+	if !udr.get(collection+"/"+created.SubscriptionId, &stored) || stored.SubscriptionId != created.SubscriptionId {
+		t.Fatalf("expected the subscription to be stored with its ID %s, got %+v", created.SubscriptionId, stored)
+	}
+
+	forgetEeSubscription(ueIdentity, created.SubscriptionId)
+	if err := LoadEeSubscriptions(ueIdentity); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	restored, ok := udm_context.UDM_Self().FindEeSubscription(ueIdentity, created.SubscriptionId)
+	if !ok {
+		t.Fatalf("expected subscription %s to be restored", created.SubscriptionId)
+	}
+	if restored.EeSubscription().CallbackReference != "http://nef/callback" {
+		t.Errorf("unexpected restored subscription %+v", restored.EeSubscription())
+	}
+
+	// the update and the removal after a restart load the subscriptions themselves
+	forgetEeSubscription(ueIdentity, created.SubscriptionId)
+	problemDetails = UpdateEeSubscriptionProcedure(ueIdentity, created.SubscriptionId, []models.PatchItem{{
+		Op:    models.PatchOperation_REPLACE,
+		Path:  "/callbackReference",
+		Value: "http://nef/other-callback",
+	}})
+	if problemDetails != nil {
+		t.Fatalf("unexpected problem details on update: %+v", problemDetails)
+	}
+	if !udr.get(collection+"/"+created.SubscriptionId, &stored) ||
+		stored.CallbackReference != "http://nef/other-callback" || stored.SubscriptionId != created.SubscriptionId {
+		t.Errorf("unexpected stored subscription after update %+v", stored)
+	}
+
+	forgetEeSubscription(ueIdentity, created.SubscriptionId)
+	if problemDetails := DeleteEeSubscriptionProcedure(ueIdentity, created.SubscriptionId); problemDetails != nil {
+		t.Fatalf("unexpected problem details on removal: %+v", problemDetails)
+	}
+	if udr.get(collection+"/"+created.SubscriptionId, &stored) {
+		t.Errorf("expected the subscription to be removed from the UDR")
+	}
+	if _, ok := udm_context.UDM_Self().FindEeSubscription(ueIdentity, created.SubscriptionId); ok {
+		t.Errorf("expected the subscription to be removed from the UDM")
+	}
+}
+
+func TestCreateEeSubscription_UdrFailure(t *testing.T) {
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+	const ueIdentity = "extgroupid-udr-failure@example.com"
+	udr.handle(http.MethodPost, "/subscription-data/group-data/"+ueIdentity+"/ee-subscriptions",
+		func(w http.ResponseWriter, r *http.Request) {
+			writeUdrProblem(w, http.StatusInternalServerError, "UNSPECIFIED_NF_FAILURE")
+		})
+
+	created, problemDetails := CreateEeSubscriptionProcedure(ueIdentity, models.EeSubscription{
+		CallbackReference: "http://nef/callback",
+		MonitoringConfigurations: map[string]models.MonitoringConfiguration{
+			"1": {EventType: models.EventType_LOSS_OF_CONNECTIVITY},
+		},
+	})
+	if problemDetails == nil {
+		t.Fatalf("expected the creation to fail, got %+v", created)
+	}
+	if problemDetails.Status != http.StatusInternalServerError || problemDetails.Cause != "UNSPECIFIED_NF_FAILURE" {
+		t.Errorf("unexpected problem details: %+v", problemDetails)
+	}
+	if _, ok := udm_context.UDM_Self().FindEeSubscription(ueIdentity, "1"); ok {
+		t.Errorf("expected no subscription to be kept")
+	}
+}
+
+func TestLoadEeSubscriptions_PerUeIdentity(t *testing.T) {
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+	const slow, other = "extgroupid-slow@example.com", "extgroupid-other@example.com"
+	slowCollection := "/subscription-data/group-data/" + slow + "/ee-subscriptions"
+	arrived, release := make(chan struct{}, 2), make(chan struct{})
+	udr.handle(http.MethodGet, slowCollection, func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		writeUdrJSON(w, http.StatusOK, []byte("[]"))
+	})
+
+	var loads sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		loads.Add(1)
+		go func() {
+			defer loads.Done()
+			if err := LoadEeSubscriptions(slow); err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+		}()
+	}
+	<-arrived
+
+	// the subscriptions of the other ueIdentities are loaded meanwhile
+	done := make(chan error)
+	go func() { done <- LoadEeSubscriptions(other) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the load of another ueIdentity not to wait for the UDR")
+	}
+
+	close(release)
+	loads.Wait()
+	if requests := udr.received(http.MethodGet, slowCollection); len(requests) != 1 {
+		t.Errorf("expected the concurrent loads of a ueIdentity to query the UDR once, got %d", len(requests))
+	}
+}
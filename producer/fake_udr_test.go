@@ -0,0 +1,233 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/omec-project/openapi/Nnrf_NFDiscovery"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/consumer"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/util"
+)
+
+// udrRequest is a request received by the fake UDR
+type udrRequest struct {
+	method string
+	path   string // relative to the apiRoot of the Nudr_DataRepository
+	query  map[string][]string
+	body   []byte
+}
+
+// fakeUdr is a UDR storing the JSON documents by path. A GET of a path without document returns
+// the list of the documents below it, if any. A POST creates a document below the path, with the
+// next ID. Handlers of "METHOD path" take precedence over the documents.
+type fakeUdr struct {
+	lock      sync.Mutex
+	documents map[string]json.RawMessage
+	handlers  map[string]http.HandlerFunc
+	requests  []udrRequest
+	nextID    int
+}
+
+func newFakeUdr() *fakeUdr {
+	return &fakeUdr{
+		documents: make(map[string]json.RawMessage),
+		handlers:  make(map[string]http.HandlerFunc),
+		nextID:    1,
+	}
+}
+
+// useFakeUdr starts the fake UDR and makes it the UDR discovered by the procedures
+func useFakeUdr(t *testing.T, udr *fakeUdr) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(udr)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	originalSendSearchNFInstances := consumer.SendSearchNFInstances
+	originalEnableNrfCaching := udm_context.UDM_Self().EnableNrfCaching
+	t.Cleanup(func() {
+		consumer.SendSearchNFInstances = originalSendSearchNFInstances
+		udm_context.UDM_Self().EnableNrfCaching = originalEnableNrfCaching
+	})
+	udm_context.UDM_Self().EnableNrfCaching = false
+	consumer.SendSearchNFInstances = func(nrfUri string, targetNfType, requestNfType models.NfType,
+		param *Nnrf_NFDiscovery.SearchNFInstancesParamOpts,
+	) (models.SearchResult, error) {
+		return models.SearchResult{NfInstances: []models.NfProfile{{
+			NfType: models.NfType_UDR,
+			Fqdn:   server.URL,
+			NfServices: &[]models.NfService{{
+				ServiceName:     models.ServiceName_NUDR_DR,
+				NfServiceStatus: models.NfServiceStatus_REGISTERED,
+			}},
+		}}}, nil
+	}
+}
+
+func (u *fakeUdr) set(path string, document interface{}) {
+	raw, err := json.Marshal(document)
+	if err != nil {
+		panic(err)
+	}
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.documents[path] = raw
+}
+
+// get decodes the document of the path, reporting whether there is one
+func (u *fakeUdr) get(path string, document interface{}) bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	raw, ok := u.documents[path]
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(raw, document); err != nil {
+		panic(err)
+	}
+	return true
+}
+
+func (u *fakeUdr) handle(method string, path string, handler http.HandlerFunc) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.handlers[method+" "+path] = handler
+}
+
+// received returns the requests received with the method on the path
+func (u *fakeUdr) received(method string, path string) []udrRequest {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	var requests []udrRequest
+	for _, request := range u.requests {
+		if request.method == method && request.path == path {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func (u *fakeUdr) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/nudr-dr/v1")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	u.lock.Lock()
+	u.requests = append(u.requests, udrRequest{method: r.Method, path: path, query: r.URL.Query(), body: body})
+	handler, ok := u.handlers[r.Method+" "+path]
+	u.lock.Unlock()
+	if ok {
+		handler(w, r)
+		return
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		if document, ok := u.documents[path]; ok {
+			writeUdrJSON(w, http.StatusOK, document)
+			return
+		}
+		if list := u.list(path); len(list) != 0 {
+			raw, _ := json.Marshal(list)
+			writeUdrJSON(w, http.StatusOK, raw)
+			return
+		}
+		writeUdrProblem(w, http.StatusNotFound, "DATA_NOT_FOUND")
+	case http.MethodPut:
+		u.documents[path] = body
+		// as the omec UDR, which creates the SMF registrations and replaces the other documents
+		if strings.Contains(path, "/context-data/smf-registrations/") {
+			writeUdrJSON(w, http.StatusCreated, body)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		id := strconv.Itoa(u.nextID)
+		u.nextID++
+		created := json.RawMessage(body)
+		// as the omec UDR, which stores the ID of the SDM subscriptions only
+		var document map[string]interface{}
+		if strings.HasSuffix(path, "/sdm-subscriptions") && json.Unmarshal(body, &document) == nil {
+			document["subscriptionId"] = id
+			created, _ = json.Marshal(document)
+		}
+		u.documents[path+"/"+id] = created
+		w.Header().Set("Location", "https://udr/nudr-dr/v1"+path+"/"+id)
+		writeUdrJSON(w, http.StatusCreated, created)
+	case http.MethodPatch:
+		raw, ok := u.documents[path]
+		if !ok {
+			writeUdrProblem(w, http.StatusNotFound, "DATA_NOT_FOUND")
+			return
+		}
+		var patchItems []models.PatchItem
+		var document interface{}
+		if json.Unmarshal(body, &patchItems) != nil || json.Unmarshal(raw, &document) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := util.ApplyJSONPatch(&document, patchItems); err != nil {
+			writeUdrProblem(w, http.StatusForbidden, "MODIFICATION_NOT_ALLOWED")
+			return
+		}
+		u.documents[path], _ = json.Marshal(document)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if _, ok := u.documents[path]; !ok {
+			writeUdrProblem(w, http.StatusNotFound, "DATA_NOT_FOUND")
+			return
+		}
+		delete(u.documents, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list returns the documents directly below the path, ordered by path
+func (u *fakeUdr) list(path string) []json.RawMessage {
+	var paths []string
+	for documentPath := range u.documents {
+		if rest, ok := strings.CutPrefix(documentPath, path+"/"); ok && !strings.Contains(rest, "/") {
+			paths = append(paths, documentPath)
+		}
+	}
+	sort.Strings(paths)
+	list := make([]json.RawMessage, 0, len(paths))
+	for _, documentPath := range paths {
+		list = append(list, u.documents[documentPath])
+	}
+	return list
+}
+
+func writeUdrJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func writeUdrProblem(w http.ResponseWriter, status int, cause string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(models.ProblemDetails{Status: int32(status), Cause: cause})
+}
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/logger"
+	stats "github.com/omec-project/udm/metrics"
+	"github.com/omec-project/udm/util"
+	"github.com/omec-project/util/httpwrapper"
+)
+
+// GbaAuthType is the GBA authentication type of TS 29.503 6.3.6.3.4
+type GbaAuthType string
+
+// GbaAuthTypeDigestAkaV1Md5 is the HTTP Digest AKA of the bootstrapping of TS 33.220 4.5.2
+const GbaAuthTypeDigestAkaV1Md5 GbaAuthType = "DIGEST_AKAV1_MD5"
+
+// GbaAuthenticationInfoRequest is the GbaAuthenticationInfoRequest of TS 29.503 6.3.6.2.14, sent by the
+// BSF, which models lacks
+type GbaAuthenticationInfoRequest struct {
+	AuthType              GbaAuthType                   `json:"authType"`
+	ResynchronizationInfo *models.ResynchronizationInfo `json:"resynchronizationInfo,omitempty"`
+	SupportedFeatures     string                        `json:"supportedFeatures,omitempty"`
+}
+
+// GbaAuthenticationInfoResult is the GbaAuthenticationInfoResult of TS 29.503 6.3.6.2.15
+type GbaAuthenticationInfoResult struct {
+	ThreeGAkaAv       *ThreeGAkaAv `json:"3gAkaAv,omitempty"`
+	SupportedFeatures string       `json:"supportedFeatures,omitempty"`
+}
+
+// ThreeGAkaAv is the 3GAkaAv of TS 29.503 6.3.6.2.16, the authentication vector of TS 33.102 6.3.2
+type ThreeGAkaAv struct {
+	Rand string `json:"rand"`
+	Xres string `json:"xres"`
+	Autn string `json:"autn"`
+	Ck   string `json:"ck"`
+	Ik   string `json:"ik"`
+}
+
+func HandleGenerateGbaAvRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UeauLog.Infoln("handle GenerateGbaAvRequest")
+	gbaAuthInfoRequest := request.Body.(GbaAuthenticationInfoRequest)
+	supi := request.Params["supi"]
+	response, problemDetails := GenerateGbaAvProcedure(gbaAuthInfoRequest, supi)
+	if problemDetails != nil {
+		stats.IncrementUdmUeAuthenticationStats("gba", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdmUeAuthenticationStats("gba", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, response)
+}
+
+// GenerateGbaAvProcedure generates the authentication vector of the bootstrapping of the UE by the BSF
+// (TS 33.220 4.5.2) with the SQN of its 5G vectors. The vector is a UMTS AKA vector, whose AMF does
+// not have the separation bit (TS 33.401 6.1.2).
+func GenerateGbaAvProcedure(gbaAuthInfoRequest GbaAuthenticationInfoRequest, supi string) (
+	response *GbaAuthenticationInfoResult, problemDetails *models.ProblemDetails,
+) {
+	if gbaAuthInfoRequest.AuthType != GbaAuthTypeDigestAkaV1Md5 {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: fmt.Sprintf("unsupported GBA authentication type %s", gbaAuthInfoRequest.AuthType),
+			InvalidParams: []models.InvalidParam{
+				{
+					Param:  "authType",
+					Reason: "unsupported",
+				},
+			},
+		}
+		return nil, problemDetails
+	}
+	if err := validateSupi(supi); err != nil {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: err.Error(),
+			InvalidParams: []models.InvalidParam{
+				{
+					Param:  "supi",
+					Reason: "incorrect format",
+				},
+			},
+		}
+
+		logger.UeauLog.Errorln("invalid SUPI:", err)
+		return nil, problemDetails
+	}
+
+	// the SQN of the UDR is read and updated by one request of the UE at a time
+	defer udm_context.UDM_Self().LockAuthentication(supi)()
+
+	cfg, err := createUDRConfiguration(supi)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+	authSubs, etag, err := queryAuthenticationSubscription(cfg, supi)
+	if err != nil {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("return from UDR QueryAuthSubsData error")
+		return nil, problemDetails
+	}
+	if _, ok := isCertificateBased(authSubs.AuthenticationMethod); ok {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: fmt.Sprintf("no GBA vector for the authentication method %s", authSubs.AuthenticationMethod),
+		}
+		return nil, problemDetails
+	}
+
+	algorithm, err := newAuthAlgorithm(&authSubs.AuthenticationSubscription)
+	if err != nil {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("authentication algorithm error:", err)
+		return nil, problemDetails
+	}
+	AMF, err := gbaManagementField(&authSubs.AuthenticationSubscription)
+	if err != nil {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+		return nil, problemDetails
+	}
+
+	var sqnMS []byte
+	if gbaAuthInfoRequest.ResynchronizationInfo != nil {
+		sqnMS, problemDetails = resynchronizedSqn(algorithm, supi, gbaAuthInfoRequest.ResynchronizationInfo)
+		if problemDetails != nil {
+			return nil, problemDetails
+		}
+	}
+
+	sqns, err := reserveSqns(cfg, supi, authSubs.SequenceNumber, etag, sqnMS, 1)
+	if err != nil {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  "modification is rejected ",
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("update sqn error", err)
+		return nil, problemDetails
+	}
+
+	vector, err := generateAkaVector(algorithm, sqns[0], AMF)
+	if err != nil {
+		logger.UeauLog.Errorln("authentication vector generation error:", err)
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+	response = &GbaAuthenticationInfoResult{
+		ThreeGAkaAv: &ThreeGAkaAv{
+			Rand: hex.EncodeToString(vector.rand),
+			Xres: hex.EncodeToString(vector.xres),
+			Autn: hex.EncodeToString(vector.autn),
+			Ck:   hex.EncodeToString(vector.ck),
+			Ik:   hex.EncodeToString(vector.ik),
+		},
+	}
+	return response, nil
+}
+
+// gbaManagementField returns the AMF of the subscriber, or the default AMF, without the separation
+// bit, which is only set in the vectors of E-UTRAN and 5G (TS 33.102 Annex H)
+func gbaManagementField(authSubs *models.AuthenticationSubscription) ([]byte, error) {
+	amf, err := subscribedAmf(authSubs)
+	if err != nil {
+		return nil, err
+	}
+	amf[0] &^= amfSeparationBit
+	return amf, nil
+}
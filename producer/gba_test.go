@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestGbaManagementField(t *testing.T) {
+	for _, tc := range []struct{ amf, expected string }{
+		{amf: "b9b9", expected: "39b9"},
+		{amf: "0000", expected: "0000"},
+		{amf: "8000", expected: "0000"},
+	} {
+		amf, err := gbaManagementField(&models.AuthenticationSubscription{AuthenticationManagementField: tc.amf})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if hex.EncodeToString(amf) != tc.expected {
+			t.Errorf("AMF %s: expected %s, got %x", tc.amf, tc.expected, amf)
+		}
+	}
+}
+
+// TestGenerateGbaAvProcedure checks the vector against the outputs of the algorithm with the test set 1
+// of TS 35.208, and the AMF of its AUTN without the separation bit
+func TestGenerateGbaAvProcedure(t *testing.T) {
+	const supi = "imsi-208930000000022"
+	authSubs := models.AuthenticationSubscription{
+		AuthenticationMethod:          models.AuthMethod__5_G_AKA,
+		PermanentKey:                  &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"},
+		Opc:                           &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+		AuthenticationManagementField: "b9b9",
+	}
+	algorithm, err := newAuthAlgorithm(&authSubs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	scheme := currentSqnScheme()
+	authSubs.SequenceNumber = formatSqn(scheme.join(100, 2))
+	udr := &fakeAuthSubsUdr{authSubs: authSubs}
+	discoverFakeUdr(t, newFakeAuthSubsUdrConfiguration(t, udr))
+
+	response, problemDetails := GenerateGbaAvProcedure(GbaAuthenticationInfoRequest{
+		AuthType: GbaAuthTypeDigestAkaV1Md5,
+	}, supi)
+	if problemDetails != nil {
+		t.Fatalf("unexpected problem: %+v", problemDetails)
+	}
+	av := response.ThreeGAkaAv
+
+	rand := decodeHexString(t, av.Rand)
+	res, ck, ik, ak, _, err := algorithm.f2345(rand)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if av.Xres != hex.EncodeToString(res) || av.Ck != hex.EncodeToString(ck) || av.Ik != hex.EncodeToString(ik) {
+		t.Errorf("expected XRES %x, CK %x and IK %x, got %+v", res, ck, ik, av)
+	}
+	sqn := sqnToBytes(scheme.join(101, 3))
+	amf := decodeHexString(t, "39b9")
+	macA, _, err := algorithm.f1(rand, sqn, amf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	autn := make([]byte, 0, 16)
+	for i := range sqn {
+		autn = append(autn, sqn[i]^ak[i])
+	}
+	autn = append(append(autn, amf...), macA...)
+	if !bytes.Equal(decodeHexString(t, av.Autn), autn) {
+		t.Errorf("expected AUTN %x, got %s", autn, av.Autn)
+	}
+	if stored, patches := udr.state(); stored != formatSqn(scheme.join(101, 3)) || patches != 1 {
+		t.Errorf("expected the SQN to be stored, got %s with %d updates", stored, patches)
+	}
+}
+
+func TestGenerateGbaAvProcedure_Rejected(t *testing.T) {
+	udr := &fakeAuthSubsUdr{authSubs: models.AuthenticationSubscription{AuthenticationMethod: AuthMethodEapTls}}
+	discoverFakeUdr(t, newFakeAuthSubsUdrConfiguration(t, udr))
+
+	testCases := []struct {
+		name           string
+		request        GbaAuthenticationInfoRequest
+		supi           string
+		expectedStatus int32
+		expectedCause  string
+	}{
+		{
+			name:           "unsupported authType",
+			request:        GbaAuthenticationInfoRequest{AuthType: "DIGEST_MD5"},
+			supi:           "imsi-208930000000022",
+			expectedStatus: http.StatusBadRequest,
+			expectedCause:  "MANDATORY_IE_INCORRECT",
+		},
+		{
+			name:           "invalid SUPI",
+			request:        GbaAuthenticationInfoRequest{AuthType: GbaAuthTypeDigestAkaV1Md5},
+			supi:           "imsi-2089",
+			expectedStatus: http.StatusBadRequest,
+			expectedCause:  "MANDATORY_IE_INCORRECT",
+		},
+		{
+			name:           "certificate based",
+			request:        GbaAuthenticationInfoRequest{AuthType: GbaAuthTypeDigestAkaV1Md5},
+			supi:           "imsi-208930000000022",
+			expectedStatus: http.StatusForbidden,
+			expectedCause:  authenticationRejected,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, problemDetails := GenerateGbaAvProcedure(tc.request, tc.supi)
+			if problemDetails == nil || problemDetails.Status != tc.expectedStatus ||
+				problemDetails.Cause != tc.expectedCause {
+				t.Errorf("expected %d %s, got %+v", tc.expectedStatus, tc.expectedCause, problemDetails)
+			}
+		})
+	}
+}
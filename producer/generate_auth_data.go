@@ -7,31 +7,32 @@ package producer
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/big"
+	"io"
 	"net/http"
-	"reflect"
+	"net/url"
 	"strings"
+	"time"
 
-	"github.com/antihax/optional"
+	"github.com/google/uuid"
 	"github.com/omec-project/openapi"
 	"github.com/omec-project/openapi/Nudr_DataRepository"
 	"github.com/omec-project/openapi/models"
 	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/factory"
 	"github.com/omec-project/udm/logger"
 	stats "github.com/omec-project/udm/metrics"
 	"github.com/omec-project/udm/util"
 	"github.com/omec-project/util/httpwrapper"
-	"github.com/omec-project/util/milenage"
 	"github.com/omec-project/util/ueauth"
-	"github.com/omec-project/util/util_3gpp/suci"
 )
 
 const (
 	SqnMAx    int64 = 0xFFFFFFFFFFFF
-	ind       int64 = 32
 	keyStrLen int   = 32
 	opStrLen  int   = 32
 	opcStrLen int   = 32
@@ -41,29 +42,77 @@ const (
 	authenticationRejected string = "AUTHENTICATION_REJECTED"
 )
 
-func aucSQN(opc, k, auts, rand []byte) ([]byte, []byte) {
-	AK, SQNms := make([]byte, 6), make([]byte, 6)
-	macS := make([]byte, 8)
+// reasons of the authentication failures counted in the metrics
+const (
+	authFailureReasonAuthEvent = "AUTH_EVENT_FAILURE"  // failure confirmed by the AUSF
+	authFailureReasonSynch     = "SYNCH_FAILURE"       // resynchronization requested by the USIM
+	authFailureReasonResyncMac = "RESYNCH_MAC_FAILURE" // AUTS with an invalid MAC-S
+	authFailureReasonBlocked   = "BLOCKED"             // rejected after consecutive failures
+)
+
+const (
+	// amfSeparationBit is the bit 0 of the AMF
+	amfSeparationBit byte = 0x80
+	autsLen          int  = 14
+	randLen          int  = 16
+)
+
+// maxRequestedVectors is the highest number of vectors a request may ask for
+const maxRequestedVectors = 5
+
+// resyncAmf is the dummy AMF of MAC-S
+var resyncAmf = []byte{0x00, 0x00}
+
+// AuthenticationInfoRequest is the AuthenticationInfoRequest of TS 29.503 6.3.6.2.2, with the number of
+// vectors to generate at once, as the Number-Of-Requested-Vectors of TS 29.272 7.3.11, and the PLMN ID
+// of the serving network of E-UTRAN requesting EPS-AKA vectors
+type AuthenticationInfoRequest struct {
+	models.AuthenticationInfoRequest
+	NumberOfRequestedVectors int32          `json:"numberOfRequestedVectors,omitempty"`
+	ServingNetworkId         *models.PlmnId `json:"servingNetworkId,omitempty"`
+}
+
+// AuthenticationInfoResult is the AuthenticationInfoResult of TS 29.503 6.3.6.2.3, with the list of
+// the vectors generated when several are requested or for EPS-AKA, AuthenticationVector being the
+// first, the identity of the Credentials Holder of the SNPN subscribers authenticated with EAP-TLS or
+// EAP-TTLS, and the AKMA indication with the routing indicator of the UE (TS 33.535 6.1)
+type AuthenticationInfoResult struct {
+	models.AuthenticationInfoResult
+	AuthenticationVectors []AuthenticationVector `json:"authenticationVectors,omitempty"`
+	CredentialHolderId    string                 `json:"credentialHolderId,omitempty"`
+	AkmaInd               bool                   `json:"akmaInd,omitempty"`
+	RoutingId             string                 `json:"routingId,omitempty"`
+}
+
+// AuthenticationVector is the AuthenticationVector of TS 29.503 6.3.6.2.4, with the KASME of the EPS-AKA
+// vectors (TS 33.401 6.1.2)
+type AuthenticationVector struct {
+	models.AuthenticationVector
+	Kasme string `json:"kasme,omitempty"`
+}
+
+// aucSQN recovers SQNms from AUTS = SQNms xor AK* || MAC-S and returns it with the expected MAC-S,
+// computed with the dummy AMF (TS 33.102 6.3.3 and 6.3.5)
+func aucSQN(algorithm authAlgorithm, auts, rand []byte) ([]byte, []byte) {
+	SQNms := make([]byte, 6)
 	ConcSQNms := auts[:6]
-	AMF, err := hex.DecodeString("0000")
-	if err != nil {
-		return nil, nil
-	}
 
 	logger.UeauLog.Debugln("ConcSQNms", ConcSQNms)
 
-	err = milenage.F2345(opc, k, rand, nil, nil, nil, nil, AK)
+	_, _, _, _, AKstar, err := algorithm.f2345(rand)
 	if err != nil {
-		logger.UeauLog.Errorln("milenage F2345 err ", err)
+		logger.UeauLog.Errorln("f2345 err ", err)
+		return nil, nil
 	}
 
 	for i := 0; i < 6; i++ {
-		SQNms[i] = AK[i] ^ ConcSQNms[i]
+		SQNms[i] = AKstar[i] ^ ConcSQNms[i]
 	}
 
-	err = milenage.F1(opc, k, rand, SQNms, AMF, nil, macS)
+	_, macS, err := algorithm.f1(rand, SQNms, resyncAmf)
 	if err != nil {
-		logger.UeauLog.Errorln("milenage F1 err", err)
+		logger.UeauLog.Errorln("f1 err", err)
+		return nil, nil
 	}
 
 	logger.UeauLog.Debugln("SQNms", SQNms)
@@ -71,6 +120,34 @@ func aucSQN(opc, k, auts, rand []byte) ([]byte, []byte) {
 	return SQNms, macS
 }
 
+// authenticationManagementField returns the AMF of the subscriber, or the default AMF, with the
+// separation bit set as required in 5G (TS 33.102 Annex H, TS 33.501 6.1.3)
+func authenticationManagementField(authSubs *models.AuthenticationSubscription) ([]byte, error) {
+	amf, err := subscribedAmf(authSubs)
+	if err != nil {
+		return nil, err
+	}
+	if amf[0]&amfSeparationBit == 0 {
+		logger.UeauLog.Warnf("AMF %x without the separation bit, setting it", amf)
+		amf[0] |= amfSeparationBit
+	}
+	return amf, nil
+}
+
+// subscribedAmf returns a copy of the AMF of the subscriber, or of the default AMF
+func subscribedAmf(authSubs *models.AuthenticationSubscription) ([]byte, error) {
+	amf := udm_context.UDM_Self().DefaultAmf
+	if authSubs.AuthenticationManagementField != "" {
+		var err error
+		if amf, err = hex.DecodeString(authSubs.AuthenticationManagementField); err != nil || len(amf) != 2 {
+			return nil, fmt.Errorf("invalid authenticationManagementField %s", authSubs.AuthenticationManagementField)
+		}
+	} else if len(amf) != 2 {
+		amf, _ = hex.DecodeString(factory.UDM_DEFAULT_AMF)
+	}
+	return []byte{amf[0], amf[1]}, nil
+}
+
 func strictHex(s string, n int) string {
 	l := len(s)
 	if l < n {
@@ -82,7 +159,7 @@ func strictHex(s string, n int) string {
 
 func HandleGenerateAuthDataRequest(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.UeauLog.Infoln("handle GenerateAuthDataRequest")
-	authInfoRequest := request.Body.(models.AuthenticationInfoRequest)
+	authInfoRequest := request.Body.(AuthenticationInfoRequest)
 	supiOrSuci := request.Params["supiOrSuci"]
 	response, problemDetails := GenerateAuthDataProcedure(authInfoRequest, supiOrSuci)
 	if response != nil {
@@ -107,201 +184,337 @@ func HandleConfirmAuthDataRequest(request *httpwrapper.Request) *httpwrapper.Res
 	authEvent := request.Body.(models.AuthEvent)
 	supi := request.Params["supi"]
 
-	problemDetails := ConfirmAuthDataProcedure(authEvent, supi)
+	authEventID, problemDetails := ConfirmAuthDataProcedure(authEvent, supi)
 
 	if problemDetails != nil {
 		stats.IncrementUdmUeAuthenticationStats("create", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	} else {
 		stats.IncrementUdmUeAuthenticationStats("create", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusCreated, nil, nil)
+		headers := http.Header{
+			"Location": {udm_context.UDM_Self().GetIPv4Uri() + "/nudm-ueau/v1/" + supi + "/auth-events/" +
+				authEventID},
+		}
+		return httpwrapper.NewResponse(http.StatusCreated, headers, authEvent)
 	}
 }
 
-func ConfirmAuthDataProcedure(authEvent models.AuthEvent, supi string) (problemDetails *models.ProblemDetails) {
-	var createAuthParam Nudr_DataRepository.CreateAuthenticationStatusParamOpts
-	optInterface := optional.NewInterface(authEvent)
-	createAuthParam.AuthEvent = optInterface
+// ConfirmAuthDataProcedure counts the authentication failures of the SUPI in the serving network and
+// stores the AuthEvent in the UDR, returning its ID
+func ConfirmAuthDataProcedure(authEvent models.AuthEvent, supi string) (authEventID string,
+	problemDetails *models.ProblemDetails,
+) {
+	udmSelf := udm_context.UDM_Self()
+	if authEvent.Success {
+		udmSelf.AuthFailureTracker.RecordSuccess(supi, authEvent.ServingNetworkName)
+	} else {
+		stats.IncrementUdmUeAuthenticationFailureStats(authFailureReasonAuthEvent)
+		if udmSelf.AuthFailureTracker.RecordFailure(supi, authEvent.ServingNetworkName) {
+			logger.UeauLog.Warnf("authentication of %s in %s blocked after consecutive failures", supi,
+				authEvent.ServingNetworkName)
+		}
+	}
 
-	client, err := createUDMClientToUDR(supi)
+	cfg, err := createUDRConfiguration(supi)
 	if err != nil {
-		return util.ProblemDetailsSystemFailure(err.Error())
+		return "", util.ProblemDetailsSystemFailure(err.Error())
 	}
-	resp, err := client.AuthenticationStatusDocumentApi.CreateAuthenticationStatus(
-		context.Background(), supi, &createAuthParam)
+	// the AuthEvent replaces the previous one of the UE in the UDR, and is stored with its ID so that
+	// its removal can be checked against the UDR
+	authEventID = uuid.New().String()
+	res, body, err := sendAuthenticationStatusRequest(cfg, supi, http.MethodPut,
+		&storedAuthEvent{AuthEvent: authEvent, AuthEventId: authEventID})
 	if err != nil {
+		logger.UeauLog.Errorln("[ConfirmAuth]", err.Error())
+		return "", util.ProblemDetailsSystemFailure(err.Error())
+	}
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK &&
+		res.StatusCode != http.StatusCreated {
+		logger.UeauLog.Errorln("[ConfirmAuth] unexpected status", res.Status)
 		problemDetails = &models.ProblemDetails{
-			Status: int32(resp.StatusCode),
-			Cause:  err.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause,
-			Detail: err.Error(),
+			Status: int32(res.StatusCode),
+			Cause:  "UNSPECIFIED_NF_FAILURE",
+			Detail: res.Status,
+		}
+		var udrProblemDetails models.ProblemDetails
+		if openapi.Deserialize(&udrProblemDetails, body, res.Header.Get("Content-Type")) == nil &&
+			udrProblemDetails.Cause != "" {
+			problemDetails.Cause = udrProblemDetails.Cause
 		}
+		return "", problemDetails
+	}
+	return authEventID, nil
+}
 
-		logger.UeauLog.Errorln("[ConfirmAuth]", err.Error())
-		return problemDetails
+func HandleDeleteAuthDataRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UeauLog.Infoln("Handle DeleteAuthDataRequest")
+
+	supi := request.Params["supi"]
+	authEventID := request.Params["authEventId"]
+
+	problemDetails := DeleteAuthDataProcedure(supi, authEventID)
+
+	if problemDetails != nil {
+		stats.IncrementUdmUeAuthenticationStats("delete", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	} else {
+		stats.IncrementUdmUeAuthenticationStats("delete", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, nil)
 	}
-	defer func() {
-		if rspCloseErr := resp.Body.Close(); rspCloseErr != nil {
-			logger.UeauLog.Errorf("CreateAuthenticationStatus response body cannot close: %+v", rspCloseErr)
-		}
-	}()
+}
 
+// DeleteAuthDataProcedure removes the result of the authentication of the AuthEvent from the UDR
+// (TS 33.501 6.1.4.1b, authentication result removal), if it is still the AuthEvent stored there
+func DeleteAuthDataProcedure(supi string, authEventID string) *models.ProblemDetails {
+	cfg, err := createUDRConfiguration(supi)
+	if err != nil {
+		return util.ProblemDetailsSystemFailure(err.Error())
+	}
+	stored, err := queryAuthenticationStatus(cfg, supi)
+	if err != nil {
+		logger.UeauLog.Errorln("[DeleteAuth]", err.Error())
+		return util.ProblemDetailsSystemFailure(err.Error())
+	}
+	if stored == nil || stored.AuthEventId != authEventID {
+		return &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "DATA_NOT_FOUND",
+		}
+	}
+	if err = deleteAuthenticationStatus(cfg, supi); err != nil {
+		logger.UeauLog.Errorln("[DeleteAuth]", err.Error())
+		return util.ProblemDetailsSystemFailure(err.Error())
+	}
 	return nil
 }
 
-func GenerateAuthDataProcedure(authInfoRequest models.AuthenticationInfoRequest, supiOrSuci string) (
-	response *models.AuthenticationInfoResult, problemDetails *models.ProblemDetails,
-) {
-	logger.UeauLog.Debugln("in GenerateAuthDataProcedure")
+// storedAuthEvent is the AuthEvent stored in the UDR, with the authEventId identifying it
+type storedAuthEvent struct {
+	models.AuthEvent
+	AuthEventId string `json:"authEventId,omitempty"`
+}
 
-	response = &models.AuthenticationInfoResult{}
-	supi, err := suci.ToSupi(supiOrSuci, udm_context.UDM_Self().SuciProfiles)
+// queryAuthenticationStatus returns the AuthEvent of the UE stored in the UDR (TS 29.505), nil if
+// there is none. The request is built here because the generated query drops the authEventId.
+func queryAuthenticationStatus(cfg *Nudr_DataRepository.Configuration, supi string) (*storedAuthEvent, error) {
+	res, body, err := sendAuthenticationStatusRequest(cfg, supi, http.MethodGet, nil)
 	if err != nil {
-		problemDetails = &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
-			Detail: err.Error(),
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		var stored storedAuthEvent
+		if err := openapi.Deserialize(&stored, body, res.Header.Get("Content-Type")); err != nil {
+			return nil, err
 		}
-
-		logger.UeauLog.Errorln("suciToSupi error:", err.Error())
-		return nil, problemDetails
+		return &stored, nil
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
 	}
+}
 
-	logger.UeauLog.Debugf("supi conversion => %s", supi)
+// deleteAuthenticationStatus removes the AuthEvent of the UE from the UDR (TS 29.505), whose
+// operation is not generated
+func deleteAuthenticationStatus(cfg *Nudr_DataRepository.Configuration, supi string) error {
+	res, _, err := sendAuthenticationStatusRequest(cfg, supi, http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent &&
+		res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
 
-	client, err := createUDMClientToUDR(supi)
+// sendAuthenticationStatusRequest sends the request to the authentication status of the UE in the
+// UDR and returns the response with its body read
+func sendAuthenticationStatusRequest(cfg *Nudr_DataRepository.Configuration, supi string, method string,
+	body interface{},
+) (*http.Response, []byte, error) {
+	headers := map[string]string{"Accept": "application/json, application/problem+json"}
+	if body != nil {
+		headers["Content-Type"] = "application/json"
+	}
+	request, err := openapi.PrepareRequest(context.Background(), cfg,
+		cfg.BasePath()+"/subscription-data/"+url.PathEscape(supi)+"/authentication-data/authentication-status",
+		method, body, headers, url.Values{}, url.Values{}, "", "", nil)
 	if err != nil {
-		return nil, util.ProblemDetailsSystemFailure(err.Error())
+		return nil, nil, err
 	}
-	authSubs, res, err := client.AuthenticationDataDocumentApi.QueryAuthSubsData(context.Background(), supi, nil)
+	res, err := openapi.CallAPI(cfg, request)
 	if err != nil {
-		problemDetails = &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
-			Detail: err.Error(),
-		}
-
-		logger.UeauLog.Errorln("return from UDR QueryAuthSubsData error")
-		return nil, problemDetails
+		return nil, nil, err
 	}
 	defer func() {
 		if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
-			logger.SdmLog.Errorf("QueryAuthSubsData response body cannot close: %+v", rspCloseErr)
+			logger.UeauLog.Errorf("authentication status response body cannot close: %+v", rspCloseErr)
 		}
 	}()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, resBody, nil
+}
 
-	/*
-		K, RAND, CK, IK: 128 bits (16 bytes) (hex len = 32)
-		SQN, AK: 48 bits (6 bytes) (hex len = 12) TS33.102 - 6.3.2
-		AMF: 16 bits (2 bytes) (hex len = 4) TS33.102 - Annex H
-	*/
+// requestedVectors returns the number of vectors to generate, and the SN id of the serving network of
+// EPS-AKA vectors. A request for more than maxRequestedVectors is rejected rather than answered with
+// fewer vectors than requested.
+func requestedVectors(authInfoRequest AuthenticationInfoRequest) (int, []byte, *models.ProblemDetails) {
+	count := int(authInfoRequest.NumberOfRequestedVectors)
+	if count < 0 {
+		return 0, nil, &models.ProblemDetails{
+			Status:        http.StatusBadRequest,
+			Cause:         "OPTIONAL_IE_INCORRECT",
+			InvalidParams: []models.InvalidParam{{Param: "numberOfRequestedVectors", Reason: "negative"}},
+		}
+	}
+	if count > maxRequestedVectors {
+		return 0, nil, &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "OPTIONAL_IE_INCORRECT",
+			InvalidParams: []models.InvalidParam{{
+				Param:  "numberOfRequestedVectors",
+				Reason: fmt.Sprintf("greater than %d", maxRequestedVectors),
+			}},
+		}
+	}
+	count = max(count, 1)
 
-	hasK, hasOP, hasOPC := false, false, false
+	if authInfoRequest.ServingNetworkId == nil {
+		return count, nil, nil
+	}
+	snId, err := servingNetworkId(*authInfoRequest.ServingNetworkId)
+	if err != nil {
+		return 0, nil, &models.ProblemDetails{
+			Status:        http.StatusBadRequest,
+			Cause:         "OPTIONAL_IE_INCORRECT",
+			InvalidParams: []models.InvalidParam{{Param: "servingNetworkId", Reason: err.Error()}},
+		}
+	}
+	return count, snId, nil
+}
 
-	var kStr, opStr, opcStr string
+func GenerateAuthDataProcedure(authInfoRequest AuthenticationInfoRequest, supiOrSuci string) (
+	response *AuthenticationInfoResult, problemDetails *models.ProblemDetails,
+) {
+	logger.UeauLog.Debugln("in GenerateAuthDataProcedure")
 
-	k, op, opc := make([]byte, 16), make([]byte, 16), make([]byte, 16)
+	count, snId, problemDetails := requestedVectors(authInfoRequest)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
 
-	logger.UeauLog.Debugln("K", k)
+	if err := udm_context.UDM_Self().SuciProtectionPolicy.Check(supiOrSuci); err != nil {
+		var protectionErr *udm_context.SuciProtectionError
+		cause := authenticationRejected
+		if errors.As(err, &protectionErr) {
+			cause = protectionErr.Cause
+		}
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  cause,
+			Detail: err.Error(),
+		}
 
-	if authSubs.PermanentKey != nil {
-		kStr = authSubs.PermanentKey.PermanentKeyValue
-		if len(kStr) == keyStrLen {
-			k, err = hex.DecodeString(kStr)
-			if err != nil {
-				logger.UeauLog.Errorln("err", err)
-			} else {
-				hasK = true
-			}
-		} else {
-			problemDetails = &models.ProblemDetails{
-				Status: http.StatusForbidden,
-				Cause:  authenticationRejected,
-			}
+		logger.UeauLog.Warnln("SUCI rejected by the protection policy:", err)
+		return nil, problemDetails
+	}
 
-			logger.UeauLog.Errorln("kStr length is", len(kStr))
-			return nil, problemDetails
-		}
-	} else {
+	supi, err := udm_context.UDM_Self().SuciKeyRing.ToSupi(supiOrSuci)
+	if err != nil {
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  authenticationRejected,
+			Detail: err.Error(),
 		}
 
-		logger.UeauLog.Errorln("Nil PermanentKey")
+		logger.UeauLog.Errorln("suciToSupi error:", err.Error())
 		return nil, problemDetails
 	}
 
-	if authSubs.Milenage != nil {
-		if authSubs.Milenage.Op != nil {
-			opStr = authSubs.Milenage.Op.OpValue
-			if len(opStr) == opStrLen {
-				op, err = hex.DecodeString(opStr)
-				if err != nil {
-					logger.UeauLog.Errorln("err", err)
-				} else {
-					hasOP = true
-				}
-			} else {
-				logger.UeauLog.Errorln("opStr length is", len(opStr))
-			}
-		} else {
-			logger.UeauLog.Infoln("Nil Op")
+	logger.UeauLog.Debugf("supi conversion => %s", supi)
+	if err = validateSupi(supi); err != nil {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: err.Error(),
+			InvalidParams: []models.InvalidParam{
+				{
+					Param:  "supiOrSuci",
+					Reason: "incorrect format",
+				},
+			},
 		}
-	} else {
+
+		logger.UeauLog.Errorln("invalid SUPI:", err)
+		return nil, problemDetails
+	}
+
+	if blockedUntil, blocked := udm_context.UDM_Self().AuthFailureTracker.BlockedUntil(supi,
+		authInfoRequest.ServingNetworkName); blocked {
+		stats.IncrementUdmUeAuthenticationFailureStats(authFailureReasonBlocked)
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  authenticationRejected,
+			Detail: fmt.Sprintf("authentication blocked until %s after consecutive failures",
+				blockedUntil.Format(time.RFC3339)),
 		}
 
-		logger.UeauLog.Infoln("Nil Milenage")
+		logger.UeauLog.Warnf("authentication of %s in %s blocked", supi, authInfoRequest.ServingNetworkName)
 		return nil, problemDetails
 	}
 
-	if authSubs.Opc != nil && authSubs.Opc.OpcValue != "" {
-		opcStr = authSubs.Opc.OpcValue
-		if len(opcStr) == opcStrLen {
-			opc, err = hex.DecodeString(opcStr)
-			if err != nil {
-				logger.UeauLog.Errorln("err", err)
-			} else {
-				hasOPC = true
-			}
-		} else {
-			logger.UeauLog.Errorln("opcStr length is", len(opcStr))
-		}
-	} else {
-		logger.UeauLog.Infoln("Nil Opc")
-	}
+	// the SQN of the UDR is read and updated by one request of the UE at a time
+	defer udm_context.UDM_Self().LockAuthentication(supi)()
 
-	if !hasOPC && !hasOP {
+	cfg, err := createUDRConfiguration(supi)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+	authSubs, etag, err := queryAuthenticationSubscription(cfg, supi)
+	if err != nil {
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  authenticationRejected,
+			Detail: err.Error(),
 		}
 
+		logger.UeauLog.Errorln("return from UDR QueryAuthSubsData error")
 		return nil, problemDetails
 	}
 
-	if !hasOPC {
-		if hasK && hasOP {
-			opc, err = milenage.GenerateOPC(k, op)
-			if err != nil {
-				logger.UeauLog.Errorln("milenage GenerateOPC err", err)
-			}
-		} else {
+	if authType, ok := isCertificateBased(authSubs.AuthenticationMethod); ok {
+		if snId != nil {
 			problemDetails = &models.ProblemDetails{
 				Status: http.StatusForbidden,
 				Cause:  authenticationRejected,
+				Detail: fmt.Sprintf("no EPS-AKA vector for the authentication method %s", authSubs.AuthenticationMethod),
 			}
 
-			logger.UeauLog.Errorln("unable to derive OPC")
+			logger.UeauLog.Errorln("EPS-AKA vector requested for", supi, authSubs.AuthenticationMethod)
 			return nil, problemDetails
 		}
+
+		// the UE is authenticated with its certificate by the EAP server of the AUSF, or of the AAA server
+		// of the Credentials Holder
+		response = &AuthenticationInfoResult{CredentialHolderId: credentialHolderId(supi)}
+		response.AuthType = authType
+		response.Supi = supi
+		return response, nil
 	}
 
-	sqnStr := strictHex(authSubs.SequenceNumber, 12)
-	logger.UeauLog.Debugln("sqnStr", sqnStr)
-	sqn, err := hex.DecodeString(sqnStr)
+	/*
+		K: 128 bits (16 bytes) (hex len = 32), or 256 bits with TUAK
+		RAND, CK, IK: 128 bits (16 bytes) (hex len = 32)
+		SQN, AK: 48 bits (6 bytes) (hex len = 12) TS33.102 - 6.3.2
+		AMF: 16 bits (2 bytes) (hex len = 4) TS33.102 - Annex H
+	*/
+
+	algorithm, err := newAuthAlgorithm(&authSubs.AuthenticationSubscription)
 	if err != nil {
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusForbidden,
@@ -309,14 +522,13 @@ func GenerateAuthDataProcedure(authInfoRequest models.AuthenticationInfoRequest,
 			Detail: err.Error(),
 		}
 
-		logger.UeauLog.Errorln("err", err)
+		logger.UeauLog.Errorln("authentication algorithm error:", err)
 		return nil, problemDetails
 	}
 
-	logger.UeauLog.Debugln("sqn", sqn)
+	logger.UeauLog.Debugln("sqnHE", authSubs.SequenceNumber)
 
-	RAND := make([]byte, 16)
-	_, err = rand.Read(RAND)
+	AMF, err := authenticationManagementField(&authSubs.AuthenticationSubscription)
 	if err != nil {
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusForbidden,
@@ -328,149 +540,149 @@ func GenerateAuthDataProcedure(authInfoRequest models.AuthenticationInfoRequest,
 		return nil, problemDetails
 	}
 
-	AMF, err := hex.DecodeString("8000")
+	// re-synchroniztion
+	var sqnMS []byte
+	if authInfoRequest.ResynchronizationInfo != nil {
+		sqnMS, problemDetails = resynchronizedSqn(algorithm, supi, authInfoRequest.ResynchronizationInfo)
+		if problemDetails != nil {
+			return nil, problemDetails
+		}
+	}
+
+	sqns, err := reserveSqns(cfg, supi, authSubs.SequenceNumber, etag, sqnMS, count)
 	if err != nil {
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
+			Cause:  "modification is rejected ",
 			Detail: err.Error(),
 		}
 
-		logger.UeauLog.Errorln("err", err)
+		logger.UeauLog.Errorln("update sqn error", err)
 		return nil, problemDetails
 	}
 
-	// re-synchroniztion
-	if authInfoRequest.ResynchronizationInfo != nil {
-		Auts, deCodeErr := hex.DecodeString(authInfoRequest.ResynchronizationInfo.Auts)
-		if deCodeErr != nil {
-			problemDetails = &models.ProblemDetails{
-				Status: http.StatusForbidden,
-				Cause:  authenticationRejected,
-				Detail: deCodeErr.Error(),
-			}
-
-			logger.UeauLog.Errorln("err", deCodeErr)
-			return nil, problemDetails
+	response = &AuthenticationInfoResult{}
+	switch {
+	case snId != nil:
+		response.AuthType = AuthTypeEpsAka
+	case authSubs.AuthenticationMethod == models.AuthMethod__5_G_AKA:
+		response.AuthType = models.AuthType__5_G_AKA
+	default:
+		response.AuthType = models.AuthType_EAP_AKA_PRIME
+	}
+	for _, sqn := range sqns {
+		logger.UeauLog.Debugf("sqn %x", sqn)
+		av, err := generateAuthenticationVector(algorithm, response.AuthType, sqn, AMF,
+			authInfoRequest.ServingNetworkName, snId)
+		if err != nil {
+			logger.UeauLog.Errorln("authentication vector generation error:", err)
+			return nil, util.ProblemDetailsSystemFailure(err.Error())
 		}
+		response.AuthenticationVectors = append(response.AuthenticationVectors, av)
+	}
 
-		randHex, deCodeErr := hex.DecodeString(authInfoRequest.ResynchronizationInfo.Rand)
-		if deCodeErr != nil {
-			problemDetails = &models.ProblemDetails{
-				Status: http.StatusForbidden,
-				Cause:  authenticationRejected,
-				Detail: deCodeErr.Error(),
-			}
+	response.AuthenticationVector = &response.AuthenticationVectors[0].AuthenticationVector
+	if authInfoRequest.NumberOfRequestedVectors == 0 && snId == nil {
+		// the list is only returned to the NFs requesting it
+		response.AuthenticationVectors = nil
+	}
+	if authSubs.AkmaAllowed && snId == nil {
+		// the AUSF derives K_AKMA and A-KID after the primary authentication
+		response.AkmaInd = true
+		response.RoutingId = routingIndicator(supiOrSuci, authSubs)
+	}
+	response.Supi = supi
+	return response, nil
+}
 
-			logger.UeauLog.Errorln("err", deCodeErr)
-			return nil, problemDetails
+// resynchronizedSqn returns the SQN_MS of the AUTS of a resynchronization requested by the USIM
+// (TS 33.102 6.3.5), checking its MAC-S
+func resynchronizedSqn(algorithm authAlgorithm, supi string, resynchronizationInfo *models.ResynchronizationInfo) (
+	[]byte, *models.ProblemDetails,
+) {
+	var problemDetails *models.ProblemDetails
+	stats.IncrementUdmUeAuthenticationFailureStats(authFailureReasonSynch)
+	Auts, deCodeErr := hex.DecodeString(resynchronizationInfo.Auts)
+	if deCodeErr != nil {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: deCodeErr.Error(),
 		}
 
-		SQNms, macS := aucSQN(opc, k, Auts, randHex)
-		if reflect.DeepEqual(macS, Auts[6:]) {
-			_, err = rand.Read(RAND)
-			if err != nil {
-				problemDetails = &models.ProblemDetails{
-					Status: http.StatusForbidden,
-					Cause:  authenticationRejected,
-					Detail: err.Error(),
-				}
-
-				logger.UeauLog.Errorln("err", err)
-				return nil, problemDetails
-			}
-
-			// increment sqn authSubs.SequenceNumber
-			bigSQN := big.NewInt(0)
-			sqnStr = hex.EncodeToString(SQNms)
-			logger.UeauLog.Infof("SQNstr %s", sqnStr)
-			bigSQN.SetString(sqnStr, 16)
-
-			bigInc := big.NewInt(ind + 1)
-
-			bigP := big.NewInt(SqnMAx)
-			bigSQN = bigInc.Add(bigSQN, bigInc)
-			bigSQN = bigSQN.Mod(bigSQN, bigP)
-			sqnStr = fmt.Sprintf("%x", bigSQN)
-			sqnStr = strictHex(sqnStr, 12)
-		} else {
-			logger.UeauLog.Errorln("Re-Sync MAC failed", supi)
-			logger.UeauLog.Errorln("MACS", macS)
-			logger.UeauLog.Errorln("Auts[6:]", Auts[6:])
-			logger.UeauLog.Errorln("Sqn", SQNms)
-			problemDetails = &models.ProblemDetails{
-				Status: http.StatusForbidden,
-				Cause:  "modification is rejected",
-			}
-			return nil, problemDetails
-		}
+		logger.UeauLog.Errorln("err", deCodeErr)
+		return nil, problemDetails
 	}
 
-	// increment sqn
-	bigSQN := big.NewInt(0)
-	sqn, err = hex.DecodeString(sqnStr)
-	if err != nil {
+	randHex, deCodeErr := hex.DecodeString(resynchronizationInfo.Rand)
+	if deCodeErr != nil {
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  authenticationRejected,
-			Detail: err.Error(),
+			Detail: deCodeErr.Error(),
 		}
 
-		logger.UeauLog.Errorln("err", err)
+		logger.UeauLog.Errorln("err", deCodeErr)
 		return nil, problemDetails
 	}
 
-	bigSQN.SetString(sqnStr, 16)
-
-	bigInc := big.NewInt(1)
-	bigSQN = bigInc.Add(bigSQN, bigInc)
+	if len(Auts) != autsLen || len(randHex) != randLen {
+		problemDetails = &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: fmt.Sprintf("AUTS and RAND are %d and %d bytes", len(Auts), len(randHex)),
+		}
 
-	SQNheStr := fmt.Sprintf("%x", bigSQN)
-	SQNheStr = strictHex(SQNheStr, 12)
-	patchItemArray := []models.PatchItem{
-		{
-			Op:    models.PatchOperation_REPLACE,
-			Path:  "/sequenceNumber",
-			Value: SQNheStr,
-		},
+		logger.UeauLog.Errorln("invalid resynchronization info", supi)
+		return nil, problemDetails
 	}
 
-	var rsp *http.Response
-	rsp, err = client.AuthenticationDataDocumentApi.ModifyAuthentication(
-		context.Background(), supi, patchItemArray)
-	if err != nil {
+	SQNms, macS := aucSQN(algorithm, Auts, randHex)
+	if macS == nil || !hmac.Equal(macS, Auts[6:]) {
+		stats.IncrementUdmUeAuthenticationFailureStats(authFailureReasonResyncMac)
+		logger.UeauLog.Errorln("Re-Sync MAC failed", supi)
+		logger.UeauLog.Errorln("MACS", macS)
+		logger.UeauLog.Errorln("Auts[6:]", Auts[6:])
+		logger.UeauLog.Errorln("Sqn", SQNms)
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusForbidden,
-			Cause:  "modification is rejected ",
-			Detail: err.Error(),
+			Cause:  "modification is rejected",
 		}
-
-		logger.UeauLog.Errorln("update sqn error", err)
 		return nil, problemDetails
 	}
-	defer func() {
-		if rspCloseErr := rsp.Body.Close(); rspCloseErr != nil {
-			logger.SdmLog.Errorf("ModifyAuthentication response body cannot close: %+v", rspCloseErr)
-		}
-	}()
+	return SQNms, nil
+}
 
-	// Run milenage
-	macA, macS := make([]byte, 8), make([]byte, 8)
-	CK, IK := make([]byte, 16), make([]byte, 16)
-	RES := make([]byte, 8)
-	AK, AKstar := make([]byte, 6), make([]byte, 6)
+// akaVector is the authentication vector of TS 33.102 6.3.2, from which the vectors of the
+// authentication types are derived
+type akaVector struct {
+	rand     []byte
+	xres     []byte
+	ck       []byte
+	ik       []byte
+	sqnXorAk []byte
+	autn     []byte
+}
 
-	// Generate macA, macS
-	err = milenage.F1(opc, k, RAND, sqn, AMF, macA, macS)
+// generateAkaVector generates the vector of a new RAND with the SQN and AMF
+func generateAkaVector(algorithm authAlgorithm, sqn, AMF []byte) (akaVector, error) {
+	var vector akaVector
+	RAND := make([]byte, randLen)
+	if _, err := rand.Read(RAND); err != nil {
+		return vector, err
+	}
+
+	// Generate macA
+	macA, _, err := algorithm.f1(RAND, sqn, AMF)
 	if err != nil {
-		logger.UeauLog.Errorln("milenage F1 err ", err)
+		return vector, fmt.Errorf("f1: %w", err)
 	}
 
-	// Generate RES, CK, IK, AK, AKstar
+	// Generate RES, CK, IK, AK
 	// RES == XRES (expected RES) for server
-	err = milenage.F2345(opc, k, RAND, RES, CK, IK, AK, AKstar)
+	RES, CK, IK, AK, _, err := algorithm.f2345(RAND)
 	if err != nil {
-		logger.UeauLog.Errorln("milenage F2345 err", err)
+		return vector, fmt.Errorf("f2345: %w", err)
 	}
 
 	// Generate AUTN
@@ -478,52 +690,75 @@ func GenerateAuthDataProcedure(authInfoRequest models.AuthenticationInfoRequest,
 	for i := 0; i < len(sqn); i++ {
 		SQNxorAK[i] = sqn[i] ^ AK[i]
 	}
-	AUTN := append(append(SQNxorAK, AMF...), macA...)
+	AUTN := append(append(append([]byte{}, SQNxorAK...), AMF...), macA...)
 	logger.UeauLog.Infof("AUTN = %x", AUTN)
 
-	var av models.AuthenticationVector
-	if authSubs.AuthenticationMethod == models.AuthMethod__5_G_AKA {
-		response.AuthType = models.AuthType__5_G_AKA
+	return akaVector{rand: RAND, xres: RES, ck: CK, ik: IK, sqnXorAk: SQNxorAK, autn: AUTN}, nil
+}
+
+// generateAuthenticationVector generates the vector of the authentication type with a new RAND and the SQN
+func generateAuthenticationVector(algorithm authAlgorithm, authType models.AuthType, sqn, AMF []byte,
+	servingNetworkName string, snId []byte,
+) (AuthenticationVector, error) {
+	var av AuthenticationVector
+	vector, err := generateAkaVector(algorithm, sqn, AMF)
+	if err != nil {
+		return av, err
+	}
+	RAND, RES, CK, IK, SQNxorAK := vector.rand, vector.xres, vector.ck, vector.ik, vector.sqnXorAk
+
+	av.Rand = hex.EncodeToString(RAND)
+	av.Autn = hex.EncodeToString(vector.autn)
+	key := append(append([]byte{}, CK...), IK...)
+	switch authType {
+	case AuthTypeEpsAka:
+		av.AvType = AvTypeEpsAka
+		kasme, err := deriveKasme(CK, IK, snId, SQNxorAK)
+		if err != nil {
+			return av, err
+		}
+
+		// Fill in xres, kasme
+		av.Xres = hex.EncodeToString(RES)
+		av.Kasme = hex.EncodeToString(kasme)
+	case models.AuthType__5_G_AKA:
+		av.AvType = models.AvType__5_G_HE_AKA
 
 		// derive XRES*
-		key := append(CK, IK...)
 		FC := ueauth.FC_FOR_RES_STAR_XRES_STAR_DERIVATION
-		P0 := []byte(authInfoRequest.ServingNetworkName)
+		P0 := []byte(servingNetworkName)
 		P1 := RAND
 		P2 := RES
 
 		kdfValForXresStar, err := ueauth.GetKDFValue(
 			key, FC, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1), P2, ueauth.KDFLen(P2))
 		if err != nil {
-			logger.UeauLog.Error(err)
+			return av, err
 		}
 		xresStar := kdfValForXresStar[len(kdfValForXresStar)/2:]
 
 		// derive Kausf
 		FC = ueauth.FC_FOR_KAUSF_DERIVATION
-		P0 = []byte(authInfoRequest.ServingNetworkName)
+		P0 = []byte(servingNetworkName)
 		P1 = SQNxorAK
 		kdfValForKausf, err := ueauth.GetKDFValue(key, FC, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1))
 		if err != nil {
-			logger.UeauLog.Error(err)
+			return av, err
 		}
 
-		// Fill in rand, xresStar, autn, kausf
-		av.Rand = hex.EncodeToString(RAND)
+		// Fill in xresStar, kausf
 		av.XresStar = hex.EncodeToString(xresStar)
-		av.Autn = hex.EncodeToString(AUTN)
 		av.Kausf = hex.EncodeToString(kdfValForKausf)
-	} else { // EAP-AKA'
-		response.AuthType = models.AuthType_EAP_AKA_PRIME
+	default: // EAP-AKA'
+		av.AvType = models.AvType_EAP_AKA_PRIME
 
 		// derive CK' and IK'
-		key := append(CK, IK...)
 		FC := ueauth.FC_FOR_CK_PRIME_IK_PRIME_DERIVATION
-		P0 := []byte(authInfoRequest.ServingNetworkName)
+		P0 := []byte(servingNetworkName)
 		P1 := SQNxorAK
 		kdfVal, err := ueauth.GetKDFValue(key, FC, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1))
 		if err != nil {
-			logger.UeauLog.Error(err)
+			return av, err
 		}
 
 		// For TS 35.208 test set 19 & RFC 5448 test vector 1
@@ -533,15 +768,10 @@ func GenerateAuthDataProcedure(authInfoRequest models.AuthenticationInfoRequest,
 		ckPrime := kdfVal[:len(kdfVal)/2]
 		ikPrime := kdfVal[len(kdfVal)/2:]
 
-		// Fill in rand, xres, autn, ckPrime, ikPrime
-		av.Rand = hex.EncodeToString(RAND)
+		// Fill in xres, ckPrime, ikPrime
 		av.Xres = hex.EncodeToString(RES)
-		av.Autn = hex.EncodeToString(AUTN)
 		av.CkPrime = hex.EncodeToString(ckPrime)
 		av.IkPrime = hex.EncodeToString(ikPrime)
 	}
-
-	response.AuthenticationVector = &av
-	response.Supi = supi
-	return response, nil
+	return av, nil
 }
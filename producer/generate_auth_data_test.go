@@ -0,0 +1,338 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/Nnrf_NFDiscovery"
+	"github.com/omec-project/openapi/Nudr_DataRepository"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/consumer"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/util/milenage"
+)
+
+// discoverFakeUdr makes the fake UDR of the configuration the UDR discovered through the NRF
+func discoverFakeUdr(t *testing.T, cfg *Nudr_DataRepository.Configuration) {
+	t.Helper()
+	sendSearchNFInstances := consumer.SendSearchNFInstances
+	t.Cleanup(func() { consumer.SendSearchNFInstances = sendSearchNFInstances })
+	consumer.SendSearchNFInstances = func(nrfUri string, targetNfType, requestNfType models.NfType,
+		param *Nnrf_NFDiscovery.SearchNFInstancesParamOpts,
+	) (models.SearchResult, error) {
+		return models.SearchResult{NfInstances: []models.NfProfile{{
+			NfServices: &[]models.NfService{{
+				ServiceName:     models.ServiceName_NUDR_DR,
+				NfServiceStatus: models.NfServiceStatus_REGISTERED,
+				ApiPrefix:       cfg.BasePath(),
+			}},
+		}}}, nil
+	}
+}
+
+func TestAuthenticationManagementField(t *testing.T) {
+	udmContext := udm_context.UDM_Self()
+	defaultAmf := udmContext.DefaultAmf
+	t.Cleanup(func() { udmContext.DefaultAmf = defaultAmf })
+	udmContext.DefaultAmf = []byte{0x90, 0x01}
+
+	testCases := []struct {
+		name     string
+		amf      string
+		expected string
+	}{
+		{name: "default", expected: "9001"},
+		{name: "subscription", amf: "b9b9", expected: "b9b9"},
+		{name: "separation bit set", amf: "0123", expected: "8123"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amf, err := authenticationManagementField(&models.AuthenticationSubscription{
+				AuthenticationManagementField: tc.amf,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hex.EncodeToString(amf) != tc.expected {
+				t.Errorf("expected %s, got %x", tc.expected, amf)
+			}
+		})
+	}
+
+	udmContext.DefaultAmf = []byte{0x00, 0x00}
+	amf, err := authenticationManagementField(&models.AuthenticationSubscription{})
+	if err != nil || hex.EncodeToString(amf) != "8000" {
+		t.Errorf("expected the separation bit to be set on the default AMF, got %x, %v", amf, err)
+	}
+	if udmContext.DefaultAmf[0] != 0x00 {
+		t.Errorf("expected the default AMF to be left unchanged")
+	}
+
+	for _, invalid := range []string{"80", "800000", "zzzz"} {
+		if _, err := authenticationManagementField(&models.AuthenticationSubscription{
+			AuthenticationManagementField: invalid,
+		}); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+// TestAucSQN builds the AUTS of TS 33.102 6.3.3 with the test set 1 of TS 35.208
+func TestAucSQN(t *testing.T) {
+	k := decodeHexString(t, "465b5ce8b199b49faa5f0a2ee238a6bc")
+	opc := decodeHexString(t, "cd63cb71954a9f4e48a5994e37a02baf")
+	rand := decodeHexString(t, "23553cbe9637a89d218ae64dae47bf35")
+	sqnMS := decodeHexString(t, "ff9bb4d0b607")
+
+	akStar := make([]byte, 6)
+	if err := milenage.F2345(opc, k, rand, nil, nil, nil, nil, akStar); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	macS := make([]byte, 8)
+	if err := milenage.F1(opc, k, rand, sqnMS, []byte{0x00, 0x00}, nil, macS); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	auts := make([]byte, 0, autsLen)
+	for i := range sqnMS {
+		auts = append(auts, sqnMS[i]^akStar[i])
+	}
+	auts = append(auts, macS...)
+
+	algorithm, err := newAuthAlgorithm(&models.AuthenticationSubscription{
+		PermanentKey: &models.PermanentKey{PermanentKeyValue: hex.EncodeToString(k)},
+		Opc:          &models.Opc{OpcValue: hex.EncodeToString(opc)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recoveredSQN, expectedMacS := aucSQN(algorithm, auts, rand)
+	if !bytes.Equal(recoveredSQN, sqnMS) {
+		t.Errorf("expected SQNms %x, got %x", sqnMS, recoveredSQN)
+	}
+	if !bytes.Equal(expectedMacS, auts[6:]) {
+		t.Errorf("expected MAC-S %x, got %x", auts[6:], expectedMacS)
+	}
+}
+
+func TestGenerateAuthDataProcedure_Batch(t *testing.T) {
+	scheme := currentSqnScheme()
+	udr := &fakeAuthSubsUdr{authSubs: models.AuthenticationSubscription{
+		AuthenticationMethod: models.AuthMethod__5_G_AKA,
+		PermanentKey:         &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"},
+		Opc:                  &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+		SequenceNumber:       formatSqn(scheme.join(100, 2)),
+	}}
+	discoverFakeUdr(t, newFakeAuthSubsUdrConfiguration(t, udr))
+
+	request := AuthenticationInfoRequest{NumberOfRequestedVectors: 3}
+	request.ServingNetworkName = "5G:mnc093.mcc208.3gppnetwork.org"
+	response, problemDetails := GenerateAuthDataProcedure(request, "imsi-208930000000001")
+	if problemDetails != nil {
+		t.Fatalf("unexpected problem: %+v", problemDetails)
+	}
+	if response.AuthType != models.AuthType__5_G_AKA || len(response.AuthenticationVectors) != 3 {
+		t.Fatalf("expected 3 5G AKA vectors, got %s with %d", response.AuthType, len(response.AuthenticationVectors))
+	}
+	rands := make(map[string]bool)
+	for _, av := range response.AuthenticationVectors {
+		if av.AvType != models.AvType__5_G_HE_AKA || av.XresStar == "" || av.Kausf == "" || rands[av.Rand] {
+			t.Errorf("expected 5G HE AKA vectors with distinct RANDs, got %+v", av)
+		}
+		rands[av.Rand] = true
+	}
+	if *response.AuthenticationVector != response.AuthenticationVectors[0].AuthenticationVector {
+		t.Errorf("expected authenticationVector to be the first vector")
+	}
+	if stored, patches := udr.state(); stored != formatSqn(scheme.join(103, 5)) || patches != 1 {
+		t.Errorf("expected the last SQN to be stored by a single update, got %s with %d updates", stored, patches)
+	}
+}
+
+func TestGenerateAuthDataProcedure_EapTls(t *testing.T) {
+	for _, method := range []models.AuthMethod{AuthMethodEapTls, AuthMethodEapTtls} {
+		t.Run(string(method), func(t *testing.T) {
+			udr := &fakeAuthSubsUdr{authSubs: models.AuthenticationSubscription{AuthenticationMethod: method}}
+			discoverFakeUdr(t, newFakeAuthSubsUdrConfiguration(t, udr))
+
+			request := AuthenticationInfoRequest{}
+			request.ServingNetworkName = "5G:mnc093.mcc208.3gppnetwork.org"
+			response, problemDetails := GenerateAuthDataProcedure(request, "nai-sensor-1@ch.Example.com")
+			if problemDetails != nil {
+				t.Fatalf("unexpected problem: %+v", problemDetails)
+			}
+			if expected, _ := isCertificateBased(method); response.AuthType != expected {
+				t.Errorf("expected %s, got %s", expected, response.AuthType)
+			}
+			if response.AuthenticationVector != nil || response.AuthenticationVectors != nil {
+				t.Errorf("expected no vector, got %+v", response)
+			}
+			if response.Supi != "nai-sensor-1@ch.Example.com" || response.CredentialHolderId != "ch.example.com" {
+				t.Errorf("expected the SUPI and its Credentials Holder, got %s and %s", response.Supi,
+					response.CredentialHolderId)
+			}
+			if _, patches := udr.state(); patches != 0 {
+				t.Errorf("expected the SQN to be left unchanged, got %d updates", patches)
+			}
+
+			request.ServingNetworkId = &models.PlmnId{Mcc: "208", Mnc: "93"}
+			if _, problemDetails = GenerateAuthDataProcedure(request, "nai-sensor-1@ch.example.com"); problemDetails == nil ||
+				problemDetails.Cause != authenticationRejected {
+				t.Errorf("expected EPS-AKA to be rejected, got %+v", problemDetails)
+			}
+		})
+	}
+}
+
+func TestGenerateAuthDataProcedure_InvalidSupi(t *testing.T) {
+	request := AuthenticationInfoRequest{}
+	request.ServingNetworkName = "5G:mnc093.mcc208.3gppnetwork.org"
+	for _, supi := range []string{"imsi-2089", "imsi-20893000000000a", "nai-sensor-1", "nai-@ch.example.com",
+		"nai-sensor-1@-ch.example.com", "nai-sensor-1@ch..example.com"} {
+		_, problemDetails := GenerateAuthDataProcedure(request, supi)
+		if problemDetails == nil || problemDetails.Status != http.StatusBadRequest ||
+			problemDetails.Cause != "MANDATORY_IE_INCORRECT" {
+			t.Errorf("%s: expected MANDATORY_IE_INCORRECT, got %+v", supi, problemDetails)
+		}
+	}
+}
+
+func TestConfirmAuthDataProcedure_FailureProtection(t *testing.T) {
+	const supi = "imsi-208930000000019"
+	const snn = "5G:mnc093.mcc208.3gppnetwork.org"
+	udmContext := udm_context.UDM_Self()
+	tracker := udmContext.AuthFailureTracker
+	t.Cleanup(func() { udmContext.AuthFailureTracker = tracker })
+	udmContext.AuthFailureTracker = udm_context.NewAuthFailureTracker(2, time.Minute)
+
+	scheme := currentSqnScheme()
+	udr := &fakeAuthSubsUdr{authSubs: models.AuthenticationSubscription{
+		AuthenticationMethod: models.AuthMethod__5_G_AKA,
+		PermanentKey:         &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"},
+		Opc:                  &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+		SequenceNumber:       formatSqn(scheme.join(100, 2)),
+	}}
+	discoverFakeUdr(t, newFakeAuthSubsUdrConfiguration(t, udr))
+
+	var authEventIDs []string
+	for range 2 {
+		authEventID, problemDetails := ConfirmAuthDataProcedure(models.AuthEvent{
+			AuthType:           models.AuthType__5_G_AKA,
+			ServingNetworkName: snn,
+		}, supi)
+		if problemDetails != nil {
+			t.Fatalf("unexpected problem: %+v", problemDetails)
+		}
+		authEventIDs = append(authEventIDs, authEventID)
+	}
+	if authEventIDs[0] == authEventIDs[1] || udr.storedAuthEvent() == nil {
+		t.Fatalf("expected the AuthEvents to be stored with distinct IDs, got %v", authEventIDs)
+	}
+
+	request := AuthenticationInfoRequest{}
+	request.ServingNetworkName = snn
+	if _, problemDetails := GenerateAuthDataProcedure(request, supi); problemDetails == nil ||
+		problemDetails.Status != http.StatusForbidden || problemDetails.Cause != authenticationRejected {
+		t.Errorf("expected the authentication to be rejected after 2 failures, got %+v", problemDetails)
+	}
+	request.ServingNetworkName = "5G:mnc001.mcc001.3gppnetwork.org"
+	if _, problemDetails := GenerateAuthDataProcedure(request, supi); problemDetails != nil {
+		t.Errorf("expected the authentication in another serving network, got %+v", problemDetails)
+	}
+
+	// only the last AuthEvent is stored in the UDR
+	if problemDetails := DeleteAuthDataProcedure(supi, authEventIDs[0]); problemDetails == nil ||
+		problemDetails.Status != http.StatusNotFound {
+		t.Errorf("expected the replaced AuthEvent not to be found, got %+v", problemDetails)
+	}
+	if problemDetails := DeleteAuthDataProcedure(supi, authEventIDs[1]); problemDetails != nil {
+		t.Fatalf("unexpected problem: %+v", problemDetails)
+	}
+	if udr.storedAuthEvent() != nil {
+		t.Errorf("expected the AuthEvent to be removed from the UDR")
+	}
+	if problemDetails := DeleteAuthDataProcedure(supi, authEventIDs[1]); problemDetails == nil ||
+		problemDetails.Status != http.StatusNotFound {
+		t.Errorf("expected the removed AuthEvent not to be found, got %+v", problemDetails)
+	}
+}
+
+func TestDeleteAuthDataProcedure_StoredAuthEvent(t *testing.T) {
+	const supi = "imsi-208930000000020"
+	// the AuthEvent confirmed through another UDM instance, or before a restart
+	authEvent, _ := json.Marshal(storedAuthEvent{
+		AuthEvent:   models.AuthEvent{AuthType: models.AuthType__5_G_AKA, Success: true},
+		AuthEventId: "f5a8fd5c-0a3a-4d4c-8d3e-7f6b4c1e2a90",
+	})
+	udr := &fakeAuthSubsUdr{authEvent: authEvent}
+	discoverFakeUdr(t, newFakeAuthSubsUdrConfiguration(t, udr))
+
+	if problemDetails := DeleteAuthDataProcedure(supi, "1"); problemDetails == nil ||
+		problemDetails.Status != http.StatusNotFound {
+		t.Errorf("expected another AuthEvent not to be found, got %+v", problemDetails)
+	}
+	if udr.storedAuthEvent() == nil {
+		t.Fatal("expected the stored AuthEvent to be kept")
+	}
+	if problemDetails := DeleteAuthDataProcedure(supi, "f5a8fd5c-0a3a-4d4c-8d3e-7f6b4c1e2a90"); problemDetails != nil {
+		t.Fatalf("unexpected problem: %+v", problemDetails)
+	}
+	if udr.storedAuthEvent() != nil {
+		t.Errorf("expected the AuthEvent to be removed from the UDR")
+	}
+}
+
+func TestGenerateAuthDataProcedure_SuciProtectionPolicy(t *testing.T) {
+	udmContext := udm_context.UDM_Self()
+	policy := udmContext.SuciProtectionPolicy
+	t.Cleanup(func() { udmContext.SuciProtectionPolicy = policy })
+	udmContext.SuciProtectionPolicy = udm_context.NewSuciProtectionPolicy(
+		[]string{udm_context.ProfileAScheme, udm_context.ProfileBScheme},
+		[]udm_context.SuciProtectionRule{
+			{Mcc: "001", Mnc: "01", Schemes: []string{udm_context.NullScheme}},
+			{Mcc: "208", Mnc: "93", RoutingIndicator: "0001", Schemes: []string{udm_context.ProfileAScheme}},
+		})
+
+	scheme := currentSqnScheme()
+	udr := &fakeAuthSubsUdr{authSubs: models.AuthenticationSubscription{
+		AuthenticationMethod: models.AuthMethod__5_G_AKA,
+		PermanentKey:         &models.PermanentKey{PermanentKeyValue: "465b5ce8b199b49faa5f0a2ee238a6bc"},
+		Opc:                  &models.Opc{OpcValue: "cd63cb71954a9f4e48a5994e37a02baf"},
+		SequenceNumber:       formatSqn(scheme.join(100, 2)),
+	}}
+	discoverFakeUdr(t, newFakeAuthSubsUdrConfiguration(t, udr))
+
+	request := AuthenticationInfoRequest{}
+	request.ServingNetworkName = "5G:mnc093.mcc208.3gppnetwork.org"
+	testCases := []struct {
+		suci          string
+		expectedCause string
+	}{
+		{suci: "suci-0-208-93-0000-0-0-0000000001", expectedCause: udm_context.CauseNullSchemeNotAllowed},
+		{suci: "suci-0-208-93-0001-2-1-0a0b0c", expectedCause: udm_context.CauseProtectionSchemeNotAllowed},
+		{suci: "suci-0-001-01-0000-0-0-0000000001"},
+	}
+	for _, tc := range testCases {
+		response, problemDetails := GenerateAuthDataProcedure(request, tc.suci)
+		if tc.expectedCause == "" {
+			if problemDetails != nil {
+				t.Errorf("%s: unexpected problem: %+v", tc.suci, problemDetails)
+			} else if response.Supi != "imsi-001010000000001" {
+				t.Errorf("%s: expected imsi-001010000000001, got %s", tc.suci, response.Supi)
+			}
+			continue
+		}
+		if problemDetails == nil || problemDetails.Status != http.StatusForbidden ||
+			problemDetails.Cause != tc.expectedCause {
+			t.Errorf("%s: expected %s, got %+v", tc.suci, tc.expectedCause, problemDetails)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/omec-project/openapi"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/logger"
+)
+
+// groupIdentifiers are the GroupIdentifiers of a group of UEs in the UDR (TS 29.505), whose model is
+// not generated
+type groupIdentifiers struct {
+	ExtGroupId string `json:"extGroupId,omitempty"`
+	IntGroupId string `json:"intGroupId,omitempty"`
+}
+
+// setUeExternalGroups indexes the UE under the external groups of the internal groups of its AM
+// subscription data, so that the EE subscriptions of those groups apply to it. The index is left
+// as is if a group cannot be resolved.
+func setUeExternalGroups(ue *udm_context.UdmUeContext, internalGroupIDs []string) {
+	externalGroupIDs := make([]string, 0, len(internalGroupIDs))
+	for _, internalGroupID := range internalGroupIDs {
+		externalGroupID, err := queryExternalGroupID(ue.Supi, internalGroupID)
+		if err != nil {
+			logger.SdmLog.Warnf("external group of the internal group %s of %s not resolved: %+v",
+				internalGroupID, ue.Supi, err)
+			return
+		}
+		if externalGroupID != "" {
+			externalGroupIDs = append(externalGroupIDs, externalGroupID)
+		}
+	}
+	udm_context.UDM_Self().SetUeExternalGroupIDs(ue, externalGroupIDs)
+}
+
+// queryExternalGroupID returns the external group ID of the internal group from the group
+// identifiers of the UDR, or an empty ID if the group has none
+func queryExternalGroupID(supi string, internalGroupID string) (string, error) {
+	cfg, err := createUDRConfiguration(supi)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("int-group-id", internalGroupID)
+	request, err := openapi.PrepareRequest(context.Background(), cfg,
+		cfg.BasePath()+"/subscription-data/group-data/group-identifiers", http.MethodGet, nil,
+		map[string]string{"Accept": "application/json, application/problem+json"}, query, url.Values{},
+		"", "", nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := openapi.CallAPI(cfg, request)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+			logger.SdmLog.Errorf("GetGroupIdentifiers response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return "", err
+		}
+		var identifiers groupIdentifiers
+		if err := openapi.Deserialize(&identifiers, body, res.Header.Get("Content-Type")); err != nil {
+			return "", err
+		}
+		return identifiers.ExtGroupId, nil
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unexpected status %s", res.Status)
+	}
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+)
+
+func TestGetAmDataProcedure_ExternalGroups(t *testing.T) {
+	const supi = "imsi-001010000000071"
+	const amDataPath = "/subscription-data/" + supi + "/00101/provisioned-data/am-data"
+	udr := newFakeUdr()
+	udr.set(amDataPath, models.AccessAndMobilitySubscriptionData{
+		InternalGroupIds: []string{"001-01-0000000a", "001-01-0000000b"},
+	})
+	udr.handle(http.MethodGet, "/subscription-data/group-data/group-identifiers",
+		func(w http.ResponseWriter, r *http.Request) {
+			internalGroupID := r.URL.Query().Get("int-group-id")
+			if internalGroupID == "001-01-0000000b" {
+				writeUdrProblem(w, http.StatusNotFound, "DATA_NOT_FOUND")
+				return
+			}
+			body, _ := json.Marshal(groupIdentifiers{
+				ExtGroupId: "extgroupid-" + internalGroupID + "@example.com",
+				IntGroupId: internalGroupID,
+			})
+			writeUdrJSON(w, http.StatusOK, body)
+		})
+	useFakeUdr(t, udr)
+	udmSelf := udm_context.UDM_Self()
+	t.Cleanup(func() {
+		udmSelf.SubscriberDataCache.Invalidate(supi, "")
+		udmSelf.UdmUePool.Delete(supi)
+	})
+
+	if _, problemDetails := getAmDataProcedure(supi, "00101", ""); problemDetails != nil {
+		t.Fatalf("unexpected problem details: %+v", problemDetails)
+	}
+	ues := udmSelf.UdmUesFindByExternalGroupID("extgroupid-001-01-0000000a@example.com")
+	if len(ues) != 1 || ues[0].Supi != supi {
+		t.Fatalf("expected %s in its external group, got %d UEs", supi, len(ues))
+	}
+	if requests := udr.received(http.MethodGet, "/subscription-data/group-data/group-identifiers"); len(requests) != 2 {
+		t.Errorf("expected the 2 internal groups to be resolved, got %d requests", len(requests))
+	}
+
+	// the UE leaves the group once its AM data no longer lists it
+	udr.set(amDataPath, models.AccessAndMobilitySubscriptionData{})
+	udmSelf.SubscriberDataCache.Invalidate(supi, "")
+	if _, problemDetails := getAmDataProcedure(supi, "00101", ""); problemDetails != nil {
+		t.Fatalf("unexpected problem details: %+v", problemDetails)
+	}
+	if ues := udmSelf.UdmUesFindByExternalGroupID("extgroupid-001-01-0000000a@example.com"); len(ues) != 0 {
+		t.Errorf("expected the group to be empty, got %d UEs", len(ues))
+	}
+}
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/Nudr_DataRepository"
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/logger"
+)
+
+// SQN management of TS 33.102 Annex C: SQN = SEQ || IND, the SEQ of the vectors being incremented
+// and their IND cycling through the 2^IND length entries of the array of the USIM
+const (
+	sqnLen              = 6
+	sqnBits             = 8 * sqnLen
+	defaultSqnIndLength = 5
+	// defaultSqnDelta is the limit Δ of C.2.2 on the difference SEQ - SEQ_MS accepted by the USIM
+	defaultSqnDelta uint64 = 1 << 28
+	// sqnUpdateMaxAttempts bounds the attempts of reserving a SQN when other UDMs update it
+	sqnUpdateMaxAttempts = 3
+)
+
+// sqnScheme is the SQN management configuration
+type sqnScheme struct {
+	indLength uint
+	delta     uint64
+}
+
+func currentSqnScheme() sqnScheme {
+	scheme := sqnScheme{indLength: defaultSqnIndLength, delta: defaultSqnDelta}
+	self := udm_context.UDM_Self()
+	if self.SqnIndLength > 0 {
+		scheme.indLength = self.SqnIndLength
+	}
+	if self.SqnDelta > 0 {
+		scheme.delta = self.SqnDelta
+	}
+	return scheme
+}
+
+func (s sqnScheme) split(sqn uint64) (seq uint64, ind uint64) {
+	return sqn >> s.indLength, sqn & (1<<s.indLength - 1)
+}
+
+func (s sqnScheme) join(seq uint64, ind uint64) uint64 {
+	return (seq<<s.indLength | ind&(1<<s.indLength-1)) & (1<<sqnBits - 1)
+}
+
+// next returns the SQN of the vector generated after sqnHE (C.3.2): SEQ is incremented and IND
+// takes the next entry of the array
+func (s sqnScheme) next(sqnHE uint64) uint64 {
+	seq, ind := s.split(sqnHE)
+	return s.join(seq+1, ind+1)
+}
+
+// inRange tells whether the SQN generated after sqnHE will be accepted by a USIM whose highest
+// accepted SQN is sqnMS (6.3.5): its SEQ is fresh and not ahead of SEQ_MS by more than Δ (C.2.2)
+func (s sqnScheme) inRange(sqnHE uint64, sqnMS uint64) bool {
+	seq, _ := s.split(s.next(sqnHE))
+	seqMS, _ := s.split(sqnMS)
+	return seq > seqMS && seq-seqMS <= s.delta
+}
+
+func sqnFromBytes(sqn []byte) uint64 {
+	var buf [8]byte
+	copy(buf[8-sqnLen:], sqn)
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+func sqnToBytes(sqn uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], sqn)
+	return buf[8-sqnLen:]
+}
+
+func parseSqn(sqn string) (uint64, error) {
+	value, err := strconv.ParseUint(strictHex(sqn, 2*sqnLen), 16, sqnBits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sequenceNumber %s: %w", sqn, err)
+	}
+	return value, nil
+}
+
+func formatSqn(sqn uint64) string {
+	return fmt.Sprintf("%0*x", 2*sqnLen, sqn)
+}
+
+// reserveSqns generates the SQNs of count new vectors from the SQN_HE of the UDR, resetting SQN_HE to
+// the sqnMS of a resynchronization when the USIM would reject it, and stores the last one as the new
+// SQN_HE, so that a batch of vectors costs a single update. The UDR is only updated if SQN_HE was not
+// changed meanwhile by another UDM, which is checked by If-Match when the UDR returned the etag of the
+// authentication subscription (TS 29.500 5.2.3.2.3), and otherwise by the test operation of the patch.
+// The test operation compares the JSON strings (RFC 6902 4.6), so it is given the SQN_HE exactly as
+// read: a UDR storing it in another case or length does not make it fail.
+// TS 29.505 does not specify the status of a failed update, which differs between UDRs, so SQN_HE is
+// read again after any rejected update: the update is attempted again if the etag, or without etag the
+// stored SQN_HE, changed meanwhile, so that no SQN is issued twice, and fails otherwise.
+// This only relies on TS 29.505 and RFC 6902; it has not been checked against a given UDR release.
+func reserveSqns(cfg *Nudr_DataRepository.Configuration, supi string, sqnHE string, etag string,
+	sqnMS []byte, count int,
+) ([][]byte, error) {
+	scheme := currentSqnScheme()
+	for attempt := 1; ; attempt++ {
+		current, err := parseSqn(sqnHE)
+		if err != nil {
+			return nil, err
+		}
+		if sqnMS != nil && !scheme.inRange(current, sqnFromBytes(sqnMS)) {
+			logger.UeauLog.Infof("SQN_HE %s of %s out of range, reset to SQN_MS %x", sqnHE, supi, sqnMS)
+			current = sqnFromBytes(sqnMS)
+		}
+		sqns := make([][]byte, count)
+		sqn := current
+		for i := range sqns {
+			sqn = scheme.next(sqn)
+			sqns[i] = sqnToBytes(sqn)
+		}
+
+		var patchItemArray []models.PatchItem
+		if etag == "" {
+			patchItemArray = append(patchItemArray, models.PatchItem{
+				Op:    models.PatchOperation_TEST,
+				Path:  "/sequenceNumber",
+				Value: sqnHE,
+			})
+		}
+		patchItemArray = append(patchItemArray, models.PatchItem{
+			Op:    models.PatchOperation_REPLACE,
+			Path:  "/sequenceNumber",
+			Value: formatSqn(sqn),
+		})
+		status, err := modifyAuthentication(cfg, supi, patchItemArray, etag)
+		if err == nil {
+			return sqns, nil
+		}
+		if status == 0 || attempt == sqnUpdateMaxAttempts {
+			return nil, err
+		}
+
+		logger.UeauLog.Warnf("update of SQN %s of %s failed, reading it again: %+v", sqnHE, supi, err)
+		client := Nudr_DataRepository.NewAPIClient(cfg)
+		authSubs, res, queryErr := client.AuthenticationDataDocumentApi.QueryAuthSubsData(context.Background(),
+			supi, nil)
+		if res != nil {
+			if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+				logger.UeauLog.Errorf("QueryAuthSubsData response body cannot close: %+v", rspCloseErr)
+			}
+		}
+		if queryErr != nil {
+			return nil, queryErr
+		}
+		newEtag := res.Header.Get("ETag")
+		if (etag != "" && newEtag == etag) || (etag == "" && authSubs.SequenceNumber == sqnHE) {
+			// the update was not rejected by a concurrent one
+			return nil, err
+		}
+		sqnHE, etag = authSubs.SequenceNumber, newEtag
+	}
+}
+
+// modifyAuthentication patches the authentication subscription as ModifyAuthentication, which cannot
+// set If-Match. It returns the status of the UDR response, if any, with the error.
+func modifyAuthentication(cfg *Nudr_DataRepository.Configuration, supi string, patchItemArray []models.PatchItem,
+	etag string,
+) (int, error) {
+	headers := map[string]string{
+		"Content-Type": "application/json-patch+json",
+		"Accept":       "application/problem+json",
+	}
+	if etag != "" {
+		headers["If-Match"] = etag
+	}
+	request, err := openapi.PrepareRequest(context.Background(), cfg,
+		cfg.BasePath()+"/subscription-data/"+url.PathEscape(supi)+"/authentication-data/authentication-subscription",
+		http.MethodPatch, &patchItemArray, headers, url.Values{}, url.Values{}, "", "", nil)
+	if err != nil {
+		return 0, err
+	}
+	res, err := openapi.CallAPI(cfg, request)
+	if err != nil {
+		return 0, err
+	}
+	if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+		logger.UeauLog.Errorf("ModifyAuthentication response body cannot close: %+v", rspCloseErr)
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return res.StatusCode, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,504 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/omec-project/openapi/Nudr_DataRepository"
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/util"
+)
+
+// fakeAuthSubsUdr serves the authentication subscription of a UE, applying the JSON patches as the UDR,
+// and its authentication status. With etags, it returns the version of the authentication
+// subscription and checks If-Match. A patch that cannot be applied, e.g. whose test operation fails,
+// is rejected with patchFailureStatus, 403 by default as TS 29.505 MODIFICATION_NOT_ALLOWED.
+type fakeAuthSubsUdr struct {
+	lock      sync.Mutex
+	authSubs  models.AuthenticationSubscription
+	akma      AuthenticationSubscription // AKMA subscription data of the authentication subscription
+	authEvent json.RawMessage            // stored authentication status
+	etags     bool
+	version   int
+	patches   int // applied
+	testOps   int // test operations received
+	// patchFailureStatus is the status of a patch that cannot be applied
+	patchFailureStatus int
+	// rejectPatches rejects all the patches with patchFailureStatus
+	rejectPatches bool
+	rejected      int
+	// beforePatch is called before applying each patch, to emulate the updates of other UDMs
+	beforePatch func(authSubs *models.AuthenticationSubscription)
+}
+
+func (u *fakeAuthSubsUdr) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if strings.HasSuffix(r.URL.Path, "/authentication-status") {
+		u.serveAuthenticationStatus(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if u.etags {
+			w.Header().Set("ETag", u.etag())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		authSubs := u.akma
+		authSubs.AuthenticationSubscription = u.authSubs
+		_ = json.NewEncoder(w).Encode(authSubs)
+	case http.MethodPatch:
+		var patchItems []models.PatchItem
+		if err := json.NewDecoder(r.Body).Decode(&patchItems); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, patchItem := range patchItems {
+			if patchItem.Op == models.PatchOperation_TEST {
+				u.testOps++
+			}
+		}
+		if u.beforePatch != nil {
+			u.beforePatch(&u.authSubs)
+			u.version++
+		}
+		if ifMatch := r.Header.Get("If-Match"); u.etags && ifMatch != "" && ifMatch != u.etag() {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_ = json.NewEncoder(w).Encode(models.ProblemDetails{Status: http.StatusPreconditionFailed})
+			return
+		}
+		if u.rejectPatches || util.ApplyJSONPatch(&u.authSubs, patchItems) != nil {
+			u.rejected++
+			status := u.patchFailureStatus
+			if status == 0 {
+				status = http.StatusForbidden
+			}
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(models.ProblemDetails{Status: int32(status), Cause: "MODIFICATION_NOT_ALLOWED"})
+			return
+		}
+		u.version++
+		u.patches++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (u *fakeAuthSubsUdr) serveAuthenticationStatus(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		authEvent, err := io.ReadAll(r.Body)
+		if err != nil || !json.Valid(authEvent) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		u.authEvent = authEvent
+	case http.MethodGet:
+		if u.authEvent == nil {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(models.ProblemDetails{Status: http.StatusNotFound, Cause: "DATA_NOT_FOUND"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(u.authEvent)
+		return
+	case http.MethodDelete:
+		u.authEvent = nil
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (u *fakeAuthSubsUdr) storedAuthEvent() *models.AuthEvent {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.authEvent == nil {
+		return nil
+	}
+	var authEvent models.AuthEvent
+	if err := json.Unmarshal(u.authEvent, &authEvent); err != nil {
+		panic(err)
+	}
+	return &authEvent
+}
+
+func (u *fakeAuthSubsUdr) etag() string {
+	return fmt.Sprintf("\"%d\"", u.version)
+}
+
+func (u *fakeAuthSubsUdr) rejectedPatches() int {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.rejected
+}
+
+// state returns the stored SQN and the number of patches applied
+func (u *fakeAuthSubsUdr) state() (string, int) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.authSubs.SequenceNumber, u.patches
+}
+
+func newFakeAuthSubsUdrConfiguration(t *testing.T, udr *fakeAuthSubsUdr) *Nudr_DataRepository.Configuration {
+	t.Helper()
+	server := httptest.NewUnstartedServer(udr)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	cfg := Nudr_DataRepository.NewConfiguration()
+	cfg.SetBasePath(server.URL)
+	// NewAPIClient sets the HTTP client of the configuration, before it is shared by goroutines
+	Nudr_DataRepository.NewAPIClient(cfg)
+	return cfg
+}
+
+// reserveSqn reserves the SQN of a single vector
+func reserveSqn(cfg *Nudr_DataRepository.Configuration, supi string, sqnHE string, etag string, sqnMS []byte) (
+	[]byte, error,
+) {
+	sqns, err := reserveSqns(cfg, supi, sqnHE, etag, sqnMS, 1)
+	if err != nil {
+		return nil, err
+	}
+	return sqns[0], nil
+}
+
+func TestSqnScheme_Next(t *testing.T) {
+	scheme := sqnScheme{indLength: 5, delta: defaultSqnDelta}
+	testCases := []struct {
+		name     string
+		sqnHE    uint64
+		expected uint64
+	}{
+		{name: "SEQ and IND incremented", sqnHE: scheme.join(10, 3), expected: scheme.join(11, 4)},
+		{name: "IND wraps around", sqnHE: scheme.join(10, 31), expected: scheme.join(11, 0)},
+		{name: "SQN wraps around", sqnHE: 1<<sqnBits - 1, expected: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if sqn := scheme.next(tc.sqnHE); sqn != tc.expected {
+				t.Errorf("expected %x, got %x", tc.expected, sqn)
+			}
+		})
+	}
+
+	seq, ind := sqnScheme{indLength: 8}.split(0x000000012345)
+	if seq != 0x123 || ind != 0x45 {
+		t.Errorf("expected SEQ 123 and IND 45 with 8 bits IND, got %x and %x", seq, ind)
+	}
+}
+
+func TestSqnScheme_InRange(t *testing.T) {
+	scheme := sqnScheme{indLength: 5, delta: 100}
+	testCases := []struct {
+		name     string
+		sqnHE    uint64
+		sqnMS    uint64
+		expected bool
+	}{
+		{name: "SQN_HE ahead of SQN_MS", sqnHE: scheme.join(50, 1), sqnMS: scheme.join(40, 7), expected: true},
+		{name: "next SEQ equal to SEQ_MS", sqnHE: scheme.join(39, 1), sqnMS: scheme.join(40, 7)},
+		{name: "SQN_HE behind SQN_MS", sqnHE: scheme.join(20, 1), sqnMS: scheme.join(40, 7)},
+		{name: "SQN_HE ahead by more than delta", sqnHE: scheme.join(140, 1), sqnMS: scheme.join(40, 7)},
+		{name: "SQN_HE ahead by delta", sqnHE: scheme.join(139, 1), sqnMS: scheme.join(40, 7), expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if inRange := scheme.inRange(tc.sqnHE, tc.sqnMS); inRange != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, inRange)
+			}
+		})
+	}
+}
+
+func TestReserveSqn(t *testing.T) {
+	scheme := currentSqnScheme()
+	udr := &fakeAuthSubsUdr{authSubs: models.AuthenticationSubscription{SequenceNumber: formatSqn(scheme.join(100, 2))}}
+	cfg := newFakeAuthSubsUdrConfiguration(t, udr)
+
+	sqn, err := reserveSqn(cfg, "imsi-208930000000001", udr.authSubs.SequenceNumber, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := scheme.join(101, 3); sqnFromBytes(sqn) != expected || udr.authSubs.SequenceNumber != formatSqn(expected) {
+		t.Errorf("expected SQN %x to be issued and stored, got %x and %s", expected, sqn, udr.authSubs.SequenceNumber)
+	}
+
+	// resynchronization with a USIM ahead of the UDR
+	sqnMS := sqnToBytes(scheme.join(500, 9))
+	sqn, err = reserveSqn(cfg, "imsi-208930000000001", udr.authSubs.SequenceNumber, "", sqnMS)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := scheme.join(501, 10); sqnFromBytes(sqn) != expected {
+		t.Errorf("expected SQN_HE to be reset to SQN_MS, got %x", sqn)
+	}
+
+	// resynchronization with a USIM behind the UDR keeps SQN_HE
+	sqn, err = reserveSqn(cfg, "imsi-208930000000001", udr.authSubs.SequenceNumber, "", sqnToBytes(scheme.join(450, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := scheme.join(502, 11); sqnFromBytes(sqn) != expected {
+		t.Errorf("expected SQN_HE to be kept, got %x", sqn)
+	}
+}
+
+func TestReserveSqns_Batch(t *testing.T) {
+	scheme := currentSqnScheme()
+	udr := &fakeAuthSubsUdr{
+		authSubs: models.AuthenticationSubscription{SequenceNumber: formatSqn(scheme.join(100, 30))},
+		etags:    true,
+	}
+	cfg := newFakeAuthSubsUdrConfiguration(t, udr)
+
+	sqns, err := reserveSqns(cfg, "imsi-208930000000001", udr.authSubs.SequenceNumber, udr.etag(), nil, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []uint64{scheme.join(101, 31), scheme.join(102, 0), scheme.join(103, 1), scheme.join(104, 2)}
+	if len(sqns) != len(expected) {
+		t.Fatalf("expected %d SQNs, got %d", len(expected), len(sqns))
+	}
+	for i := range expected {
+		if sqnFromBytes(sqns[i]) != expected[i] {
+			t.Errorf("SQN %d: expected %x, got %x", i, expected[i], sqns[i])
+		}
+	}
+	if stored, patches := udr.state(); stored != formatSqn(expected[3]) || patches != 1 {
+		t.Errorf("expected the last SQN to be stored by a single update, got %s with %d updates", stored, patches)
+	}
+}
+
+func TestReserveSqn_ConcurrentUpdate(t *testing.T) {
+	scheme := currentSqnScheme()
+	udr := &fakeAuthSubsUdr{authSubs: models.AuthenticationSubscription{SequenceNumber: formatSqn(scheme.join(100, 2))}}
+	updated := false
+	udr.beforePatch = func(authSubs *models.AuthenticationSubscription) {
+		// another UDM issues a SQN between the read and the update of this one
+		if !updated {
+			updated = true
+			authSubs.SequenceNumber = formatSqn(scheme.join(101, 3))
+		}
+	}
+	cfg := newFakeAuthSubsUdrConfiguration(t, udr)
+
+	sqn, err := reserveSqn(cfg, "imsi-208930000000001", formatSqn(scheme.join(100, 2)), "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := scheme.join(102, 4); sqnFromBytes(sqn) != expected {
+		t.Errorf("expected the SQN following the one of the other UDM, got %s", hex.EncodeToString(sqn))
+	}
+
+	// other UDMs issue a SQN before each update of this one
+	udr.beforePatch = func(authSubs *models.AuthenticationSubscription) {
+		sqnHE, _ := parseSqn(authSubs.SequenceNumber)
+		authSubs.SequenceNumber = formatSqn(scheme.next(sqnHE))
+	}
+	if _, err = reserveSqn(cfg, "imsi-208930000000001", udr.authSubs.SequenceNumber, "", nil); err == nil {
+		t.Errorf("expected an error after %d conflicting updates", sqnUpdateMaxAttempts)
+	}
+}
+
+func TestReserveSqn_ConflictStatus(t *testing.T) {
+	scheme := currentSqnScheme()
+	for _, status := range []int{
+		http.StatusBadRequest, http.StatusForbidden, http.StatusConflict, http.StatusUnprocessableEntity,
+		http.StatusInternalServerError,
+	} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			udr := &fakeAuthSubsUdr{
+				authSubs:           models.AuthenticationSubscription{SequenceNumber: formatSqn(scheme.join(100, 2))},
+				patchFailureStatus: status,
+			}
+			updated := false
+			udr.beforePatch = func(authSubs *models.AuthenticationSubscription) {
+				if !updated {
+					updated = true
+					authSubs.SequenceNumber = formatSqn(scheme.join(101, 3))
+				}
+			}
+			cfg := newFakeAuthSubsUdrConfiguration(t, udr)
+
+			sqn, err := reserveSqn(cfg, "imsi-208930000000001", formatSqn(scheme.join(100, 2)), "", nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := scheme.join(102, 4); sqnFromBytes(sqn) != expected {
+				t.Errorf("expected the SQN following the one of the other UDM, got %s", hex.EncodeToString(sqn))
+			}
+		})
+	}
+}
+
+func TestReserveSqn_RejectedWithoutConflict(t *testing.T) {
+	scheme := currentSqnScheme()
+	sqnHE := formatSqn(scheme.join(100, 2))
+	udr := &fakeAuthSubsUdr{
+		authSubs:           models.AuthenticationSubscription{SequenceNumber: sqnHE},
+		patchFailureStatus: http.StatusForbidden,
+		rejectPatches:      true,
+	}
+	cfg := newFakeAuthSubsUdrConfiguration(t, udr)
+
+	// SQN_HE is read again once, and is unchanged
+	if _, err := reserveSqn(cfg, "imsi-208930000000001", sqnHE, "", nil); err == nil {
+		t.Fatal("expected the update to fail")
+	}
+	if rejected := udr.rejectedPatches(); rejected != 1 {
+		t.Errorf("expected no retry of an update rejected without conflict, got %d rejected updates", rejected)
+	}
+}
+
+func TestReserveSqn_IfMatch(t *testing.T) {
+	scheme := currentSqnScheme()
+	udr := &fakeAuthSubsUdr{
+		authSubs: models.AuthenticationSubscription{SequenceNumber: formatSqn(scheme.join(100, 2))},
+		etags:    true,
+	}
+	cfg := newFakeAuthSubsUdrConfiguration(t, udr)
+
+	// the authentication subscription was updated since the etag was read, although not its SQN
+	sqn, err := reserveSqn(cfg, "imsi-208930000000001", udr.authSubs.SequenceNumber, "\"-1\"", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, patches := udr.state(); sqnFromBytes(sqn) != scheme.join(101, 3) || patches != 1 {
+		t.Errorf("expected SQN %x after reading the etag again, got %x with %d updates", scheme.join(101, 3), sqn,
+			patches)
+	}
+
+	sqn, err = reserveSqn(cfg, "imsi-208930000000001", udr.authSubs.SequenceNumber, udr.etag(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := scheme.join(102, 4); sqnFromBytes(sqn) != expected {
+		t.Errorf("expected SQN %x, got %x", expected, sqn)
+	}
+}
+
+func TestReserveSqn_SqnRepresentation(t *testing.T) {
+	scheme := currentSqnScheme()
+	for name, etags := range map[string]bool{"etag": true, "test operation": false} {
+		t.Run(name, func(t *testing.T) {
+			udr := &fakeAuthSubsUdr{
+				authSubs: models.AuthenticationSubscription{SequenceNumber: formatSqn(scheme.join(0x100, 10))},
+				etags:    etags,
+			}
+			udr.beforePatch = func(authSubs *models.AuthenticationSubscription) {
+				// another UDM stores the same SQN_HE in upper case
+				authSubs.SequenceNumber = strings.ToUpper(authSubs.SequenceNumber)
+				udr.beforePatch = nil
+			}
+			cfg := newFakeAuthSubsUdrConfiguration(t, udr)
+			etag := ""
+			if etags {
+				etag = udr.etag()
+			}
+
+			sqn, err := reserveSqn(cfg, "imsi-208930000000001", udr.authSubs.SequenceNumber, etag, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := scheme.join(0x101, 11); sqnFromBytes(sqn) != expected {
+				t.Errorf("expected SQN %x, got %x", expected, sqn)
+			}
+			if etags && udr.testOps != 0 {
+				t.Errorf("expected If-Match alone with an etag, got %d test operations", udr.testOps)
+			}
+			if !etags && udr.testOps != 2 {
+				t.Errorf("expected a test operation in each update, got %d", udr.testOps)
+			}
+		})
+	}
+}
+
+// TestReserveSqn_Concurrent generates vectors of a UE in parallel, as the requests of the AUSFs handled
+// by this UDM or by several replicas sharing the UDR
+func TestReserveSqn_Concurrent(t *testing.T) {
+	const supi = "imsi-208930000000001"
+	const requests = 20
+	scheme := currentSqnScheme()
+
+	for name, replicas := range map[string]bool{"one UDM": false, "replicas": true} {
+		t.Run(name, func(t *testing.T) {
+			udr := &fakeAuthSubsUdr{
+				authSubs: models.AuthenticationSubscription{SequenceNumber: formatSqn(scheme.join(100, 2))},
+				etags:    true,
+			}
+			cfg := newFakeAuthSubsUdrConfiguration(t, udr)
+
+			var wg sync.WaitGroup
+			sqns := make([][]byte, requests)
+			errs := make([]error, requests)
+			for i := range requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if !replicas {
+						defer udm_context.UDM_Self().LockAuthentication(supi)()
+					}
+					client := Nudr_DataRepository.NewAPIClient(cfg)
+					authSubs, res, err := client.AuthenticationDataDocumentApi.QueryAuthSubsData(
+						context.Background(), supi, nil)
+					if err != nil {
+						errs[i] = err
+						return
+					}
+					_ = res.Body.Close()
+					sqns[i], errs[i] = reserveSqn(cfg, supi, authSubs.SequenceNumber, res.Header.Get("ETag"), nil)
+				}()
+			}
+			wg.Wait()
+
+			issued := make(map[uint64]bool)
+			var highest uint64
+			for i := range requests {
+				if errs[i] != nil {
+					if !replicas {
+						t.Errorf("unexpected error: %v", errs[i])
+					}
+					continue
+				}
+				sqn := sqnFromBytes(sqns[i])
+				if issued[sqn] {
+					t.Errorf("SQN %x issued twice", sqn)
+				}
+				issued[sqn] = true
+				highest = max(highest, sqn)
+			}
+			stored, patches := udr.state()
+			if len(issued) != patches {
+				t.Errorf("expected %d updates of the UDR, got %d", len(issued), patches)
+			}
+			if !replicas && len(issued) != requests {
+				t.Errorf("expected %d SQNs, got %d", requests, len(issued))
+			}
+			if stored != formatSqn(highest) {
+				t.Errorf("expected the highest SQN %x to be stored, got %s", highest, stored)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/omec-project/openapi/Nudr_DataRepository"
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/logger"
+	stats "github.com/omec-project/udm/metrics"
+)
+
+// cachedSubscriberData looks the resource of supi up in the subscriber data cache
+func cachedSubscriberData(supi string, resource string, qualifier string) (interface{}, bool) {
+	cache := udm_context.UDM_Self().SubscriberDataCache
+	if cache == nil {
+		return nil, false
+	}
+	value, ok := cache.Get(supi, resource, qualifier)
+	if ok {
+		stats.IncrementUdmSubscriberDataCacheStats(resource, "HIT")
+	} else {
+		stats.IncrementUdmSubscriberDataCacheStats(resource, "MISS")
+	}
+	return value, ok
+}
+
+// cacheSubscriberData stores the resource of the UE in the subscriber data cache, subscribing to
+// the data change notifications of the UDR first so that the entry is invalidated on change
+func cacheSubscriberData(clientAPI *Nudr_DataRepository.APIClient, udmUe *udm_context.UdmUeContext,
+	resource string, qualifier string, value interface{},
+) {
+	cache := udm_context.UDM_Self().SubscriberDataCache
+	if cache == nil {
+		return
+	}
+	if !subscribeToUdrDataChange(clientAPI, udmUe) {
+		return
+	}
+	cache.Put(udmUe.Supi, resource, qualifier, value)
+}
+
+// udrDataChangeCallbackUri is the URI on which the UDM receives the data changes notified by the UDR
+func udrDataChangeCallbackUri() string {
+	return udm_context.UDM_Self().GetIPv4Uri() + "/sdm-subscriptions"
+}
+
+// subscribeToUdrDataChange subscribes the UDM to the changes of the subscription data of the UE in
+// the UDR (TS 29.504 5.2.2.8) and reports whether the subscription exists
+func subscribeToUdrDataChange(clientAPI *Nudr_DataRepository.APIClient, udmUe *udm_context.UdmUeContext) bool {
+	if udmUe.UdrSubscriptionExists() {
+		return true
+	}
+	unlock := udmUe.LockUdrSubscribe()
+	defer unlock()
+	if udmUe.UdrSubscriptionExists() {
+		return true
+	}
+
+	callbackReference := udrDataChangeCallbackUri()
+	subscriptionDataSubscriptions := models.SubscriptionDataSubscriptions{
+		UeId:                      udmUe.Supi,
+		CallbackReference:         callbackReference,
+		OriginalCallbackReference: callbackReference,
+		MonitoredResourceUri:      []string{"/nudr-dr/v1/subscription-data/" + udmUe.Supi},
+	}
+	subscriptionDataSubscriptionsResp, res, err := clientAPI.SubsToNofifyCollectionApi.
+		PostSubscriptionDataSubscriptions(context.Background(), subscriptionDataSubscriptions)
+	if res != nil {
+		defer func() {
+			if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+				logger.SdmLog.Errorf("PostSubscriptionDataSubscriptions response body cannot close: %+v", rspCloseErr)
+			}
+		}()
+	}
+	if err != nil {
+		logger.SdmLog.Warnf("subscription to UDR data changes of %s failed, data not cached: %+v", udmUe.Supi, err)
+		return false
+	}
+
+	location := res.Header.Get("Location")
+	subscriptionID := location[strings.LastIndex(location, "/")+1:]
+	udmUe.CreateUdrSubscription(subscriptionID, &subscriptionDataSubscriptionsResp)
+	return true
+}
+
+// RemoveUdrDataChangeSubscriptions removes from the UDR the data change subscriptions of a UE whose
+// context has been evicted
+func RemoveUdrDataChangeSubscriptions(udmUe *udm_context.UdmUeContext) {
+	subscriptionIDs := udmUe.UdrSubscriptionIDs()
+	udrUri := udmUe.UdrUri()
+	if len(subscriptionIDs) == 0 || udrUri == "" {
+		return
+	}
+	cfg := Nudr_DataRepository.NewConfiguration()
+	cfg.SetBasePath(udrUri)
+	clientAPI := Nudr_DataRepository.NewAPIClient(cfg)
+
+	for _, subscriptionID := range subscriptionIDs {
+		res, err := clientAPI.SubsToNotifyDocumentApi.RemovesubscriptionDataSubscriptions(
+			context.Background(), subscriptionID)
+		if res != nil {
+			if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+				logger.SdmLog.Errorf("RemovesubscriptionDataSubscriptions response body cannot close: %+v", rspCloseErr)
+			}
+		}
+		if err != nil {
+			logger.SdmLog.Warnf("removal of UDR subscription %s of %s failed: %+v", subscriptionID, udmUe.Supi, err)
+		}
+	}
+}
@@ -7,8 +7,11 @@ package producer
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/antihax/optional"
 	"github.com/omec-project/openapi"
@@ -48,6 +51,11 @@ func HandleGetAmDataRequest(request *httpwrapper.Request) *httpwrapper.Response
 func getAmDataProcedure(supi string, plmnID string, supportedFeatures string) (
 	response *models.AccessAndMobilitySubscriptionData, problemDetails *models.ProblemDetails,
 ) {
+	if cached, ok := cachedSubscriberData(supi, "am-data", plmnID); ok {
+		accessAndMobilitySubscriptionData := cached.(models.AccessAndMobilitySubscriptionData)
+		return &accessAndMobilitySubscriptionData, nil
+	}
+
 	var queryAmDataParamOpts Nudr.QueryAmDataParamOpts
 	queryAmDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
 
@@ -79,8 +87,16 @@ func getAmDataProcedure(supi string, plmnID string, supportedFeatures string) (
 	}()
 
 	if res.StatusCode == http.StatusOK {
-		udmUe := udm_context.UDM_Self().NewUdmUe(supi)
+		udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+		if !ok {
+			udmUe = udm_context.UDM_Self().NewUdmUe(supi)
+		}
 		udmUe.SetAMSubsriptionData(&accessAndMobilitySubscriptionDataResp)
+		if len(accessAndMobilitySubscriptionDataResp.Gpsis) != 0 {
+			udm_context.UDM_Self().SetUeGpsis(udmUe, accessAndMobilitySubscriptionDataResp.Gpsis)
+		}
+		setUeExternalGroups(udmUe, accessAndMobilitySubscriptionDataResp.InternalGroupIds)
+		cacheSubscriberData(clientAPI, udmUe, "am-data", plmnID, accessAndMobilitySubscriptionDataResp)
 		return &accessAndMobilitySubscriptionDataResp, nil
 	} else {
 		problemDetails = &models.ProblemDetails{
@@ -150,6 +166,9 @@ func getIdTranslationResultProcedure(gpsi string) (response *models.IdTranslatio
 			// GetCorrespondingSupi get corresponding Supi(here IMSI) matching the given Gpsi from the queried SUPI list from UDR
 			idTranslationResult.Supi = udm_context.GetCorrespondingSupi(idTranslationResultResp)
 			idTranslationResult.Gpsi = gpsi
+			if udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(idTranslationResult.Supi); ok {
+				udm_context.UDM_Self().AddUeGpsi(udmUe, gpsi)
+			}
 
 			return &idTranslationResult, nil
 		} else {
@@ -193,243 +212,284 @@ func HandleGetSupiRequest(request *httpwrapper.Request) *httpwrapper.Response {
 	return httpwrapper.NewResponse(http.StatusForbidden, nil, problemDetails)
 }
 
+// supportedDataSetNames lists the datasets that can be retrieved with GET /{supi} (TS 29.503 6.1.6.3.3)
+var supportedDataSetNames = map[models.DataSetName]bool{
+	models.DataSetName_AM:       true,
+	models.DataSetName_SMF_SEL:  true,
+	models.DataSetName_UEC_SMF:  true,
+	models.DataSetName_UEC_SMSF: true,
+	models.DataSetName_SMS_SUB:  true,
+	models.DataSetName_SM:       true,
+	models.DataSetName_TRACE:    true,
+	models.DataSetName_SMS_MNG:  true,
+}
+
+// getSupiProcedure TS 29.503 5.2.2.2.2 retrieves the requested datasets concurrently. Datasets that
+// are not available are left out of the response; the request only fails if none of them is available.
 func getSupiProcedure(supi string, plmnID string, dataSetNames []string, supportedFeatures string) (
 	response *models.SubscriptionDataSets, problemDetails *models.ProblemDetails,
 ) {
+	requestedDataSets, problemDetails := parseDataSetNames(dataSetNames)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+
 	clientAPI, err := createUDMClientToUDR(supi)
 	if err != nil {
 		return nil, util.ProblemDetailsSystemFailure(err.Error())
 	}
 
-	var subscriptionDataSets, subsDataSetBody models.SubscriptionDataSets
-	var ueContextInSmfDataResp models.UeContextInSmfData
-	pduSessionMap := make(map[string]models.PduSession)
-	var pgwInfoArray []models.PgwInfo
-
-	var queryAmDataParamOpts Nudr.QueryAmDataParamOpts
-	queryAmDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
-	var querySmfSelectDataParamOpts Nudr.QuerySmfSelectDataParamOpts
-	var queryTraceDataParamOpts Nudr.QueryTraceDataParamOpts
-	var querySmDataParamOpts Nudr.QuerySmDataParamOpts
+	udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		udmUe = udm_context.UDM_Self().NewUdmUe(supi)
+	}
 
-	queryAmDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
-	querySmfSelectDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
-	udm_context.UDM_Self().CreateSubsDataSetsForUe(supi, subsDataSetBody)
-
-	var body models.AccessAndMobilitySubscriptionData
-	udm_context.UDM_Self().CreateAccessMobilitySubsDataForUe(supi, body)
-	amData, res1, err1 := clientAPI.AccessAndMobilitySubscriptionDataDocumentApi.QueryAmData(
-		context.Background(), supi, plmnID, &queryAmDataParamOpts)
-	if err1 != nil {
-		if res1 == nil {
-			logger.SdmLog.Errorln(err1.Error())
-		} else if err1.Error() != res1.Status {
-			logger.SdmLog.Errorln(err1.Error())
-		} else {
-			problemDetails = &models.ProblemDetails{
-				Status: int32(res1.StatusCode),
-				Cause:  err1.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause,
-				Detail: err1.Error(),
-			}
+	var subscriptionDataSets models.SubscriptionDataSets
+	var dataSetsLock sync.Mutex
+	var wg sync.WaitGroup
+	failures := make([]*models.ProblemDetails, len(requestedDataSets))
+	for i, dataSetName := range requestedDataSets {
+		wg.Add(1)
+		go func(i int, dataSetName models.DataSetName) {
+			defer wg.Done()
+			failures[i] = fetchDataSet(clientAPI, udmUe, dataSetName, supi, plmnID, supportedFeatures,
+				&subscriptionDataSets, &dataSetsLock)
+		}(i, dataSetName)
+	}
+	wg.Wait()
 
-			return nil, problemDetails
+	var firstFailure *models.ProblemDetails
+	failed := 0
+	for i, failure := range failures {
+		if failure == nil {
+			continue
 		}
-	}
-	defer func() {
-		if rspCloseErr := res1.Body.Close(); rspCloseErr != nil {
-			logger.SdmLog.Errorf("QueryAmData response body cannot close: %+v", rspCloseErr)
+		failed++
+		logger.SdmLog.Warnf("dataset %s of %s not retrieved: %s", requestedDataSets[i], supi, failure.Cause)
+		if firstFailure == nil || (firstFailure.Status == http.StatusNotFound && failure.Status != http.StatusNotFound) {
+			firstFailure = failure
 		}
-	}()
-	if res1.StatusCode == http.StatusOK {
-		udmUe := udm_context.UDM_Self().NewUdmUe(supi)
-		udmUe.SetAMSubsriptionData(&amData)
-		subscriptionDataSets.AmData = &amData
-	} else {
-		problemDetails = &models.ProblemDetails{
-			Status: http.StatusNotFound,
-			Cause:  "DATA_NOT_FOUND",
+	}
+	if failed == len(requestedDataSets) {
+		if firstFailure.Status == http.StatusNotFound {
+			return nil, &models.ProblemDetails{
+				Status: http.StatusNotFound,
+				Cause:  "DATA_NOT_FOUND",
+			}
 		}
-
-		return nil, problemDetails
+		return nil, firstFailure
 	}
 
-	var smfSelSubsbody models.SmfSelectionSubscriptionData
-	udm_context.UDM_Self().CreateSmfSelectionSubsDataforUe(supi, smfSelSubsbody)
-	smfSelData, res2, err2 := clientAPI.SMFSelectionSubscriptionDataDocumentApi.QuerySmfSelectData(context.Background(),
-		supi, plmnID, &querySmfSelectDataParamOpts)
-	if err2 != nil {
-		if res2 == nil {
-			logger.SdmLog.Errorln(err2.Error())
-		} else if err2.Error() != res2.Status {
-			logger.SdmLog.Errorln(err2.Error())
-		} else {
-			problemDetails = &models.ProblemDetails{
-				Status: int32(res2.StatusCode),
-				Cause:  err2.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause,
-				Detail: err2.Error(),
+	udm_context.UDM_Self().CreateSubsDataSetsForUe(supi, subscriptionDataSets)
+	return &subscriptionDataSets, nil
+}
+
+// parseDataSetNames validates the dataset-names query parameter, which may be given either as
+// repeated values or as a comma-separated list, and returns the requested datasets without duplicates.
+func parseDataSetNames(dataSetNames []string) ([]models.DataSetName, *models.ProblemDetails) {
+	var requested []models.DataSetName
+	var invalidParams []models.InvalidParam
+	seen := make(map[models.DataSetName]bool)
+	for _, value := range dataSetNames {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			dataSetName := models.DataSetName(name)
+			if !supportedDataSetNames[dataSetName] {
+				invalidParams = append(invalidParams, models.InvalidParam{
+					Param:  "dataset-names",
+					Reason: fmt.Sprintf("unknown dataset name %s", name),
+				})
+				continue
 			}
+			if !seen[dataSetName] {
+				seen[dataSetName] = true
+				requested = append(requested, dataSetName)
+			}
+		}
+	}
 
-			return nil, problemDetails
+	if len(invalidParams) != 0 {
+		return nil, &models.ProblemDetails{
+			Status:        http.StatusBadRequest,
+			Cause:         "INVALID_QUERY_PARAM",
+			InvalidParams: invalidParams,
 		}
 	}
-	defer func() {
-		if rspCloseErr := res2.Body.Close(); rspCloseErr != nil {
-			logger.SdmLog.Errorf("QuerySmfSelectData response body cannot close: %+v", rspCloseErr)
+	if len(requested) < 2 {
+		return nil, &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "INVALID_QUERY_PARAM",
+			InvalidParams: []models.InvalidParam{
+				{
+					Param:  "dataset-names",
+					Reason: "at least two dataset names shall be provided",
+				},
+			},
+		}
+	}
+	return requested, nil
+}
+
+// fetchDataSet queries one dataset from the UDR, caches it in the UE context and stores it in
+// subscriptionDataSets under dataSetsLock.
+func fetchDataSet(clientAPI *Nudr.APIClient, udmUe *udm_context.UdmUeContext, dataSetName models.DataSetName,
+	supi string, plmnID string, supportedFeatures string, subscriptionDataSets *models.SubscriptionDataSets,
+	dataSetsLock *sync.Mutex,
+) *models.ProblemDetails {
+	switch dataSetName {
+	case models.DataSetName_AM:
+		var queryAmDataParamOpts Nudr.QueryAmDataParamOpts
+		queryAmDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+		amData, res, err := clientAPI.AccessAndMobilitySubscriptionDataDocumentApi.QueryAmData(
+			context.Background(), supi, plmnID, &queryAmDataParamOpts)
+		if problemDetails := udrQueryProblemDetails(err, res, "QueryAmData"); problemDetails != nil {
+			return problemDetails
+		}
+		udmUe.SetAMSubsriptionData(&amData)
+		if len(amData.Gpsis) != 0 {
+			udm_context.UDM_Self().SetUeGpsis(udmUe, amData.Gpsis)
+		}
+		setUeExternalGroups(udmUe, amData.InternalGroupIds)
+		dataSetsLock.Lock()
+		subscriptionDataSets.AmData = &amData
+		dataSetsLock.Unlock()
+	case models.DataSetName_SMF_SEL:
+		var querySmfSelectDataParamOpts Nudr.QuerySmfSelectDataParamOpts
+		querySmfSelectDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+		smfSelData, res, err := clientAPI.SMFSelectionSubscriptionDataDocumentApi.QuerySmfSelectData(
+			context.Background(), supi, plmnID, &querySmfSelectDataParamOpts)
+		if problemDetails := udrQueryProblemDetails(err, res, "QuerySmfSelectData"); problemDetails != nil {
+			return problemDetails
 		}
-	}()
-	if res2.StatusCode == http.StatusOK {
-		udmUe := udm_context.UDM_Self().NewUdmUe(supi)
 		udmUe.SetSmfSelectionSubsData(&smfSelData)
+		dataSetsLock.Lock()
 		subscriptionDataSets.SmfSelData = &smfSelData
-	} else {
-		problemDetails = &models.ProblemDetails{
-			Status: http.StatusNotFound,
-			Cause:  "DATA_NOT_FOUND",
+		dataSetsLock.Unlock()
+	case models.DataSetName_TRACE:
+		var queryTraceDataParamOpts Nudr.QueryTraceDataParamOpts
+		traceData, res, err := clientAPI.TraceDataDocumentApi.QueryTraceData(
+			context.Background(), supi, plmnID, &queryTraceDataParamOpts)
+		if problemDetails := udrQueryProblemDetails(err, res, "QueryTraceData"); problemDetails != nil {
+			return problemDetails
 		}
-
-		return nil, problemDetails
-	}
-
-	var TraceDatabody models.TraceData
-	udm_context.UDM_Self().CreateTraceDataforUe(supi, TraceDatabody)
-	traceData, res3, err3 := clientAPI.TraceDataDocumentApi.QueryTraceData(
-		context.Background(), supi, plmnID, &queryTraceDataParamOpts)
-	if err3 != nil {
-		if res3 == nil {
-			logger.SdmLog.Errorln(err3.Error())
-		} else if err3.Error() != res3.Status {
-			logger.SdmLog.Errorln(err3.Error())
-		} else {
-			problemDetails = &models.ProblemDetails{
-				Status: int32(res3.StatusCode),
-				Cause:  err3.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause,
-				Detail: err3.Error(),
+		// the UDR client does not report the failures of QueryTraceData as errors
+		if res.StatusCode != http.StatusOK {
+			return &models.ProblemDetails{
+				Status: int32(res.StatusCode),
+				Cause:  "DATA_NOT_FOUND",
 			}
 		}
-		return nil, problemDetails
-	}
-	defer func() {
-		if rspCloseErr := res3.Body.Close(); rspCloseErr != nil {
-			logger.SdmLog.Errorf("QueryTraceData response body cannot close: %+v", rspCloseErr)
-		}
-	}()
-	if res3.StatusCode == http.StatusOK {
-		udmUe := udm_context.UDM_Self().NewUdmUe(supi)
+		dataSetsLock.Lock()
 		udmUe.TraceData = &traceData
 		udmUe.TraceDataResponse.TraceData = &traceData
 		subscriptionDataSets.TraceData = &traceData
-	} else {
-		problemDetails = &models.ProblemDetails{
-			Status: http.StatusNotFound,
-			Cause:  "DATA_NOT_FOUND",
+		dataSetsLock.Unlock()
+	case models.DataSetName_SM:
+		var querySmDataParamOpts Nudr.QuerySmDataParamOpts
+		querySmDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+		smData, res, err := clientAPI.SessionManagementSubscriptionDataApi.QuerySmData(
+			context.Background(), supi, plmnID, &querySmDataParamOpts)
+		if problemDetails := udrQueryProblemDetails(err, res, "QuerySmData"); problemDetails != nil {
+			return problemDetails
 		}
-
-		return nil, problemDetails
-	}
-
-	sessionManagementSubscriptionData, res4, err4 := clientAPI.SessionManagementSubscriptionDataApi.
-		QuerySmData(context.Background(), supi, plmnID, &querySmDataParamOpts)
-	if err4 != nil {
-		if res4 == nil {
-			logger.SdmLog.Errorln(err4.Error())
-		} else if err4.Error() != res4.Status {
-			logger.SdmLog.Errorln(err4.Error())
-		} else {
-			problemDetails = &models.ProblemDetails{
-				Status: int32(res4.StatusCode),
-				Cause:  err4.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause,
-				Detail: err4.Error(),
-			}
-
-			return nil, problemDetails
+		smDataMap, _, _, _ := udm_context.UDM_Self().ManageSmData(smData, "", "")
+		udmUe.SetSMSubsData(smDataMap)
+		dataSetsLock.Lock()
+		subscriptionDataSets.SmData = smData
+		dataSetsLock.Unlock()
+	case models.DataSetName_UEC_SMF:
+		var querySmfRegListParamOpts Nudr.QuerySmfRegListParamOpts
+		querySmfRegListParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+		smfRegistrations, res, err := clientAPI.SMFRegistrationsCollectionApi.QuerySmfRegList(
+			context.Background(), supi, &querySmfRegListParamOpts)
+		if problemDetails := udrQueryProblemDetails(err, res, "QuerySmfRegList"); problemDetails != nil {
+			return problemDetails
 		}
-	}
-	defer func() {
-		if rspCloseErr := res4.Body.Close(); rspCloseErr != nil {
-			logger.SdmLog.Errorf("QuerySmData response body cannot close: %+v", rspCloseErr)
+		ueContextInSmfData := buildUeContextInSmfData(smfRegistrations)
+		dataSetsLock.Lock()
+		udmUe.UeCtxtInSmfData = ueContextInSmfData
+		subscriptionDataSets.UecSmfData = ueContextInSmfData
+		dataSetsLock.Unlock()
+	case models.DataSetName_UEC_SMSF:
+		ueContextInSmsfData, problemDetails := queryUeContextInSmsfData(clientAPI, supi, supportedFeatures)
+		if problemDetails != nil {
+			return problemDetails
 		}
-	}()
-	if res4.StatusCode == http.StatusOK {
-		udmUe := udm_context.UDM_Self().NewUdmUe(supi)
-		smData, _, _, _ := udm_context.UDM_Self().ManageSmData(sessionManagementSubscriptionData, "", "")
-		udmUe.SetSMSubsData(smData)
-		subscriptionDataSets.SmData = sessionManagementSubscriptionData
-	} else {
-		problemDetails = &models.ProblemDetails{
-			Status: http.StatusNotFound,
-			Cause:  "DATA_NOT_FOUND",
+		udmUe.SetUeCtxtInSmsfData(ueContextInSmsfData)
+		dataSetsLock.Lock()
+		subscriptionDataSets.UecSmsfData = ueContextInSmsfData
+		dataSetsLock.Unlock()
+	case models.DataSetName_SMS_SUB:
+		smsSubsData, problemDetails := querySmsData(clientAPI, supi, plmnID, supportedFeatures)
+		if problemDetails != nil {
+			return problemDetails
 		}
-
-		return nil, problemDetails
+		udmUe.SetSmsSubsData(smsSubsData)
+		dataSetsLock.Lock()
+		subscriptionDataSets.SmsSubsData = smsSubsData
+		dataSetsLock.Unlock()
+	case models.DataSetName_SMS_MNG:
+		smsMngData, problemDetails := querySmsMngData(clientAPI, supi, plmnID, supportedFeatures)
+		if problemDetails != nil {
+			return problemDetails
+		}
+		udmUe.SetSmsMngData(smsMngData)
+		dataSetsLock.Lock()
+		subscriptionDataSets.SmsMngData = smsMngData
+		dataSetsLock.Unlock()
 	}
+	return nil
+}
 
-	var UeContextInSmfbody models.UeContextInSmfData
-	var querySmfRegListParamOpts Nudr.QuerySmfRegListParamOpts
-	querySmfRegListParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
-	udm_context.UDM_Self().CreateUeContextInSmfDataforUe(supi, UeContextInSmfbody)
-	pdusess, res, err := clientAPI.SMFRegistrationsCollectionApi.QuerySmfRegList(
-		context.Background(), supi, &querySmfRegListParamOpts)
-	if err != nil {
-		if res == nil {
-			logger.SdmLog.Errorln(err.Error())
-		} else if err.Error() != res.Status {
-			logger.SdmLog.Errorln(err.Error())
-		} else {
-			problemDetails = &models.ProblemDetails{
-				Status: int32(res.StatusCode),
-				Cause:  err.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause,
-				Detail: err.Error(),
+// udrQueryProblemDetails closes the UDR response and maps a failed query to a ProblemDetails
+func udrQueryProblemDetails(err error, res *http.Response, operation string) *models.ProblemDetails {
+	if res != nil {
+		defer func() {
+			if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
+				logger.SdmLog.Errorf("%s response body cannot close: %+v", operation, rspCloseErr)
 			}
-
-			return nil, problemDetails
-		}
+		}()
 	}
-	defer func() {
-		if rspCloseErr := res.Body.Close(); rspCloseErr != nil {
-			logger.SdmLog.Errorf("QuerySmfRegList response body cannot close: %+v", rspCloseErr)
-		}
-	}()
-
-	for _, element := range pdusess {
-		var pduSession models.PduSession
-		pduSession.Dnn = element.Dnn
-		pduSession.SmfInstanceId = element.SmfInstanceId
-		pduSession.PlmnId = element.PlmnId
-		pduSessionMap[strconv.Itoa(int(element.PduSessionId))] = pduSession
+	if err == nil {
+		return nil
 	}
-	ueContextInSmfDataResp.PduSessions = pduSessionMap
-
-	for _, element := range pdusess {
-		var pgwInfo models.PgwInfo
-		pgwInfo.Dnn = element.Dnn
-		pgwInfo.PgwFqdn = element.PgwFqdn
-		pgwInfo.PlmnId = element.PlmnId
-		pgwInfoArray = append(pgwInfoArray, pgwInfo)
+	if res == nil {
+		logger.SdmLog.Warnln(err)
+		return util.ProblemDetailsSystemFailure(err.Error())
 	}
-	ueContextInSmfDataResp.PgwInfo = pgwInfoArray
-
-	if res.StatusCode == http.StatusOK {
-		udmUe := udm_context.UDM_Self().NewUdmUe(supi)
-		udmUe.UeCtxtInSmfData = &ueContextInSmfDataResp
-	} else {
-		var problemDetails models.ProblemDetails
-		problemDetails.Cause = "DATA_NOT_FOUND"
-		logger.SdmLog.Errorln(problemDetails.Cause)
+	problemDetails := &models.ProblemDetails{
+		Status: int32(res.StatusCode),
+		Cause:  "DATA_NOT_FOUND",
+		Detail: err.Error(),
 	}
-
-	if (res.StatusCode == http.StatusOK) && (res1.StatusCode == http.StatusOK) &&
-		(res2.StatusCode == http.StatusOK) && (res3.StatusCode == http.StatusOK) &&
-		(res4.StatusCode == http.StatusOK) {
-		subscriptionDataSets.UecSmfData = &ueContextInSmfDataResp
-		return &subscriptionDataSets, nil
-	} else {
-		problemDetails = &models.ProblemDetails{
-			Status: http.StatusNotFound,
-			Cause:  "DATA_NOT_FOUND",
+	if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+		if model, ok := apiErr.Model().(models.ProblemDetails); ok && model.Cause != "" {
+			problemDetails.Cause = model.Cause
 		}
+	}
+	return problemDetails
+}
 
-		return nil, problemDetails
+func buildUeContextInSmfData(smfRegistrations []models.SmfRegistration) *models.UeContextInSmfData {
+	var ueContextInSmfData models.UeContextInSmfData
+	pduSessionMap := make(map[string]models.PduSession)
+	for _, element := range smfRegistrations {
+		pduSessionMap[strconv.Itoa(int(element.PduSessionId))] = models.PduSession{
+			Dnn:           element.Dnn,
+			SmfInstanceId: element.SmfInstanceId,
+			PlmnId:        element.PlmnId,
+		}
+		ueContextInSmfData.PgwInfo = append(ueContextInSmfData.PgwInfo, models.PgwInfo{
+			Dnn:     element.Dnn,
+			PgwFqdn: element.PgwFqdn,
+			PlmnId:  element.PlmnId,
+		})
 	}
+	ueContextInSmfData.PduSessions = pduSessionMap
+	return &ueContextInSmfData
 }
 
 func HandleGetSharedDataRequest(request *httpwrapper.Request) *httpwrapper.Response {
@@ -530,13 +590,60 @@ func getSmDataProcedure(supi string, plmnID string, Dnn string, Snssai string, s
 ) {
 	logger.SdmLog.Infof("getSmDataProcedure: SUPI[%s] PLMNID[%s] DNN[%s] SNssai[%s]", supi, plmnID, Dnn, Snssai)
 
+	sessionManagementSubscriptionData, problemDetails := fetchSmData(supi, plmnID, Snssai)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+
+	udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		udmUe = udm_context.UDM_Self().NewUdmUe(supi)
+	}
+	smData, snssaikey, AllDnnConfigsbyDnn, AllDnns := udm_context.UDM_Self().ManageSmData(
+		sessionManagementSubscriptionData, Snssai, Dnn)
+	udmUe.SetSMSubsData(smData)
+
+	rspSMSubDataList := make([]models.SessionManagementSubscriptionData, 0, 4)
+
+	udmUe.SmSubsDataLock.RLock()
+	for _, eachSMSubData := range udmUe.SessionManagementSubsData {
+		rspSMSubDataList = append(rspSMSubDataList, eachSMSubData)
+	}
+	udmUe.SmSubsDataLock.RUnlock()
+
+	switch {
+	case Snssai == "" && Dnn == "":
+		return AllDnns, nil
+	case Snssai != "" && Dnn == "":
+		udmUe.SmSubsDataLock.RLock()
+		defer udmUe.SmSubsDataLock.RUnlock()
+		return udmUe.SessionManagementSubsData[snssaikey].DnnConfigurations, nil
+	case Snssai == "" && Dnn != "":
+		return AllDnnConfigsbyDnn, nil
+	case Snssai != "" && Dnn != "":
+		return rspSMSubDataList, nil
+	default:
+		udmUe.SmSubsDataLock.RLock()
+		defer udmUe.SmSubsDataLock.RUnlock()
+		return udmUe.SessionManagementSubsData, nil
+	}
+}
+
+// fetchSmData returns the session management subscription data of supi from the cache or the UDR
+func fetchSmData(supi string, plmnID string, snssai string) (
+	[]models.SessionManagementSubscriptionData, *models.ProblemDetails,
+) {
+	if cached, ok := cachedSubscriberData(supi, "sm-data", plmnID+"/"+snssai); ok {
+		return cached.([]models.SessionManagementSubscriptionData), nil
+	}
+
 	clientAPI, err := createUDMClientToUDR(supi)
 	if err != nil {
 		return nil, util.ProblemDetailsSystemFailure(err.Error())
 	}
 
 	var querySmDataParamOpts Nudr.QuerySmDataParamOpts
-	querySmDataParamOpts.SingleNssai = optional.NewInterface(Snssai)
+	querySmDataParamOpts.SingleNssai = optional.NewInterface(snssai)
 
 	sessionManagementSubscriptionDataResp, res, err := clientAPI.SessionManagementSubscriptionDataApi.
 		QuerySmData(context.Background(), supi, plmnID, &querySmDataParamOpts)
@@ -547,7 +654,7 @@ func getSmDataProcedure(supi string, plmnID string, Dnn string, Snssai string, s
 			logger.SdmLog.Warnln(err)
 		} else {
 			logger.SdmLog.Warnln(err)
-			problemDetails = &models.ProblemDetails{
+			problemDetails := &models.ProblemDetails{
 				Status: int32(res.StatusCode),
 				Cause:  err.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause,
 				Detail: err.Error(),
@@ -562,44 +669,20 @@ func getSmDataProcedure(supi string, plmnID string, Dnn string, Snssai string, s
 		}
 	}()
 
-	if res.StatusCode == http.StatusOK {
-		udmUe := udm_context.UDM_Self().NewUdmUe(supi)
-		smData, snssaikey, AllDnnConfigsbyDnn, AllDnns := udm_context.UDM_Self().ManageSmData(
-			sessionManagementSubscriptionDataResp, Snssai, Dnn)
-		udmUe.SetSMSubsData(smData)
-
-		rspSMSubDataList := make([]models.SessionManagementSubscriptionData, 0, 4)
-
-		udmUe.SmSubsDataLock.RLock()
-		for _, eachSMSubData := range udmUe.SessionManagementSubsData {
-			rspSMSubDataList = append(rspSMSubDataList, eachSMSubData)
-		}
-		udmUe.SmSubsDataLock.RUnlock()
-
-		switch {
-		case Snssai == "" && Dnn == "":
-			return AllDnns, nil
-		case Snssai != "" && Dnn == "":
-			udmUe.SmSubsDataLock.RLock()
-			defer udmUe.SmSubsDataLock.RUnlock()
-			return udmUe.SessionManagementSubsData[snssaikey].DnnConfigurations, nil
-		case Snssai == "" && Dnn != "":
-			return AllDnnConfigsbyDnn, nil
-		case Snssai != "" && Dnn != "":
-			return rspSMSubDataList, nil
-		default:
-			udmUe.SmSubsDataLock.RLock()
-			defer udmUe.SmSubsDataLock.RUnlock()
-			return udmUe.SessionManagementSubsData, nil
-		}
-	} else {
-		problemDetails = &models.ProblemDetails{
+	if res.StatusCode != http.StatusOK {
+		problemDetails := &models.ProblemDetails{
 			Status: http.StatusNotFound,
 			Cause:  "DATA_NOT_FOUND",
 		}
-
 		return nil, problemDetails
 	}
+
+	udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		udmUe = udm_context.UDM_Self().NewUdmUe(supi)
+	}
+	cacheSubscriberData(clientAPI, udmUe, "sm-data", plmnID+"/"+snssai, sessionManagementSubscriptionDataResp)
+	return sessionManagementSubscriptionDataResp, nil
 }
 
 func HandleGetNssaiRequest(request *httpwrapper.Request) *httpwrapper.Response {
@@ -699,6 +782,11 @@ func HandleGetSmfSelectDataRequest(request *httpwrapper.Request) *httpwrapper.Re
 func getSmfSelectDataProcedure(supi string, plmnID string, supportedFeatures string) (
 	response *models.SmfSelectionSubscriptionData, problemDetails *models.ProblemDetails,
 ) {
+	if cached, ok := cachedSubscriberData(supi, "smf-select-data", plmnID); ok {
+		smfSelectionSubscriptionData := cached.(models.SmfSelectionSubscriptionData)
+		return &smfSelectionSubscriptionData, nil
+	}
+
 	var querySmfSelectDataParamOpts Nudr.QuerySmfSelectDataParamOpts
 	querySmfSelectDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
 	var body models.SmfSelectionSubscriptionData
@@ -735,9 +823,13 @@ func getSmfSelectDataProcedure(supi string, plmnID string, supportedFeatures str
 	}()
 
 	if res.StatusCode == http.StatusOK {
-		udmUe := udm_context.UDM_Self().NewUdmUe(supi)
+		udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+		if !ok {
+			udmUe = udm_context.UDM_Self().NewUdmUe(supi)
+		}
 		udmUe.SetSmfSelectionSubsData(&smfSelectionSubscriptionDataResp)
-		return udmUe.SmfSelSubsData, nil
+		cacheSubscriberData(clientAPI, udmUe, "smf-select-data", plmnID, smfSelectionSubscriptionDataResp)
+		return &smfSelectionSubscriptionDataResp, nil
 	} else {
 		problemDetails = &models.ProblemDetails{
 			Status: http.StatusNotFound,
@@ -842,8 +934,12 @@ func subscribeProcedure(sdmSubscription *models.SdmSubscription, supi string) (
 		return nil, nil, util.ProblemDetailsSystemFailure(err.Error())
 	}
 
+	// the UDR keeps the subscription with the callback of the UDM, which relays the data changes to the
+	// callback of the NF kept in the UE context
+	udrSdmSubscription := *sdmSubscription
+	udrSdmSubscription.CallbackReference = udrDataChangeCallbackUri()
 	sdmSubscriptionResp, res, err := clientAPI.SDMSubscriptionsCollectionApi.CreateSdmSubscriptions(
-		context.Background(), supi, *sdmSubscription)
+		context.Background(), supi, udrSdmSubscription)
 	if err != nil {
 		if res == nil {
 			logger.SdmLog.Warnln(err)
@@ -872,7 +968,10 @@ func subscribeProcedure(sdmSubscription *models.SdmSubscription, supi string) (
 		if udmUe == nil {
 			udmUe = udm_context.UDM_Self().NewUdmUe(supi)
 		}
+		sdmSubscriptionResp.CallbackReference = sdmSubscription.CallbackReference
 		udmUe.CreateSubscriptiontoNotifChange(sdmSubscriptionResp.SubscriptionId, &sdmSubscriptionResp)
+		// the data changes reach the UDM through its subscription to the UDR
+		subscribeToUdrDataChange(clientAPI, udmUe)
 		header.Set("Location", udmUe.GetLocationURI2(udm_context.LocationUriSdmSubscription, supi))
 		return header, &sdmSubscriptionResp, nil
 	case http.StatusNotFound:
@@ -982,6 +1081,9 @@ func unsubscribeProcedure(supi string, subscriptionID string) *models.ProblemDet
 	}()
 
 	if res.StatusCode == http.StatusNoContent {
+		if udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi); ok {
+			udmUe.DeleteSubscriptiontoNotifChange(subscriptionID)
+		}
 		return nil
 	} else {
 		problemDetails := &models.ProblemDetails{
@@ -1295,3 +1397,203 @@ func getUeContextInSmfDataProcedure(supi string, supportedFeatures string) (
 		return nil, problemDetails
 	}
 }
+
+func HandleGetSmsDataRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.SdmLog.Infoln("handle GetSmsData")
+	supi := request.Params["supi"]
+	plmnID := request.Query.Get("plmn-id")
+	supportedFeatures := request.Query.Get("supported-features")
+	response, problemDetails := getSmsDataProcedure(supi, plmnID, supportedFeatures)
+	if response != nil {
+		stats.IncrementUdmSubscriberDataManagementStats("get", "sms-data", "SUCCESS")
+		// status code is based on SPEC, and option headers
+		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+	} else if problemDetails != nil {
+		stats.IncrementUdmSubscriberDataManagementStats("get", "sms-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	problemDetails = &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  "UNSPECIFIED",
+	}
+	stats.IncrementUdmSubscriberDataManagementStats("get", "sms-data", "FAILURE")
+	return httpwrapper.NewResponse(http.StatusForbidden, nil, problemDetails)
+}
+
+func getSmsDataProcedure(supi string, plmnID string, supportedFeatures string) (
+	response *models.SmsSubscriptionData, problemDetails *models.ProblemDetails,
+) {
+	clientAPI, err := createUDMClientToUDR(supi)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	smsSubsData, problemDetails := querySmsData(clientAPI, supi, plmnID, supportedFeatures)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+
+	udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		udmUe = udm_context.UDM_Self().NewUdmUe(supi)
+	}
+	udmUe.SetSmsSubsData(smsSubsData)
+	return smsSubsData, nil
+}
+
+func querySmsData(clientAPI *Nudr.APIClient, supi string, plmnID string, supportedFeatures string) (
+	*models.SmsSubscriptionData, *models.ProblemDetails,
+) {
+	var querySmsDataParamOpts Nudr.QuerySmsDataParamOpts
+	querySmsDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+
+	smsSubscriptionDataResp, res, err := clientAPI.SMSSubscriptionDataDocumentApi.QuerySmsData(
+		context.Background(), supi, plmnID, &querySmsDataParamOpts)
+	if problemDetails := udrQueryProblemDetails(err, res, "QuerySmsData"); problemDetails != nil {
+		return nil, problemDetails
+	}
+	return &smsSubscriptionDataResp, nil
+}
+
+func HandleGetSmsMngDataRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.SdmLog.Infoln("handle GetSmsMngData")
+	supi := request.Params["supi"]
+	plmnID := request.Query.Get("plmn-id")
+	supportedFeatures := request.Query.Get("supported-features")
+	response, problemDetails := getSmsMngDataProcedure(supi, plmnID, supportedFeatures)
+	if response != nil {
+		stats.IncrementUdmSubscriberDataManagementStats("get", "sms-mng-data", "SUCCESS")
+		// status code is based on SPEC, and option headers
+		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+	} else if problemDetails != nil {
+		stats.IncrementUdmSubscriberDataManagementStats("get", "sms-mng-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	problemDetails = &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  "UNSPECIFIED",
+	}
+	stats.IncrementUdmSubscriberDataManagementStats("get", "sms-mng-data", "FAILURE")
+	return httpwrapper.NewResponse(http.StatusForbidden, nil, problemDetails)
+}
+
+func getSmsMngDataProcedure(supi string, plmnID string, supportedFeatures string) (
+	response *models.SmsManagementSubscriptionData, problemDetails *models.ProblemDetails,
+) {
+	clientAPI, err := createUDMClientToUDR(supi)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	smsMngData, problemDetails := querySmsMngData(clientAPI, supi, plmnID, supportedFeatures)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+
+	udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		udmUe = udm_context.UDM_Self().NewUdmUe(supi)
+	}
+	udmUe.SetSmsMngData(smsMngData)
+	return smsMngData, nil
+}
+
+func querySmsMngData(clientAPI *Nudr.APIClient, supi string, plmnID string, supportedFeatures string) (
+	*models.SmsManagementSubscriptionData, *models.ProblemDetails,
+) {
+	var querySmsMngDataParamOpts Nudr.QuerySmsMngDataParamOpts
+	querySmsMngDataParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+
+	smsMngDataResp, res, err := clientAPI.SMSManagementSubscriptionDataDocumentApi.QuerySmsMngData(
+		context.Background(), supi, plmnID, &querySmsMngDataParamOpts)
+	if problemDetails := udrQueryProblemDetails(err, res, "QuerySmsMngData"); problemDetails != nil {
+		return nil, problemDetails
+	}
+	return &smsMngDataResp, nil
+}
+
+func HandleGetUeContextInSmsfDataRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.SdmLog.Infoln("handle GetUeContextInSmsfData")
+	supi := request.Params["supi"]
+	supportedFeatures := request.Query.Get("supported-features")
+	response, problemDetails := getUeContextInSmsfDataProcedure(supi, supportedFeatures)
+	if response != nil {
+		stats.IncrementUdmSubscriberDataManagementStats("get", "ue-context-in-smsf-data", "SUCCESS")
+		// status code is based on SPEC, and option headers
+		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+	} else if problemDetails != nil {
+		stats.IncrementUdmSubscriberDataManagementStats("get", "ue-context-in-smsf-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	problemDetails = &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  "UNSPECIFIED",
+	}
+	stats.IncrementUdmSubscriberDataManagementStats("get", "ue-context-in-smsf-data", "FAILURE")
+	return httpwrapper.NewResponse(http.StatusForbidden, nil, problemDetails)
+}
+
+func getUeContextInSmsfDataProcedure(supi string, supportedFeatures string) (
+	response *models.UeContextInSmsfData, problemDetails *models.ProblemDetails,
+) {
+	clientAPI, err := createUDMClientToUDR(supi)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	ueContextInSmsfData, problemDetails := queryUeContextInSmsfData(clientAPI, supi, supportedFeatures)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+
+	udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		udmUe = udm_context.UDM_Self().NewUdmUe(supi)
+	}
+	udmUe.SetUeCtxtInSmsfData(ueContextInSmsfData)
+	return ueContextInSmsfData, nil
+}
+
+// queryUeContextInSmsfData builds the UeContextInSmsfData from the SMSF registrations stored
+// in the UDR for both accesses; a missing registration on one access is not an error.
+func queryUeContextInSmsfData(clientAPI *Nudr.APIClient, supi string, supportedFeatures string) (
+	*models.UeContextInSmsfData, *models.ProblemDetails,
+) {
+	var ueContextInSmsfData models.UeContextInSmsfData
+
+	var querySmsfContext3gppParamOpts Nudr.QuerySmsfContext3gppParamOpts
+	querySmsfContext3gppParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+	smsf3gpp, res, err := clientAPI.SMSF3GPPRegistrationDocumentApi.QuerySmsfContext3gpp(
+		context.Background(), supi, &querySmsfContext3gppParamOpts)
+	if problemDetails := udrQueryProblemDetails(err, res, "QuerySmsfContext3gpp"); problemDetails != nil {
+		if problemDetails.Status != http.StatusNotFound {
+			return nil, problemDetails
+		}
+	} else {
+		ueContextInSmsfData.SmsfInfo3GppAccess = smsfRegistrationToSmsfInfo(smsf3gpp)
+	}
+
+	var querySmsfContextNon3gppParamOpts Nudr.QuerySmsfContextNon3gppParamOpts
+	querySmsfContextNon3gppParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+	smsfNon3gpp, res, err := clientAPI.SMSFNon3GPPRegistrationDocumentApi.QuerySmsfContextNon3gpp(
+		context.Background(), supi, &querySmsfContextNon3gppParamOpts)
+	if problemDetails := udrQueryProblemDetails(err, res, "QuerySmsfContextNon3gpp"); problemDetails != nil {
+		if problemDetails.Status != http.StatusNotFound {
+			return nil, problemDetails
+		}
+	} else {
+		ueContextInSmsfData.SmsfInfoNon3GppAccess = smsfRegistrationToSmsfInfo(smsfNon3gpp)
+	}
+
+	return &ueContextInSmsfData, nil
+}
+
+func smsfRegistrationToSmsfInfo(smsfRegistration models.SmsfRegistration) *models.SmsfInfo {
+	if smsfRegistration.SmsfInstanceId == "" {
+		return nil
+	}
+	return &models.SmsfInfo{
+		SmsfInstanceId: smsfRegistration.SmsfInstanceId,
+		PlmnId:         smsfRegistration.PlmnId,
+	}
+}
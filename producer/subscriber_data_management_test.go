@@ -0,0 +1,342 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/util/httpwrapper"
+)
+
+func TestParseDataSetNames(t *testing.T) {
+	testCases := []struct {
+		name          string
+		dataSetNames  []string
+		expected      []models.DataSetName
+		expectedError bool
+	}{
+		{
+			name:         "repeated values",
+			dataSetNames: []string{"AM", "SMF_SEL"},
+			expected:     []models.DataSetName{models.DataSetName_AM, models.DataSetName_SMF_SEL},
+		},
+		{
+			name:         "comma separated values with duplicates",
+			dataSetNames: []string{"AM,SM, AM", "UEC_SMSF"},
+			expected: []models.DataSetName{
+				models.DataSetName_AM, models.DataSetName_SM, models.DataSetName_UEC_SMSF,
+			},
+		},
+		{
+			name:          "unknown dataset name",
+			dataSetNames:  []string{"AM,FOO"},
+			expectedError: true,
+		},
+		{
+			name:          "single dataset name",
+			dataSetNames:  []string{"AM"},
+			expectedError: true,
+		},
+		{
+			name:          "no dataset name",
+			dataSetNames:  nil,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requested, problemDetails := parseDataSetNames(tc.dataSetNames)
+			if tc.expectedError {
+				if problemDetails == nil {
+					t.Fatalf("expected problem details, got %v", requested)
+				}
+				if problemDetails.Status != http.StatusBadRequest || problemDetails.Cause != "INVALID_QUERY_PARAM" {
+					t.Errorf("unexpected problem details: %+v", problemDetails)
+				}
+				if len(problemDetails.InvalidParams) == 0 || problemDetails.InvalidParams[0].Param != "dataset-names" {
+					t.Errorf("expected invalid param dataset-names, got %+v", problemDetails.InvalidParams)
+				}
+				return
+			}
+			if problemDetails != nil {
+				t.Fatalf("unexpected problem details: %+v", problemDetails)
+			}
+			if !reflect.DeepEqual(requested, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, requested)
+			}
+		})
+	}
+}
+
+func TestSubscribeProcedure_RelaysUdrDataChanges(t *testing.T) {
+	const supi = "imsi-208930000000201"
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+
+	notifications := make(chan models.ModificationNotification, 4)
+	nf := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var modificationNotification models.ModificationNotification
+		if err := json.NewDecoder(r.Body).Decode(&modificationNotification); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		notifications <- modificationNotification
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	nf.EnableHTTP2 = true
+	nf.StartTLS()
+	defer nf.Close()
+
+	amDataUri := "/nudm-sdm/v1/" + supi + "/am-data"
+	header, response, problemDetails := subscribeProcedure(&models.SdmSubscription{
+		NfInstanceId:          "b5a3d2e1-8c4f-4e6a-9d7b-1f2e3c4d5a6b",
+		CallbackReference:     nf.URL + "/sdm-notify",
+		MonitoredResourceUris: []string{amDataUri},
+	}, supi)
+	if problemDetails != nil || header.Get("Location") == "" {
+		t.Fatalf("subscribeProcedure failed: %+v", problemDetails)
+	}
+	if response.CallbackReference != nf.URL+"/sdm-notify" {
+		t.Errorf("expected the callback of the NF in the response, got %s", response.CallbackReference)
+	}
+
+	var udrSdmSubscription models.SdmSubscription
+	if !udr.get("/subscription-data/"+supi+"/context-data/sdm-subscriptions/"+response.SubscriptionId,
+		&udrSdmSubscription) {
+		t.Fatal("expected the subscription to be stored in the UDR")
+	}
+	if udrSdmSubscription.CallbackReference != udrDataChangeCallbackUri() {
+		t.Errorf("expected the callback of the UDM in the UDR, got %s", udrSdmSubscription.CallbackReference)
+	}
+	if len(udr.received(http.MethodPost, "/subscription-data/subs-to-notify")) != 1 {
+		t.Error("expected the UDM to subscribe to the data changes of the UDR")
+	}
+
+	// the data change notified by the UDR to the UDM
+	request := httpwrapper.NewRequest(httptest.NewRequest(http.MethodPost, "/sdm-subscriptions", nil),
+		models.DataChangeNotify{
+			UeId: supi,
+			NotifyItems: []models.NotifyItem{
+				{ResourceId: "/nudr-dr/v1/subscription-data/" + supi + "/20893/provisioned-data/am-data"},
+			},
+		})
+	if rsp := HandleDataChangeNotificationToNFRequest(request); rsp.Status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rsp.Status)
+	}
+
+	select {
+	case modificationNotification := <-notifications:
+		if len(modificationNotification.NotifyItems) != 1 ||
+			modificationNotification.NotifyItems[0].ResourceId != amDataUri {
+			t.Errorf("unexpected notification %+v", modificationNotification)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the data change to be relayed to the NF")
+	}
+	select {
+	case modificationNotification := <-notifications:
+		t.Errorf("expected a single notification, got %+v", modificationNotification)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestGetSmsDataProcedures(t *testing.T) {
+	const supi = "imsi-208930000000203"
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+	smsData := models.SmsSubscriptionData{SmsSubscribed: true}
+	smsMngData := models.SmsManagementSubscriptionData{MtSmsSubscribed: true, MoSmsBarringRoaming: true}
+	udr.set("/subscription-data/"+supi+"/20893/provisioned-data/sms-data", smsData)
+	udr.set("/subscription-data/"+supi+"/20893/provisioned-data/sms-mng-data", smsMngData)
+	udr.set("/subscription-data/"+supi+"/context-data/smsf-3gpp-access",
+		models.SmsfRegistration{SmsfInstanceId: "smsf-1", PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"}})
+
+	smsSubsData, problemDetails := getSmsDataProcedure(supi, "20893", "1f")
+	if problemDetails != nil || !reflect.DeepEqual(*smsSubsData, smsData) {
+		t.Fatalf("unexpected SMS subscription data %+v, %+v", smsSubsData, problemDetails)
+	}
+	smsManagementData, problemDetails := getSmsMngDataProcedure(supi, "20893", "1f")
+	if problemDetails != nil || !reflect.DeepEqual(*smsManagementData, smsMngData) {
+		t.Fatalf("unexpected SMS management data %+v, %+v", smsManagementData, problemDetails)
+	}
+	ueContextInSmsfData, problemDetails := getUeContextInSmsfDataProcedure(supi, "1f")
+	if problemDetails != nil {
+		t.Fatalf("unexpected problem details: %+v", problemDetails)
+	}
+	if ueContextInSmsfData.SmsfInfo3GppAccess == nil ||
+		ueContextInSmsfData.SmsfInfo3GppAccess.SmsfInstanceId != "smsf-1" ||
+		ueContextInSmsfData.SmsfInfoNon3GppAccess != nil {
+		t.Fatalf("unexpected UE context in SMSF data %+v", ueContextInSmsfData)
+	}
+
+	for _, path := range []string{
+		"/subscription-data/" + supi + "/20893/provisioned-data/sms-data",
+		"/subscription-data/" + supi + "/20893/provisioned-data/sms-mng-data",
+		"/subscription-data/" + supi + "/context-data/smsf-3gpp-access",
+		"/subscription-data/" + supi + "/context-data/smsf-non-3gpp-access",
+	} {
+		requests := udr.received(http.MethodGet, path)
+		if len(requests) != 1 {
+			t.Fatalf("expected one query of %s, got %d", path, len(requests))
+		}
+		if features := requests[0].query["supported-features"]; !reflect.DeepEqual(features, []string{"1f"}) {
+			t.Errorf("expected the supported features to be forwarded to %s, got %v", path, features)
+		}
+	}
+
+	udmUe, ok := udm_context.UDM_Self().UdmUeFindBySupi(supi)
+	if !ok {
+		t.Fatal("expected the UE context to be created")
+	}
+	if !reflect.DeepEqual(udmUe.SmsSubsData, smsSubsData) || !reflect.DeepEqual(udmUe.SmsMngData, smsManagementData) ||
+		!reflect.DeepEqual(udmUe.UeCtxtInSmsfData, ueContextInSmsfData) {
+		t.Errorf("expected the SMS data to be cached in the UE context, got %+v, %+v, %+v",
+			udmUe.SmsSubsData, udmUe.SmsMngData, udmUe.UeCtxtInSmsfData)
+	}
+
+	if _, problemDetails = getSmsDataProcedure(supi, "00101", ""); problemDetails == nil ||
+		problemDetails.Status != http.StatusNotFound {
+		t.Errorf("expected the SMS subscription data of another PLMN to be not found, got %+v", problemDetails)
+	}
+}
+
+func TestGetSupiProcedure_SmsDataSets(t *testing.T) {
+	const supi = "imsi-208930000000204"
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+	smsData := models.SmsSubscriptionData{SmsSubscribed: true}
+	smsMngData := models.SmsManagementSubscriptionData{MoSmsSubscribed: true}
+	udr.set("/subscription-data/"+supi+"/20893/provisioned-data/sms-data", smsData)
+	udr.set("/subscription-data/"+supi+"/20893/provisioned-data/sms-mng-data", smsMngData)
+	udmUe := udm_context.UDM_Self().NewUdmUe(supi)
+
+	response, problemDetails := getSupiProcedure(supi, "20893",
+		[]string{string(models.DataSetName_SMS_SUB), string(models.DataSetName_SMS_MNG)}, "1f")
+	if problemDetails != nil {
+		t.Fatalf("unexpected problem details: %+v", problemDetails)
+	}
+	if response.SmsSubsData == nil || !reflect.DeepEqual(*response.SmsSubsData, smsData) ||
+		response.SmsMngData == nil || !reflect.DeepEqual(*response.SmsMngData, smsMngData) {
+		t.Fatalf("unexpected datasets %+v", response)
+	}
+	if !reflect.DeepEqual(udmUe.SmsSubsData, response.SmsSubsData) ||
+		!reflect.DeepEqual(udmUe.SmsMngData, response.SmsMngData) {
+		t.Errorf("expected the SMS datasets to be cached in the UE context")
+	}
+	for _, path := range []string{
+		"/subscription-data/" + supi + "/20893/provisioned-data/sms-data",
+		"/subscription-data/" + supi + "/20893/provisioned-data/sms-mng-data",
+	} {
+		requests := udr.received(http.MethodGet, path)
+		if len(requests) != 1 || !reflect.DeepEqual(requests[0].query["supported-features"], []string{"1f"}) {
+			t.Errorf("expected one query of %s with the supported features, got %+v", path, requests)
+		}
+	}
+}
+
+func TestGetSupiProcedure_PartialConcurrentResult(t *testing.T) {
+	const supi = "imsi-208930000000205"
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+	smsDataPath := "/subscription-data/" + supi + "/20893/provisioned-data/sms-data"
+	smsMngDataPath := "/subscription-data/" + supi + "/20893/provisioned-data/sms-mng-data"
+	traceDataPath := "/subscription-data/" + supi + "/20893/provisioned-data/trace-data"
+
+	// each dataset is only returned once all of them are being fetched
+	var arrivals sync.WaitGroup
+	arrivals.Add(3)
+	allArrived := make(chan struct{})
+	go func() {
+		arrivals.Wait()
+		close(allArrived)
+	}()
+	barrier := func(status int, body interface{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			arrivals.Done()
+			select {
+			case <-allArrived:
+			case <-time.After(2 * time.Second):
+				writeUdrProblem(w, http.StatusServiceUnavailable, "NOT_CONCURRENT")
+				return
+			}
+			if status != http.StatusOK {
+				writeUdrProblem(w, status, "DATA_NOT_FOUND")
+				return
+			}
+			raw, _ := json.Marshal(body)
+			writeUdrJSON(w, status, raw)
+		}
+	}
+	smsData := models.SmsSubscriptionData{SmsSubscribed: true}
+	smsMngData := models.SmsManagementSubscriptionData{MtSmsSubscribed: true}
+	udr.handle(http.MethodGet, smsDataPath, barrier(http.StatusOK, smsData))
+	udr.handle(http.MethodGet, smsMngDataPath, barrier(http.StatusOK, smsMngData))
+	udr.handle(http.MethodGet, traceDataPath, barrier(http.StatusNotFound, nil))
+
+	// the UE is not known to the UDM yet
+	response, problemDetails := getSupiProcedure(supi, "20893", []string{"SMS_SUB,SMS_MNG", "TRACE"}, "")
+	if problemDetails != nil {
+		t.Fatalf("unexpected problem details: %+v", problemDetails)
+	}
+	if response.SmsSubsData == nil || !reflect.DeepEqual(*response.SmsSubsData, smsData) ||
+		response.SmsMngData == nil || !reflect.DeepEqual(*response.SmsMngData, smsMngData) {
+		t.Fatalf("expected the available datasets, got %+v", response)
+	}
+	if response.TraceData != nil || response.AmData != nil || response.SmfSelData != nil ||
+		response.SmData != nil || response.UecSmfData != nil || response.UecSmsfData != nil {
+		t.Errorf("expected only the available requested datasets, got %+v", response)
+	}
+
+	udr.lock.Lock()
+	defer udr.lock.Unlock()
+	for _, request := range udr.requests {
+		if request.path != smsDataPath && request.path != smsMngDataPath && request.path != traceDataPath {
+			t.Errorf("unexpected query of %s %s", request.method, request.path)
+		}
+	}
+}
+
+func TestSubscribeToUdrDataChange_Concurrent(t *testing.T) {
+	const supi = "imsi-208930000000206"
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+	udmUe := udm_context.UDM_Self().NewUdmUe(supi)
+	t.Cleanup(func() {
+		udmUe.UdmSubsToNotify = make(map[string]*models.SubscriptionDataSubscriptions)
+	})
+	clientAPI, err := createUDMClientToUDR(supi)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var subscriptions sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		subscriptions.Add(1)
+		go func() {
+			defer subscriptions.Done()
+			if !subscribeToUdrDataChange(clientAPI, udmUe) {
+				t.Error("expected the UE to be subscribed to the UDR data changes")
+			}
+		}()
+	}
+	subscriptions.Wait()
+
+	if requests := udr.received(http.MethodPost, "/subscription-data/subs-to-notify"); len(requests) != 1 {
+		t.Errorf("expected a single subscription to the UDR data changes, got %d", len(requests))
+	}
+	if subscriptionIDs := udmUe.UdrSubscriptionIDs(); len(subscriptionIDs) != 1 {
+		t.Errorf("expected a single subscription in the UE context, got %v", subscriptionIDs)
+	}
+}
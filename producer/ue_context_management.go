@@ -25,7 +25,19 @@ import (
 	"github.com/omec-project/util/httpwrapper"
 )
 
+// sendOnDeregistrationNotification is overridden in tests
+var sendOnDeregistrationNotification = callback.SendOnDeregistrationNotification
+
 func createUDMClientToUDR(id string) (*Nudr_DataRepository.APIClient, error) {
+	cfg, err := createUDRConfiguration(id)
+	if err != nil {
+		return nil, err
+	}
+	clientAPI := Nudr_DataRepository.NewAPIClient(cfg)
+	return clientAPI, nil
+}
+
+func createUDRConfiguration(id string) (*Nudr_DataRepository.Configuration, error) {
 	uri := getUdrURI(id)
 	if uri == "" {
 		logger.Handlelog.Errorf("ID[%s] does not match any UDR", id)
@@ -33,37 +45,26 @@ func createUDMClientToUDR(id string) (*Nudr_DataRepository.APIClient, error) {
 	}
 	cfg := Nudr_DataRepository.NewConfiguration()
 	cfg.SetBasePath(uri)
-	clientAPI := Nudr_DataRepository.NewAPIClient(cfg)
-	return clientAPI, nil
+	return cfg, nil
 }
 
 func getUdrURI(id string) string {
 	if strings.Contains(id, "imsi") || strings.Contains(id, "nai") { // supi
 		ue, ok := udmContext.UDM_Self().UdmUeFindBySupi(id)
-		if ok {
-			ue.UdrUri = consumer.SendNFInstancesUDR(id, consumer.NFDiscoveryToUDRParamSupi)
-			return ue.UdrUri
-		} else {
+		if !ok {
 			ue = udmContext.UDM_Self().NewUdmUe(id)
-			ue.UdrUri = consumer.SendNFInstancesUDR(id, consumer.NFDiscoveryToUDRParamSupi)
-			return ue.UdrUri
 		}
+		udrUri := consumer.SendNFInstancesUDR(id, consumer.NFDiscoveryToUDRParamSupi)
+		ue.SetUdrUri(udrUri)
+		return udrUri
 	} else if strings.Contains(id, "pei") {
-		var udrURI string
-		udmContext.UDM_Self().UdmUePool.Range(func(key, value interface{}) bool {
-			ue := value.(*udmContext.UdmUeContext)
-			if ue.Amf3GppAccessRegistration != nil && ue.Amf3GppAccessRegistration.Pei == id {
-				ue.UdrUri = consumer.SendNFInstancesUDR(ue.Supi, consumer.NFDiscoveryToUDRParamSupi)
-				udrURI = ue.UdrUri
-				return false
-			} else if ue.AmfNon3GppAccessRegistration != nil && ue.AmfNon3GppAccessRegistration.Pei == id {
-				ue.UdrUri = consumer.SendNFInstancesUDR(ue.Supi, consumer.NFDiscoveryToUDRParamSupi)
-				udrURI = ue.UdrUri
-				return false
-			}
-			return true
-		})
-		return udrURI
+		ue, ok := udmContext.UDM_Self().UdmUeFindByPei(id)
+		if !ok {
+			return ""
+		}
+		udrUri := consumer.SendNFInstancesUDR(ue.Supi, consumer.NFDiscoveryToUDRParamSupi)
+		ue.SetUdrUri(udrUri)
+		return udrUri
 	} else if strings.Contains(id, "extgroupid") {
 		// extra group id
 		return consumer.SendNFInstancesUDR(id, consumer.NFDiscoveryToUDRParamExtGroupId)
@@ -200,6 +201,7 @@ func RegistrationAmf3gppAccessProcedure(registerRequest models.Amf3GppAccessRegi
 	header http.Header, response *models.Amf3GppAccessRegistration, problemDetails *models.ProblemDetails,
 ) {
 	// TODO: EPS interworking with N26 is not supported yet in this stage
+	ueEventStateBefore := ueEventStateOf(ueID)
 	var oldAmf3GppAccessRegContext *models.Amf3GppAccessRegistration
 	if udmContext.UDM_Self().UdmAmf3gppRegContextExists(ueID) {
 		ue, _ := udmContext.UDM_Self().UdmUeFindBySupi(ueID)
@@ -232,16 +234,21 @@ func RegistrationAmf3gppAccessProcedure(registerRequest models.Amf3GppAccessRegi
 			logger.UecmLog.Errorf("CreateAmfContext3gpp response body cannot close: %+v", rspCloseErr)
 		}
 	}()
+	reportUeEvents(ueID, ueEventStateBefore)
+	go delegateAmfEventsOfUe(ueID)
 
 	// TS 23.502 4.2.2.2.2 14d: UDM initiate a Nudm_UECM_DeregistrationNotification to the old AMF
-	// corresponding to the same (e.g. 3GPP) access, if one exists
+	// corresponding to the same (e.g. 3GPP) access, if one exists. An MME registered through S6a
+	// is not notified through Nudm_UECM.
 	if oldAmf3GppAccessRegContext != nil {
-		deregistData := models.DeregistrationData{
-			DeregReason: models.DeregistrationReason_SUBSCRIPTION_WITHDRAWN,
-			AccessType:  models.AccessType__3_GPP_ACCESS,
+		if isSbiCallback(oldAmf3GppAccessRegContext.DeregCallbackUri) {
+			deregistData := models.DeregistrationData{
+				DeregReason: models.DeregistrationReason_SUBSCRIPTION_WITHDRAWN,
+				AccessType:  models.AccessType__3_GPP_ACCESS,
+			}
+			sendOnDeregistrationNotification(ueID, oldAmf3GppAccessRegContext.DeregCallbackUri,
+				deregistData) // Deregistration Notify Triggered
 		}
-		callback.SendOnDeregistrationNotification(ueID, oldAmf3GppAccessRegContext.DeregCallbackUri,
-			deregistData) // Deregistration Notify Triggered
 
 		return nil, nil, nil
 	} else {
@@ -274,6 +281,7 @@ func HandleRegisterAmfNon3gppAccessRequest(request *httpwrapper.Request) *httpwr
 func RegisterAmfNon3gppAccessProcedure(registerRequest models.AmfNon3GppAccessRegistration, ueID string) (
 	header http.Header, response *models.AmfNon3GppAccessRegistration, problemDetails *models.ProblemDetails,
 ) {
+	ueEventStateBefore := ueEventStateOf(ueID)
 	var oldAmfNon3GppAccessRegContext *models.AmfNon3GppAccessRegistration
 	if udmContext.UDM_Self().UdmAmfNon3gppRegContextExists(ueID) {
 		ue, _ := udmContext.UDM_Self().UdmUeFindBySupi(ueID)
@@ -305,6 +313,7 @@ func RegisterAmfNon3gppAccessProcedure(registerRequest models.AmfNon3GppAccessRe
 			logger.UecmLog.Errorf("CreateAmfContext3gpp response body cannot close: %+v", rspCloseErr)
 		}
 	}()
+	reportUeEvents(ueID, ueEventStateBefore)
 
 	// TS 23.502 4.2.2.2.2 14d: UDM initiate a Nudm_UECM_DeregistrationNotification to the old AMF
 	// corresponding to the same (e.g. 3GPP) access, if one exists
@@ -313,7 +322,7 @@ func RegisterAmfNon3gppAccessProcedure(registerRequest models.AmfNon3GppAccessRe
 			DeregReason: models.DeregistrationReason_SUBSCRIPTION_WITHDRAWN,
 			AccessType:  models.AccessType_NON_3_GPP_ACCESS,
 		}
-		callback.SendOnDeregistrationNotification(ueID, oldAmfNon3GppAccessRegContext.DeregCallbackUri,
+		sendOnDeregistrationNotification(ueID, oldAmfNon3GppAccessRegContext.DeregCallbackUri,
 			deregistData) // Deregistration Notify Triggered
 
 		return nil, nil, nil
@@ -344,6 +353,7 @@ func UpdateAmf3gppAccessProcedure(request models.Amf3GppAccessRegistrationModifi
 	problemDetails *models.ProblemDetails,
 ) {
 	var patchItemReqArray []models.PatchItem
+	ueEventStateBefore := ueEventStateOf(ueID)
 	currentContext := udmContext.UDM_Self().GetAmf3gppRegContext(ueID)
 	if currentContext == nil {
 		logger.UecmLog.Errorln("[UpdateAmf3gppAccess] Empty Amf3gppRegContext")
@@ -429,6 +439,12 @@ func UpdateAmf3gppAccessProcedure(request models.Amf3GppAccessRegistrationModifi
 		}
 	}()
 
+	if request.Pei != "" {
+		if udmUe, ok := udmContext.UDM_Self().UdmUeFindBySupi(ueID); ok {
+			udmContext.UDM_Self().SetUePei(udmUe, request.Pei)
+		}
+	}
+	reportUeEvents(ueID, ueEventStateBefore)
 	return nil
 }
 
@@ -451,6 +467,7 @@ func UpdateAmfNon3gppAccessProcedure(request models.AmfNon3GppAccessRegistration
 	problemDetails *models.ProblemDetails,
 ) {
 	var patchItemReqArray []models.PatchItem
+	ueEventStateBefore := ueEventStateOf(ueID)
 	currentContext := udmContext.UDM_Self().GetAmfNon3gppRegContext(ueID)
 	if currentContext == nil {
 		logger.UecmLog.Errorln("[UpdateAmfNon3gppAccess] Empty AmfNon3gppRegContext")
@@ -535,6 +552,12 @@ func UpdateAmfNon3gppAccessProcedure(request models.AmfNon3GppAccessRegistration
 		}
 	}()
 
+	if request.Pei != "" {
+		if udmUe, ok := udmContext.UDM_Self().UdmUeFindBySupi(ueID); ok {
+			udmContext.UDM_Self().SetUePei(udmUe, request.Pei)
+		}
+	}
+	reportUeEvents(ueID, ueEventStateBefore)
 	return nil
 }
 
@@ -573,6 +596,7 @@ func DeregistrationSmfRegistrationsProcedure(ueID string, pduSessionID string) (
 		}
 	}()
 
+	udmContext.UDM_Self().DeleteSmfRegContext(ueID, pduSessionID)
 	return nil
 }
 
@@ -585,8 +609,11 @@ func HandleRegistrationSmfRegistrationsRequest(request *httpwrapper.Request) *ht
 	header, response, problemDetails := RegistrationSmfRegistrationsProcedure(&registerRequest, ueID, pduSessionID)
 	if response != nil {
 		stats.IncrementUdmUeContextManagementStats("create", "smf-registrations", "SUCCESS")
-		// status code is based on SPEC, and option headers
-		return httpwrapper.NewResponse(http.StatusCreated, header, response)
+		// a new PDU session is created, the registration of an existing one is replaced
+		if header != nil {
+			return httpwrapper.NewResponse(http.StatusCreated, header, response)
+		}
+		return httpwrapper.NewResponse(http.StatusOK, nil, response)
 	} else if problemDetails != nil {
 		stats.IncrementUdmUeContextManagementStats("create", "smf-registrations", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -597,40 +624,56 @@ func HandleRegistrationSmfRegistrationsRequest(request *httpwrapper.Request) *ht
 	}
 }
 
-// RegistrationSmfRegistrationsProcedure SmfRegistrationsProcedure
+// RegistrationSmfRegistrationsProcedure stores the SMF registration, with the Location header only when the
+// UDR holds no registration of the PDU session yet
 func RegistrationSmfRegistrationsProcedure(request *models.SmfRegistration, ueID string, pduSessionID string) (
 	header http.Header, response *models.SmfRegistration, problemDetails *models.ProblemDetails,
 ) {
-	contextExisted := false
-	udmContext.UDM_Self().CreateSmfRegContext(ueID, pduSessionID)
-	if !udmContext.UDM_Self().UdmSmfRegContextNotExists(ueID) {
-		contextExisted = true
-	}
-
 	pduID64, err := strconv.ParseInt(pduSessionID, 10, 32)
 	if err != nil {
 		logger.UecmLog.Errorln(err.Error())
+		return nil, nil, &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			InvalidParams: []models.InvalidParam{
+				{
+					Param:  "pduSessionId",
+					Reason: "incorrect format",
+				},
+			},
+		}
 	}
 	pduID32 := int32(pduID64)
+	request.PduSessionId = pduID32
 
 	var createSmfContextNon3gppParamOpts Nudr_DataRepository.CreateSmfContextNon3gppParamOpts
-	optInterface := optional.NewInterface(request)
+	optInterface := optional.NewInterface(*request)
 	createSmfContextNon3gppParamOpts.SmfRegistration = optInterface
 
 	clientAPI, err := createUDMClientToUDR(ueID)
 	if err != nil {
 		return nil, nil, util.ProblemDetailsSystemFailure(err.Error())
 	}
+	stored, problemDetails := smfRegistrationStored(clientAPI, ueID, pduSessionID)
+	if problemDetails != nil {
+		return nil, nil, problemDetails
+	}
 
 	resp, err := clientAPI.SMFRegistrationDocumentApi.CreateSmfContextNon3gpp(context.Background(), ueID,
 		pduID32, &createSmfContextNon3gppParamOpts)
 	if err != nil {
-		problemDetails.Cause = err.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause
+		if resp == nil {
+			return nil, nil, util.ProblemDetailsSystemFailure(err.Error())
+		}
 		problemDetails = &models.ProblemDetails{
 			Status: int32(resp.StatusCode),
-			Cause:  err.(openapi.GenericOpenAPIError).Model().(models.ProblemDetails).Cause,
 			Detail: err.Error(),
 		}
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if model, ok := apiErr.Model().(models.ProblemDetails); ok {
+				problemDetails.Cause = model.Cause
+			}
+		}
 		return nil, nil, problemDetails
 	}
 	defer func() {
@@ -639,12 +682,485 @@ func RegistrationSmfRegistrationsProcedure(request *models.SmfRegistration, ueID
 		}
 	}()
 
-	if contextExisted {
+	udmContext.UDM_Self().CreateSmfRegContext(ueID, pduSessionID, *request)
+	if stored {
+		return nil, request, nil
+	}
+	header = make(http.Header)
+	udmUe, _ := udmContext.UDM_Self().UdmUeFindBySupi(ueID)
+	header.Set("Location", udmUe.GetSmfRegistrationLocationURI(pduSessionID))
+	return header, request, nil
+}
+
+// smfRegistrationStored reports whether the UDR holds a registration of the PDU session, which the
+// new one replaces, e.g. stored through another UDM instance or before a restart. The registration
+// fails when the UDR cannot tell.
+func smfRegistrationStored(clientAPI *Nudr_DataRepository.APIClient, ueID string, pduSessionID string) (
+	bool, *models.ProblemDetails,
+) {
+	_, res, err := clientAPI.SMFRegistrationDocumentApi.QuerySmfRegistration(context.Background(), ueID,
+		pduSessionID, nil)
+	problemDetails := udrQueryProblemDetails(err, res, "QuerySmfRegistration")
+	if problemDetails == nil {
+		return true, nil
+	}
+	if problemDetails.Status == http.StatusNotFound {
+		return false, nil
+	}
+	logger.UecmLog.Errorf("SMF registration of PDU session %s of %s not queried: %+v", pduSessionID, ueID, err)
+	return false, problemDetails
+}
+
+// SmfRegistrationInfo is the list of the SMF registrations of a UE (TS 29.503 Rel-16)
+type SmfRegistrationInfo struct {
+	SmfRegistrationList []models.SmfRegistration `json:"smfRegistrationList"`
+}
+
+func HandleGetSmfRegistrationsRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UecmLog.Infoln("handle GetSmfRegistrations")
+	ueID := request.Params["ueId"]
+	singleNssai := request.Query.Get("single-nssai")
+	dnn := request.Query.Get("dnn")
+	supportedFeatures := request.Query.Get("supported-features")
+	response, problemDetails := GetSmfRegistrationsProcedure(ueID, singleNssai, dnn, supportedFeatures)
+	if response != nil {
+		stats.IncrementUdmUeContextManagementStats("get", "smf-registrations", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+	}
+	stats.IncrementUdmUeContextManagementStats("get", "smf-registrations", "FAILURE")
+	return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+}
+
+// GetSmfRegistrationsProcedure returns the SMF registrations of the UE filtered by the
+// optional S-NSSAI and DNN
+func GetSmfRegistrationsProcedure(ueID string, singleNssai string, dnn string, supportedFeatures string) (
+	response *SmfRegistrationInfo, problemDetails *models.ProblemDetails,
+) {
+	var snssai *models.Snssai
+	if singleNssai != "" {
+		snssai = new(models.Snssai)
+		if err := openapi.Deserialize(snssai, []byte(singleNssai), "application/json"); err != nil {
+			return nil, &models.ProblemDetails{
+				Status: http.StatusBadRequest,
+				Cause:  "MANDATORY_IE_INCORRECT",
+				InvalidParams: []models.InvalidParam{
+					{
+						Param:  "single-nssai",
+						Reason: "incorrect format",
+					},
+				},
+			}
+		}
+	}
+
+	clientAPI, err := createUDMClientToUDR(ueID)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	var querySmfRegListParamOpts Nudr_DataRepository.QuerySmfRegListParamOpts
+	querySmfRegListParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+	smfRegistrations, res, err := clientAPI.SMFRegistrationsCollectionApi.QuerySmfRegList(
+		context.Background(), ueID, &querySmfRegListParamOpts)
+	if problemDetails := udrQueryProblemDetails(err, res, "QuerySmfRegList"); problemDetails != nil {
+		return nil, problemDetails
+	}
+
+	response = &SmfRegistrationInfo{SmfRegistrationList: filterSmfRegistrations(smfRegistrations, snssai, dnn)}
+	if len(response.SmfRegistrationList) == 0 {
+		return nil, &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "CONTEXT_NOT_FOUND",
+		}
+	}
+	return response, nil
+}
+
+// filterSmfRegistrations keeps the registrations of the S-NSSAI and the DNN, when given
+func filterSmfRegistrations(registrations []models.SmfRegistration, snssai *models.Snssai, dnn string) (
+	filtered []models.SmfRegistration,
+) {
+	for _, registration := range registrations {
+		if snssai != nil && (registration.SingleNssai == nil ||
+			registration.SingleNssai.Sst != snssai.Sst || registration.SingleNssai.Sd != snssai.Sd) {
+			continue
+		}
+		if dnn != "" && registration.Dnn != dnn {
+			continue
+		}
+		filtered = append(filtered, registration)
+	}
+	return filtered
+}
+
+func HandleGetSmsf3gppAccessRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UecmLog.Infoln("handle GetSmsf3gppAccessRequest")
+	ueID := request.Params["ueId"]
+	supportedFeatures := request.Query.Get("supported-features")
+	response, problemDetails := GetSmsf3gppAccessProcedure(ueID, supportedFeatures)
+	if response != nil {
+		stats.IncrementUdmUeContextManagementStats("get", "smsf-3gpp-access", "SUCCESS")
+		// status code is based on SPEC, and option headers
+		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+	} else if problemDetails != nil {
+		stats.IncrementUdmUeContextManagementStats("get", "smsf-3gpp-access", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	problemDetails = &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  "UNSPECIFIED",
+	}
+	stats.IncrementUdmUeContextManagementStats("get", "smsf-3gpp-access", "FAILURE")
+	return httpwrapper.NewResponse(http.StatusForbidden, nil, problemDetails)
+}
+
+// GetSmsf3gppAccessProcedure TS 29.503 5.3.2.5.4
+func GetSmsf3gppAccessProcedure(ueID string, supportedFeatures string) (
+	response *models.SmsfRegistration, problemDetails *models.ProblemDetails,
+) {
+	var querySmsfContext3gppParamOpts Nudr_DataRepository.QuerySmsfContext3gppParamOpts
+	querySmsfContext3gppParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+
+	clientAPI, err := createUDMClientToUDR(ueID)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	smsfRegistration, resp, err := clientAPI.SMSF3GPPRegistrationDocumentApi.
+		QuerySmsfContext3gpp(context.Background(), ueID, &querySmsfContext3gppParamOpts)
+	if err != nil {
+		if resp == nil {
+			return nil, util.ProblemDetailsSystemFailure(err.Error())
+		}
+		problemDetails = &models.ProblemDetails{
+			Status: int32(resp.StatusCode),
+			Detail: err.Error(),
+		}
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if model, ok := apiErr.Model().(models.ProblemDetails); ok {
+				problemDetails.Cause = model.Cause
+			}
+		}
+		return nil, problemDetails
+	}
+	defer func() {
+		if rspCloseErr := resp.Body.Close(); rspCloseErr != nil {
+			logger.UecmLog.Errorf("QuerySmsfContext3gpp response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+
+	udmContext.UDM_Self().CreateSmsf3gppRegContext(ueID, smsfRegistration)
+	return &smsfRegistration, nil
+}
+
+func HandleGetSmsfNon3gppAccessRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UecmLog.Infoln("handle GetSmsfNon3gppAccessRequest")
+	ueID := request.Params["ueId"]
+	supportedFeatures := request.Query.Get("supported-features")
+	response, problemDetails := GetSmsfNon3gppAccessProcedure(ueID, supportedFeatures)
+	if response != nil {
+		stats.IncrementUdmUeContextManagementStats("get", "smsf-non-3gpp-access", "SUCCESS")
+		// status code is based on SPEC, and option headers
+		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+	} else if problemDetails != nil {
+		stats.IncrementUdmUeContextManagementStats("get", "smsf-non-3gpp-access", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	problemDetails = &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  "UNSPECIFIED",
+	}
+	stats.IncrementUdmUeContextManagementStats("get", "smsf-non-3gpp-access", "FAILURE")
+	return httpwrapper.NewResponse(http.StatusForbidden, nil, problemDetails)
+}
+
+// GetSmsfNon3gppAccessProcedure TS 29.503 5.3.2.5.5
+func GetSmsfNon3gppAccessProcedure(ueID string, supportedFeatures string) (
+	response *models.SmsfRegistration, problemDetails *models.ProblemDetails,
+) {
+	var querySmsfContextNon3gppParamOpts Nudr_DataRepository.QuerySmsfContextNon3gppParamOpts
+	querySmsfContextNon3gppParamOpts.SupportedFeatures = optional.NewString(supportedFeatures)
+
+	clientAPI, err := createUDMClientToUDR(ueID)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	smsfRegistration, resp, err := clientAPI.SMSFNon3GPPRegistrationDocumentApi.
+		QuerySmsfContextNon3gpp(context.Background(), ueID, &querySmsfContextNon3gppParamOpts)
+	if err != nil {
+		if resp == nil {
+			return nil, util.ProblemDetailsSystemFailure(err.Error())
+		}
+		problemDetails = &models.ProblemDetails{
+			Status: int32(resp.StatusCode),
+			Detail: err.Error(),
+		}
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if model, ok := apiErr.Model().(models.ProblemDetails); ok {
+				problemDetails.Cause = model.Cause
+			}
+		}
+		return nil, problemDetails
+	}
+	defer func() {
+		if rspCloseErr := resp.Body.Close(); rspCloseErr != nil {
+			logger.UecmLog.Errorf("QuerySmsfContextNon3gpp response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+
+	udmContext.UDM_Self().CreateSmsfNon3gppRegContext(ueID, smsfRegistration)
+	return &smsfRegistration, nil
+}
+
+func HandleRegistrationSmsf3gppAccessRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UecmLog.Infoln("handle RegistrationSmsf3gppAccess")
+	registerRequest := request.Body.(models.SmsfRegistration)
+	ueID := request.Params["ueId"]
+	header, response, problemDetails := RegistrationSmsf3gppAccessProcedure(registerRequest, ueID)
+	if response != nil {
+		stats.IncrementUdmUeContextManagementStats("create", "smsf-3gpp-access", "SUCCESS")
+		// status code is based on SPEC, and option headers
+		return httpwrapper.NewResponse(http.StatusCreated, header, response)
+	} else if problemDetails != nil {
+		stats.IncrementUdmUeContextManagementStats("create", "smsf-3gpp-access", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	} else {
+		stats.IncrementUdmUeContextManagementStats("create", "smsf-3gpp-access", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, nil)
+	}
+}
+
+// RegistrationSmsf3gppAccessProcedure TS 29.503 5.3.2.2.5
+func RegistrationSmsf3gppAccessProcedure(registerRequest models.SmsfRegistration, ueID string) (
+	header http.Header, response *models.SmsfRegistration, problemDetails *models.ProblemDetails,
+) {
+	clientAPI, err := createUDMClientToUDR(ueID)
+	if err != nil {
+		return nil, nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+	stored, problemDetails := smsfRegistrationStored(clientAPI, ueID, models.AccessType__3_GPP_ACCESS)
+	if problemDetails != nil {
+		return nil, nil, problemDetails
+	}
+
+	var createSmsfContext3gppParamOpts Nudr_DataRepository.CreateSmsfContext3gppParamOpts
+	createSmsfContext3gppParamOpts.SmsfRegistration = optional.NewInterface(registerRequest)
+	resp, err := clientAPI.SMSF3GPPRegistrationDocumentApi.CreateSmsfContext3gpp(context.Background(),
+		ueID, &createSmsfContext3gppParamOpts)
+	if err != nil {
+		logger.UecmLog.Errorln("CreateSmsfContext3gpp error:", err)
+		if resp == nil {
+			return nil, nil, util.ProblemDetailsSystemFailure(err.Error())
+		}
+		problemDetails = &models.ProblemDetails{
+			Status: int32(resp.StatusCode),
+			Detail: err.Error(),
+		}
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if model, ok := apiErr.Model().(models.ProblemDetails); ok {
+				problemDetails.Cause = model.Cause
+			}
+		}
+		return nil, nil, problemDetails
+	}
+	defer func() {
+		if rspCloseErr := resp.Body.Close(); rspCloseErr != nil {
+			logger.UecmLog.Errorf("CreateSmsfContext3gpp response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+
+	udmContext.UDM_Self().CreateSmsf3gppRegContext(ueID, registerRequest)
+
+	if stored {
 		return nil, nil, nil
+	}
+	header = make(http.Header)
+	udmUe, _ := udmContext.UDM_Self().UdmUeFindBySupi(ueID)
+	header.Set("Location", udmUe.GetLocationURI(udmContext.LocationUriSmsf3GppAccessRegistration))
+	return header, &registerRequest, nil
+}
+
+func HandleRegistrationSmsfNon3gppAccessRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UecmLog.Infoln("handle RegistrationSmsfNon3gppAccess")
+	registerRequest := request.Body.(models.SmsfRegistration)
+	ueID := request.Params["ueId"]
+	header, response, problemDetails := RegistrationSmsfNon3gppAccessProcedure(registerRequest, ueID)
+	if response != nil {
+		stats.IncrementUdmUeContextManagementStats("create", "smsf-non-3gpp-access", "SUCCESS")
+		// status code is based on SPEC, and option headers
+		return httpwrapper.NewResponse(http.StatusCreated, header, response)
+	} else if problemDetails != nil {
+		stats.IncrementUdmUeContextManagementStats("create", "smsf-non-3gpp-access", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	} else {
-		header = make(http.Header)
-		udmUe, _ := udmContext.UDM_Self().UdmUeFindBySupi(ueID)
-		header.Set("Location", udmUe.GetLocationURI(udmContext.LocationUriSmfRegistration))
-		return header, request, nil
+		stats.IncrementUdmUeContextManagementStats("create", "smsf-non-3gpp-access", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, nil)
+	}
+}
+
+// RegistrationSmsfNon3gppAccessProcedure TS 29.503 5.3.2.2.6
+func RegistrationSmsfNon3gppAccessProcedure(registerRequest models.SmsfRegistration, ueID string) (
+	header http.Header, response *models.SmsfRegistration, problemDetails *models.ProblemDetails,
+) {
+	clientAPI, err := createUDMClientToUDR(ueID)
+	if err != nil {
+		return nil, nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+	stored, problemDetails := smsfRegistrationStored(clientAPI, ueID, models.AccessType_NON_3_GPP_ACCESS)
+	if problemDetails != nil {
+		return nil, nil, problemDetails
 	}
+
+	var createSmsfContextNon3gppParamOpts Nudr_DataRepository.CreateSmsfContextNon3gppParamOpts
+	createSmsfContextNon3gppParamOpts.SmsfRegistration = optional.NewInterface(registerRequest)
+	resp, err := clientAPI.SMSFNon3GPPRegistrationDocumentApi.CreateSmsfContextNon3gpp(context.Background(),
+		ueID, &createSmsfContextNon3gppParamOpts)
+	if err != nil {
+		logger.UecmLog.Errorln("CreateSmsfContextNon3gpp error:", err)
+		if resp == nil {
+			return nil, nil, util.ProblemDetailsSystemFailure(err.Error())
+		}
+		problemDetails = &models.ProblemDetails{
+			Status: int32(resp.StatusCode),
+			Detail: err.Error(),
+		}
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if model, ok := apiErr.Model().(models.ProblemDetails); ok {
+				problemDetails.Cause = model.Cause
+			}
+		}
+		return nil, nil, problemDetails
+	}
+	defer func() {
+		if rspCloseErr := resp.Body.Close(); rspCloseErr != nil {
+			logger.UecmLog.Errorf("CreateSmsfContextNon3gpp response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+
+	udmContext.UDM_Self().CreateSmsfNon3gppRegContext(ueID, registerRequest)
+
+	if stored {
+		return nil, nil, nil
+	}
+	header = make(http.Header)
+	udmUe, _ := udmContext.UDM_Self().UdmUeFindBySupi(ueID)
+	header.Set("Location", udmUe.GetLocationURI(udmContext.LocationUriSmsfNon3GppAccessRegistration))
+	return header, &registerRequest, nil
+}
+
+// smsfRegistrationStored reports whether the UDR holds an SMSF registration of the UE for the access
+// type, which the new one replaces, e.g. stored through another UDM instance or before a restart
+func smsfRegistrationStored(clientAPI *Nudr_DataRepository.APIClient, ueID string, accessType models.AccessType) (
+	bool, *models.ProblemDetails,
+) {
+	var res *http.Response
+	var err error
+	if accessType == models.AccessType__3_GPP_ACCESS {
+		_, res, err = clientAPI.SMSF3GPPRegistrationDocumentApi.QuerySmsfContext3gpp(context.Background(), ueID, nil)
+	} else {
+		_, res, err = clientAPI.SMSFNon3GPPRegistrationDocumentApi.QuerySmsfContextNon3gpp(context.Background(),
+			ueID, nil)
+	}
+	problemDetails := udrQueryProblemDetails(err, res, "QuerySmsfContext")
+	if problemDetails == nil {
+		return true, nil
+	}
+	if problemDetails.Status == http.StatusNotFound {
+		return false, nil
+	}
+	logger.UecmLog.Errorf("SMSF registration of %s for %s not queried: %+v", ueID, accessType, err)
+	return false, problemDetails
+}
+
+func HandleDeregistrationSmsf3gppAccessRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UecmLog.Infoln("handle DeregistrationSmsf3gppAccess")
+	ueID := request.Params["ueId"]
+	problemDetails := DeregistrationSmsf3gppAccessProcedure(ueID)
+	if problemDetails != nil {
+		stats.IncrementUdmUeContextManagementStats("delete", "smsf-3gpp-access", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	} else {
+		stats.IncrementUdmUeContextManagementStats("delete", "smsf-3gpp-access", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, nil)
+	}
+}
+
+// DeregistrationSmsf3gppAccessProcedure TS 29.503 5.3.2.3.4
+func DeregistrationSmsf3gppAccessProcedure(ueID string) (problemDetails *models.ProblemDetails) {
+	clientAPI, err := createUDMClientToUDR(ueID)
+	if err != nil {
+		return util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	resp, err := clientAPI.SMSF3GPPRegistrationDocumentApi.DeleteSmsfContext3gpp(context.Background(), ueID)
+	if err != nil {
+		if resp == nil {
+			return util.ProblemDetailsSystemFailure(err.Error())
+		}
+		problemDetails = &models.ProblemDetails{
+			Status: int32(resp.StatusCode),
+			Detail: err.Error(),
+		}
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if model, ok := apiErr.Model().(models.ProblemDetails); ok {
+				problemDetails.Cause = model.Cause
+			}
+		}
+		return problemDetails
+	}
+	defer func() {
+		if rspCloseErr := resp.Body.Close(); rspCloseErr != nil {
+			logger.UecmLog.Errorf("DeleteSmsfContext3gpp response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+
+	udmContext.UDM_Self().DeleteSmsf3gppRegContext(ueID)
+	return nil
+}
+
+func HandleDeregistrationSmsfNon3gppAccessRequest(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.UecmLog.Infoln("handle DeregistrationSmsfNon3gppAccess")
+	ueID := request.Params["ueId"]
+	problemDetails := DeregistrationSmsfNon3gppAccessProcedure(ueID)
+	if problemDetails != nil {
+		stats.IncrementUdmUeContextManagementStats("delete", "smsf-non-3gpp-access", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	} else {
+		stats.IncrementUdmUeContextManagementStats("delete", "smsf-non-3gpp-access", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, nil)
+	}
+}
+
+// DeregistrationSmsfNon3gppAccessProcedure TS 29.503 5.3.2.3.5
+func DeregistrationSmsfNon3gppAccessProcedure(ueID string) (problemDetails *models.ProblemDetails) {
+	clientAPI, err := createUDMClientToUDR(ueID)
+	if err != nil {
+		return util.ProblemDetailsSystemFailure(err.Error())
+	}
+
+	resp, err := clientAPI.SMSFNon3GPPRegistrationDocumentApi.DeleteSmsfContextNon3gpp(context.Background(), ueID)
+	if err != nil {
+		if resp == nil {
+			return util.ProblemDetailsSystemFailure(err.Error())
+		}
+		problemDetails = &models.ProblemDetails{
+			Status: int32(resp.StatusCode),
+			Detail: err.Error(),
+		}
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if model, ok := apiErr.Model().(models.ProblemDetails); ok {
+				problemDetails.Cause = model.Cause
+			}
+		}
+		return problemDetails
+	}
+	defer func() {
+		if rspCloseErr := resp.Body.Close(); rspCloseErr != nil {
+			logger.UecmLog.Errorf("DeleteSmsfContextNon3gpp response body cannot close: %+v", rspCloseErr)
+		}
+	}()
+
+	udmContext.UDM_Self().DeleteSmsfNon3gppRegContext(ueID)
+	return nil
 }
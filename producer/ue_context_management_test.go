@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package producer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	udm_context "github.com/omec-project/udm/context"
+	"github.com/omec-project/util/httpwrapper"
+)
+
+func TestFilterSmfRegistrations(t *testing.T) {
+	embb := &models.Snssai{Sst: 1, Sd: "010203"}
+	registrations := []models.SmfRegistration{
+		{PduSessionId: 1, SingleNssai: embb, Dnn: "internet"},
+		{PduSessionId: 2, SingleNssai: embb, Dnn: "ims"},
+		{PduSessionId: 3, SingleNssai: &models.Snssai{Sst: 2}, Dnn: "internet"},
+		{PduSessionId: 4, Dnn: "internet"},
+	}
+
+	testCases := []struct {
+		name     string
+		snssai   *models.Snssai
+		dnn      string
+		expected []int32
+	}{
+		{
+			name:     "no filter",
+			expected: []int32{1, 2, 3, 4},
+		},
+		{
+			name:     "S-NSSAI",
+			snssai:   &models.Snssai{Sst: 1, Sd: "010203"},
+			expected: []int32{1, 2},
+		},
+		{
+			name:     "DNN",
+			dnn:      "internet",
+			expected: []int32{1, 3, 4},
+		},
+		{
+			name:     "S-NSSAI and DNN",
+			snssai:   &models.Snssai{Sst: 2},
+			dnn:      "internet",
+			expected: []int32{3},
+		},
+		{
+			name:   "no match",
+			snssai: &models.Snssai{Sst: 1},
+			dnn:    "ims",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pduSessionIDs []int32
+			for _, registration := range filterSmfRegistrations(registrations, tc.snssai, tc.dnn) {
+				pduSessionIDs = append(pduSessionIDs, registration.PduSessionId)
+			}
+			if !reflect.DeepEqual(pduSessionIDs, tc.expected) {
+				t.Errorf("expected PDU sessions %v, got %v", tc.expected, pduSessionIDs)
+			}
+		})
+	}
+}
+
+func TestRegistrationSmfRegistrationsProcedure_InvalidPduSessionID(t *testing.T) {
+	header, response, problemDetails := RegistrationSmfRegistrationsProcedure(
+		&models.SmfRegistration{}, "imsi-208930000000001", "five")
+	if header != nil || response != nil {
+		t.Fatalf("expected no registration, got %v, %+v", header, response)
+	}
+	if problemDetails == nil || problemDetails.Status != http.StatusBadRequest ||
+		problemDetails.Cause != "MANDATORY_IE_INCORRECT" {
+		t.Fatalf("expected MANDATORY_IE_INCORRECT, got %+v", problemDetails)
+	}
+}
+
+func TestGetSmfRegistrationsProcedure_InvalidSingleNssai(t *testing.T) {
+	response, problemDetails := GetSmfRegistrationsProcedure("imsi-208930000000001", "{sst", "", "")
+	if response != nil {
+		t.Fatalf("expected no response, got %+v", response)
+	}
+	if problemDetails == nil || problemDetails.Status != http.StatusBadRequest {
+		t.Fatalf("expected a bad request, got %+v", problemDetails)
+	}
+}
+
+func TestRegistrationSmfRegistrationsProcedure_CreatedOrReplaced(t *testing.T) {
+	const supi = "imsi-208930000000251"
+	udr := newFakeUdr()
+	useFakeUdr(t, udr)
+	udmSelf := udm_context.UDM_Self()
+	t.Cleanup(func() {
+		udmSelf.DeleteSmfRegContext(supi, "5")
+		udmSelf.DeleteSmfRegContext(supi, "6")
+	})
+
+	header, response, problemDetails := RegistrationSmfRegistrationsProcedure(
+		&models.SmfRegistration{SmfInstanceId: "smf-1", Dnn: "internet"}, supi, "5")
+	if problemDetails != nil || response == nil {
+		t.Fatalf("unexpected problem details: %+v", problemDetails)
+	}
+	if header == nil || header.Get("Location") == "" {
+		t.Fatal("expected the new PDU session to be created with its Location")
+	}
+	var stored models.SmfRegistration
+	if !udr.get("/subscription-data/"+supi+"/context-data/smf-registrations/5", &stored) ||
+		stored.SmfInstanceId != "smf-1" {
+		t.Fatalf("unexpected stored registration %+v", stored)
+	}
+
+	header, _, problemDetails = RegistrationSmfRegistrationsProcedure(
+		&models.SmfRegistration{SmfInstanceId: "smf-2", Dnn: "internet"}, supi, "5")
+	if problemDetails != nil || header != nil {
+		t.Fatalf("expected the registration to be replaced, got %v, %+v", header, problemDetails)
+	}
+
+	// the registration stored through another UDM instance, or before a restart
+	udr.set("/subscription-data/"+supi+"/context-data/smf-registrations/6",
+		models.SmfRegistration{SmfInstanceId: "smf-1", PduSessionId: 6})
+	header, _, problemDetails = RegistrationSmfRegistrationsProcedure(
+		&models.SmfRegistration{SmfInstanceId: "smf-2", Dnn: "ims"}, supi, "6")
+	if problemDetails != nil || header != nil {
+		t.Fatalf("expected the registration stored in the UDR to be replaced, got %v, %+v", header, problemDetails)
+	}
+
+	// the UDR cannot tell whether the registration is stored
+	udr.handle(http.MethodGet, "/subscription-data/"+supi+"/context-data/smf-registrations/7",
+		func(w http.ResponseWriter, r *http.Request) {
+			writeUdrProblem(w, http.StatusInternalServerError, "UNSPECIFIED_NF_FAILURE")
+		})
+	_, _, problemDetails = RegistrationSmfRegistrationsProcedure(
+		&models.SmfRegistration{SmfInstanceId: "smf-1", Dnn: "internet"}, supi, "7")
+	if problemDetails == nil || problemDetails.Status != http.StatusInternalServerError {
+		t.Fatalf("expected the failure of the UDR query to be returned, got %+v", problemDetails)
+	}
+	if len(udr.received(http.MethodPut, "/subscription-data/"+supi+"/context-data/smf-registrations/7")) != 0 {
+		t.Error("expected the registration not to be stored")
+	}
+}
+
+func TestSmsfRegistrations(t *testing.T) {
+	testCases := []struct {
+		access       string
+		registration func(*httpwrapper.Request) *httpwrapper.Response
+		get          func(*httpwrapper.Request) *httpwrapper.Response
+		deregister   func(*httpwrapper.Request) *httpwrapper.Response
+		context      func(string) *models.SmsfRegistration
+	}{
+		{
+			access:       "smsf-3gpp-access",
+			registration: HandleRegistrationSmsf3gppAccessRequest,
+			get:          HandleGetSmsf3gppAccessRequest,
+			deregister:   HandleDeregistrationSmsf3gppAccessRequest,
+			context:      udm_context.UDM_Self().GetSmsf3gppRegContext,
+		},
+		{
+			access:       "smsf-non-3gpp-access",
+			registration: HandleRegistrationSmsfNon3gppAccessRequest,
+			get:          HandleGetSmsfNon3gppAccessRequest,
+			deregister:   HandleDeregistrationSmsfNon3gppAccessRequest,
+			context:      udm_context.UDM_Self().GetSmsfNon3gppRegContext,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.access, func(t *testing.T) {
+			const supi = "imsi-208930000000252"
+			udr := newFakeUdr()
+			useFakeUdr(t, udr)
+			udmSelf := udm_context.UDM_Self()
+			t.Cleanup(func() {
+				udmSelf.DeleteSmsf3gppRegContext(supi)
+				udmSelf.DeleteSmsfNon3gppRegContext(supi)
+			})
+			udrPath := "/subscription-data/" + supi + "/context-data/" + tc.access
+			newRequest := func(method string, body interface{}) *httpwrapper.Request {
+				request := httpwrapper.NewRequest(httptest.NewRequest(method, "/"+supi+"/registrations/"+tc.access, nil), body)
+				request.Params["ueId"] = supi
+				return request
+			}
+
+			rsp := tc.registration(newRequest(http.MethodPut, models.SmsfRegistration{SmsfInstanceId: "smsf-1"}))
+			if rsp.Status != http.StatusCreated || rsp.Header.Get("Location") == "" {
+				t.Fatalf("expected 201 with a Location, got %d %v", rsp.Status, rsp.Header)
+			}
+			var stored models.SmsfRegistration
+			if !udr.get(udrPath, &stored) || stored.SmsfInstanceId != "smsf-1" {
+				t.Fatalf("unexpected stored registration %+v", stored)
+			}
+
+			rsp = tc.registration(newRequest(http.MethodPut, models.SmsfRegistration{SmsfInstanceId: "smsf-2"}))
+			if rsp.Status != http.StatusNoContent {
+				t.Fatalf("expected the registration to be replaced with 204, got %d", rsp.Status)
+			}
+			if !udr.get(udrPath, &stored) || stored.SmsfInstanceId != "smsf-2" {
+				t.Fatalf("unexpected stored registration %+v", stored)
+			}
+
+			rsp = tc.get(newRequest(http.MethodGet, nil))
+			if rsp.Status != http.StatusOK {
+				t.Fatalf("expected 200, got %d", rsp.Status)
+			}
+			if registration, ok := rsp.Body.(*models.SmsfRegistration); !ok || registration.SmsfInstanceId != "smsf-2" {
+				t.Fatalf("unexpected registration %+v", rsp.Body)
+			}
+
+			rsp = tc.deregister(newRequest(http.MethodDelete, nil))
+			if rsp.Status != http.StatusNoContent {
+				t.Fatalf("expected 204, got %d", rsp.Status)
+			}
+			if udr.get(udrPath, &stored) {
+				t.Fatal("expected the registration to be removed from the UDR")
+			}
+			if tc.context(supi) != nil {
+				t.Fatal("expected the registration to be removed from the UE context")
+			}
+
+			rsp = tc.get(newRequest(http.MethodGet, nil))
+			if problemDetails, ok := rsp.Body.(*models.ProblemDetails); !ok || rsp.Status != http.StatusNotFound ||
+				problemDetails.Cause != "DATA_NOT_FOUND" {
+				t.Fatalf("expected 404 DATA_NOT_FOUND, got %d %+v", rsp.Status, rsp.Body)
+			}
+
+			// the registration stored through another UDM instance, or before a restart
+			udr.set(udrPath, models.SmsfRegistration{SmsfInstanceId: "smsf-1"})
+			rsp = tc.registration(newRequest(http.MethodPut, models.SmsfRegistration{SmsfInstanceId: "smsf-2"}))
+			if rsp.Status != http.StatusNoContent {
+				t.Fatalf("expected the registration stored in the UDR to be replaced with 204, got %d", rsp.Status)
+			}
+
+			// the UDR cannot tell whether the registration is stored
+			udr.handle(http.MethodGet, udrPath, func(w http.ResponseWriter, r *http.Request) {
+				writeUdrProblem(w, http.StatusInternalServerError, "UNSPECIFIED_NF_FAILURE")
+			})
+			rsp = tc.registration(newRequest(http.MethodPut, models.SmsfRegistration{SmsfInstanceId: "smsf-3"}))
+			if rsp.Status != http.StatusInternalServerError {
+				t.Fatalf("expected the failure of the UDR query to be returned, got %d", rsp.Status)
+			}
+			if !udr.get(udrPath, &stored) || stored.SmsfInstanceId != "smsf-2" {
+				t.Fatalf("expected the registration not to be replaced, got %+v", stored)
+			}
+
+			// an error of the UDR without ProblemDetails
+			udr.handle(http.MethodDelete, udrPath, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+			if rsp = tc.deregister(newRequest(http.MethodDelete, nil)); rsp.Status != http.StatusInternalServerError {
+				t.Fatalf("expected 500, got %d", rsp.Status)
+			}
+		})
+	}
+}
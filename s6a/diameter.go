@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package s6a
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Diameter base protocol of RFC 6733, limited to what the S6a front end needs
+
+const (
+	diameterVersion  = 1
+	headerLen        = 20
+	avpHeaderLen     = 8
+	avpVendorLen     = 4
+	maxMessageLength = 1 << 16
+)
+
+// Command flags (RFC 6733 3)
+const (
+	flagRequest   uint8 = 0x80
+	flagProxiable uint8 = 0x40
+	flagError     uint8 = 0x20
+)
+
+// AVP flags (RFC 6733 4.1)
+const (
+	avpFlagVendor    uint8 = 0x80
+	avpFlagMandatory uint8 = 0x40
+)
+
+// avp is an AVP, whose data is the encoded value without the padding
+type avp struct {
+	code     uint32
+	flags    uint8
+	vendorID uint32
+	data     []byte
+}
+
+// message is a Diameter message
+type message struct {
+	flags         uint8
+	commandCode   uint32
+	applicationID uint32
+	hopByHopID    uint32
+	endToEndID    uint32
+	avps          []avp
+}
+
+func (m *message) isRequest() bool {
+	return m.flags&flagRequest != 0
+}
+
+// find returns the first AVP of the code and vendor
+func (m *message) find(code uint32, vendorID uint32) (avp, bool) {
+	return findAvp(m.avps, code, vendorID)
+}
+
+func findAvp(avps []avp, code uint32, vendorID uint32) (avp, bool) {
+	for _, a := range avps {
+		if a.code == code && a.vendorID == vendorID {
+			return a, true
+		}
+	}
+	return avp{}, false
+}
+
+// answer returns the answer to the request, with the same identifiers
+func (m *message) answer(avps ...avp) *message {
+	return &message{
+		flags:         m.flags & flagProxiable,
+		commandCode:   m.commandCode,
+		applicationID: m.applicationID,
+		hopByHopID:    m.hopByHopID,
+		endToEndID:    m.endToEndID,
+		avps:          avps,
+	}
+}
+
+// readMessage reads a message of the connection
+func readMessage(r io.Reader) (*message, error) {
+	var header [headerLen]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != diameterVersion {
+		return nil, fmt.Errorf("unsupported Diameter version %d", header[0])
+	}
+	length := int(uint32(header[1])<<16 | uint32(header[2])<<8 | uint32(header[3]))
+	if length < headerLen || length > maxMessageLength || length%4 != 0 {
+		return nil, fmt.Errorf("invalid message length %d", length)
+	}
+	body := make([]byte, length-headerLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	avps, err := decodeAvps(body)
+	if err != nil {
+		return nil, err
+	}
+	return &message{
+		flags:         header[4],
+		commandCode:   uint32(header[5])<<16 | uint32(header[6])<<8 | uint32(header[7]),
+		applicationID: binary.BigEndian.Uint32(header[8:12]),
+		hopByHopID:    binary.BigEndian.Uint32(header[12:16]),
+		endToEndID:    binary.BigEndian.Uint32(header[16:20]),
+		avps:          avps,
+	}, nil
+}
+
+// encode returns the message on the wire
+func (m *message) encode() []byte {
+	body := encodeAvps(m.avps)
+	length := headerLen + len(body)
+	b := make([]byte, headerLen, length)
+	b[0] = diameterVersion
+	b[1], b[2], b[3] = byte(length>>16), byte(length>>8), byte(length)
+	b[4] = m.flags
+	b[5], b[6], b[7] = byte(m.commandCode>>16), byte(m.commandCode>>8), byte(m.commandCode)
+	binary.BigEndian.PutUint32(b[8:12], m.applicationID)
+	binary.BigEndian.PutUint32(b[12:16], m.hopByHopID)
+	binary.BigEndian.PutUint32(b[16:20], m.endToEndID)
+	return append(b, body...)
+}
+
+func decodeAvps(b []byte) ([]avp, error) {
+	var avps []avp
+	for len(b) > 0 {
+		if len(b) < avpHeaderLen {
+			return nil, errors.New("truncated AVP header")
+		}
+		a := avp{code: binary.BigEndian.Uint32(b[0:4]), flags: b[4]}
+		length := int(uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7]))
+		offset := avpHeaderLen
+		if a.flags&avpFlagVendor != 0 {
+			offset += avpVendorLen
+		}
+		if length < offset || length > len(b) {
+			return nil, fmt.Errorf("invalid length %d of AVP %d", length, a.code)
+		}
+		if a.flags&avpFlagVendor != 0 {
+			a.vendorID = binary.BigEndian.Uint32(b[8:12])
+		}
+		a.data = b[offset:length]
+		avps = append(avps, a)
+		padded := (length + 3) &^ 3
+		if padded > len(b) {
+			padded = len(b)
+		}
+		b = b[padded:]
+	}
+	return avps, nil
+}
+
+func encodeAvps(avps []avp) []byte {
+	var b []byte
+	for _, a := range avps {
+		flags := a.flags
+		headerLength := avpHeaderLen
+		if a.vendorID != 0 {
+			flags |= avpFlagVendor
+			headerLength += avpVendorLen
+		}
+		length := headerLength + len(a.data)
+		header := make([]byte, headerLength)
+		binary.BigEndian.PutUint32(header[0:4], a.code)
+		header[4] = flags
+		header[5], header[6], header[7] = byte(length>>16), byte(length>>8), byte(length)
+		if a.vendorID != 0 {
+			binary.BigEndian.PutUint32(header[8:12], a.vendorID)
+		}
+		b = append(append(b, header...), a.data...)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
+
+// AVPs of the basic types of RFC 6733 4.2 and 4.3, mandatory
+
+func octetStringAvp(code uint32, vendorID uint32, value []byte) avp {
+	return avp{code: code, flags: avpFlagMandatory, vendorID: vendorID, data: value}
+}
+
+func utf8StringAvp(code uint32, vendorID uint32, value string) avp {
+	return octetStringAvp(code, vendorID, []byte(value))
+}
+
+func unsigned32Avp(code uint32, vendorID uint32, value uint32) avp {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, value)
+	return octetStringAvp(code, vendorID, data)
+}
+
+func groupedAvp(code uint32, vendorID uint32, avps ...avp) avp {
+	return octetStringAvp(code, vendorID, encodeAvps(avps))
+}
+
+// addressAvp is an Address AVP of an IP address
+func addressAvp(code uint32, ip net.IP) avp {
+	if ip4 := ip.To4(); ip4 != nil {
+		return octetStringAvp(code, 0, append([]byte{0, 1}, ip4...))
+	}
+	return octetStringAvp(code, 0, append([]byte{0, 2}, ip.To16()...))
+}
+
+func (a avp) unsigned32() (uint32, error) {
+	if len(a.data) != 4 {
+		return 0, fmt.Errorf("invalid Unsigned32 AVP %d", a.code)
+	}
+	return binary.BigEndian.Uint32(a.data), nil
+}
+
+func (a avp) grouped() ([]avp, error) {
+	return decodeAvps(a.data)
+}
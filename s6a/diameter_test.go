@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package s6a
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestMessageEncodeDecode(t *testing.T) {
+	m := &message{
+		flags:         flagRequest | flagProxiable,
+		commandCode:   commandAuthenticationInformation,
+		applicationID: applicationIDS6a,
+		hopByHopID:    0x01020304,
+		endToEndID:    0x05060708,
+		avps: []avp{
+			utf8StringAvp(avpSessionID, 0, "mme.epc;1;2"),
+			utf8StringAvp(avpUserName, 0, "001010000000001"),
+			octetStringAvp(avpVisitedPlmnID, vendorID3gpp, []byte{0x00, 0xf1, 0x10}),
+			groupedAvp(avpRequestedEutranAuthenticationInfo, vendorID3gpp,
+				unsigned32Avp(avpNumberOfRequestedVectors, vendorID3gpp, 2)),
+			addressAvp(avpHostIPAddress, net.IPv4(10, 0, 0, 1)),
+		},
+	}
+
+	b := m.encode()
+	if len(b)%4 != 0 {
+		t.Fatalf("message length %d is not padded", len(b))
+	}
+	decoded, err := readMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("readMessage failed: %+v", err)
+	}
+	for i := range decoded.avps {
+		if decoded.avps[i].vendorID != 0 {
+			// the V flag is set on encoding
+			m.avps[i].flags |= avpFlagVendor
+		}
+	}
+	if !reflect.DeepEqual(decoded, m) {
+		t.Fatalf("decoded %+v, want %+v", decoded, m)
+	}
+
+	requested, ok := decoded.find(avpRequestedEutranAuthenticationInfo, vendorID3gpp)
+	if !ok {
+		t.Fatal("Requested-EUTRAN-Authentication-Info not found")
+	}
+	grouped, err := requested.grouped()
+	if err != nil {
+		t.Fatalf("grouped failed: %+v", err)
+	}
+	number, ok := findAvp(grouped, avpNumberOfRequestedVectors, vendorID3gpp)
+	if !ok {
+		t.Fatal("Number-Of-Requested-Vectors not found")
+	}
+	if count, err := number.unsigned32(); err != nil || count != 2 {
+		t.Fatalf("Number-Of-Requested-Vectors %d, %v", count, err)
+	}
+	if _, ok := decoded.find(avpVisitedPlmnID, 0); ok {
+		t.Fatal("Visited-PLMN-Id found without its vendor")
+	}
+}
+
+func TestReadMessage_Invalid(t *testing.T) {
+	valid := (&message{commandCode: commandDeviceWatchdog, avps: []avp{
+		utf8StringAvp(avpOriginHost, 0, "mme"),
+	}}).encode()
+
+	testCases := []struct {
+		name  string
+		input func([]byte) []byte
+	}{
+		{
+			name:  "version",
+			input: func(b []byte) []byte { b[0] = 2; return b },
+		},
+		{
+			name:  "message length",
+			input: func(b []byte) []byte { b[3]++; return b },
+		},
+		{
+			name:  "truncated",
+			input: func(b []byte) []byte { return b[:len(b)-4] },
+		},
+		{
+			name:  "AVP length",
+			input: func(b []byte) []byte { b[headerLen+7] = 0xff; return b },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := tc.input(append([]byte{}, valid...))
+			if _, err := readMessage(bytes.NewReader(input)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
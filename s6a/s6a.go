@@ -0,0 +1,505 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package s6a
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/logger"
+	stats "github.com/omec-project/udm/metrics"
+	"github.com/omec-project/udm/producer"
+)
+
+// S6a application of TS 29.272 7.1.8
+const (
+	applicationIDS6a uint32 = 16777251
+	vendorID3gpp     uint32 = 10415
+)
+
+// S6a commands (TS 29.272 7.2)
+const (
+	commandUpdateLocation            uint32 = 316
+	commandAuthenticationInformation uint32 = 318
+	commandPurgeUe                   uint32 = 321
+)
+
+// S6a AVPs (TS 29.272 7.3), of the 3GPP vendor unless noted
+const (
+	avpServiceSelection                  uint32 = 493 // RFC 5778, of no vendor
+	avpMaxRequestedBandwidthDL           uint32 = 515
+	avpMaxRequestedBandwidthUL           uint32 = 516
+	avpMsisdn                            uint32 = 701
+	avpQosClassIdentifier                uint32 = 1028
+	avpAllocationRetentionPriority       uint32 = 1034
+	avpPriorityLevel                     uint32 = 1046
+	avpPreemptionCapability              uint32 = 1047
+	avpPreemptionVulnerability           uint32 = 1048
+	avpSubscriptionData                  uint32 = 1400
+	avpUlrFlags                          uint32 = 1405
+	avpUlaFlags                          uint32 = 1406
+	avpVisitedPlmnID                     uint32 = 1407
+	avpRequestedEutranAuthenticationInfo uint32 = 1408
+	avpNumberOfRequestedVectors          uint32 = 1410
+	avpResynchronizationInfo             uint32 = 1411
+	avpAuthenticationInfo                uint32 = 1413
+	avpEutranVector                      uint32 = 1414
+	avpNetworkAccessMode                 uint32 = 1417
+	avpItemNumber                        uint32 = 1419
+	avpContextIdentifier                 uint32 = 1423
+	avpSubscriberStatus                  uint32 = 1424
+	avpAllApnConfigurationsIncludedInd   uint32 = 1428
+	avpApnConfigurationProfile           uint32 = 1429
+	avpApnConfiguration                  uint32 = 1430
+	avpEpsSubscribedQosProfile           uint32 = 1431
+	avpAmbr                              uint32 = 1435
+	avpPuaFlags                          uint32 = 1442
+	avpRand                              uint32 = 1447
+	avpXres                              uint32 = 1448
+	avpAutn                              uint32 = 1449
+	avpKasme                             uint32 = 1450
+	avpPdnType                           uint32 = 1456
+)
+
+// Experimental result codes of the 3GPP vendor (TS 29.272 7.4)
+const (
+	experimentalResultAuthenticationDataUnavailable uint32 = 4181
+	experimentalResultUserUnknown                   uint32 = 5001
+)
+
+// Values of the S6a AVPs
+const (
+	ulrFlagInitialAttach    uint32 = 1 << 5 // ULR-Flags, Initial-Attach-Indicator
+	puaFlagFreezeMTmsi      uint32 = 1      // PUA-Flags, Freeze M-TMSI
+	subscriberStatusGranted uint32 = 0      // SERVICE_GRANTED
+	networkAccessPacketOnly uint32 = 2      // ONLY_PACKET
+	allApnConfigurations    uint32 = 0      // All_APN_CONFIGURATIONS_INCLUDED
+	pdnTypeIPv4             uint32 = 0
+	pdnTypeIPv6             uint32 = 1
+	pdnTypeIPv4v6           uint32 = 2
+	preemptionEnabled       uint32 = 0 // Pre-emption-Capability and Pre-emption-Vulnerability
+	preemptionDisabled      uint32 = 1
+	rsyncInfoLen                   = 30 // RAND || AUTS
+	randLen                        = 16
+)
+
+// The procedures of the UDM, replaced by the tests
+var (
+	generateAuthData = producer.GenerateAuthDataProcedure
+	updateLocation   = producer.UpdateLocationProcedure
+	purgeUe          = producer.PurgeUeProcedure
+)
+
+var commandNames = map[uint32]string{
+	commandUpdateLocation:            "ULR",
+	commandAuthenticationInformation: "AIR",
+	commandPurgeUe:                   "PUR",
+}
+
+// result is the Result-Code, or the Experimental-Result-Code of the 3GPP vendor, of an answer
+type result struct {
+	code         uint32
+	experimental bool
+}
+
+func (r result) String() string {
+	switch {
+	case r.experimental:
+		return "EXPERIMENTAL_" + strconv.FormatUint(uint64(r.code), 10)
+	case r.code == resultSuccess:
+		return "SUCCESS"
+	default:
+		return strconv.FormatUint(uint64(r.code), 10)
+	}
+}
+
+// handle answers the S6a request of an MME
+func (s *Server) handle(request *message) *message {
+	name, ok := commandNames[request.commandCode]
+	if !ok {
+		logger.S6aLog.Warnf("unsupported S6a command %d", request.commandCode)
+		return s.errorAnswer(request, resultCommandUnsupported)
+	}
+
+	var avps []avp
+	res := result{code: resultSuccess}
+	userName, hasUserName := request.find(avpUserName, 0)
+	originHost, hasOriginHost := request.find(avpOriginHost, 0)
+	_, hasSessionID := request.find(avpSessionID, 0)
+	if !hasUserName || !hasOriginHost || !hasSessionID {
+		res = result{code: resultMissingAvp}
+	} else {
+		supi := "imsi-" + string(userName.data)
+		mmeHost := string(originHost.data)
+		logger.S6aLog.Infof("%s of %s from %s", name, supi, mmeHost)
+		switch request.commandCode {
+		case commandAuthenticationInformation:
+			avps, res = authenticationInformation(request, supi)
+		case commandUpdateLocation:
+			avps, res = updateLocationRequest(request, supi, mmeHost)
+		case commandPurgeUe:
+			avps, res = purgeUeRequest(supi, mmeHost)
+		}
+	}
+	stats.IncrementUdmS6aRequestsStats(name, res.String())
+	if res.code != resultSuccess {
+		logger.S6aLog.Warnf("%s rejected with %s", name, res)
+	}
+	return s.answer(request, res, avps...)
+}
+
+// answer is the answer of a request of the S6a application (TS 29.272 7.2)
+func (s *Server) answer(request *message, res result, avps ...avp) *message {
+	var answerAvps []avp
+	if sessionID, ok := request.find(avpSessionID, 0); ok {
+		answerAvps = append(answerAvps, sessionID)
+	}
+	answerAvps = append(answerAvps, unsigned32Avp(avpAuthSessionState, 0, noStateMaintained))
+	if res.experimental {
+		answerAvps = append(answerAvps,
+			groupedAvp(avpExperimentalResult, 0,
+				unsigned32Avp(avpVendorID, 0, vendorID3gpp),
+				unsigned32Avp(avpExperimentalResultCode, 0, res.code)),
+			utf8StringAvp(avpOriginHost, 0, s.originHost),
+			utf8StringAvp(avpOriginRealm, 0, s.originRealm))
+	} else {
+		answerAvps = append(answerAvps, s.resultAvps(res.code)...)
+	}
+	if res.code == resultSuccess {
+		answerAvps = append(answerAvps, avps...)
+	}
+	return request.answer(answerAvps...)
+}
+
+// problemResult maps the problem of a procedure to the result of the answer
+func problemResult(problemDetails *models.ProblemDetails, command uint32) result {
+	switch {
+	case problemDetails.Status == http.StatusNotFound:
+		return result{code: experimentalResultUserUnknown, experimental: true}
+	case problemDetails.Status == http.StatusBadRequest:
+		return result{code: resultInvalidAvpValue}
+	case problemDetails.Status == http.StatusForbidden && command == commandAuthenticationInformation:
+		return result{code: experimentalResultAuthenticationDataUnavailable, experimental: true}
+	default:
+		return result{code: resultUnableToComply}
+	}
+}
+
+// authenticationInformation answers the E-UTRAN vectors requested by the AIR (TS 29.272 5.2.3.1)
+func authenticationInformation(request *message, supi string) ([]avp, result) {
+	visitedPlmnID, res, ok := visitedPlmn(request)
+	if !ok {
+		return nil, res
+	}
+	requested, ok := request.find(avpRequestedEutranAuthenticationInfo, vendorID3gpp)
+	if !ok {
+		// the vectors of UTRAN and GERAN are not supported
+		return nil, result{code: experimentalResultAuthenticationDataUnavailable, experimental: true}
+	}
+	requestedAvps, err := requested.grouped()
+	if err != nil {
+		return nil, result{code: resultInvalidAvpValue}
+	}
+
+	authInfoRequest := producer.AuthenticationInfoRequest{ServingNetworkId: &visitedPlmnID}
+	if number, ok := findAvp(requestedAvps, avpNumberOfRequestedVectors, vendorID3gpp); ok {
+		count, err := number.unsigned32()
+		if err != nil || count > math.MaxInt32 {
+			return nil, result{code: resultInvalidAvpValue}
+		}
+		authInfoRequest.NumberOfRequestedVectors = int32(count)
+	}
+	if resync, ok := findAvp(requestedAvps, avpResynchronizationInfo, vendorID3gpp); ok {
+		if len(resync.data) != rsyncInfoLen {
+			return nil, result{code: resultInvalidAvpValue}
+		}
+		authInfoRequest.ResynchronizationInfo = &models.ResynchronizationInfo{
+			Rand: hex.EncodeToString(resync.data[:randLen]),
+			Auts: hex.EncodeToString(resync.data[randLen:]),
+		}
+	}
+
+	response, problemDetails := generateAuthData(authInfoRequest, supi)
+	if problemDetails != nil {
+		logger.S6aLog.Warnf("no vector for %s: %s", supi, problemDetails.Detail)
+		return nil, problemResult(problemDetails, commandAuthenticationInformation)
+	}
+
+	var vectors []avp
+	for i, av := range response.AuthenticationVectors {
+		vector, err := eutranVector(i+1, av)
+		if err != nil {
+			logger.S6aLog.Errorf("invalid vector of %s: %+v", supi, err)
+			return nil, result{code: resultUnableToComply}
+		}
+		vectors = append(vectors, vector)
+	}
+	return []avp{groupedAvp(avpAuthenticationInfo, vendorID3gpp, vectors...)}, result{code: resultSuccess}
+}
+
+// eutranVector is the E-UTRAN-Vector of TS 29.272 7.3.18 of an EPS-AKA vector
+func eutranVector(itemNumber int, av producer.AuthenticationVector) (avp, error) {
+	fields := []struct {
+		code  uint32
+		value string
+	}{
+		{avpRand, av.Rand},
+		{avpXres, av.Xres},
+		{avpAutn, av.Autn},
+		{avpKasme, av.Kasme},
+	}
+	avps := []avp{unsigned32Avp(avpItemNumber, vendorID3gpp, uint32(itemNumber))}
+	for _, field := range fields {
+		value, err := hex.DecodeString(field.value)
+		if err != nil || len(value) == 0 {
+			return avp{}, fmt.Errorf("invalid AVP %d of the vector %d", field.code, itemNumber)
+		}
+		avps = append(avps, octetStringAvp(field.code, vendorID3gpp, value))
+	}
+	return groupedAvp(avpEutranVector, vendorID3gpp, avps...), nil
+}
+
+// updateLocationRequest registers the MME of the ULR and answers the subscription data of the UE
+// (TS 29.272 5.2.1.1)
+func updateLocationRequest(request *message, supi string, mmeHost string) ([]avp, result) {
+	visitedPlmnID, res, ok := visitedPlmn(request)
+	if !ok {
+		return nil, res
+	}
+	var ulrFlags uint32
+	if flags, ok := request.find(avpUlrFlags, vendorID3gpp); ok {
+		var err error
+		if ulrFlags, err = flags.unsigned32(); err != nil {
+			return nil, result{code: resultInvalidAvpValue}
+		}
+	}
+
+	data, problemDetails := updateLocation(supi, mmeHost, visitedPlmnID, ulrFlags&ulrFlagInitialAttach != 0)
+	if problemDetails != nil {
+		logger.S6aLog.Warnf("update location of %s failed: %s", supi, problemDetails.Detail)
+		return nil, problemResult(problemDetails, commandUpdateLocation)
+	}
+	return []avp{
+		unsigned32Avp(avpUlaFlags, vendorID3gpp, 0),
+		subscriptionData(data),
+	}, result{code: resultSuccess}
+}
+
+// purgeUeRequest answers the PUR of the MME (TS 29.272 5.2.1.3)
+func purgeUeRequest(supi string, mmeHost string) ([]avp, result) {
+	purged, problemDetails := purgeUe(supi, mmeHost)
+	if problemDetails != nil {
+		logger.S6aLog.Warnf("purge of %s failed: %s", supi, problemDetails.Detail)
+		return nil, problemResult(problemDetails, commandPurgeUe)
+	}
+	var puaFlags uint32
+	if purged {
+		puaFlags |= puaFlagFreezeMTmsi
+	}
+	return []avp{unsigned32Avp(avpPuaFlags, vendorID3gpp, puaFlags)}, result{code: resultSuccess}
+}
+
+// visitedPlmn returns the Visited-PLMN-Id of the request
+func visitedPlmn(request *message) (models.PlmnId, result, bool) {
+	visited, ok := request.find(avpVisitedPlmnID, vendorID3gpp)
+	if !ok {
+		return models.PlmnId{}, result{code: resultMissingAvp}, false
+	}
+	plmnID, err := decodePlmnID(visited.data)
+	if err != nil {
+		logger.S6aLog.Warnln("invalid Visited-PLMN-Id:", err)
+		return models.PlmnId{}, result{code: resultInvalidAvpValue}, false
+	}
+	return plmnID, result{code: resultSuccess}, true
+}
+
+// decodePlmnID decodes the PLMN ID of TS 24.008 10.5.1.13 of the Visited-PLMN-Id
+func decodePlmnID(b []byte) (models.PlmnId, error) {
+	if len(b) != 3 {
+		return models.PlmnId{}, fmt.Errorf("invalid PLMN ID length %d", len(b))
+	}
+	digits := []byte{b[0] & 0x0f, b[0] >> 4, b[1] & 0x0f, b[2] & 0x0f, b[2] >> 4, b[1] >> 4}
+	if digits[5] == 0x0f {
+		digits = digits[:5]
+	}
+	for _, digit := range digits {
+		if digit > 9 {
+			return models.PlmnId{}, fmt.Errorf("invalid PLMN ID %x", b)
+		}
+	}
+	var sb strings.Builder
+	for _, digit := range digits {
+		sb.WriteByte('0' + digit)
+	}
+	plmnID := sb.String()
+	return models.PlmnId{Mcc: plmnID[:3], Mnc: plmnID[3:]}, nil
+}
+
+// subscriptionData is the Subscription-Data of TS 29.272 7.3.2, of a UE granted the packet services
+func subscriptionData(data *producer.EpsSubscriptionData) avp {
+	avps := []avp{
+		unsigned32Avp(avpSubscriberStatus, vendorID3gpp, subscriberStatusGranted),
+		unsigned32Avp(avpNetworkAccessMode, vendorID3gpp, networkAccessPacketOnly),
+	}
+	if amData := data.AmData; amData != nil {
+		if msisdn := msisdn(amData.Gpsis); msisdn != nil {
+			avps = append(avps, octetStringAvp(avpMsisdn, vendorID3gpp, msisdn))
+		}
+		if ambr := amData.SubscribedUeAmbr; ambr != nil {
+			avps = append(avps, ambrAvp(ambr.Uplink, ambr.Downlink))
+		}
+	}
+	if profile, ok := apnConfigurationProfile(data.SmData); ok {
+		avps = append(avps, profile)
+	}
+	return groupedAvp(avpSubscriptionData, vendorID3gpp, avps...)
+}
+
+// msisdn returns the MSISDN of the GPSIs in the TBCD of TS 29.329 6.3.2
+func msisdn(gpsis []string) []byte {
+	for _, gpsi := range gpsis {
+		digits, ok := strings.CutPrefix(gpsi, "msisdn-")
+		if !ok || digits == "" || !isDigits(digits) {
+			continue
+		}
+		tbcd := make([]byte, (len(digits)+1)/2)
+		for i := range tbcd {
+			low := digits[2*i] - '0'
+			high := byte(0x0f)
+			if 2*i+1 < len(digits) {
+				high = digits[2*i+1] - '0'
+			}
+			tbcd[i] = high<<4 | low
+		}
+		return tbcd
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// apnConfigurationProfile is the APN-Configuration-Profile of TS 29.272 7.3.34 of the DNNs of the
+// session management subscription data, whose first DNN in alphabetical order is the default
+func apnConfigurationProfile(smData []models.SessionManagementSubscriptionData) (avp, bool) {
+	configurations := make(map[string]models.DnnConfiguration)
+	for _, data := range smData {
+		for dnn, configuration := range data.DnnConfigurations {
+			if _, ok := configurations[dnn]; !ok {
+				configurations[dnn] = configuration
+			}
+		}
+	}
+	if len(configurations) == 0 {
+		return avp{}, false
+	}
+	dnns := make([]string, 0, len(configurations))
+	for dnn := range configurations {
+		dnns = append(dnns, dnn)
+	}
+	sort.Strings(dnns)
+
+	avps := []avp{
+		unsigned32Avp(avpContextIdentifier, vendorID3gpp, 1),
+		unsigned32Avp(avpAllApnConfigurationsIncludedInd, vendorID3gpp, allApnConfigurations),
+	}
+	for i, dnn := range dnns {
+		avps = append(avps, apnConfiguration(uint32(i+1), dnn, configurations[dnn]))
+	}
+	return groupedAvp(avpApnConfigurationProfile, vendorID3gpp, avps...), true
+}
+
+// apnConfiguration is the APN-Configuration of TS 29.272 7.3.35 of a DNN
+func apnConfiguration(contextID uint32, dnn string, configuration models.DnnConfiguration) avp {
+	avps := []avp{
+		unsigned32Avp(avpContextIdentifier, vendorID3gpp, contextID),
+		unsigned32Avp(avpPdnType, vendorID3gpp, pdnType(configuration.PduSessionTypes)),
+		utf8StringAvp(avpServiceSelection, 0, dnn),
+	}
+	if qos := configuration.Var5gQosProfile; qos != nil {
+		var arp []avp
+		if qos.Arp != nil {
+			arp = []avp{
+				unsigned32Avp(avpPriorityLevel, vendorID3gpp, uint32(qos.Arp.PriorityLevel)),
+				unsigned32Avp(avpPreemptionCapability, vendorID3gpp,
+					preemption(qos.Arp.PreemptCap == models.PreemptionCapability_MAY_PREEMPT)),
+				unsigned32Avp(avpPreemptionVulnerability, vendorID3gpp,
+					preemption(qos.Arp.PreemptVuln == models.PreemptionVulnerability_PREEMPTABLE)),
+			}
+		}
+		avps = append(avps, groupedAvp(avpEpsSubscribedQosProfile, vendorID3gpp,
+			unsigned32Avp(avpQosClassIdentifier, vendorID3gpp, uint32(qos.Var5qi)),
+			groupedAvp(avpAllocationRetentionPriority, vendorID3gpp, arp...)))
+	}
+	if ambr := configuration.SessionAmbr; ambr != nil {
+		avps = append(avps, ambrAvp(ambr.Uplink, ambr.Downlink))
+	}
+	return groupedAvp(avpApnConfiguration, vendorID3gpp, avps...)
+}
+
+func pdnType(pduSessionTypes *models.PduSessionTypes) uint32 {
+	if pduSessionTypes == nil {
+		return pdnTypeIPv4
+	}
+	switch pduSessionTypes.DefaultSessionType {
+	case models.PduSessionType_IPV6:
+		return pdnTypeIPv6
+	case models.PduSessionType_IPV4_V6:
+		return pdnTypeIPv4v6
+	default:
+		return pdnTypeIPv4
+	}
+}
+
+func preemption(enabled bool) uint32 {
+	if enabled {
+		return preemptionEnabled
+	}
+	return preemptionDisabled
+}
+
+// ambrAvp is the AMBR of TS 29.272 7.3.41 of the bit rates of TS 29.571 5.5.2
+func ambrAvp(uplink string, downlink string) avp {
+	return groupedAvp(avpAmbr, vendorID3gpp,
+		unsigned32Avp(avpMaxRequestedBandwidthUL, vendorID3gpp, bitRate(uplink)),
+		unsigned32Avp(avpMaxRequestedBandwidthDL, vendorID3gpp, bitRate(downlink)))
+}
+
+var bitRatePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?) (bps|Kbps|Mbps|Gbps|Tbps)$`)
+
+var bitRateUnits = map[string]float64{
+	"bps":  1,
+	"Kbps": 1e3,
+	"Mbps": 1e6,
+	"Gbps": 1e9,
+	"Tbps": 1e12,
+}
+
+// bitRate returns the bit rate in bits per second, bounded by the Unsigned32 of the AVP, or 0 when invalid
+func bitRate(s string) uint32 {
+	match := bitRatePattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0
+	}
+	return uint32(math.Min(value*bitRateUnits[match[2]], math.MaxUint32))
+}
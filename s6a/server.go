@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+/*
+ * S6a front end of the UDM, serving the MMEs of E-UTRAN as an HSS (TS 29.272)
+ */
+
+package s6a
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/omec-project/udm/logger"
+)
+
+// Base protocol commands and AVPs (RFC 6733)
+const (
+	commandCapabilitiesExchange uint32 = 257
+	commandDeviceWatchdog       uint32 = 280
+	commandDisconnectPeer       uint32 = 282
+
+	avpUserName                    uint32 = 1
+	avpHostIPAddress               uint32 = 257
+	avpAuthApplicationID           uint32 = 258
+	avpVendorSpecificApplicationID uint32 = 260
+	avpSessionID                   uint32 = 263
+	avpOriginHost                  uint32 = 264
+	avpSupportedVendorID           uint32 = 265
+	avpVendorID                    uint32 = 266
+	avpResultCode                  uint32 = 268
+	avpProductName                 uint32 = 269
+	avpAuthSessionState            uint32 = 277
+	avpOriginRealm                 uint32 = 296
+	avpExperimentalResult          uint32 = 297
+	avpExperimentalResultCode      uint32 = 298
+)
+
+// Result codes (RFC 6733 7.1)
+const (
+	resultSuccess                uint32 = 2001
+	resultCommandUnsupported     uint32 = 3001
+	resultApplicationUnsupported uint32 = 3007
+	resultInvalidAvpValue        uint32 = 5004
+	resultMissingAvp             uint32 = 5005
+	resultUnableToComply         uint32 = 5012
+)
+
+const (
+	productName          = "UDM"
+	noStateMaintained    = 1 // Auth-Session-State
+	DefaultListenAddress = ":3868"
+	DefaultOriginHost    = "udm"
+	DefaultOriginRealm   = "epc"
+)
+
+// Server is a Diameter server accepting the connections of the MMEs
+type Server struct {
+	originHost  string
+	originRealm string
+
+	lock     sync.Mutex
+	listener net.Listener
+}
+
+// NewServer returns the server of the identity, DefaultOriginHost and DefaultOriginRealm when empty
+func NewServer(originHost string, originRealm string) *Server {
+	if originHost == "" {
+		originHost = DefaultOriginHost
+	}
+	if originRealm == "" {
+		originRealm = DefaultOriginRealm
+	}
+	return &Server{originHost: originHost, originRealm: originRealm}
+}
+
+// ListenAndServe serves the connections of the address until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves the connections of the listener until ctx is done
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	s.lock.Lock()
+	s.listener = listener
+	s.lock.Unlock()
+	logger.S6aLog.Infof("S6a front end listening on %s as %s", listener.Addr(), s.originHost)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	stop := context.AfterFunc(ctx, func() {
+		if err := listener.Close(); err != nil {
+			logger.S6aLog.Warnf("failed to close the listener: %+v", err)
+		}
+	})
+	defer stop()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.S6aLog.Infoln("S6a front end shutting down")
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			newPeer(s, conn).serve(ctx)
+		}()
+	}
+}
+
+// Addr returns the address of the listener, once serving
+func (s *Server) Addr() net.Addr {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// peer is the connection of an MME
+type peer struct {
+	server *Server
+	conn   net.Conn
+
+	writeLock sync.Mutex
+	requests  sync.WaitGroup
+}
+
+func newPeer(server *Server, conn net.Conn) *peer {
+	return &peer{server: server, conn: conn}
+}
+
+// serve handles the requests of the peer until it disconnects or ctx is done. The S6a requests are
+// handled concurrently, the base protocol ones in order.
+func (p *peer) serve(ctx context.Context) {
+	stop := context.AfterFunc(ctx, func() { p.close() })
+	defer stop()
+	defer p.requests.Wait()
+	defer p.close()
+	for {
+		request, err := readMessage(p.conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.S6aLog.Errorf("failed to read the message of %s: %+v", p.conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if !request.isRequest() {
+			logger.S6aLog.Warnf("unexpected answer %d from %s", request.commandCode, p.conn.RemoteAddr())
+			continue
+		}
+
+		switch {
+		case request.commandCode == commandCapabilitiesExchange:
+			p.write(p.capabilitiesExchangeAnswer(request))
+		case request.commandCode == commandDeviceWatchdog:
+			p.write(request.answer(p.server.resultAvps(resultSuccess)...))
+		case request.commandCode == commandDisconnectPeer:
+			p.write(request.answer(p.server.resultAvps(resultSuccess)...))
+			return
+		case request.applicationID == applicationIDS6a:
+			p.requests.Add(1)
+			go func() {
+				defer p.requests.Done()
+				p.write(p.server.handle(request))
+			}()
+		default:
+			p.write(p.server.errorAnswer(request, resultApplicationUnsupported))
+		}
+	}
+}
+
+func (p *peer) write(answer *message) {
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+	if _, err := p.conn.Write(answer.encode()); err != nil {
+		logger.S6aLog.Errorf("failed to write the answer to %s: %+v", p.conn.RemoteAddr(), err)
+	}
+}
+
+func (p *peer) close() {
+	if err := p.conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.S6aLog.Warnf("failed to close the connection of %s: %+v", p.conn.RemoteAddr(), err)
+	}
+}
+
+// capabilitiesExchangeAnswer advertises the S6a application (RFC 6733 5.3.2)
+func (p *peer) capabilitiesExchangeAnswer(request *message) *message {
+	var ip net.IP
+	if address, ok := p.conn.LocalAddr().(*net.TCPAddr); ok {
+		ip = address.IP
+	}
+	if ip == nil || ip.IsUnspecified() {
+		ip = net.IPv4(127, 0, 0, 1)
+	}
+	if originHost, ok := request.find(avpOriginHost, 0); ok {
+		logger.S6aLog.Infof("capabilities exchanged with %s", originHost.data)
+	}
+	return request.answer(append(p.server.resultAvps(resultSuccess),
+		addressAvp(avpHostIPAddress, ip),
+		unsigned32Avp(avpVendorID, 0, vendorID3gpp),
+		utf8StringAvp(avpProductName, 0, productName),
+		unsigned32Avp(avpSupportedVendorID, 0, vendorID3gpp),
+		groupedAvp(avpVendorSpecificApplicationID, 0,
+			unsigned32Avp(avpVendorID, 0, vendorID3gpp),
+			unsigned32Avp(avpAuthApplicationID, 0, applicationIDS6a)),
+	)...)
+}
+
+// resultAvps are the Result-Code and the identity of the server
+func (s *Server) resultAvps(resultCode uint32) []avp {
+	return []avp{
+		unsigned32Avp(avpResultCode, 0, resultCode),
+		utf8StringAvp(avpOriginHost, 0, s.originHost),
+		utf8StringAvp(avpOriginRealm, 0, s.originRealm),
+	}
+}
+
+// errorAnswer is the answer with the E bit of a protocol error (RFC 6733 7.2)
+func (s *Server) errorAnswer(request *message, resultCode uint32) *message {
+	var avps []avp
+	if sessionID, ok := request.find(avpSessionID, 0); ok {
+		avps = append(avps, sessionID)
+	}
+	answer := request.answer(append(avps, s.resultAvps(resultCode)...)...)
+	answer.flags |= flagError
+	return answer
+}
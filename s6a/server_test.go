@@ -0,0 +1,538 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package s6a
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/producer"
+)
+
+const (
+	testImsi      = "001010000000001"
+	testMmeHost   = "mme.epc"
+	testRand      = "000102030405060708090a0b0c0d0e0f"
+	testXres      = "1011121314151617"
+	testAutn      = "202122232425262728292a2b2c2d2e2f"
+	testKasme     = "303132333435363738393a3b3c3d3e3f303132333435363738393a3b3c3d3e3f"
+	testAuts      = "404142434445464748494a4b4c4d"
+	testHopByHop  = 7
+	testEndToEnd  = 9
+	testSessionID = "mme.epc;1;1"
+)
+
+// mme is a local peer standing in for an MME
+type mme struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func startServer(t *testing.T) *mme {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %+v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	server := NewServer("udm.epc", "")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := server.Serve(ctx, listener); err != nil {
+			t.Errorf("Serve failed: %+v", err)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %+v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+		if err := conn.Close(); err != nil {
+			t.Logf("close failed: %+v", err)
+		}
+	})
+	return &mme{t: t, conn: conn}
+}
+
+func (m *mme) exchange(request *message) *message {
+	m.t.Helper()
+	request.flags |= flagRequest | flagProxiable
+	request.hopByHopID, request.endToEndID = testHopByHop, testEndToEnd
+	if err := m.conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		m.t.Fatalf("SetDeadline failed: %+v", err)
+	}
+	if _, err := m.conn.Write(request.encode()); err != nil {
+		m.t.Fatalf("write failed: %+v", err)
+	}
+	answer, err := readMessage(m.conn)
+	if err != nil {
+		m.t.Fatalf("read failed: %+v", err)
+	}
+	if answer.isRequest() || answer.commandCode != request.commandCode ||
+		answer.hopByHopID != testHopByHop || answer.endToEndID != testEndToEnd {
+		m.t.Fatalf("unexpected answer %+v", answer)
+	}
+	return answer
+}
+
+func s6aRequest(commandCode uint32, avps ...avp) *message {
+	return &message{
+		commandCode:   commandCode,
+		applicationID: applicationIDS6a,
+		avps: append([]avp{
+			utf8StringAvp(avpSessionID, 0, testSessionID),
+			utf8StringAvp(avpOriginHost, 0, testMmeHost),
+			utf8StringAvp(avpOriginRealm, 0, "epc"),
+			utf8StringAvp(avpUserName, 0, testImsi),
+		}, avps...),
+	}
+}
+
+func visitedPlmnAvp() avp {
+	return octetStringAvp(avpVisitedPlmnID, vendorID3gpp, []byte{0x00, 0xf1, 0x10})
+}
+
+func unsigned32Of(t *testing.T, avps []avp, code uint32, vendorID uint32) uint32 {
+	t.Helper()
+	a, ok := findAvp(avps, code, vendorID)
+	if !ok {
+		t.Fatalf("AVP %d not found", code)
+	}
+	value, err := a.unsigned32()
+	if err != nil {
+		t.Fatalf("AVP %d: %+v", code, err)
+	}
+	return value
+}
+
+func groupedOf(t *testing.T, avps []avp, code uint32) []avp {
+	t.Helper()
+	a, ok := findAvp(avps, code, vendorID3gpp)
+	if !ok {
+		t.Fatalf("AVP %d not found", code)
+	}
+	grouped, err := a.grouped()
+	if err != nil {
+		t.Fatalf("AVP %d: %+v", code, err)
+	}
+	return grouped
+}
+
+func experimentalResultCode(t *testing.T, answer *message) uint32 {
+	t.Helper()
+	a, ok := answer.find(avpExperimentalResult, 0)
+	if !ok {
+		t.Fatal("Experimental-Result not found")
+	}
+	grouped, err := a.grouped()
+	if err != nil {
+		t.Fatalf("Experimental-Result: %+v", err)
+	}
+	if vendorID := unsigned32Of(t, grouped, avpVendorID, 0); vendorID != vendorID3gpp {
+		t.Fatalf("Vendor-Id %d", vendorID)
+	}
+	return unsigned32Of(t, grouped, avpExperimentalResultCode, 0)
+}
+
+func TestServer_BaseProtocol(t *testing.T) {
+	peer := startServer(t)
+
+	answer := peer.exchange(&message{commandCode: commandCapabilitiesExchange, avps: []avp{
+		utf8StringAvp(avpOriginHost, 0, testMmeHost),
+		utf8StringAvp(avpOriginRealm, 0, "epc"),
+	}})
+	if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != resultSuccess {
+		t.Fatalf("CEA Result-Code %d", code)
+	}
+	if realm, ok := answer.find(avpOriginRealm, 0); !ok || string(realm.data) != DefaultOriginRealm {
+		t.Fatalf("CEA Origin-Realm %q", realm.data)
+	}
+	application, ok := answer.find(avpVendorSpecificApplicationID, 0)
+	if !ok {
+		t.Fatal("CEA without Vendor-Specific-Application-Id")
+	}
+	grouped, err := application.grouped()
+	if err != nil {
+		t.Fatalf("Vendor-Specific-Application-Id: %+v", err)
+	}
+	if id := unsigned32Of(t, grouped, avpAuthApplicationID, 0); id != applicationIDS6a {
+		t.Fatalf("Auth-Application-Id %d", id)
+	}
+
+	answer = peer.exchange(&message{commandCode: commandDeviceWatchdog})
+	if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != resultSuccess {
+		t.Fatalf("DWA Result-Code %d", code)
+	}
+
+	answer = peer.exchange(&message{commandCode: 272, applicationID: 4})
+	if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != resultApplicationUnsupported ||
+		answer.flags&flagError == 0 {
+		t.Fatalf("answer of another application: Result-Code %d, flags %x", code, answer.flags)
+	}
+
+	answer = peer.exchange(s6aRequest(319))
+	if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != resultCommandUnsupported ||
+		answer.flags&flagError == 0 {
+		t.Fatalf("answer of an unsupported command: Result-Code %d, flags %x", code, answer.flags)
+	}
+
+	answer = peer.exchange(&message{commandCode: commandDisconnectPeer})
+	if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != resultSuccess {
+		t.Fatalf("DPA Result-Code %d", code)
+	}
+	if _, err := readMessage(peer.conn); err == nil {
+		t.Fatal("connection not closed after the DPA")
+	}
+}
+
+func TestServer_AuthenticationInformation(t *testing.T) {
+	origGenerateAuthData := generateAuthData
+	defer func() { generateAuthData = origGenerateAuthData }()
+
+	var received producer.AuthenticationInfoRequest
+	var receivedSupi string
+	generateAuthData = func(authInfoRequest producer.AuthenticationInfoRequest, supi string) (
+		*producer.AuthenticationInfoResult, *models.ProblemDetails,
+	) {
+		received, receivedSupi = authInfoRequest, supi
+		if supi != "imsi-"+testImsi {
+			return nil, &models.ProblemDetails{Status: http.StatusForbidden}
+		}
+		av := producer.AuthenticationVector{Kasme: testKasme}
+		av.Rand, av.Xres, av.Autn = testRand, testXres, testAutn
+		return &producer.AuthenticationInfoResult{
+			AuthenticationVectors: []producer.AuthenticationVector{av, av},
+		}, nil
+	}
+	peer := startServer(t)
+
+	resync, err := hex.DecodeString(testRand + testAuts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer := peer.exchange(s6aRequest(commandAuthenticationInformation,
+		visitedPlmnAvp(),
+		groupedAvp(avpRequestedEutranAuthenticationInfo, vendorID3gpp,
+			unsigned32Avp(avpNumberOfRequestedVectors, vendorID3gpp, 2),
+			octetStringAvp(avpResynchronizationInfo, vendorID3gpp, resync)),
+	))
+	if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != resultSuccess {
+		t.Fatalf("AIA Result-Code %d", code)
+	}
+	if sessionID, ok := answer.find(avpSessionID, 0); !ok || string(sessionID.data) != testSessionID {
+		t.Fatalf("AIA Session-Id %q", sessionID.data)
+	}
+	if receivedSupi != "imsi-"+testImsi || received.NumberOfRequestedVectors != 2 ||
+		received.ServingNetworkId == nil || *received.ServingNetworkId != (models.PlmnId{Mcc: "001", Mnc: "01"}) {
+		t.Fatalf("unexpected request %+v of %s", received, receivedSupi)
+	}
+	if received.ResynchronizationInfo == nil || received.ResynchronizationInfo.Rand != testRand ||
+		received.ResynchronizationInfo.Auts != testAuts {
+		t.Fatalf("unexpected resynchronization %+v", received.ResynchronizationInfo)
+	}
+
+	authenticationInfo := groupedOf(t, answer.avps, avpAuthenticationInfo)
+	if len(authenticationInfo) != 2 {
+		t.Fatalf("%d E-UTRAN vectors, want 2", len(authenticationInfo))
+	}
+	for i, vector := range authenticationInfo {
+		fields, err := vector.grouped()
+		if err != nil {
+			t.Fatalf("E-UTRAN-Vector: %+v", err)
+		}
+		if item := unsigned32Of(t, fields, avpItemNumber, vendorID3gpp); item != uint32(i+1) {
+			t.Fatalf("Item-Number %d, want %d", item, i+1)
+		}
+		for code, want := range map[uint32]string{
+			avpRand: testRand, avpXres: testXres, avpAutn: testAutn, avpKasme: testKasme,
+		} {
+			field, ok := findAvp(fields, code, vendorID3gpp)
+			if !ok || hex.EncodeToString(field.data) != want {
+				t.Fatalf("AVP %d of the vector %x, want %s", code, field.data, want)
+			}
+		}
+	}
+
+	unknown := s6aRequest(commandAuthenticationInformation, visitedPlmnAvp(),
+		groupedAvp(avpRequestedEutranAuthenticationInfo, vendorID3gpp))
+	unknown.avps[3] = utf8StringAvp(avpUserName, 0, "001010000000002")
+	answer = peer.exchange(unknown)
+	if code := experimentalResultCode(t, answer); code != experimentalResultAuthenticationDataUnavailable {
+		t.Fatalf("Experimental-Result-Code %d", code)
+	}
+	if _, ok := answer.find(avpAuthenticationInfo, vendorID3gpp); ok {
+		t.Fatal("Authentication-Info in a failed answer")
+	}
+}
+
+func TestServer_AuthenticationInformation_Invalid(t *testing.T) {
+	origGenerateAuthData := generateAuthData
+	defer func() { generateAuthData = origGenerateAuthData }()
+	generateAuthData = func(producer.AuthenticationInfoRequest, string) (
+		*producer.AuthenticationInfoResult, *models.ProblemDetails,
+	) {
+		t.Error("unexpected vector generation")
+		return nil, &models.ProblemDetails{Status: http.StatusInternalServerError}
+	}
+	peer := startServer(t)
+
+	requested := groupedAvp(avpRequestedEutranAuthenticationInfo, vendorID3gpp)
+	testCases := []struct {
+		name    string
+		request *message
+		result  uint32
+	}{
+		{
+			name:    "no User-Name",
+			request: &message{commandCode: commandAuthenticationInformation, applicationID: applicationIDS6a},
+			result:  resultMissingAvp,
+		},
+		{
+			name:    "no Visited-PLMN-Id",
+			request: s6aRequest(commandAuthenticationInformation, requested),
+			result:  resultMissingAvp,
+		},
+		{
+			name: "invalid Visited-PLMN-Id",
+			request: s6aRequest(commandAuthenticationInformation, requested,
+				octetStringAvp(avpVisitedPlmnID, vendorID3gpp, []byte{0xaa, 0xf1, 0x10})),
+			result: resultInvalidAvpValue,
+		},
+		{
+			name: "invalid Re-Synchronization-Info",
+			request: s6aRequest(commandAuthenticationInformation, visitedPlmnAvp(),
+				groupedAvp(avpRequestedEutranAuthenticationInfo, vendorID3gpp,
+					octetStringAvp(avpResynchronizationInfo, vendorID3gpp, []byte{1, 2, 3}))),
+			result: resultInvalidAvpValue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			answer := peer.exchange(tc.request)
+			if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != tc.result {
+				t.Fatalf("Result-Code %d, want %d", code, tc.result)
+			}
+		})
+	}
+}
+
+func TestServer_UpdateLocation(t *testing.T) {
+	origUpdateLocation := updateLocation
+	defer func() { updateLocation = origUpdateLocation }()
+
+	var receivedMme string
+	var receivedInitialAttach bool
+	updateLocation = func(supi string, mmeHost string, visitedPlmnId models.PlmnId, initialAttach bool) (
+		*producer.EpsSubscriptionData, *models.ProblemDetails,
+	) {
+		receivedMme, receivedInitialAttach = mmeHost, initialAttach
+		if supi != "imsi-"+testImsi {
+			return nil, &models.ProblemDetails{Status: http.StatusNotFound}
+		}
+		return &producer.EpsSubscriptionData{
+			AmData: &models.AccessAndMobilitySubscriptionData{
+				Gpsis:            []string{"msisdn-123456789"},
+				SubscribedUeAmbr: &models.AmbrRm{Uplink: "1 Gbps", Downlink: "2.5 Mbps"},
+			},
+			SmData: []models.SessionManagementSubscriptionData{{
+				DnnConfigurations: map[string]models.DnnConfiguration{
+					"internet": {
+						PduSessionTypes: &models.PduSessionTypes{DefaultSessionType: models.PduSessionType_IPV4_V6},
+						Var5gQosProfile: &models.SubscribedDefaultQos{
+							Var5qi: 9,
+							Arp: &models.Arp{
+								PriorityLevel: 8,
+								PreemptCap:    models.PreemptionCapability_NOT_PREEMPT,
+								PreemptVuln:   models.PreemptionVulnerability_PREEMPTABLE,
+							},
+						},
+						SessionAmbr: &models.Ambr{Uplink: "100 Mbps", Downlink: "200 Mbps"},
+					},
+					"ims": {},
+				},
+			}},
+		}, nil
+	}
+	peer := startServer(t)
+
+	answer := peer.exchange(s6aRequest(commandUpdateLocation, visitedPlmnAvp(),
+		unsigned32Avp(avpUlrFlags, vendorID3gpp, ulrFlagInitialAttach)))
+	if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != resultSuccess {
+		t.Fatalf("ULA Result-Code %d", code)
+	}
+	if receivedMme != testMmeHost || !receivedInitialAttach {
+		t.Fatalf("registration of %s, initial attach %v", receivedMme, receivedInitialAttach)
+	}
+
+	subscription := groupedOf(t, answer.avps, avpSubscriptionData)
+	if status := unsigned32Of(t, subscription, avpSubscriberStatus, vendorID3gpp); status != subscriberStatusGranted {
+		t.Fatalf("Subscriber-Status %d", status)
+	}
+	msisdn, ok := findAvp(subscription, avpMsisdn, vendorID3gpp)
+	if !ok || !bytes.Equal(msisdn.data, []byte{0x21, 0x43, 0x65, 0x87, 0xf9}) {
+		t.Fatalf("MSISDN %x", msisdn.data)
+	}
+	ambr := groupedOf(t, subscription, avpAmbr)
+	if ul := unsigned32Of(t, ambr, avpMaxRequestedBandwidthUL, vendorID3gpp); ul != 1000000000 {
+		t.Fatalf("Max-Requested-Bandwidth-UL %d", ul)
+	}
+	if dl := unsigned32Of(t, ambr, avpMaxRequestedBandwidthDL, vendorID3gpp); dl != 2500000 {
+		t.Fatalf("Max-Requested-Bandwidth-DL %d", dl)
+	}
+
+	profile := groupedOf(t, subscription, avpApnConfigurationProfile)
+	var apns []string
+	for _, a := range profile {
+		if a.code != avpApnConfiguration {
+			continue
+		}
+		configuration, err := a.grouped()
+		if err != nil {
+			t.Fatalf("APN-Configuration: %+v", err)
+		}
+		apn, _ := findAvp(configuration, avpServiceSelection, 0)
+		apns = append(apns, string(apn.data))
+		if string(apn.data) != "internet" {
+			continue
+		}
+		if pdnType := unsigned32Of(t, configuration, avpPdnType, vendorID3gpp); pdnType != pdnTypeIPv4v6 {
+			t.Fatalf("PDN-Type %d", pdnType)
+		}
+		qos := groupedOf(t, configuration, avpEpsSubscribedQosProfile)
+		if qci := unsigned32Of(t, qos, avpQosClassIdentifier, vendorID3gpp); qci != 9 {
+			t.Fatalf("QCI %d", qci)
+		}
+		arp := groupedOf(t, qos, avpAllocationRetentionPriority)
+		if unsigned32Of(t, arp, avpPriorityLevel, vendorID3gpp) != 8 ||
+			unsigned32Of(t, arp, avpPreemptionCapability, vendorID3gpp) != preemptionDisabled ||
+			unsigned32Of(t, arp, avpPreemptionVulnerability, vendorID3gpp) != preemptionEnabled {
+			t.Fatalf("unexpected ARP %+v", arp)
+		}
+	}
+	if len(apns) != 2 || apns[0] != "ims" || apns[1] != "internet" {
+		t.Fatalf("APNs %v", apns)
+	}
+
+	unknown := s6aRequest(commandUpdateLocation, visitedPlmnAvp())
+	unknown.avps[3] = utf8StringAvp(avpUserName, 0, "001010000000002")
+	answer = peer.exchange(unknown)
+	if code := experimentalResultCode(t, answer); code != experimentalResultUserUnknown {
+		t.Fatalf("Experimental-Result-Code %d", code)
+	}
+	if receivedInitialAttach {
+		t.Fatal("initial attach without the ULR-Flags")
+	}
+}
+
+func TestServer_PurgeUe(t *testing.T) {
+	origPurgeUe := purgeUe
+	defer func() { purgeUe = origPurgeUe }()
+
+	testCases := []struct {
+		name           string
+		purged         bool
+		problemDetails *models.ProblemDetails
+		result         uint32
+		puaFlags       uint32
+	}{
+		{
+			name:     "purged",
+			purged:   true,
+			result:   resultSuccess,
+			puaFlags: puaFlagFreezeMTmsi,
+		},
+		{
+			name:   "other MME",
+			result: resultSuccess,
+		},
+		{
+			name:           "failure",
+			problemDetails: &models.ProblemDetails{Status: http.StatusInternalServerError},
+			result:         resultUnableToComply,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			purgeUe = func(supi string, mmeHost string) (bool, *models.ProblemDetails) {
+				if supi != "imsi-"+testImsi || mmeHost != testMmeHost {
+					t.Errorf("purge of %s by %s", supi, mmeHost)
+				}
+				return tc.purged, tc.problemDetails
+			}
+			peer := startServer(t)
+
+			answer := peer.exchange(s6aRequest(commandPurgeUe))
+			if code := unsigned32Of(t, answer.avps, avpResultCode, 0); code != tc.result {
+				t.Fatalf("Result-Code %d, want %d", code, tc.result)
+			}
+			if tc.result != resultSuccess {
+				return
+			}
+			if flags := unsigned32Of(t, answer.avps, avpPuaFlags, vendorID3gpp); flags != tc.puaFlags {
+				t.Fatalf("PUA-Flags %d, want %d", flags, tc.puaFlags)
+			}
+		})
+	}
+}
+
+func TestDecodePlmnID(t *testing.T) {
+	testCases := []struct {
+		input   []byte
+		plmnID  models.PlmnId
+		invalid bool
+	}{
+		{input: []byte{0x00, 0xf1, 0x10}, plmnID: models.PlmnId{Mcc: "001", Mnc: "01"}},
+		{input: []byte{0x13, 0x00, 0x62}, plmnID: models.PlmnId{Mcc: "310", Mnc: "260"}},
+		{input: []byte{0x00, 0xf1}, invalid: true},
+		{input: []byte{0x0a, 0xf1, 0x10}, invalid: true},
+	}
+
+	for _, tc := range testCases {
+		plmnID, err := decodePlmnID(tc.input)
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("%x: expected an error", tc.input)
+			}
+			continue
+		}
+		if err != nil || plmnID != tc.plmnID {
+			t.Errorf("%x: %+v, %v, want %+v", tc.input, plmnID, err, tc.plmnID)
+		}
+	}
+}
+
+func TestBitRate(t *testing.T) {
+	testCases := map[string]uint32{
+		"100 bps":   100,
+		"1.5 Kbps":  1500,
+		"10 Gbps":   4294967295,
+		"2 Tbps":    4294967295,
+		"100":       0,
+		"1 Mbit/s":  0,
+		"":          0,
+		"12.5 Mbps": 12500000,
+	}
+
+	for input, want := range testCases {
+		if got := bitRate(input); got != want {
+			t.Errorf("bitRate(%q) = %d, want %d", input, got, want)
+		}
+	}
+}
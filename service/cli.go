@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	udmContext "github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/factory"
+	"github.com/omec-project/udm/util"
+	utilLogger "github.com/omec-project/util/logger"
+	"github.com/urfave/cli/v3"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// GetCliSubcommands returns the subcommands managing the home network keys of the SUCIs
+func (udm *UDM) GetCliSubcommands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:      "keygen",
+			Usage:     "generate a home network key pair, printed as the keys section of the config",
+			UsageText: "udm keygen [--scheme profileA|profileB] [--key-id <id>]",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "scheme", Usage: "protection scheme, profileA or profileB", Value: "profileA"},
+				&cli.IntFlag{Name: "key-id", Usage: "home network public key ID", Value: 1},
+			},
+			Action: keygen,
+		},
+		{
+			Name:      "suci-decode",
+			Usage:     "de-conceal a SUCI with the keys of the config",
+			UsageText: "udm suci-decode --cfg <udm_config_file.conf> <suci>",
+			Before:    quietLibrary,
+			Action:    suciDecode,
+		},
+		{
+			Name:  "supi-to-suci",
+			Usage: "conceal an IMSI into a SUCI, for testing",
+			UsageText: "udm supi-to-suci [--scheme null|profileA|profileB] [--key-id <id>] " +
+				"[--public-key <hex> | --cfg <udm_config_file.conf>] <imsi>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "scheme", Usage: "protection scheme, null, profileA or profileB", Value: "profileA"},
+				&cli.IntFlag{Name: "key-id", Usage: "home network public key ID", Value: 1},
+				&cli.StringFlag{
+					Name:  "public-key",
+					Usage: "home network public key in hex, the one of the key ID in the config by default",
+				},
+				&cli.IntFlag{Name: "mnc-length", Usage: "number of digits of the MNC", Value: 2},
+				&cli.StringFlag{Name: "routing-indicator", Usage: "routing indicator", Value: "0000"},
+			},
+			Action: supiToSuci,
+		},
+	}
+}
+
+// keysSection is the keys section of the config
+type keysSection struct {
+	Keys factory.Keys `yaml:"keys"`
+}
+
+func keygen(ctx context.Context, c *cli.Command) error {
+	scheme, err := util.SuciScheme(c.String("scheme"))
+	if err != nil {
+		return err
+	}
+	keyID := c.Int("key-id")
+	if keyID < 1 || keyID > 255 {
+		return fmt.Errorf("key ID %d out of range [1, 255]", keyID)
+	}
+	privateKey, publicKey, err := udmContext.NewSuciKeyPair(scheme)
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(keysSection{Keys: factory.Keys{
+		HomeNetworkKeys: []factory.HomeNetworkKey{{
+			KeyId:      keyID,
+			Scheme:     c.String("scheme"),
+			PrivateKey: privateKey,
+			PublicKey:  publicKey,
+		}},
+	}})
+	if err != nil {
+		return err
+	}
+	_, err = c.Root().Writer.Write(out)
+	return err
+}
+
+func suciDecode(ctx context.Context, c *cli.Command) error {
+	if c.NArg() != 1 {
+		return errors.New("expected one SUCI")
+	}
+	keyRing, err := configuredSuciKeyRing(c)
+	if err != nil {
+		return err
+	}
+	supi, err := keyRing.ToSupi(c.Args().First())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.Root().Writer, supi)
+	return err
+}
+
+func supiToSuci(ctx context.Context, c *cli.Command) error {
+	if c.NArg() != 1 {
+		return errors.New("expected one IMSI")
+	}
+	imsi := strings.TrimPrefix(c.Args().First(), "imsi-")
+	mncLength := c.Int("mnc-length")
+	if mncLength != 2 && mncLength != 3 {
+		return fmt.Errorf("invalid MNC length %d", mncLength)
+	}
+	if len(imsi) <= 3+mncLength {
+		return fmt.Errorf("invalid IMSI %s", imsi)
+	}
+	scheme, err := util.SuciScheme(c.String("scheme"))
+	if err != nil {
+		return err
+	}
+
+	keyID, publicKey := c.Int("key-id"), c.String("public-key")
+	if scheme != udmContext.NullScheme && publicKey == "" {
+		keyRing, err := configuredSuciKeyRing(c)
+		if err != nil {
+			return fmt.Errorf("no public key: %w", err)
+		}
+		key, ok := keyRing.Key(keyID)
+		if !ok || key.PublicKey == "" {
+			return fmt.Errorf("no public key of key ID %d in the config", keyID)
+		}
+		publicKey = key.PublicKey
+	}
+
+	suci, err := udmContext.ConcealImsi(imsi[:3], imsi[3:3+mncLength], imsi[3+mncLength:],
+		c.String("routing-indicator"), scheme, keyID, publicKey)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.Root().Writer, suci)
+	return err
+}
+
+// configuredSuciKeyRing returns the key ring of the keys of the config given with --cfg
+func configuredSuciKeyRing(c *cli.Command) (*udmContext.SuciKeyRing, error) {
+	cfg := c.String("cfg")
+	if cfg == "" {
+		return nil, errors.New("required flag \"cfg\" not set")
+	}
+	absPath, err := filepath.Abs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := factory.InitConfigFactory(absPath); err != nil {
+		return nil, err
+	}
+	if factory.UdmConfig.Configuration == nil {
+		return nil, fmt.Errorf("no configuration in %s", cfg)
+	}
+	return util.SuciKeyRingFromConfig(factory.UdmConfig.Configuration.Keys)
+}
+
+// quietLibrary keeps the de-concealment logs of the library out of the output
+func quietLibrary(ctx context.Context, c *cli.Command) (context.Context, error) {
+	utilLogger.SetLogLevel(zap.WarnLevel)
+	return ctx, nil
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v2"
+)
+
+func runCli(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	udm := &UDM{}
+	var out bytes.Buffer
+	app := &cli.Command{
+		Name:     "udm",
+		Flags:    udm.GetCliCmd(),
+		Commands: udm.GetCliSubcommands(),
+		Writer:   &out,
+	}
+	err := app.Run(context.Background(), append([]string{"udm"}, args...))
+	return out.String(), err
+}
+
+func TestCliSubcommands_RoundTrip(t *testing.T) {
+	for _, scheme := range []string{"profileA", "profileB"} {
+		t.Run(scheme, func(t *testing.T) {
+			keys, err := runCli(t, "keygen", "--scheme", scheme, "--key-id", "4")
+			if err != nil {
+				t.Fatalf("keygen failed: %+v", err)
+			}
+			var section keysSection
+			if err = yaml.Unmarshal([]byte(keys), &section); err != nil {
+				t.Fatalf("invalid keys section %s: %+v", keys, err)
+			}
+			if len(section.Keys.HomeNetworkKeys) != 1 || section.Keys.HomeNetworkKeys[0].KeyId != 4 ||
+				section.Keys.HomeNetworkKeys[0].Scheme != scheme {
+				t.Fatalf("unexpected keys section %s", keys)
+			}
+
+			cfg := filepath.Join(t.TempDir(), "udmcfg.yaml")
+			content := "info:\n  version: 1.0.0\nconfiguration:\n  webuiUri: http://webui:5001\n" +
+				"  " + strings.ReplaceAll(strings.TrimSpace(keys), "\n", "\n  ") + "\n"
+			if err = os.WriteFile(cfg, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			suci, err := runCli(t, "supi-to-suci", "--cfg", cfg, "--scheme", scheme, "--key-id", "4",
+				"--mnc-length", "3", "imsi-310260000000001")
+			if err != nil {
+				t.Fatalf("supi-to-suci failed: %+v", err)
+			}
+			if !strings.HasPrefix(suci, "suci-0-310-260-0000-") {
+				t.Fatalf("unexpected SUCI %s", suci)
+			}
+			supi, err := runCli(t, "suci-decode", "--cfg", cfg, strings.TrimSpace(suci))
+			if err != nil {
+				t.Fatalf("suci-decode failed: %+v", err)
+			}
+			if strings.TrimSpace(supi) != "imsi-310260000000001" {
+				t.Fatalf("expected imsi-310260000000001, got %s", supi)
+			}
+		})
+	}
+}
+
+func TestCliSubcommands_Invalid(t *testing.T) {
+	testCases := [][]string{
+		{"keygen", "--scheme", "null"},
+		{"keygen", "--key-id", "0"},
+		{"supi-to-suci", "imsi-20893000000001"},
+		{"supi-to-suci", "--mnc-length", "4", "--scheme", "null", "imsi-20893000000001"},
+		{"supi-to-suci", "--scheme", "null", "imsi-20893"},
+		{"suci-decode", "suci-0-208-93-0000-0-0-000000001"},
+		{"suci-decode"},
+	}
+	for _, args := range testCases {
+		if out, err := runCli(t, args...); err == nil {
+			t.Errorf("%v: expected an error, got %s", args, out)
+		}
+	}
+
+	suci, err := runCli(t, "supi-to-suci", "--scheme", "null", "imsi-20893000000001")
+	if err != nil || suci != "suci-0-208-93-0000-0-0-000000001\n" {
+		t.Errorf("expected suci-0-208-93-0000-0-0-000000001, got %s, %v", suci, err)
+	}
+}
@@ -10,6 +10,7 @@ package service
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -31,6 +32,8 @@ import (
 	"github.com/omec-project/udm/nfregistration"
 	"github.com/omec-project/udm/parameterprovision"
 	"github.com/omec-project/udm/polling"
+	"github.com/omec-project/udm/producer"
+	"github.com/omec-project/udm/s6a"
 	"github.com/omec-project/udm/subscribecallback"
 	"github.com/omec-project/udm/subscriberdatamanagement"
 	"github.com/omec-project/udm/ueauthentication"
@@ -56,9 +59,8 @@ var config Config
 
 var udmCLi = []cli.Flag{
 	&cli.StringFlag{
-		Name:     "cfg",
-		Usage:    "udm config file",
-		Required: true,
+		Name:  "cfg",
+		Usage: "udm config file, required to run the UDM",
 	},
 }
 
@@ -70,6 +72,9 @@ func (udm *UDM) Initialize(c *cli.Command) error {
 	config = Config{
 		cfg: c.String("cfg"),
 	}
+	if config.cfg == "" {
+		return errors.New("required flag \"cfg\" not set")
+	}
 
 	absPath, err := filepath.Abs(config.cfg)
 	if err != nil {
@@ -171,6 +176,59 @@ func (udm *UDM) Start() {
 		defer wg.Done()
 		nfregistration.StartNfRegistrationService(ctx, plmnConfigChan)
 	}()
+	// the EE subscriptions of the GPSIs and groups are loaded when they are first used
+	go func() {
+		if err := producer.LoadEeSubscriptions(udmContext.AnyUE); err != nil {
+			logger.InitLog.Warnf("EE subscriptions not loaded from the UDR: %+v", err)
+		}
+	}()
+	if self.UeContextIdleTimeout > 0 {
+		logger.InitLog.Infof("enable eviction of UE contexts idle for %v", self.UeContextIdleTimeout)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			self.StartUdmUeEviction(ctx.Done(), producer.RemoveUdrDataChangeSubscriptions)
+		}()
+	}
+
+	if keys := factory.UdmConfig.Configuration.Keys; keys != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			util.StartSuciKeyReload(ctx.Done(), keys, self.SuciKeyRing, util.SuciKeyReloadInterval(keys))
+		}()
+	}
+
+	if policy := factory.UdmConfig.Configuration.SuciProtectionPolicy; policy != nil && policy.PollWebconsole {
+		policyChan := make(chan factory.SuciProtectionPolicy, 1)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			polling.StartSuciProtectionPolicyPolling(ctx, factory.UdmConfig.Configuration.WebuiUri, policyChan)
+		}()
+		go func() {
+			defer wg.Done()
+			util.StartSuciProtectionPolicyUpdate(ctx.Done(), policyChan, self.SuciProtectionPolicy)
+		}()
+	}
+
+	if s6aConfig := factory.UdmConfig.Configuration.S6a; s6aConfig != nil && s6aConfig.Enable {
+		originHost := s6aConfig.OriginHost
+		if originHost == "" {
+			originHost = self.Name
+		}
+		listenAddress := s6aConfig.ListenAddress
+		if listenAddress == "" {
+			listenAddress = s6a.DefaultListenAddress
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s6a.NewServer(originHost, s6aConfig.OriginRealm).ListenAndServe(ctx, listenAddress); err != nil {
+				logger.InitLog.Errorf("S6a front end failed: %+v", err)
+			}
+		}()
+	}
 
 	signalChannel := make(chan os.Signal, 1)
 	signal.Notify(signalChannel, os.Interrupt, syscall.SIGTERM)
@@ -30,7 +30,7 @@ func HTTPGetSupi(c *gin.Context) {
 	req := httpwrapper.NewRequest(c.Request, nil)
 	req.Params["supi"] = c.Params.ByName("supi")
 	req.Query.Set("plmn-id", c.Query("plmn-id"))
-	req.Query.Set("dataset-names", c.Query("dataset-names"))
+	req.Query["dataset-names"] = c.QueryArray("dataset-names")
 	req.Query.Set("supported-features", c.Query("supported-features"))
 
 	rsp := producer.HandleGetSupiRequest(req)
@@ -18,9 +18,32 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/logger"
+	"github.com/omec-project/udm/producer"
+	"github.com/omec-project/util/httpwrapper"
 )
 
 // GetSmsData - retrieve a UE's SMS Subscription Data
 func HTTPGetSmsData(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{})
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["supi"] = c.Params.ByName("supi")
+	req.Query.Set("plmn-id", c.Query("plmn-id"))
+	req.Query.Set("supported-features", c.Query("supported-features"))
+
+	rsp := producer.HandleGetSmsDataRequest(req)
+
+	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
+	if err != nil {
+		logger.SdmLog.Errorln(err)
+		problemDetails := models.ProblemDetails{
+			Status: http.StatusInternalServerError,
+			Cause:  "SYSTEM_FAILURE",
+			Detail: err.Error(),
+		}
+		c.JSON(http.StatusInternalServerError, problemDetails)
+	} else {
+		c.Data(rsp.Status, "application/json", responseBody)
+	}
 }
@@ -19,17 +19,18 @@ var UDM = &service.UDM{}
 func main() {
 	app := &cli.Command{}
 	app.Name = "udm"
-	logger.AppLog.Infoln(app.Name)
 	app.Usage = "Unified Data Management"
 	app.UsageText = "udm -cfg <udm_config_file.conf>"
 	app.Action = action
 	app.Flags = UDM.GetCliCmd()
+	app.Commands = UDM.GetCliSubcommands()
 	if err := app.Run(context.Background(), os.Args); err != nil {
 		logger.AppLog.Fatalf("UDM run error: %v", err)
 	}
 }
 
 func action(ctx context.Context, c *cli.Command) error {
+	logger.AppLog.Infoln(c.Name)
 	if err := UDM.Initialize(c); err != nil {
 		logger.CfgLog.Errorf("%+v", err)
 		return fmt.Errorf("failed to initialize")
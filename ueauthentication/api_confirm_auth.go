@@ -60,6 +60,9 @@ func HTTPConfirmAuth(c *gin.Context) {
 	req.Params["supi"] = c.Params.ByName("supi")
 
 	rsp := producer.HandleConfirmAuthDataRequest(req)
+	for key, val := range rsp.Header {
+		c.Header(key, val[0])
+	}
 
 	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
 	if err != nil {
@@ -27,7 +27,7 @@ import (
 
 // GenerateAuthData - Generate authentication data for the UE
 func HttpGenerateAuthData(c *gin.Context) {
-	var authInfoReq models.AuthenticationInfoRequest
+	var authInfoReq producer.AuthenticationInfoRequest
 
 	// step 1: retrieve http request body
 	requestBody, err := c.GetRawData()
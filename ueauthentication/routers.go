@@ -98,4 +98,18 @@ var routes = Routes{
 		"/:supi/auth-events",
 		HTTPConfirmAuth,
 	},
+
+	{
+		"DeleteAuth",
+		strings.ToUpper("Delete"),
+		"/:supi/auth-events/:authEventId",
+		HTTPDeleteAuth,
+	},
+
+	{
+		"GenerateGbaAv",
+		strings.ToUpper("Post"),
+		"/:supi/gba-security-information/generate-av",
+		HTTPGenerateGbaAv,
+	},
 }
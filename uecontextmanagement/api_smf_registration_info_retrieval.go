@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package uecontextmanagement
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/logger"
+	"github.com/omec-project/udm/producer"
+	"github.com/omec-project/util/httpwrapper"
+)
+
+// GetSmfRegistrations - retrieve the SMF registrations of the PDU sessions of the UE
+func HTTPGetSmfRegistrations(c *gin.Context) {
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["ueId"] = c.Param("ueId")
+	req.Query.Add("single-nssai", c.Query("single-nssai"))
+	req.Query.Add("dnn", c.Query("dnn"))
+	req.Query.Add("supported-features", c.Query("supported-features"))
+
+	rsp := producer.HandleGetSmfRegistrationsRequest(req)
+
+	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
+	if err != nil {
+		logger.UecmLog.Errorln(err)
+		problemDetails := models.ProblemDetails{
+			Status: http.StatusInternalServerError,
+			Cause:  "SYSTEM_FAILURE",
+			Detail: err.Error(),
+		}
+		c.JSON(http.StatusInternalServerError, problemDetails)
+	} else {
+		c.Data(rsp.Status, "application/json", responseBody)
+	}
+}
@@ -18,9 +18,31 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udm/logger"
+	"github.com/omec-project/udm/producer"
+	"github.com/omec-project/util/httpwrapper"
 )
 
 // GetSmsf3gppAccess - retrieve the SMSF registration for 3GPP access information
 func HTTPGetSmsf3gppAccess(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{})
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["ueId"] = c.Param("ueId")
+	req.Query.Add("supported-features", c.Query("supported-features"))
+
+	rsp := producer.HandleGetSmsf3gppAccessRequest(req)
+
+	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
+	if err != nil {
+		logger.UecmLog.Errorln(err)
+		problemDetails := models.ProblemDetails{
+			Status: http.StatusInternalServerError,
+			Cause:  "SYSTEM_FAILURE",
+			Detail: err.Error(),
+		}
+		c.JSON(http.StatusInternalServerError, problemDetails)
+	} else {
+		c.Data(rsp.Status, "application/json", responseBody)
+	}
 }
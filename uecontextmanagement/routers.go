@@ -135,6 +135,13 @@ var routes = Routes{
 		HTTPRegistrationSmfRegistrations,
 	},
 
+	{
+		"GetSmfRegistrations",
+		strings.ToUpper("Get"),
+		"/:ueId/registrations/smf-registrations",
+		HTTPGetSmfRegistrations,
+	},
+
 	{
 		"GetSmsf3gppAccess",
 		strings.ToUpper("Get"),
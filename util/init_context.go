@@ -7,7 +7,10 @@
 package util
 
 import (
+	"encoding/hex"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +18,6 @@ import (
 	"github.com/omec-project/udm/context"
 	"github.com/omec-project/udm/factory"
 	"github.com/omec-project/udm/logger"
-	"github.com/omec-project/util/util_3gpp/suci"
 )
 
 func InitUDMContext(udmContext *context.UDMContext) {
@@ -62,20 +64,147 @@ func InitUDMContext(udmContext *context.UDMContext) {
 		}
 	}
 
+	if cacheConfig := configuration.SubscriberDataCache; cacheConfig != nil && cacheConfig.Enable {
+		ttl := time.Duration(cacheConfig.Ttl) * time.Second
+		if ttl == 0 {
+			ttl = 300 * time.Second // 5 mins
+		}
+		udmContext.SubscriberDataCache = context.NewSubscriberDataCache(ttl, cacheConfig.MaxEntries)
+		logger.UtilLog.Infof("subscriber data cache enabled: ttl[%v] maxEntries[%d]", ttl, cacheConfig.MaxEntries)
+	}
+
+	udmContext.UeContextIdleTimeout = time.Duration(configuration.UeContextIdleTimeout) * time.Second
+
+	udmContext.DefaultAmf, _ = hex.DecodeString(factory.UDM_DEFAULT_AMF)
+	if configuration.DefaultAmf != "" {
+		if amf, err := hex.DecodeString(configuration.DefaultAmf); err != nil || len(amf) != 2 {
+			logger.UtilLog.Errorf("invalid defaultAmf %s, using %s", configuration.DefaultAmf, factory.UDM_DEFAULT_AMF)
+		} else {
+			udmContext.DefaultAmf = amf
+		}
+	}
+
+	if sqnManagement := configuration.SqnManagement; sqnManagement != nil {
+		if sqnManagement.IndLength < 0 || sqnManagement.IndLength > 16 {
+			logger.UtilLog.Errorf("invalid sqnManagement indLength %d, using the default", sqnManagement.IndLength)
+		} else {
+			udmContext.SqnIndLength = uint(sqnManagement.IndLength)
+		}
+		if sqnManagement.Delta < 0 {
+			logger.UtilLog.Errorf("invalid sqnManagement delta %d, using the default", sqnManagement.Delta)
+		} else {
+			udmContext.SqnDelta = uint64(sqnManagement.Delta)
+		}
+	}
+
+	if protection := configuration.AuthFailureProtection; protection != nil && protection.Enable {
+		threshold := protection.Threshold
+		if threshold <= 0 {
+			threshold = 5
+		}
+		blockDuration := time.Duration(protection.BlockDuration) * time.Second
+		if blockDuration <= 0 {
+			blockDuration = 300 * time.Second // 5 mins
+		}
+		udmContext.AuthFailureTracker = context.NewAuthFailureTracker(threshold, blockDuration)
+		logger.UtilLog.Infof("authentication failure protection enabled: threshold[%d] blockDuration[%v]",
+			threshold, blockDuration)
+	}
+
 	udmContext.NrfUri = configuration.NrfUri
 	servingNameList := configuration.ServiceList
 
-	udmContext.SuciProfiles = []suci.SuciProfile{
-		{
-			ProtectionScheme: "1", // Standard defined value for Protection Scheme A (TS 33.501 Annex C)
-			PrivateKey:       configuration.Keys.UdmProfileAHNPrivateKey,
-			PublicKey:        configuration.Keys.UdmProfileAHNPublicKey,
-		},
-		{
-			ProtectionScheme: "2", // Standard defined value for Protection Scheme B (TS 33.501 Annex C)
-			PrivateKey:       configuration.Keys.UdmProfileBHNPrivateKey,
-			PublicKey:        configuration.Keys.UdmProfileBHNPublicKey,
-		},
+	var err error
+	if udmContext.SuciKeyRing, err = SuciKeyRingFromConfig(configuration.Keys); err != nil {
+		logger.UtilLog.Errorf("invalid home network keys, SUCIs cannot be de-concealed: %+v", err)
+		udmContext.SuciKeyRing, _ = context.NewSuciKeyRing(nil)
+	}
+
+	if policy := configuration.SuciProtectionPolicy; policy != nil {
+		udmContext.SuciProtectionPolicy = NewSuciProtectionPolicy(policy)
 	}
 	udmContext.InitNFService(servingNameList, config.Info.Version)
 }
+
+// SuciKeyRingFromConfig returns the key ring of the home network keys, registering the soft token
+// provider of their pkcs11: references
+func SuciKeyRingFromConfig(keys *factory.Keys) (*context.SuciKeyRing, error) {
+	if keys != nil && keys.SoftTokenDirectory != "" {
+		RegisterKeyProvider(NewSoftTokenProvider(keys.SoftTokenDirectory))
+	}
+	suciKeys, err := SuciKeysFromConfig(keys)
+	if err != nil {
+		return nil, err
+	}
+	return context.NewSuciKeyRing(suciKeys)
+}
+
+// SuciKeysFromConfig returns the home network keys of the key ring. Without homeNetworkKeys, the
+// udmProfileA and udmProfileB keys are key IDs 1 and 2, as the HN public key IDs provisioned so far.
+func SuciKeysFromConfig(keys *factory.Keys) ([]context.SuciKey, error) {
+	if keys == nil {
+		return nil, nil
+	}
+	if len(keys.HomeNetworkKeys) == 0 {
+		var suciKeys []context.SuciKey
+		if keys.UdmProfileAHNPrivateKey != "" {
+			privateKey, err := ResolvePrivateKey(keys.UdmProfileAHNPrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("udmProfileAHNPrivateKey: %w", err)
+			}
+			suciKeys = append(suciKeys, context.SuciKey{
+				KeyID:            1,
+				ProtectionScheme: context.ProfileAScheme,
+				PrivateKey:       privateKey,
+				PublicKey:        keys.UdmProfileAHNPublicKey,
+			})
+		}
+		if keys.UdmProfileBHNPrivateKey != "" {
+			privateKey, err := ResolvePrivateKey(keys.UdmProfileBHNPrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("udmProfileBHNPrivateKey: %w", err)
+			}
+			suciKeys = append(suciKeys, context.SuciKey{
+				KeyID:            2,
+				ProtectionScheme: context.ProfileBScheme,
+				PrivateKey:       privateKey,
+				PublicKey:        keys.UdmProfileBHNPublicKey,
+			})
+		}
+		return suciKeys, nil
+	}
+
+	suciKeys := make([]context.SuciKey, 0, len(keys.HomeNetworkKeys))
+	for _, key := range keys.HomeNetworkKeys {
+		privateKey, err := ResolvePrivateKey(key.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("key ID %d: %w", key.KeyId, err)
+		}
+		suciKey := context.SuciKey{
+			KeyID:      key.KeyId,
+			PrivateKey: privateKey,
+			PublicKey:  key.PublicKey,
+			State:      context.SuciKeyState(key.State),
+		}
+		switch strings.ToLower(key.Scheme) {
+		case "profilea", "a", context.ProfileAScheme:
+			suciKey.ProtectionScheme = context.ProfileAScheme
+		case "profileb", "b", context.ProfileBScheme:
+			suciKey.ProtectionScheme = context.ProfileBScheme
+		default:
+			return nil, fmt.Errorf("key ID %d: unsupported scheme %q", key.KeyId, key.Scheme)
+		}
+		if key.ActivationTime != "" {
+			if suciKey.ActivationTime, err = time.Parse(time.RFC3339, key.ActivationTime); err != nil {
+				return nil, fmt.Errorf("key ID %d: invalid activationTime: %w", key.KeyId, err)
+			}
+		}
+		if key.RetirementTime != "" {
+			if suciKey.RetirementTime, err = time.Parse(time.RFC3339, key.RetirementTime); err != nil {
+				return nil, fmt.Errorf("key ID %d: invalid retirementTime: %w", key.KeyId, err)
+			}
+		}
+		suciKeys = append(suciKeys, suciKey)
+	}
+	return suciKeys, nil
+}
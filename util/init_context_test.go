@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/factory"
+)
+
+func TestSuciKeysFromConfig_LegacyKeys(t *testing.T) {
+	keys, err := SuciKeysFromConfig(&factory.Keys{
+		UdmProfileAHNPrivateKey: "a-private",
+		UdmProfileAHNPublicKey:  "a-public",
+		UdmProfileBHNPrivateKey: "b-private",
+		UdmProfileBHNPublicKey:  "b-public",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 ||
+		keys[0].KeyID != 1 || keys[0].ProtectionScheme != context.ProfileAScheme || keys[0].PrivateKey != "a-private" ||
+		keys[1].KeyID != 2 || keys[1].ProtectionScheme != context.ProfileBScheme || keys[1].PrivateKey != "b-private" {
+		t.Errorf("expected the profile A and B keys as key IDs 1 and 2, got %+v", keys)
+	}
+}
+
+func TestSuciKeysFromConfig_HomeNetworkKeys(t *testing.T) {
+	keys, err := SuciKeysFromConfig(&factory.Keys{
+		UdmProfileAHNPrivateKey: "ignored",
+		HomeNetworkKeys: []factory.HomeNetworkKey{
+			{KeyId: 3, Scheme: "profileA", PrivateKey: "old", State: "retired"},
+			{KeyId: 4, Scheme: "profileB", PrivateKey: "new", ActivationTime: "2025-06-01T00:00:00Z"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %+v", keys)
+	}
+	if keys[0].KeyID != 3 || keys[0].ProtectionScheme != context.ProfileAScheme ||
+		keys[0].State != context.SuciKeyStateRetired {
+		t.Errorf("unexpected key %+v", keys[0])
+	}
+	if keys[1].KeyID != 4 || keys[1].ProtectionScheme != context.ProfileBScheme ||
+		!keys[1].ActivationTime.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected key %+v", keys[1])
+	}
+
+	for name, key := range map[string]factory.HomeNetworkKey{
+		"unsupported scheme":      {KeyId: 1, Scheme: "profileC", PrivateKey: "key"},
+		"invalid activation time": {KeyId: 1, Scheme: "profileA", PrivateKey: "key", ActivationTime: "tomorrow"},
+		"invalid retirement time": {KeyId: 1, Scheme: "profileA", PrivateKey: "key", RetirementTime: "2025-13-01"},
+	} {
+		if _, err := SuciKeysFromConfig(&factory.Keys{HomeNetworkKeys: []factory.HomeNetworkKey{key}}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/omec-project/openapi/models"
+)
+
+// ApplyJSONPatch applies the patch items (IETF RFC 6902) to the JSON representation of target,
+// which must be a pointer. target is left unchanged if any of the items cannot be applied.
+func ApplyJSONPatch(target interface{}, patchItems []models.PatchItem) error {
+	raw, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	if err = json.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+
+	for i, patchItem := range patchItems {
+		document, err = applyPatchItem(document, patchItem)
+		if err != nil {
+			return fmt.Errorf("patch item %d (%s %s): %w", i, patchItem.Op, patchItem.Path, err)
+		}
+	}
+
+	if raw, err = json.Marshal(document); err != nil {
+		return err
+	}
+	patched := reflect.New(reflect.TypeOf(target).Elem())
+	if err = json.Unmarshal(raw, patched.Interface()); err != nil {
+		return err
+	}
+	reflect.ValueOf(target).Elem().Set(patched.Elem())
+	return nil
+}
+
+func applyPatchItem(document interface{}, patchItem models.PatchItem) (interface{}, error) {
+	switch patchItem.Op {
+	case models.PatchOperation_ADD:
+		value, err := jsonValue(patchItem.Value)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(document, patchItem.Path, value)
+	case models.PatchOperation_REMOVE:
+		document, _, err := jsonPointerRemove(document, patchItem.Path)
+		return document, err
+	case models.PatchOperation_REPLACE:
+		value, err := jsonValue(patchItem.Value)
+		if err != nil {
+			return nil, err
+		}
+		if document, _, err = jsonPointerRemove(document, patchItem.Path); err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(document, patchItem.Path, value)
+	case models.PatchOperation_MOVE:
+		document, value, err := jsonPointerRemove(document, patchItem.From)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(document, patchItem.Path, value)
+	case models.PatchOperation_COPY:
+		value, err := jsonPointerGet(document, patchItem.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = jsonValue(value); err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(document, patchItem.Path, value)
+	case models.PatchOperation_TEST:
+		expected, err := jsonValue(patchItem.Value)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonPointerGet(document, patchItem.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, expected) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return document, nil
+	default:
+		return nil, fmt.Errorf("unsupported operation")
+	}
+}
+
+// jsonValue returns a deep copy of value in its generic JSON representation
+func jsonValue(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	err = json.Unmarshal(raw, &generic)
+	return generic, err
+}
+
+// jsonPointerTokens splits a JSON pointer (IETF RFC 6901) into its unescaped reference tokens
+func jsonPointerTokens(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func jsonArrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > length || (!allowEnd && index == length) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
+
+func jsonPointerGet(document interface{}, pointer string) (interface{}, error) {
+	tokens, err := jsonPointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := document
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %q not found", pointer)
+		}
+	}
+	return current, nil
+}
+
+// jsonPointerAdd adds value at pointer and returns the updated document
+func jsonPointerAdd(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := jsonPointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parent, err := jsonPointerGet(document, pointer[:strings.LastIndex(pointer, "/")])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return document, nil
+	case []interface{}:
+		index, err := jsonArrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, nil)
+		copy(node[index+1:], node[index:])
+		node[index] = value
+		return jsonPointerSet(document, pointer[:strings.LastIndex(pointer, "/")], node)
+	default:
+		return nil, fmt.Errorf("path %q not found", pointer)
+	}
+}
+
+// jsonPointerRemove removes the value at pointer and returns the updated document and the removed value
+func jsonPointerRemove(document interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := jsonPointerTokens(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, document, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := jsonPointerGet(document, parentPointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %q not found", pointer)
+		}
+		delete(node, last)
+		return document, value, nil
+	case []interface{}:
+		index, err := jsonArrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		node = append(node[:index:index], node[index+1:]...)
+		document, err = jsonPointerSet(document, parentPointer, node)
+		return document, value, err
+	default:
+		return nil, nil, fmt.Errorf("path %q not found", pointer)
+	}
+}
+
+// jsonPointerSet replaces the value at pointer, which must exist, and returns the updated document
+func jsonPointerSet(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := jsonPointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parent, err := jsonPointerGet(document, pointer[:strings.LastIndex(pointer, "/")])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		index, err := jsonArrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	}
+	return document, nil
+}
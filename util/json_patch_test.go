@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	testCases := []struct {
+		name        string
+		patchItems  []models.PatchItem
+		expectError bool
+		check       func(models.EeSubscription) bool
+	}{
+		{
+			name: "replace callback reference",
+			patchItems: []models.PatchItem{
+				{Op: models.PatchOperation_REPLACE, Path: "/callbackReference", Value: "http://nef2/callback"},
+			},
+			check: func(s models.EeSubscription) bool { return s.CallbackReference == "http://nef2/callback" },
+		},
+		{
+			name: "add reporting options",
+			patchItems: []models.PatchItem{
+				{Op: models.PatchOperation_ADD, Path: "/reportingOptions", Value: map[string]interface{}{"maxNumOfReports": 3}},
+			},
+			check: func(s models.EeSubscription) bool {
+				return s.ReportingOptions != nil && s.ReportingOptions.MaxNumOfReports == 3
+			},
+		},
+		{
+			name: "remove monitoring configuration",
+			patchItems: []models.PatchItem{
+				{Op: models.PatchOperation_REMOVE, Path: "/monitoringConfigurations/ref~1a"},
+			},
+			check: func(s models.EeSubscription) bool {
+				_, ok := s.MonitoringConfigurations["ref/a"]
+				return !ok && len(s.MonitoringConfigurations) == 1
+			},
+		},
+		{
+			name: "copy monitoring configuration",
+			patchItems: []models.PatchItem{
+				{Op: models.PatchOperation_COPY, From: "/monitoringConfigurations/b", Path: "/monitoringConfigurations/c"},
+			},
+			check: func(s models.EeSubscription) bool {
+				return s.MonitoringConfigurations["c"].EventType == models.EventType_LOSS_OF_CONNECTIVITY
+			},
+		},
+		{
+			name: "failed test leaves the target unchanged",
+			patchItems: []models.PatchItem{
+				{Op: models.PatchOperation_REPLACE, Path: "/callbackReference", Value: "http://nef2/callback"},
+				{Op: models.PatchOperation_TEST, Path: "/callbackReference", Value: "http://nef3/callback"},
+			},
+			expectError: true,
+			check:       func(s models.EeSubscription) bool { return s.CallbackReference == "http://nef1/callback" },
+		},
+		{
+			name: "remove of a missing path",
+			patchItems: []models.PatchItem{
+				{Op: models.PatchOperation_REMOVE, Path: "/monitoringConfigurations/z"},
+			},
+			expectError: true,
+			check:       func(s models.EeSubscription) bool { return len(s.MonitoringConfigurations) == 2 },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eeSubscription := models.EeSubscription{
+				CallbackReference: "http://nef1/callback",
+				MonitoringConfigurations: map[string]models.MonitoringConfiguration{
+					"ref/a": {EventType: models.EventType_UE_REACHABILITY_FOR_DATA},
+					"b":     {EventType: models.EventType_LOSS_OF_CONNECTIVITY},
+				},
+			}
+			err := ApplyJSONPatch(&eeSubscription, tc.patchItems)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error %v, got %v", tc.expectError, err)
+			}
+			if !tc.check(eeSubscription) {
+				t.Errorf("unexpected result %+v", eeSubscription)
+			}
+		})
+	}
+}
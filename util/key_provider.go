@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeyProvider resolves the references of its scheme to home network private keys, so that the keys
+// do not have to be written in the configuration
+type KeyProvider interface {
+	// Scheme is the URI scheme of the references resolved by the provider, e.g. "file"
+	Scheme() string
+	// PrivateKey returns the hex encoded private key referenced by ref
+	PrivateKey(ref string) (string, error)
+}
+
+var (
+	keyProviders     = map[string]KeyProvider{}
+	keyProvidersLock sync.RWMutex
+)
+
+func init() {
+	RegisterKeyProvider(fileKeyProvider{})
+	RegisterKeyProvider(envKeyProvider{})
+}
+
+// RegisterKeyProvider registers the provider of a scheme, replacing the provider registered before
+func RegisterKeyProvider(provider KeyProvider) {
+	keyProvidersLock.Lock()
+	defer keyProvidersLock.Unlock()
+	keyProviders[provider.Scheme()] = provider
+}
+
+// ResolvePrivateKey returns the hex encoded private key of a configured key. The key is either a
+// reference of a registered provider, e.g. file:///etc/udm/hn-key.pem, env://UDM_HN_KEY or
+// pkcs11:token=udm;object=hn-key-1, or the hex encoded key itself.
+func ResolvePrivateKey(key string) (string, error) {
+	scheme, _, found := strings.Cut(key, ":")
+	if !found {
+		return key, nil
+	}
+	keyProvidersLock.RLock()
+	provider, ok := keyProviders[scheme]
+	keyProvidersLock.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no key provider for scheme %q", scheme)
+	}
+	return provider.PrivateKey(key)
+}
+
+// fileKeyProvider reads the keys from files: file:///path/to/key
+type fileKeyProvider struct{}
+
+func (fileKeyProvider) Scheme() string {
+	return "file"
+}
+
+func (fileKeyProvider) PrivateKey(ref string) (string, error) {
+	path := strings.TrimPrefix(ref, "file://")
+	if path == ref || path == "" {
+		return "", fmt.Errorf("invalid file key reference %q", ref)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return parsePrivateKey(data)
+}
+
+// envKeyProvider reads the keys from environment variables: env://VARIABLE
+type envKeyProvider struct{}
+
+func (envKeyProvider) Scheme() string {
+	return "env"
+}
+
+func (envKeyProvider) PrivateKey(ref string) (string, error) {
+	name := strings.TrimPrefix(ref, "env://")
+	if name == ref || name == "" {
+		return "", fmt.Errorf("invalid environment key reference %q", ref)
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return parsePrivateKey([]byte(value))
+}
+
+// parsePrivateKey returns the hex encoded private key of a PEM block, DER encoded PKCS #8 or SEC 1
+// key, or hex encoded key
+func parsePrivateKey(data []byte) (string, error) {
+	trimmed := bytes.TrimSpace(data)
+	if block, _ := pem.Decode(trimmed); block != nil {
+		return parseDERPrivateKey(block.Bytes)
+	}
+	if _, err := hex.DecodeString(string(trimmed)); err == nil && len(trimmed) != 0 {
+		return strings.ToLower(string(trimmed)), nil
+	}
+	return parseDERPrivateKey(data)
+}
+
+func parseDERPrivateKey(der []byte) (string, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		switch privateKey := key.(type) {
+		case *ecdh.PrivateKey:
+			return hex.EncodeToString(privateKey.Bytes()), nil
+		case *ecdsa.PrivateKey:
+			return ecdsaPrivateKey(privateKey)
+		default:
+			return "", fmt.Errorf("unsupported private key type %T", key)
+		}
+	}
+	if privateKey, err := x509.ParseECPrivateKey(der); err == nil {
+		return ecdsaPrivateKey(privateKey)
+	}
+	return "", fmt.Errorf("private key is neither PEM, PKCS #8, SEC 1 nor hex encoded")
+}
+
+func ecdsaPrivateKey(privateKey *ecdsa.PrivateKey) (string, error) {
+	ecdhKey, err := privateKey.ECDH()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(ecdhKey.Bytes()), nil
+}
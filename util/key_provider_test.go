@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKeyFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("failed to create the directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write the key: %v", err)
+	}
+}
+
+func TestResolvePrivateKey_Sources(t *testing.T) {
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	x25519Hex := hex.EncodeToString(x25519Key.Bytes())
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(x25519Key)
+	if err != nil {
+		t.Fatalf("failed to marshal the key: %v", err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	p256EcdhKey, _ := p256Key.ECDH()
+	p256Hex := hex.EncodeToString(p256EcdhKey.Bytes())
+	sec1, err := x509.MarshalECPrivateKey(p256Key)
+	if err != nil {
+		t.Fatalf("failed to marshal the key: %v", err)
+	}
+
+	dir := t.TempDir()
+	writeKeyFile(t, filepath.Join(dir, "a.hex"), []byte(x25519Hex+"\n"))
+	writeKeyFile(t, filepath.Join(dir, "a.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+	writeKeyFile(t, filepath.Join(dir, "a.der"), pkcs8)
+	writeKeyFile(t, filepath.Join(dir, "b.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}))
+	writeKeyFile(t, filepath.Join(dir, "b.der"), sec1)
+	t.Setenv("UDM_TEST_HN_KEY", x25519Hex)
+	t.Setenv("UDM_TEST_HN_KEY_PEM", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})))
+
+	testCases := []struct {
+		name     string
+		key      string
+		expected string
+	}{
+		{name: "inline", key: x25519Hex, expected: x25519Hex},
+		{name: "hex file", key: "file://" + filepath.Join(dir, "a.hex"), expected: x25519Hex},
+		{name: "PKCS #8 PEM file", key: "file://" + filepath.Join(dir, "a.pem"), expected: x25519Hex},
+		{name: "PKCS #8 DER file", key: "file://" + filepath.Join(dir, "a.der"), expected: x25519Hex},
+		{name: "SEC 1 PEM file", key: "file://" + filepath.Join(dir, "b.pem"), expected: p256Hex},
+		{name: "SEC 1 DER file", key: "file://" + filepath.Join(dir, "b.der"), expected: p256Hex},
+		{name: "hex environment variable", key: "env://UDM_TEST_HN_KEY", expected: x25519Hex},
+		{name: "PEM environment variable", key: "env://UDM_TEST_HN_KEY_PEM", expected: p256Hex},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			privateKey, err := ResolvePrivateKey(tc.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if privateKey != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, privateKey)
+			}
+		})
+	}
+
+	for _, key := range []string{
+		"file://" + filepath.Join(dir, "missing.pem"),
+		"env://UDM_TEST_MISSING_KEY",
+		"vault://udm/hn-key",
+	} {
+		if _, err := ResolvePrivateKey(key); err == nil {
+			t.Errorf("%s: expected an error", key)
+		}
+	}
+}
+
+func TestSoftTokenProvider(t *testing.T) {
+	dir := t.TempDir()
+	writeKeyFile(t, filepath.Join(dir, "udm", "hn-key-1"), []byte("0a0b0c"))
+	writeKeyFile(t, filepath.Join(dir, "locked", "hn-key-1"), []byte("0d0e0f"))
+	writeKeyFile(t, filepath.Join(dir, "locked", softTokenPinFile), []byte("1234\n"))
+	writeKeyFile(t, filepath.Join(dir, "secret"), []byte("00"))
+	RegisterKeyProvider(NewSoftTokenProvider(dir))
+	t.Cleanup(func() {
+		keyProvidersLock.Lock()
+		delete(keyProviders, "pkcs11")
+		keyProvidersLock.Unlock()
+	})
+
+	for key, expected := range map[string]string{
+		"pkcs11:token=udm;object=hn-key-1":                       "0a0b0c",
+		"pkcs11:token=locked;object=hn%2Dkey%2D1?pin-value=1234": "0d0e0f",
+	} {
+		privateKey, err := ResolvePrivateKey(key)
+		if err != nil || privateKey != expected {
+			t.Errorf("%s: expected %s, got %s, %v", key, expected, privateKey, err)
+		}
+	}
+
+	for _, key := range []string{
+		"pkcs11:token=locked;object=hn-key-1",
+		"pkcs11:token=locked;object=hn-key-1?pin-value=4321",
+		"pkcs11:token=udm;object=hn-key-2",
+		"pkcs11:token=..;object=secret",
+		"pkcs11:token=udm;object=..%2F..%2Fsecret",
+		"pkcs11:token=locked;object=.pin",
+		"pkcs11:object=hn-key-1",
+	} {
+		if _, err := ResolvePrivateKey(key); err == nil {
+			t.Errorf("%s: expected an error", key)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// softTokenPinFile is the file of a token holding its PIN. Tokens without it need no PIN.
+const softTokenPinFile = ".pin"
+
+// SoftTokenProvider is a local stand-in of a PKCS #11 token, resolving the RFC 7512 references
+// pkcs11:token=<token>;object=<object>[?pin-value=<pin>] to the key stored in the file
+// <directory>/<token>/<object>, so that the keys are only readable by the token until an HSM is used
+type SoftTokenProvider struct {
+	directory string
+}
+
+func NewSoftTokenProvider(directory string) *SoftTokenProvider {
+	return &SoftTokenProvider{directory: directory}
+}
+
+func (p *SoftTokenProvider) Scheme() string {
+	return "pkcs11"
+}
+
+func (p *SoftTokenProvider) PrivateKey(ref string) (string, error) {
+	attributes, err := parsePkcs11URI(ref)
+	if err != nil {
+		return "", err
+	}
+	token, object := attributes["token"], attributes["object"]
+	if !isSoftTokenName(token) || !isSoftTokenName(object) {
+		return "", fmt.Errorf("invalid PKCS #11 token %q or object %q", token, object)
+	}
+	tokenDirectory := filepath.Join(p.directory, token)
+	if err = p.login(tokenDirectory, attributes["pin-value"]); err != nil {
+		return "", fmt.Errorf("token %s: %w", token, err)
+	}
+	data, err := os.ReadFile(filepath.Join(tokenDirectory, object))
+	if err != nil {
+		return "", fmt.Errorf("token %s: object %s not found: %w", token, object, err)
+	}
+	return parsePrivateKey(data)
+}
+
+func (p *SoftTokenProvider) login(tokenDirectory string, pin string) error {
+	expectedPin, err := os.ReadFile(filepath.Join(tokenDirectory, softTokenPinFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(string(expectedPin))), []byte(pin)) != 1 {
+		return fmt.Errorf("incorrect PIN")
+	}
+	return nil
+}
+
+// parsePkcs11URI returns the path and query attributes of a PKCS #11 URI (RFC 7512)
+func parsePkcs11URI(ref string) (map[string]string, error) {
+	rest, found := strings.CutPrefix(ref, "pkcs11:")
+	if !found {
+		return nil, fmt.Errorf("invalid PKCS #11 URI %q", ref)
+	}
+	path, query, _ := strings.Cut(rest, "?")
+	attributes := make(map[string]string)
+	var err error
+	for _, attribute := range append(strings.Split(path, ";"), strings.Split(query, "&")...) {
+		if attribute == "" {
+			continue
+		}
+		name, value, found := strings.Cut(attribute, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid PKCS #11 URI attribute %q", attribute)
+		}
+		if attributes[name], err = url.PathUnescape(value); err != nil {
+			return nil, fmt.Errorf("invalid PKCS #11 URI attribute %q: %w", attribute, err)
+		}
+	}
+	return attributes, nil
+}
+
+// isSoftTokenName rejects the names escaping the token directory
+func isSoftTokenName(name string) bool {
+	return name != "" && name != "." && name != ".." && name != softTokenPinFile &&
+		!strings.ContainsAny(name, `/\`)
+}
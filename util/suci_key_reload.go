@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/factory"
+	"github.com/omec-project/udm/logger"
+)
+
+const defaultSuciKeyReloadInterval = 30 * time.Second
+
+// SuciKeyReloadInterval returns the configured interval of the home network key reload
+func SuciKeyReloadInterval(keys *factory.Keys) time.Duration {
+	if keys == nil || keys.ReloadInterval <= 0 {
+		return defaultSuciKeyReloadInterval
+	}
+	return time.Duration(keys.ReloadInterval) * time.Second
+}
+
+// StartSuciKeyReload reloads the home network keys at each interval until done is closed, so that
+// the keys replaced in their files or providers are used without restarting the UDM
+func StartSuciKeyReload(done <-chan struct{}, keys *factory.Keys, keyRing *context.SuciKeyRing,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if changed, err := reloadSuciKeys(keys, keyRing); err != nil {
+				logger.UtilLog.Errorf("failed to reload the home network keys, keeping the current keys: %+v", err)
+			} else if changed {
+				logger.UtilLog.Infoln("home network keys reloaded")
+			}
+		}
+	}
+}
+
+// reloadSuciKeys replaces the keys of the key ring if they changed. The key ring is left unchanged
+// when the keys cannot be loaded.
+func reloadSuciKeys(keys *factory.Keys, keyRing *context.SuciKeyRing) (bool, error) {
+	suciKeys, err := SuciKeysFromConfig(keys)
+	if err != nil {
+		return false, err
+	}
+	sort.Slice(suciKeys, func(i, j int) bool { return suciKeys[i].KeyID < suciKeys[j].KeyID })
+	if currentKeys := keyRing.Keys(); len(currentKeys) == len(suciKeys) &&
+		(len(suciKeys) == 0 || reflect.DeepEqual(currentKeys, suciKeys)) {
+		return false, nil
+	}
+	if err = keyRing.SetKeys(suciKeys); err != nil {
+		return false, err
+	}
+	return true, nil
+}
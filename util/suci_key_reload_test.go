@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/factory"
+)
+
+func TestReloadSuciKeys(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "hn-key")
+	writeKeyFile(t, keyFile, []byte("0a0b0c"))
+	keys := &factory.Keys{
+		HomeNetworkKeys: []factory.HomeNetworkKey{
+			{KeyId: 2, Scheme: "profileA", PrivateKey: "file://" + keyFile},
+			{KeyId: 1, Scheme: "profileA", PrivateKey: "0d0e0f"},
+		},
+	}
+	suciKeys, err := SuciKeysFromConfig(keys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyRing, err := context.NewSuciKeyRing(suciKeys)
+	if err != nil {
+		t.Fatalf("failed to create the key ring: %v", err)
+	}
+
+	if changed, err := reloadSuciKeys(keys, keyRing); err != nil || changed {
+		t.Errorf("expected the unchanged keys not to be reloaded, got %v, %v", changed, err)
+	}
+
+	writeKeyFile(t, keyFile, []byte("1a1b1c"))
+	if changed, err := reloadSuciKeys(keys, keyRing); err != nil || !changed {
+		t.Fatalf("expected the changed key to be reloaded, got %v, %v", changed, err)
+	}
+	if key, _ := keyRing.Key(2); key.PrivateKey != "1a1b1c" {
+		t.Errorf("expected the new private key, got %s", key.PrivateKey)
+	}
+
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatalf("failed to remove the key: %v", err)
+	}
+	if _, err := reloadSuciKeys(keys, keyRing); err == nil {
+		t.Errorf("expected an error for the missing key file")
+	}
+	if key, _ := keyRing.Key(2); key.PrivateKey != "1a1b1c" {
+		t.Errorf("expected the key ring to be unchanged, got %s", key.PrivateKey)
+	}
+}
+
+func TestStartSuciKeyReload(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "hn-key")
+	writeKeyFile(t, keyFile, []byte("0a0b0c"))
+	keys := &factory.Keys{UdmProfileAHNPrivateKey: "file://" + keyFile}
+	keyRing, err := context.NewSuciKeyRing(nil)
+	if err != nil {
+		t.Fatalf("failed to create the key ring: %v", err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		StartSuciKeyReload(done, keys, keyRing, 10*time.Millisecond)
+		close(stopped)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if key, ok := keyRing.Key(1); ok && key.PrivateKey == "0a0b0c" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the key to be loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(done)
+	<-stopped
+}
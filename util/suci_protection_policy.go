@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/factory"
+	"github.com/omec-project/udm/logger"
+)
+
+// StartSuciProtectionPolicyUpdate replaces the SUCI protection policy by the policies received from
+// the webconsole until done is closed. Invalid policies are ignored.
+func StartSuciProtectionPolicyUpdate(done <-chan struct{}, policyChan <-chan factory.SuciProtectionPolicy,
+	policy *context.SuciProtectionPolicy,
+) {
+	for {
+		select {
+		case <-done:
+			return
+		case newPolicy := <-policyChan:
+			defaultSchemes, rules, err := SuciProtectionPolicyFromConfig(&newPolicy)
+			if err != nil {
+				logger.UtilLog.Errorf("invalid SUCI protection policy, keeping the current policy: %+v", err)
+				continue
+			}
+			policy.Set(defaultSchemes, rules)
+			logger.UtilLog.Infof("SUCI protection policy updated: defaultSchemes%v rules[%d]", defaultSchemes,
+				len(rules))
+		}
+	}
+}
+
+// NewSuciProtectionPolicy returns the SUCI protection policy of the configuration. An invalid policy
+// is replaced by the default schemes, profileA and profileB, so that the SUCIs are restricted to the
+// protected schemes rather than all accepted or all rejected.
+func NewSuciProtectionPolicy(policy *factory.SuciProtectionPolicy) *context.SuciProtectionPolicy {
+	defaultSchemes, rules, err := SuciProtectionPolicyFromConfig(policy)
+	if err != nil {
+		logger.UtilLog.Errorf("invalid suciProtectionPolicy, using the default schemes: %+v", err)
+		defaultSchemes, rules = []string{context.ProfileAScheme, context.ProfileBScheme}, nil
+	}
+	logger.UtilLog.Infof("SUCI protection policy enabled: defaultSchemes%v rules[%d]", defaultSchemes, len(rules))
+	return context.NewSuciProtectionPolicy(defaultSchemes, rules)
+}
+
+// SuciProtectionPolicyFromConfig returns the default schemes and the rules of the SUCI protection
+// policy, the default schemes being profileA and profileB when not given
+func SuciProtectionPolicyFromConfig(policy *factory.SuciProtectionPolicy) ([]string, []context.SuciProtectionRule,
+	error,
+) {
+	defaultSchemes := []string{context.ProfileAScheme, context.ProfileBScheme}
+	if len(policy.DefaultSchemes) > 0 {
+		var err error
+		if defaultSchemes, err = suciSchemes(policy.DefaultSchemes); err != nil {
+			return nil, nil, fmt.Errorf("defaultSchemes: %w", err)
+		}
+	}
+	rules := make([]context.SuciProtectionRule, 0, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		if !isDigits(rule.Mcc, 3, 3) || !isDigits(rule.Mnc, 2, 3) {
+			return nil, nil, fmt.Errorf("rule %d: invalid PLMN ID %s-%s", i, rule.Mcc, rule.Mnc)
+		}
+		if rule.RoutingIndicator != "" && !isDigits(rule.RoutingIndicator, 1, 4) {
+			return nil, nil, fmt.Errorf("rule %d: invalid routing indicator %q", i, rule.RoutingIndicator)
+		}
+		schemes, err := suciSchemes(rule.Schemes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		rules = append(rules, context.SuciProtectionRule{
+			Mcc:              rule.Mcc,
+			Mnc:              rule.Mnc,
+			RoutingIndicator: rule.RoutingIndicator,
+			Schemes:          schemes,
+		})
+	}
+	return defaultSchemes, rules, nil
+}
+
+// SuciScheme returns the protection scheme of its name: null, profileA or profileB
+func SuciScheme(name string) (string, error) {
+	schemes, err := suciSchemes([]string{name})
+	if err != nil {
+		return "", err
+	}
+	return schemes[0], nil
+}
+
+func suciSchemes(names []string) ([]string, error) {
+	schemes := make([]string, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case "null", context.NullScheme:
+			schemes = append(schemes, context.NullScheme)
+		case "profilea", "a", context.ProfileAScheme:
+			schemes = append(schemes, context.ProfileAScheme)
+		case "profileb", "b", context.ProfileBScheme:
+			schemes = append(schemes, context.ProfileBScheme)
+		default:
+			return nil, fmt.Errorf("unsupported scheme %q", name)
+		}
+	}
+	return schemes, nil
+}
+
+func isDigits(s string, minLen, maxLen int) bool {
+	return len(s) >= minLen && len(s) <= maxLen && strings.Trim(s, "0123456789") == ""
+}
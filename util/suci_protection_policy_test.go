@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package util
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/omec-project/udm/context"
+	"github.com/omec-project/udm/factory"
+)
+
+func TestSuciProtectionPolicyFromConfig(t *testing.T) {
+	defaultSchemes, rules, err := SuciProtectionPolicyFromConfig(&factory.SuciProtectionPolicy{
+		Rules: []factory.SuciProtectionRule{
+			{Mcc: "001", Mnc: "01", Schemes: []string{"null", "profileA"}},
+			{Mcc: "208", Mnc: "93", RoutingIndicator: "0001", Schemes: []string{"B"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(defaultSchemes, []string{context.ProfileAScheme, context.ProfileBScheme}) {
+		t.Errorf("expected profileA and profileB by default, got %v", defaultSchemes)
+	}
+	expectedRules := []context.SuciProtectionRule{
+		{Mcc: "001", Mnc: "01", Schemes: []string{context.NullScheme, context.ProfileAScheme}},
+		{Mcc: "208", Mnc: "93", RoutingIndicator: "0001", Schemes: []string{context.ProfileBScheme}},
+	}
+	if !reflect.DeepEqual(rules, expectedRules) {
+		t.Errorf("expected %+v, got %+v", expectedRules, rules)
+	}
+
+	for _, invalid := range []factory.SuciProtectionPolicy{
+		{DefaultSchemes: []string{"profileC"}},
+		{Rules: []factory.SuciProtectionRule{{Mcc: "01", Mnc: "01", Schemes: []string{"null"}}}},
+		{Rules: []factory.SuciProtectionRule{{Mcc: "001", Mnc: "1", Schemes: []string{"null"}}}},
+		{Rules: []factory.SuciProtectionRule{{Mcc: "001", Mnc: "01", RoutingIndicator: "00001"}}},
+		{Rules: []factory.SuciProtectionRule{{Mcc: "001", Mnc: "01", Schemes: []string{"clear"}}}},
+	} {
+		if _, _, err = SuciProtectionPolicyFromConfig(&invalid); err == nil {
+			t.Errorf("expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestStartSuciProtectionPolicyUpdate(t *testing.T) {
+	const nullSuci = "suci-0-001-01-0000-0-0-0000000001"
+	policy := context.NewSuciProtectionPolicy([]string{context.ProfileAScheme}, nil)
+	policyChan := make(chan factory.SuciProtectionPolicy)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		StartSuciProtectionPolicyUpdate(done, policyChan, policy)
+		close(stopped)
+	}()
+
+	policyChan <- factory.SuciProtectionPolicy{DefaultSchemes: []string{"unknown"}}
+	policyChan <- factory.SuciProtectionPolicy{
+		Rules: []factory.SuciProtectionRule{{Mcc: "001", Mnc: "01", Schemes: []string{"null"}}},
+	}
+	close(done)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the update to stop")
+	}
+	if err := policy.Check(nullSuci); err != nil {
+		t.Errorf("expected the null scheme to be accepted for the test PLMN, got %v", err)
+	}
+}
+
+func TestNewSuciProtectionPolicy_Invalid(t *testing.T) {
+	policy := NewSuciProtectionPolicy(&factory.SuciProtectionPolicy{
+		DefaultSchemes: []string{"null"},
+		Rules:          []factory.SuciProtectionRule{{Mcc: "001", Mnc: "01", Schemes: []string{"clear"}}},
+	})
+	for _, suci := range []string{"suci-0-310-410-0000-1-1-0a0b", "suci-0-310-410-0000-2-2-0a0b"} {
+		if err := policy.Check(suci); err != nil {
+			t.Errorf("expected the protected SUCI %s to be accepted by the default schemes, got %v", suci, err)
+		}
+	}
+	if err := policy.Check("suci-0-001-01-0000-0-0-0000000001"); err == nil {
+		t.Error("expected the null-scheme SUCI to be rejected")
+	}
+}
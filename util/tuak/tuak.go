@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+// Package tuak implements the TUAK authentication and key generation functions f1, f1*, f2, f3, f4,
+// f5 and f5* of 3GPP TS 35.231, based on the Keccak permutation.
+package tuak
+
+import (
+	"fmt"
+)
+
+// AlgorithmName is the ALGONAME of TS 35.231
+const AlgorithmName = "TUAK1.0"
+
+// DefaultIterations is the number of Keccak iterations when not configured
+const DefaultIterations = 1
+
+const (
+	topLen  = 32
+	randLen = 16
+	sqnLen  = 6
+	amfLen  = 2
+	akLen   = 6
+)
+
+// INSTANCE values of TS 35.231, completed with the lengths of the outputs and of K
+const (
+	instanceTopc   byte = 0x00
+	instanceF1     byte = 0x00
+	instanceF1Star byte = 0x80
+	instanceF2345  byte = 0x40
+	instanceF5Star byte = 0xc0
+	instanceKey256 byte = 0x01
+	instanceCk256  byte = 0x04
+	instanceIk256  byte = 0x02
+)
+
+// ComputeTopc derives TOPc from TOP and the 128 or 256 bits subscriber key K
+func ComputeTopc(top, k []byte, iterations int) ([]byte, error) {
+	if len(top) != topLen {
+		return nil, fmt.Errorf("TOP is %d bytes, expected %d", len(top), topLen)
+	}
+	instance, err := keyInstance(instanceTopc, k)
+	if err != nil {
+		return nil, err
+	}
+	state := keccak(instance, top, nil, nil, nil, k, iterations)
+	topc := make([]byte, topLen)
+	pull(topc, state[0:])
+	return topc, nil
+}
+
+// F1 computes MAC-A (f1) and MAC-S (f1*) of 8, 16 or 32 bytes. The outputs left nil are not computed.
+func F1(topc, k, rand, sqn, amf, macA, macS []byte, iterations int) error {
+	if err := checkInputs(topc, rand); err != nil {
+		return err
+	}
+	if len(sqn) != sqnLen || len(amf) != amfLen {
+		return fmt.Errorf("SQN and AMF are %d and %d bytes, expected %d and %d", len(sqn), len(amf), sqnLen, amfLen)
+	}
+	for _, output := range []struct {
+		instance byte
+		mac      []byte
+	}{
+		{instance: instanceF1, mac: macA},
+		{instance: instanceF1Star, mac: macS},
+	} {
+		if output.mac == nil {
+			continue
+		}
+		macLenInstance, ok := lengthInstance(len(output.mac))
+		if !ok {
+			return fmt.Errorf("MAC is %d bytes, expected 8, 16 or 32", len(output.mac))
+		}
+		instance, err := keyInstance(output.instance|macLenInstance, k)
+		if err != nil {
+			return err
+		}
+		state := keccak(instance, topc, rand, amf, sqn, k, iterations)
+		pull(output.mac, state[0:])
+	}
+	return nil
+}
+
+// F2345 computes RES (f2) of 4, 8, 16 or 32 bytes, CK (f3) and IK (f4) of 16 or 32 bytes, AK (f5)
+// and AK* (f5*). AK and AK* are not computed when left nil.
+func F2345(topc, k, rand, res, ck, ik, ak, akStar []byte, iterations int) error {
+	if err := checkInputs(topc, rand); err != nil {
+		return err
+	}
+	if (ak != nil && len(ak) != akLen) || (akStar != nil && len(akStar) != akLen) {
+		return fmt.Errorf("AK and AK* are %d bytes", akLen)
+	}
+
+	resLenInstance, ok := lengthInstance(len(res))
+	if !ok && len(res) != 4 {
+		return fmt.Errorf("RES is %d bytes, expected 4, 8, 16 or 32", len(res))
+	}
+	instance := instanceF2345 | resLenInstance
+	switch len(ck) {
+	case 16:
+	case 32:
+		instance |= instanceCk256
+	default:
+		return fmt.Errorf("CK is %d bytes, expected 16 or 32", len(ck))
+	}
+	switch len(ik) {
+	case 16:
+	case 32:
+		instance |= instanceIk256
+	default:
+		return fmt.Errorf("IK is %d bytes, expected 16 or 32", len(ik))
+	}
+	instance, err := keyInstance(instance, k)
+	if err != nil {
+		return err
+	}
+	state := keccak(instance, topc, rand, nil, nil, k, iterations)
+	pull(res, state[0:])
+	pull(ck, state[32:])
+	pull(ik, state[64:])
+	if ak != nil {
+		pull(ak, state[96:])
+	}
+
+	if akStar != nil {
+		if instance, err = keyInstance(instanceF5Star, k); err != nil {
+			return err
+		}
+		state = keccak(instance, topc, rand, nil, nil, k, iterations)
+		pull(akStar, state[96:])
+	}
+	return nil
+}
+
+func checkInputs(topc, rand []byte) error {
+	if len(topc) != topLen {
+		return fmt.Errorf("TOPc is %d bytes, expected %d", len(topc), topLen)
+	}
+	if len(rand) != randLen {
+		return fmt.Errorf("RAND is %d bytes, expected %d", len(rand), randLen)
+	}
+	return nil
+}
+
+// keyInstance sets the bit of the INSTANCE telling the length of K
+func keyInstance(instance byte, k []byte) (byte, error) {
+	switch len(k) {
+	case 16:
+		return instance, nil
+	case 32:
+		return instance | instanceKey256, nil
+	default:
+		return 0, fmt.Errorf("K is %d bytes, expected 16 or 32", len(k))
+	}
+}
+
+// lengthInstance returns the bits of the INSTANCE telling the length of MAC or RES: 64, 128 and
+// 256 bits are encoded as 001, 010 and 100, the 32 bits of RES as 000
+func lengthInstance(length int) (byte, bool) {
+	switch length {
+	case 8:
+		return 0x08, true
+	case 16:
+		return 0x10, true
+	case 32:
+		return 0x20, true
+	default:
+		return 0, false
+	}
+}
+
+// keccak runs the permutation on the INOUT of TS 35.231, in which the inputs are stored with their
+// least significant byte first. Absent inputs are zero.
+func keccak(instance byte, top, rand, amf, sqn, k []byte, iterations int) *[200]byte {
+	var state [200]byte
+	push(state[0:32], top)
+	state[32] = instance
+	push(state[33:40], []byte(AlgorithmName))
+	push(state[40:56], rand)
+	push(state[56:58], amf)
+	push(state[58:64], sqn)
+	push(state[64:64+len(k)], k)
+	// padding of the 1088 bits rate
+	state[96] = 0x1f
+	state[135] = 0x80
+
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	for i := 0; i < iterations; i++ {
+		keccakF1600(&state)
+	}
+	return &state
+}
+
+func push(dst, src []byte) {
+	for i := range src {
+		dst[i] = src[len(src)-1-i]
+	}
+}
+
+func pull(dst, src []byte) {
+	for i := range dst {
+		dst[i] = src[len(dst)-1-i]
+	}
+}
@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2025 Canonical Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package tuak
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"encoding/hex"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %s: %v", s, err)
+	}
+	return b
+}
+
+// TestKeccakF1600 checks the permutation with SHA3-256, a single block sponge over it
+func TestKeccakF1600(t *testing.T) {
+	var state [200]byte
+	copy(state[:], "abc")
+	state[3] ^= 0x06
+	state[135] ^= 0x80
+	keccakF1600(&state)
+
+	expected := sha3.Sum256([]byte("abc"))
+	if !bytes.Equal(state[:32], expected[:]) {
+		t.Errorf("expected %x, got %x", expected, state[:32])
+	}
+}
+
+// tuakTestSet is a test set of TS 35.232, with the lengths of its outputs given by the expected values
+type tuakTestSet struct {
+	name       string
+	k          string
+	top        string
+	rand       string
+	sqn        string
+	amf        string
+	iterations int
+	topc       string
+	f1         string
+	f1Star     string
+	f2         string
+	f3         string
+	f4         string
+	f5         string
+	f5Star     string
+}
+
+var tuakTestSets = []tuakTestSet{
+	{
+		name:       "test set 1",
+		k:          "abababababababababababababababab",
+		top:        "5555555555555555555555555555555555555555555555555555555555555555",
+		rand:       "42424242424242424242424242424242",
+		sqn:        "111111111111",
+		amf:        "ffff",
+		iterations: 1,
+		topc:       "bd04d9530e87513c5d837ac2ad954623a8e2330c115305a73eb45d1f40cccbff",
+		f1:         "f9a54e6aeaa8618d",
+		f1Star:     "e94b4dc6c7297df3",
+		f2:         "657acd64",
+		f3:         "d71a1e5c6caffe986a26f783e5c78be1",
+		f4:         "be849fa2564f869aecee6f62d4337e72",
+		f5:         "719f1e9b9054",
+		f5Star:     "e7af6b3d0e38",
+	},
+}
+
+func TestTuak_TestSets(t *testing.T) {
+	for _, tc := range tuakTestSets {
+		t.Run(tc.name, func(t *testing.T) {
+			k := decodeHex(t, tc.k)
+			rand := decodeHex(t, tc.rand)
+			topc, err := ComputeTopc(decodeHex(t, tc.top), k, tc.iterations)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hex.EncodeToString(topc) != tc.topc {
+				t.Errorf("TOPc: expected %s, got %x", tc.topc, topc)
+			}
+
+			macA, macS := make([]byte, len(tc.f1)/2), make([]byte, len(tc.f1Star)/2)
+			err = F1(topc, k, rand, decodeHex(t, tc.sqn), decodeHex(t, tc.amf), macA, macS, tc.iterations)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			res, ck, ik := make([]byte, len(tc.f2)/2), make([]byte, len(tc.f3)/2), make([]byte, len(tc.f4)/2)
+			ak, akStar := make([]byte, len(tc.f5)/2), make([]byte, len(tc.f5Star)/2)
+			if err = F2345(topc, k, rand, res, ck, ik, ak, akStar, tc.iterations); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, output := range []struct {
+				name     string
+				expected string
+				actual   []byte
+			}{
+				{name: "f1", expected: tc.f1, actual: macA},
+				{name: "f1*", expected: tc.f1Star, actual: macS},
+				{name: "f2", expected: tc.f2, actual: res},
+				{name: "f3", expected: tc.f3, actual: ck},
+				{name: "f4", expected: tc.f4, actual: ik},
+				{name: "f5", expected: tc.f5, actual: ak},
+				{name: "f5*", expected: tc.f5Star, actual: akStar},
+			} {
+				if hex.EncodeToString(output.actual) != output.expected {
+					t.Errorf("%s: expected %s, got %x", output.name, output.expected, output.actual)
+				}
+			}
+		})
+	}
+}
+
+func reversed(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// shakeInOut returns the SHAKE256 output over the 96 bytes of INOUT before the padding, which is the
+// state after a single Keccak iteration
+func shakeInOut(instance byte, top, rand, amf, sqn, k []byte) []byte {
+	inOut := make([]byte, 96)
+	copy(inOut[0:32], reversed(top))
+	inOut[32] = instance
+	copy(inOut[33:40], reversed([]byte(AlgorithmName)))
+	copy(inOut[40:56], reversed(rand))
+	copy(inOut[56:58], reversed(amf))
+	copy(inOut[58:64], reversed(sqn))
+	copy(inOut[64:], reversed(k))
+	return sha3.SumSHAKE256(inOut, 136)
+}
+
+// TestTuak_Shake256 checks the outputs of every length, with K of 128 and 256 bits, against SHAKE256
+func TestTuak_Shake256(t *testing.T) {
+	top := decodeHex(t, "5555555555555555555555555555555555555555555555555555555555555555")
+	rand := decodeHex(t, "0123456789abcdef0123456789abcdef")
+	sqn, amf := decodeHex(t, "0123456789ab"), decodeHex(t, "abcd")
+
+	for _, k := range [][]byte{
+		decodeHex(t, "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0"),
+		decodeHex(t, "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0efeeedecebeae9e8e7e6e5e4e3e2e1e0"),
+	} {
+		var kInstance byte
+		if len(k) == 32 {
+			kInstance = 0x01
+		}
+		topc, err := ComputeTopc(top, k, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := reversed(shakeInOut(kInstance, top, nil, nil, nil, k)[:32]); !bytes.Equal(topc, expected) {
+			t.Errorf("TOPc with %d bytes K: expected %x, got %x", len(k), expected, topc)
+		}
+
+		for _, tc := range []struct {
+			macLen, resLen, ckLen, ikLen int
+			lengthInstance               byte
+		}{
+			{macLen: 8, resLen: 4, ckLen: 16, ikLen: 16, lengthInstance: 0x00},
+			{macLen: 8, resLen: 8, ckLen: 16, ikLen: 32, lengthInstance: 0x08},
+			{macLen: 16, resLen: 16, ckLen: 32, ikLen: 16, lengthInstance: 0x10},
+			{macLen: 32, resLen: 32, ckLen: 32, ikLen: 32, lengthInstance: 0x20},
+		} {
+			macA, macS := make([]byte, tc.macLen), make([]byte, tc.macLen)
+			if err = F1(topc, k, rand, sqn, amf, macA, macS, 1); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			res, ck, ik := make([]byte, tc.resLen), make([]byte, tc.ckLen), make([]byte, tc.ikLen)
+			ak, akStar := make([]byte, 6), make([]byte, 6)
+			if err = F2345(topc, k, rand, res, ck, ik, ak, akStar, 1); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			macLenInstance := tc.lengthInstance
+			if tc.macLen == 8 {
+				macLenInstance = 0x08
+			}
+			f2345Instance := 0x40 | tc.lengthInstance | kInstance
+			if tc.ckLen == 32 {
+				f2345Instance |= 0x04
+			}
+			if tc.ikLen == 32 {
+				f2345Instance |= 0x02
+			}
+			f1State := shakeInOut(macLenInstance|kInstance, topc, rand, amf, sqn, k)
+			f1StarState := shakeInOut(0x80|macLenInstance|kInstance, topc, rand, amf, sqn, k)
+			f2345State := shakeInOut(f2345Instance, topc, rand, nil, nil, k)
+			f5StarState := shakeInOut(0xc0|kInstance, topc, rand, nil, nil, k)
+			for _, output := range []struct {
+				name     string
+				expected []byte
+				actual   []byte
+			}{
+				{name: "f1", expected: f1State[:tc.macLen], actual: macA},
+				{name: "f1*", expected: f1StarState[:tc.macLen], actual: macS},
+				{name: "f2", expected: f2345State[:tc.resLen], actual: res},
+				{name: "f3", expected: f2345State[32 : 32+tc.ckLen], actual: ck},
+				{name: "f4", expected: f2345State[64 : 64+tc.ikLen], actual: ik},
+				{name: "f5", expected: f2345State[96:102], actual: ak},
+				{name: "f5*", expected: f5StarState[96:102], actual: akStar},
+			} {
+				if expected := reversed(output.expected); !bytes.Equal(output.actual, expected) {
+					t.Errorf("%s with %d bytes K, %+v: expected %x, got %x", output.name, len(k), tc, expected,
+						output.actual)
+				}
+			}
+		}
+	}
+}
+
+func TestTuak_OutputLengthsAndIterations(t *testing.T) {
+	k := bytes.Repeat([]byte{0xab}, 32)
+	top := bytes.Repeat([]byte{0x55}, 32)
+	rand := bytes.Repeat([]byte{0x42}, 16)
+
+	topc1, err := ComputeTopc(top, k, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	topc2, err := ComputeTopc(top, k, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Equal(topc1, topc2) {
+		t.Errorf("expected the Keccak iterations to change TOPc")
+	}
+	sqn, amf := make([]byte, 6), make([]byte, 2)
+	mac1, mac2 := make([]byte, 32), make([]byte, 32)
+	res1, res2 := make([]byte, 32), make([]byte, 32)
+	for iterations, outputs := range map[int][2][]byte{1: {mac1, res1}, 2: {mac2, res2}} {
+		if err = F1(topc1, k, rand, sqn, amf, outputs[0], nil, iterations); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err = F2345(topc1, k, rand, outputs[1], make([]byte, 32), make([]byte, 32), nil, nil, iterations); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if bytes.Equal(mac1, mac2) || bytes.Equal(res1, res2) {
+		t.Errorf("expected the Keccak iterations to change MAC and RES")
+	}
+
+	// the lengths of the outputs are bound in the INSTANCE, a longer output does not extend a shorter one
+	res8, res16 := make([]byte, 8), make([]byte, 16)
+	if err = F2345(topc1, k, rand, res8, make([]byte, 16), make([]byte, 16), nil, nil, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = F2345(topc1, k, rand, res16, make([]byte, 16), make([]byte, 16), nil, nil, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Equal(res8, res16[len(res16)-8:]) {
+		t.Errorf("expected RES of different lengths to be computed independently")
+	}
+
+	for name, err := range map[string]error{
+		"TOP length":  func() error { _, err := ComputeTopc(top[:16], k, 1); return err }(),
+		"K length":    func() error { _, err := ComputeTopc(top, k[:20], 1); return err }(),
+		"MAC length":  F1(topc1, k, rand, sqn, amf, make([]byte, 4), nil, 1),
+		"SQN length":  F1(topc1, k, rand, sqn[:4], amf, make([]byte, 8), nil, 1),
+		"RAND length": F1(topc1, k, rand[:8], sqn, amf, make([]byte, 8), nil, 1),
+		"RES length":  F2345(topc1, k, rand, make([]byte, 2), make([]byte, 16), make([]byte, 16), nil, nil, 1),
+		"CK length":   F2345(topc1, k, rand, make([]byte, 8), make([]byte, 8), make([]byte, 16), nil, nil, 1),
+		"AK length":   F2345(topc1, k, rand, make([]byte, 8), make([]byte, 16), make([]byte, 16), make([]byte, 4), nil, 1),
+	} {
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}